@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/token"
 	"github.com/gin-gonic/gin"
 )
 
@@ -59,7 +60,8 @@ func (server *Server) upsertAccountAlert(ctx *gin.Context) {
 		return
 	}
 
-	alert, err := server.store.UpsertAccountAlert(ctx, db.UpsertAccountAlertParams{
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	alert, err := server.store.UpsertAccountAlertTx(auditContext(ctx, authPayload.Username, authPayload.Role), db.UpsertAccountAlertParams{
 		AccountID:            uri.AccountID,
 		LowBalanceThreshold:  req.LowBalanceThreshold,
 		HighBalanceThreshold: req.HighBalanceThreshold,
@@ -9,7 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func (server *Server) getOwnedAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+func (server *Server) getAccountByID(ctx *gin.Context, accountID int64) (db.Account, bool) {
 	account, err := server.store.GetAccount(ctx, accountID)
 	if err != nil {
 		if errors.Is(err, db.ErrRecordNotFound) {
@@ -21,6 +21,15 @@ func (server *Server) getOwnedAccount(ctx *gin.Context, accountID int64) (db.Acc
 		return account, false
 	}
 
+	return account, true
+}
+
+func (server *Server) getOwnedAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, ok := server.getAccountByID(ctx, accountID)
+	if !ok {
+		return account, false
+	}
+
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if account.Owner != authPayload.Username {
 		err := errors.New("account doesn't belong to the authenticated user")
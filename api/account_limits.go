@@ -5,7 +5,10 @@ import (
 	"net/http"
 
 	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type accountIDUri struct {
@@ -13,7 +16,8 @@ type accountIDUri struct {
 }
 
 type upsertAccountLimitRequest struct {
-	DailyTransferLimit int64 `json:"daily_transfer_limit" binding:"required,min=0"`
+	DailyTransferLimit int64  `json:"daily_transfer_limit" binding:"required,min=0"`
+	OverdraftLimit     *int64 `json:"overdraft_limit" binding:"omitempty,min=0"`
 }
 
 func (server *Server) getAccountLimit(ctx *gin.Context) {
@@ -53,14 +57,37 @@ func (server *Server) upsertAccountLimit(ctx *gin.Context) {
 		return
 	}
 
-	if _, ok := server.getOwnedAccount(ctx, uri.AccountID); !ok {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// bankers manage the limits of any account, depositors only those of their own
+	getAccount := server.getOwnedAccount
+	if authPayload.Role == util.BankerRole {
+		getAccount = server.getAccountByID
+	}
+	if _, ok := getAccount(ctx, uri.AccountID); !ok {
 		return
 	}
 
-	limit, err := server.store.UpsertAccountLimit(ctx, db.UpsertAccountLimitParams{
+	arg := db.UpsertAccountLimitParams{
 		AccountID:          uri.AccountID,
 		DailyTransferLimit: req.DailyTransferLimit,
-	})
+	}
+
+	// an overdraft is credit extended by the bank, so only bankers may grant it
+	if req.OverdraftLimit != nil {
+		if authPayload.Role != util.BankerRole {
+			err := errors.New("only bankers can set the overdraft limit")
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		arg.OverdraftLimit = pgtype.Int8{
+			Int64: *req.OverdraftLimit,
+			Valid: true,
+		}
+	}
+
+	limit, err := server.store.UpsertAccountLimitTx(auditContext(ctx, authPayload.Username, authPayload.Role), arg)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+type accountStatusUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type updateAccountStatusRequest struct {
+	Reason string `json:"reason" binding:"required,max=200"`
+}
+
+// closeAccount closes an account of the authenticated user.
+// Accounts are never deleted, since their entries and transfers refer to them.
+func (server *Server) closeAccount(ctx *gin.Context) {
+	var uri accountStatusUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getOwnedAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	server.setAccountStatus(ctx, authPayload, db.UpdateAccountStatusTxParams{
+		AccountID: uri.ID,
+		Status:    db.AccountStatusClosed,
+		Action:    db.AuditActionAccountClose,
+	})
+}
+
+func (server *Server) freezeAccount(ctx *gin.Context) {
+	server.updateAccountStatus(ctx, db.AccountStatusFrozen, db.AuditActionAccountFreeze)
+}
+
+func (server *Server) unfreezeAccount(ctx *gin.Context) {
+	server.updateAccountStatus(ctx, db.AccountStatusActive, db.AuditActionAccountUnfreeze)
+}
+
+// updateAccountStatus lets a banker change the status of any account
+func (server *Server) updateAccountStatus(ctx *gin.Context, status string, action string) {
+	var uri accountStatusUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateAccountStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if authPayload.Role != util.BankerRole {
+		err := errors.New("only bankers can freeze and unfreeze accounts")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	server.setAccountStatus(ctx, authPayload, db.UpdateAccountStatusTxParams{
+		AccountID: uri.ID,
+		Status:    status,
+		Action:    action,
+		Reason:    req.Reason,
+	})
+}
+
+func (server *Server) setAccountStatus(ctx *gin.Context, authPayload *token.Payload, arg db.UpdateAccountStatusTxParams) {
+	result, err := server.store.UpdateAccountStatusTx(auditContext(ctx, authPayload.Username, authPayload.Role), arg)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrSystemAccount) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrAccountStatusUnchanged) ||
+			errors.Is(err, db.ErrInvalidAccountStatusTransition) ||
+			errors.Is(err, db.ErrAccountBalanceNotZero) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result.Account)
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"context"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+// auditContext returns the context of the request carrying the user and the client
+// as the actor of the audit events the store records.
+// gin.Context does not look up values of the request context, so the store has to be given this one.
+// An empty username leaves the actor to the store, for requests made before logging in.
+func auditContext(ctx *gin.Context, username string, role string) context.Context {
+	return db.WithAuditActor(ctx.Request.Context(), db.AuditActor{
+		Username:  username,
+		Role:      role,
+		ClientIP:  ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	})
+}
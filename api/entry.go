@@ -5,6 +5,7 @@ import (
 	"time"
 
 	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,13 +14,22 @@ type listEntriesUri struct {
 }
 
 type listEntriesQuery struct {
-	PageID   int32  `form:"page_id" binding:"required,min=1"`
-	PageSize int32  `form:"page_size" binding:"required,min=5,max=50"`
-	Min      string `form:"min_amount"`
-	Max      string `form:"max_amount"`
-	From     string `form:"from_date"`
-	To       string `form:"to_date"`
-	Sort     string `form:"sort"`
+	PageID    int32  `form:"page_id" binding:"omitempty,min=1"`
+	PageToken string `form:"page_token"`
+	PageSize  int32  `form:"page_size" binding:"required,min=5,max=50"`
+	Min       string `form:"min_amount"`
+	Max       string `form:"max_amount"`
+	From      string `form:"from_date"`
+	To        string `form:"to_date"`
+	Sort      string `form:"sort"`
+	// IncludeJournals adds the journal transactions of the entries, with only the entries of this account in them
+	IncludeJournals bool `form:"include_journals"`
+}
+
+type listEntriesResponse struct {
+	Entries       []db.Entry   `json:"entries"`
+	NextPageToken string       `json:"next_page_token,omitempty"`
+	Journals      []db.Journal `json:"journals,omitempty"`
 }
 
 func (server *Server) listEntries(ctx *gin.Context) {
@@ -35,6 +45,11 @@ func (server *Server) listEntries(ctx *gin.Context) {
 		return
 	}
 
+	if q.PageID > 0 && q.PageToken != "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errPageIDWithPageToken))
+		return
+	}
+
 	if _, ok := server.getOwnedAccount(ctx, uri.AccountID); !ok {
 		return
 	}
@@ -75,11 +90,17 @@ func (server *Server) listEntries(ctx *gin.Context) {
 		return
 	}
 
+	if q.PageID == 0 {
+		server.listEntriesPage(ctx, uri.AccountID, q, minAmount, maxAmount, fromTime, toTime, sortOrder == "asc")
+		return
+	}
+
 	limit := q.PageSize
 	offset := (q.PageID - 1) * q.PageSize
 
+	var entries []db.Entry
 	if sortOrder == "asc" {
-		entries, err := server.store.ListEntriesFilteredAsc(ctx, db.ListEntriesFilteredAscParams{
+		entries, err = server.store.ListEntriesFilteredAsc(ctx, db.ListEntriesFilteredAscParams{
 			AccountID:   uri.AccountID,
 			Amount:      minAmount,
 			Amount_2:    maxAmount,
@@ -88,27 +109,98 @@ func (server *Server) listEntries(ctx *gin.Context) {
 			Limit:       limit,
 			Offset:      offset,
 		})
+	} else {
+		entries, err = server.store.ListEntriesFilteredDesc(ctx, db.ListEntriesFilteredDescParams{
+			AccountID:   uri.AccountID,
+			Amount:      minAmount,
+			Amount_2:    maxAmount,
+			CreatedAt:   fromTime,
+			CreatedAt_2: toTime,
+			Limit:       limit,
+			Offset:      offset,
+		})
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := listEntriesResponse{
+		Entries: entries,
+	}
+	if q.IncludeJournals {
+		rsp.Journals, err = db.ListAccountJournals(ctx, server.store, uri.AccountID, db.EntryJournalIDs(entries))
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 			return
 		}
-		ctx.JSON(http.StatusOK, entries)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// listEntriesPage lists entries after the position in the page token, ordered by (created_at, id),
+// so rows added while paging never shift the following pages
+func (server *Server) listEntriesPage(
+	ctx *gin.Context,
+	accountID int64,
+	q listEntriesQuery,
+	minAmount int64,
+	maxAmount int64,
+	fromTime time.Time,
+	toTime time.Time,
+	ascending bool,
+) {
+	cursor, err := util.PageCursor(q.PageToken, ascending, fromTime, toTime)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	entries, err := server.store.ListEntriesFilteredDesc(ctx, db.ListEntriesFilteredDescParams{
-		AccountID:   uri.AccountID,
-		Amount:      minAmount,
-		Amount_2:    maxAmount,
-		CreatedAt:   fromTime,
-		CreatedAt_2: toTime,
-		Limit:       limit,
-		Offset:      offset,
-	})
+	// one more row than the page size tells whether there is a next page
+	arg := db.ListEntriesKeysetDescParams{
+		AccountID:       accountID,
+		MinAmount:       minAmount,
+		MaxAmount:       maxAmount,
+		FromTime:        fromTime,
+		ToTime:          toTime,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		Limit:           q.PageSize + 1,
+	}
+
+	var entries []db.Entry
+	if ascending {
+		entries, err = server.store.ListEntriesKeysetAsc(ctx, db.ListEntriesKeysetAscParams(arg))
+	} else {
+		entries, err = server.store.ListEntriesKeysetDesc(ctx, arg)
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, entries)
+	rsp := listEntriesResponse{
+		Entries: make([]db.Entry, 0, len(entries)),
+	}
+	if len(entries) > int(q.PageSize) {
+		entries = entries[:q.PageSize]
+		last := entries[len(entries)-1]
+		rsp.NextPageToken = util.EncodePageToken(util.PageToken{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+			Ascending: ascending,
+		})
+	}
+	rsp.Entries = append(rsp.Entries, entries...)
+
+	if q.IncludeJournals {
+		rsp.Journals, err = db.ListAccountJournals(ctx, server.store, accountID, db.EntryJournalIDs(rsp.Entries))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
 }
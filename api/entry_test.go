@@ -0,0 +1,259 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListEntriesAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	pageSize := 5
+	entries := make([]db.Entry, pageSize+1)
+	for i := range entries {
+		entries[i] = db.Entry{
+			ID:        int64(100 - i),
+			AccountID: account.ID,
+			Amount:    util.RandomMoney(),
+			CreatedAt: time.Now().Add(-time.Duration(i) * time.Minute).UTC().Truncate(time.Microsecond),
+			// every entry is one side of its own transfer
+			JournalTransactionID: pgtype.Int8{Int64: int64(200 + i), Valid: true},
+		}
+	}
+	last := entries[pageSize-1]
+
+	journalIDs := make([]int64, pageSize)
+	for i := range journalIDs {
+		journalIDs[i] = entries[i].JournalTransactionID.Int64
+	}
+	journal := db.JournalTransaction{
+		ID:         journalIDs[0],
+		Kind:       db.JournalKindTransfer,
+		TransferID: pgtype.Int8{Int64: 7, Valid: true},
+		CreatedAt:  entries[0].CreatedAt,
+	}
+	otherSide := db.Entry{
+		ID:                   300,
+		AccountID:            account.ID + 1,
+		Amount:               -entries[0].Amount,
+		CreatedAt:            entries[0].CreatedAt,
+		JournalTransactionID: entries[0].JournalTransactionID,
+	}
+	buildJournalStubs := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			ListJournalTransactionsByIDs(gomock.Any(), gomock.Eq(journalIDs)).
+			Times(1).
+			Return([]db.JournalTransaction{journal}, nil)
+		store.EXPECT().
+			ListEntriesByJournalTransactions(gomock.Any(), gomock.Eq(journalIDs)).
+			Times(1).
+			Return([]db.Entry{entries[0], otherSide}, nil)
+	}
+	// the other side of a transaction belongs to another account, which is not disclosed
+	requireJournals := func(t *testing.T, journals []db.Journal) {
+		require.Equal(t, []db.Journal{
+			{JournalTransaction: journal, Entries: []db.Entry{entries[0]}},
+		}, journals)
+	}
+	nextPageToken := util.EncodePageToken(util.PageToken{CreatedAt: last.CreatedAt, ID: last.ID})
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "FirstPage",
+			query: url.Values{"page_size": {fmt.Sprint(pageSize)}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesFilteredDesc(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					ListEntriesKeysetDesc(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListEntriesKeysetDescParams) ([]db.Entry, error) {
+						require.Equal(t, int32(pageSize+1), arg.Limit)
+						require.Equal(t, arg.ToTime, arg.CursorCreatedAt)
+						return entries, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decodeEntriesPage(t, recorder.Body)
+				require.Len(t, rsp.Entries, pageSize)
+				require.Equal(t, nextPageToken, rsp.NextPageToken)
+			},
+		},
+		{
+			name: "NextPage",
+			query: url.Values{
+				"page_size":  {fmt.Sprint(pageSize)},
+				"page_token": {nextPageToken},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListEntriesKeysetDesc(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListEntriesKeysetDescParams) ([]db.Entry, error) {
+						require.True(t, last.CreatedAt.Equal(arg.CursorCreatedAt))
+						require.Equal(t, last.ID, arg.CursorID)
+						return entries[pageSize:], nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decodeEntriesPage(t, recorder.Body)
+				require.Len(t, rsp.Entries, 1)
+				require.Empty(t, rsp.NextPageToken)
+			},
+		},
+		{
+			name: "TokenForOtherSortOrder",
+			query: url.Values{
+				"page_size":  {fmt.Sprint(pageSize)},
+				"page_token": {nextPageToken},
+				"sort":       {"asc"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesKeysetAsc(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "PageIDWithPageToken",
+			query: url.Values{
+				"page_id":    {"1"},
+				"page_size":  {fmt.Sprint(pageSize)},
+				"page_token": {nextPageToken},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "PageID",
+			query: url.Values{
+				"page_id":   {"2"},
+				"page_size": {fmt.Sprint(pageSize)},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesKeysetDesc(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					ListEntriesFilteredDesc(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListEntriesFilteredDescParams) ([]db.Entry, error) {
+						require.Equal(t, int32(pageSize), arg.Offset)
+						return entries[:pageSize], nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decodeEntriesPage(t, recorder.Body)
+				require.Len(t, rsp.Entries, pageSize)
+				require.Empty(t, rsp.NextPageToken)
+				require.Empty(t, rsp.Journals)
+			},
+		},
+		{
+			name: "IncludeJournals",
+			query: url.Values{
+				"page_size":        {fmt.Sprint(pageSize)},
+				"include_journals": {"true"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListEntriesKeysetDesc(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(entries, nil)
+				buildJournalStubs(store)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decodeEntriesPage(t, recorder.Body)
+				require.Len(t, rsp.Entries, pageSize)
+				requireJournals(t, rsp.Journals)
+			},
+		},
+		{
+			name: "PageIDIncludeJournals",
+			query: url.Values{
+				"page_id":          {"1"},
+				"page_size":        {fmt.Sprint(pageSize)},
+				"include_journals": {"true"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListEntriesFilteredDesc(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(entries[:pageSize], nil)
+				buildJournalStubs(store)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decodeEntriesPage(t, recorder.Body)
+				require.Len(t, rsp.Entries, pageSize)
+				requireJournals(t, rsp.Journals)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/entries?%s", account.ID, tc.query.Encode())
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func decodeEntriesPage(t *testing.T, body io.Reader) listEntriesResponse {
+	var rsp listEntriesResponse
+	err := json.NewDecoder(body).Decode(&rsp)
+	require.NoError(t, err)
+	return rsp
+}
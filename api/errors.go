@@ -3,6 +3,15 @@ package api
 import "errors"
 
 var (
-	errInvalidAmountRange = errors.New("invalid amount range")
-	errInvalidDateRange   = errors.New("invalid date range")
+	errInvalidAmountRange   = errors.New("invalid amount range")
+	errInvalidDateRange     = errors.New("invalid date range")
+	errPageIDWithPageToken  = errors.New("page_id and page_token cannot be used together")
+	errIncorrectCode        = errors.New("incorrect code")
+	errIncorrectCredentials = errors.New("incorrect username or password")
+	errTOTPAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	errTOTPNotEnabled       = errors.New("two-factor authentication is not enabled")
+	errTOTPNotEnrolled      = errors.New("TOTP is not enrolled")
+	errEmailAlreadyVerified = errors.New("email is already verified")
+	errEmailNotVerified     = errors.New("email address is not verified")
+	errVerifyEmailCooldown  = errors.New("a verification email was sent recently")
 )
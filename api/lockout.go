@@ -0,0 +1,116 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/lockout"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/worker"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+)
+
+// reserveLoginAttempt counts the login attempt as failed until it succeeds.
+// It writes a 429 response with a Retry-After header and returns false
+// if the username or client IP must wait before trying to log in again.
+func (server *Server) reserveLoginAttempt(ctx *gin.Context, username string) (lockout.Reservation, bool) {
+	reservation, err := server.loginGuard.Reserve(ctx, username, ctx.ClientIP())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return reservation, false
+	}
+
+	return reservation, server.checkReservation(ctx, username, reservation)
+}
+
+// reserveOTPAttempt counts the one-time password sent with a login challenge as failed until it succeeds.
+// It writes a 429 response if the username or client IP must wait before trying again,
+// or a 401 response if the challenge has no attempts left, and returns false.
+func (server *Server) reserveOTPAttempt(ctx *gin.Context, challengePayload *token.Payload) (lockout.Reservation, bool) {
+	username := challengePayload.Username
+	reservation, err := server.loginGuard.ReserveChallenge(ctx, username, challengePayload.ID.String(), ctx.ClientIP())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return reservation, false
+	}
+
+	if !server.checkReservation(ctx, username, reservation) {
+		return reservation, false
+	}
+
+	if reservation.ChallengeSpent {
+		server.notifyLoginLockout(ctx, username, reservation)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(lockout.ErrChallengeSpent))
+		return reservation, false
+	}
+	return reservation, true
+}
+
+// checkReservation writes a 429 response with a Retry-After header and returns false
+// if the reserved attempt must wait
+func (server *Server) checkReservation(ctx *gin.Context, username string, reservation lockout.Reservation) bool {
+	if reservation.Wait <= 0 {
+		return true
+	}
+
+	// the attempt is counted even though it is turned away, so it may be the one that locks out the user
+	server.notifyLoginLockout(ctx, username, reservation)
+
+	tooManyAttemptsResponse(ctx, reservation.Wait)
+	return false
+}
+
+// reserveCodeAttempt counts a code sent by the user to change their two-factor authentication as incorrect until it is correct.
+// It writes a 429 response with a Retry-After header and returns false if the user must wait before sending another.
+func (server *Server) reserveCodeAttempt(ctx *gin.Context, username string) bool {
+	reservation, err := server.loginGuard.ReserveCode(ctx, username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if reservation.Wait > 0 {
+		tooManyAttemptsResponse(ctx, reservation.Wait)
+		return false
+	}
+	return true
+}
+
+// tooManyAttemptsResponse writes a 429 response with a Retry-After header telling when to try again
+func tooManyAttemptsResponse(ctx *gin.Context, wait time.Duration) {
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	ctx.JSON(http.StatusTooManyRequests, errorResponse(lockout.ErrTooManyAttempts))
+}
+
+// rejectLogin notifies an existing user if the failed attempt locks them out,
+// and writes the same response whether the username exists or not
+func (server *Server) rejectLogin(ctx *gin.Context, username string, reservation lockout.Reservation) {
+	server.notifyLoginLockout(ctx, username, reservation)
+	ctx.JSON(http.StatusUnauthorized, errorResponse(errIncorrectCredentials))
+}
+
+// notifyLoginLockout tells the user when their failed login attempt locked them out.
+// Nobody is told about a username that doesn't exist.
+func (server *Server) notifyLoginLockout(ctx *gin.Context, username string, reservation lockout.Reservation) {
+	if !reservation.LockedOut || server.taskDistributor == nil {
+		return
+	}
+
+	if _, err := server.store.GetUser(ctx, username); err != nil {
+		return
+	}
+
+	_ = server.taskDistributor.DistributeTaskSendLoginLockout(
+		ctx,
+		&worker.PayloadSendLoginLockout{
+			Username:    username,
+			ClientIP:    ctx.ClientIP(),
+			LockedUntil: time.Now().Add(lockout.LockoutDuration),
+		},
+		asynq.MaxRetry(10),
+		asynq.Queue(worker.QueueCritical),
+	)
+}
@@ -1,13 +1,17 @@
 package api
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"testing"
 	"time"
 
 	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/token"
 	"github.com/Ian-Balijawa/simplebank/util"
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -20,11 +24,47 @@ func newTestServer(t *testing.T, store db.Store) *Server {
 	server, err := NewServer(config, store)
 	require.NoError(t, err)
 
+	// the routes capture the checker, so they are set up again
+	server.tokenChecker = allowAllTokens{}
+	require.NoError(t, server.setupRouter())
+
 	return server
 }
 
+// allowAllTokens skips the revocation check of access tokens,
+// which is covered by the tests of the auth middleware
+type allowAllTokens struct{}
+
+func (allowAllTokens) Check(ctx context.Context, payload *token.Payload) error {
+	return nil
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 
 	os.Exit(m.Run())
 }
+
+type eqAuditActorMatcher struct {
+	username string
+	role     string
+}
+
+func (expected eqAuditActorMatcher) Matches(x interface{}) bool {
+	ctx, ok := x.(context.Context)
+	if !ok {
+		return false
+	}
+
+	actor, ok := db.AuditActorFromContext(ctx)
+	return ok && actor.Username == expected.username && actor.Role == expected.role
+}
+
+func (expected eqAuditActorMatcher) String() string {
+	return fmt.Sprintf("context with audit actor %s (%s)", expected.username, expected.role)
+}
+
+// eqAuditActor matches a context carrying the given user as the actor of its audit events
+func eqAuditActor(username string, role string) gomock.Matcher {
+	return eqAuditActorMatcher{username, role}
+}
@@ -4,8 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/Ian-Balijawa/simplebank/ratelimit"
+	"github.com/Ian-Balijawa/simplebank/revocation"
 	"github.com/Ian-Balijawa/simplebank/token"
 	"github.com/gin-gonic/gin"
 )
@@ -17,7 +20,7 @@ const (
 )
 
 // AuthMiddleware creates a gin middleware for authorization
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+func authMiddleware(tokenMaker token.Maker, tokenChecker revocation.Checker) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 
@@ -48,7 +51,65 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 			return
 		}
 
+		err = tokenChecker.Check(ctx, payload)
+		if err != nil {
+			if errors.Is(err, revocation.ErrTokenRevoked) {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
 		ctx.Set(authorizationPayloadKey, payload)
 		ctx.Next()
 	}
 }
+
+// rateLimitClasses are the routes with stricter limits than the default
+var rateLimitClasses = map[string]string{
+	"/users/login":                   ratelimit.ClassLogin,
+	"/users/login/otp":               ratelimit.ClassLogin,
+	"/users/password_reset/request":  ratelimit.ClassLogin,
+	"/users/password_reset":          ratelimit.ClassLogin,
+	"/users/verify_email/resend":     ratelimit.ClassLogin,
+	"/transfers":                     ratelimit.ClassTransfer,
+	"/transfers/pending/:id/confirm": ratelimit.ClassTransfer,
+	"/transfers/:id/reverse":         ratelimit.ClassTransfer,
+}
+
+// rateLimitMiddleware creates a gin middleware limiting the requests of each user,
+// or of each client IP for the requests without a valid access token
+func rateLimitMiddleware(limiter ratelimit.Limiter, policy ratelimit.Policy, tokenMaker token.Maker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		class, ok := rateLimitClasses[ctx.FullPath()]
+		if !ok {
+			class = ratelimit.ClassDefault
+		}
+
+		var username string
+		fields := strings.Fields(ctx.GetHeader(authorizationHeaderKey))
+		if len(fields) == 2 && strings.ToLower(fields[0]) == authorizationTypeBearer {
+			if payload, err := tokenMaker.VerifyToken(fields[1], token.TokenTypeAccessToken); err == nil {
+				username = payload.Username
+			}
+		}
+
+		key := ratelimit.Key(class, username, ctx.ClientIP())
+		result, err := limiter.Allow(ctx, key, policy.Rule(class))
+		if err != nil {
+			// the requests are let through rather than failing them all while the backend is down
+			_ = ctx.Error(err)
+			ctx.Next()
+			return
+		}
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(result.RetryAfter)))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponse(ratelimit.ErrRateLimited))
+			return
+		}
+
+		ctx.Next()
+	}
+}
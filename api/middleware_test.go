@@ -1,15 +1,22 @@
 package api
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/ratelimit"
+	"github.com/Ian-Balijawa/simplebank/revocation"
 	"github.com/Ian-Balijawa/simplebank/token"
 	"github.com/Ian-Balijawa/simplebank/util"
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -22,7 +29,7 @@ func addAuthorization(
 	role string,
 	duration time.Duration,
 ) {
-	token, payload, err := tokenMaker.CreateToken(username, role, duration, token.TokenTypeAccessToken)
+	token, payload, err := tokenMaker.CreateToken(username, role, uuid.New(), duration, token.TokenTypeAccessToken)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
@@ -93,7 +100,7 @@ func TestAuthMiddleware(t *testing.T) {
 			authPath := "/auth"
 			server.router.GET(
 				authPath,
-				authMiddleware(server.tokenMaker),
+				authMiddleware(server.tokenMaker, server.tokenChecker),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{})
 				},
@@ -109,3 +116,202 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthMiddlewareRevocation(t *testing.T) {
+	username := util.RandomOwner()
+	role := util.DepositorRole
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				status := db.GetSessionStatusRow{Username: username, Role: role, ExpiresAt: time.Now().Add(time.Hour)}
+				// the status is cached, so the second request does not read the session again
+				store.EXPECT().GetSessionStatus(gomock.Any(), gomock.Any()).Times(1).Return(status, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "BlockedSession",
+			buildStubs: func(store *mockdb.MockStore) {
+				status := db.GetSessionStatusRow{Username: username, Role: role, IsBlocked: true, ExpiresAt: time.Now().Add(time.Hour)}
+				store.EXPECT().GetSessionStatus(gomock.Any(), gomock.Any()).Times(1).Return(status, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "RoleChanged",
+			buildStubs: func(store *mockdb.MockStore) {
+				status := db.GetSessionStatusRow{Username: username, Role: util.BankerRole, ExpiresAt: time.Now().Add(time.Hour)}
+				store.EXPECT().GetSessionStatus(gomock.Any(), gomock.Any()).Times(1).Return(status, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SessionNotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetSessionStatus(gomock.Any(), gomock.Any()).Times(1).Return(db.GetSessionStatusRow{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetSessionStatus(gomock.Any(), gomock.Any()).Times(2).Return(db.GetSessionStatusRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.tokenChecker = revocation.NewCache(store, time.Minute)
+
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.tokenMaker, server.tokenChecker),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			request, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, role, time.Minute)
+
+			for j := 0; j < 2; j++ {
+				recorder := httptest.NewRecorder()
+				server.router.ServeHTTP(recorder, request)
+				tc.checkResponse(t, recorder)
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.rateLimitPolicy = ratelimit.Policy{
+		ratelimit.ClassDefault: {Limit: 2, Period: time.Minute},
+		ratelimit.ClassLogin:   {Limit: 1, Period: time.Minute},
+	}
+	require.NoError(t, server.setupRouter())
+
+	limitedPath := "/limited"
+	server.router.GET(limitedPath, func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{})
+	})
+
+	serve := func(method string, path string, setupAuth func(request *http.Request)) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(method, path, nil)
+		require.NoError(t, err)
+		request.RemoteAddr = "10.0.0.1:54321"
+
+		setupAuth(request)
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	noAuth := func(request *http.Request) {}
+
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusOK, serve(http.MethodGet, limitedPath, noAuth).Code)
+	}
+
+	recorder := serve(http.MethodGet, limitedPath, noAuth)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "30", recorder.Header().Get("Retry-After"))
+
+	// an authenticated user has a bucket of their own
+	withAuth := func(request *http.Request) {
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, util.RandomOwner(), util.DepositorRole, time.Minute)
+	}
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, limitedPath, withAuth).Code)
+
+	// an invalid token does not escape the limit of the client IP
+	withInvalidAuth := func(request *http.Request) {
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, util.RandomOwner(), util.DepositorRole, -time.Minute)
+	}
+	require.Equal(t, http.StatusTooManyRequests, serve(http.MethodGet, limitedPath, withInvalidAuth).Code)
+
+	// nor does a client claiming another IP, as no proxy is trusted
+	withForwardedFor := func(request *http.Request) {
+		request.Header.Set("X-Forwarded-For", "198.51.100.1")
+	}
+	require.Equal(t, http.StatusTooManyRequests, serve(http.MethodGet, limitedPath, withForwardedFor).Code)
+
+	// the login routes have a stricter limit
+	loginPath := "/users/login"
+	require.Equal(t, http.StatusBadRequest, serve(http.MethodPost, loginPath, noAuth).Code)
+	require.Equal(t, http.StatusTooManyRequests, serve(http.MethodPost, loginPath, noAuth).Code)
+}
+
+func TestTrustedProxies(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.config.TrustedProxies = []string{"10.0.0.0/8"}
+	require.NoError(t, server.setupRouter())
+
+	clientIPPath := "/client_ip"
+	server.router.GET(clientIPPath, func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, ctx.ClientIP())
+	})
+
+	testCases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		clientIP     string
+	}{
+		{
+			name:         "TrustedProxy",
+			remoteAddr:   "10.0.0.1:54321",
+			forwardedFor: "192.0.2.66, 198.51.100.1",
+			clientIP:     "198.51.100.1",
+		},
+		{
+			name:         "UntrustedClient",
+			remoteAddr:   "203.0.113.7:54321",
+			forwardedFor: "198.51.100.1",
+			clientIP:     "203.0.113.7",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, clientIPPath, nil)
+			require.NoError(t, err)
+			request.RemoteAddr = tc.remoteAddr
+			request.Header.Set("X-Forwarded-For", tc.forwardedFor)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.clientIP, recorder.Body.String())
+		})
+	}
+
+	server.config.TrustedProxies = []string{"proxy.local"}
+	require.Error(t, server.setupRouter())
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/Ian-Balijawa/simplebank/worker"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+)
+
+var errInvalidResetCode = errors.New("password reset code is invalid, used or expired")
+
+type requestPasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// requestPasswordReset emails a password reset link to the user with the email address.
+// It responds the same whether or not the address is registered.
+func (server *Server) requestPasswordReset(ctx *gin.Context) {
+	var req requestPasswordResetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if server.taskDistributor == nil {
+		err := errors.New("password reset emails cannot be sent")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusOK, gin.H{})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// a request during the cooldown is answered like the others, or it would tell the address is registered
+	cooldown := server.config.PasswordResetCooldown()
+	lastPasswordReset, err := server.store.GetLastPasswordReset(ctx, user.Username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil && time.Since(lastPasswordReset.CreatedAt) < cooldown {
+		ctx.JSON(http.StatusOK, gin.H{})
+		return
+	}
+
+	// the task is unique during the cooldown, so requests cannot pile up before its reset is created
+	err = server.taskDistributor.DistributeTaskSendPasswordReset(
+		ctx,
+		&worker.PayloadSendPasswordReset{Username: user.Username},
+		asynq.MaxRetry(10),
+		asynq.Queue(worker.QueueCritical),
+		asynq.Unique(cooldown),
+	)
+	if err != nil && !errors.Is(err, asynq.ErrDuplicateTask) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+type resetPasswordRequest struct {
+	ResetID     int64  `json:"reset_id" binding:"required,min=1"`
+	SecretCode  string `json:"secret_code" binding:"required,min=32,max=128"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// resetPassword sets a new password with the code of a password reset email,
+// and revokes all sessions of the user
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.ResetPasswordTx(auditContext(ctx, "", ""), db.ResetPasswordTxParams{
+		ResetID:        req.ResetID,
+		CodeHash:       util.HashOTPCode(req.SecretCode),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidResetCode))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
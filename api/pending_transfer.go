@@ -0,0 +1,227 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/transfer"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/Ian-Balijawa/simplebank/worker"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+)
+
+var errTooManyAttempts = errors.New("too many incorrect codes, please make the transfer again")
+
+type pendingTransferResponse struct {
+	ID                 int64     `json:"id"`
+	FromAccountID      int64     `json:"from_account_id"`
+	ToAccountID        int64     `json:"to_account_id"`
+	Amount             int64     `json:"amount"`
+	Currency           string    `json:"currency"`
+	ConvertCurrency    bool      `json:"convert_currency"`
+	VerificationMethod string    `json:"verification_method"`
+	Status             string    `json:"status"`
+	ExpiresAt          time.Time `json:"expires_at"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+func newPendingTransferResponse(pendingTransfer db.PendingTransfer) pendingTransferResponse {
+	return pendingTransferResponse{
+		ID:                 pendingTransfer.ID,
+		FromAccountID:      pendingTransfer.FromAccountID,
+		ToAccountID:        pendingTransfer.ToAccountID,
+		Amount:             pendingTransfer.Amount,
+		Currency:           pendingTransfer.Currency,
+		ConvertCurrency:    pendingTransfer.ConvertCurrency,
+		VerificationMethod: pendingTransfer.VerificationMethod,
+		Status:             pendingTransfer.Status,
+		ExpiresAt:          pendingTransfer.ExpiresAt,
+		CreatedAt:          pendingTransfer.CreatedAt,
+	}
+}
+
+// createPendingTransfer holds a transfer over the step-up threshold until it is confirmed
+// with a TOTP code, or with a code sent by email to users without TOTP.
+// A retry with the same idempotency key responds with the pending transfer of the first request, and sends no other code.
+func (server *Server) createPendingTransfer(ctx *gin.Context, req transferRequest, authPayload *token.Payload, idempotency *db.IdempotencyParams) {
+	totpEnabled, err := server.isTOTPEnabled(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	method := db.VerificationMethodTOTP
+	if !totpEnabled {
+		if server.taskDistributor == nil {
+			err := errors.New("transfer confirmation codes cannot be sent")
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		method = db.VerificationMethodEmail
+	}
+
+	result, err := server.store.CreatePendingTransferTx(auditContext(ctx, authPayload.Username, authPayload.Role), db.CreatePendingTransferTxParams{
+		CreatePendingTransferParams: db.CreatePendingTransferParams{
+			Owner:              authPayload.Username,
+			FromAccountID:      req.FromAccountID,
+			ToAccountID:        req.ToAccountID,
+			Amount:             req.Amount,
+			Currency:           req.Currency,
+			ConvertCurrency:    req.ConvertCurrency,
+			VerificationMethod: method,
+			ExpiresAt:          time.Now().Add(server.config.PendingTransferTTL()),
+		},
+		Idempotency: idempotency,
+	})
+	if err != nil {
+		transferErrorResponse(ctx, err)
+		return
+	}
+
+	pendingTransfer := result.PendingTransfer
+	if result.Replayed {
+		ctx.Header(idempotentReplayedHeader, "true")
+	} else if pendingTransfer.VerificationMethod == db.VerificationMethodEmail {
+		err = server.taskDistributor.DistributeTaskSendTransferCode(
+			ctx,
+			&worker.PayloadSendTransferCode{PendingTransferID: pendingTransfer.ID},
+			asynq.Queue(worker.QueueCritical),
+		)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusAccepted, newPendingTransferResponse(pendingTransfer))
+}
+
+type pendingTransferUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type confirmPendingTransferRequest struct {
+	Code string `json:"code" binding:"required,min=6,max=20"`
+}
+
+// confirmPendingTransfer makes a pending transfer once its owner sends the second factor
+func (server *Server) confirmPendingTransfer(ctx *gin.Context) {
+	var uri pendingTransferUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req confirmPendingTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	pendingTransfer, err := server.store.GetPendingTransfer(ctx, uri.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if pendingTransfer.Owner != authPayload.Username {
+		err := errors.New("pending transfer doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if pendingTransfer.Status != db.PendingTransferStatusPending {
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(db.ErrPendingTransferNotPending))
+		return
+	}
+	if !pendingTransfer.ExpiresAt.After(time.Now()) {
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(db.ErrPendingTransferExpired))
+		return
+	}
+
+	// the attempt is counted before the code is checked, so concurrent guesses are limited too
+	pendingTransfer, err = server.store.AddPendingTransferAttemptTx(auditContext(ctx, authPayload.Username, authPayload.Role), pendingTransfer.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(db.ErrPendingTransferNotPending))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if pendingTransfer.Attempts > util.MaxStepUpAttempts {
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(errTooManyAttempts))
+		return
+	}
+
+	ok := server.verifyPendingTransferCode(ctx, pendingTransfer, req.Code)
+	if !ok {
+		return
+	}
+
+	transferReq := transferRequest{
+		FromAccountID:   pendingTransfer.FromAccountID,
+		ToAccountID:     pendingTransfer.ToAccountID,
+		Amount:          pendingTransfer.Amount,
+		Currency:        pendingTransfer.Currency,
+		ConvertCurrency: pendingTransfer.ConvertCurrency,
+	}
+	prepared, valid := server.prepareTransfer(ctx, transferReq, authPayload.Username)
+	if !valid {
+		return
+	}
+
+	result, err := server.store.ConfirmPendingTransferTx(auditContext(ctx, authPayload.Username, authPayload.Role), db.ConfirmPendingTransferTxParams{
+		PendingTransferID: pendingTransfer.ID,
+		Transfer:          prepared.Arg,
+	})
+	if err != nil {
+		transferErrorResponse(ctx, err)
+		return
+	}
+
+	transfer.SendBalanceAlerts(ctx, server.store, server.taskDistributor, prepared.FromAccount, result.Transfer.FromAccount)
+	transfer.SendBalanceAlerts(ctx, server.store, server.taskDistributor, prepared.ToAccount, result.Transfer.ToAccount)
+
+	ctx.JSON(http.StatusOK, result.Transfer)
+}
+
+// verifyPendingTransferCode checks the code sent for a pending transfer
+// and writes the error response when it is incorrect
+func (server *Server) verifyPendingTransferCode(ctx *gin.Context, pendingTransfer db.PendingTransfer, code string) bool {
+	if pendingTransfer.VerificationMethod == db.VerificationMethodTOTP {
+		userTOTP, ok := server.getConfirmedTOTP(ctx, pendingTransfer.Owner)
+		if !ok {
+			return false
+		}
+
+		ok, err := server.verifySecondFactor(ctx, userTOTP, code)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+		if !ok {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errIncorrectCode))
+			return false
+		}
+		return true
+	}
+
+	codeHash := util.HashOTPCode(code)
+	if !pendingTransfer.CodeHash.Valid ||
+		subtle.ConstantTimeCompare([]byte(codeHash), []byte(pendingTransfer.CodeHash.String)) != 1 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errIncorrectCode))
+		return false
+	}
+	return true
+}
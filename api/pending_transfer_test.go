@@ -0,0 +1,341 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/util"
+	mockwk "github.com/Ian-Balijawa/simplebank/worker/mock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+const testStepUpThreshold = 1000
+
+func TestCreateTransferStepUpAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account1 := randomAccount(user.Username)
+	account2 := randomAccount(util.RandomOwner())
+	account2.ID = account1.ID + 1
+	account2.Currency = account1.Currency
+
+	testCases := []struct {
+		name           string
+		amount         int64
+		idempotencyKey string
+		buildStubs     func(store *mockdb.MockStore, distributor *mockwk.MockTaskDistributor)
+		checkResponse  func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "TOTP",
+			amount: testStepUpThreshold + 1,
+			buildStubs: func(store *mockdb.MockStore, distributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(randomUserTOTP(t, user.Username, true), nil)
+				store.EXPECT().
+					CreatePendingTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreatePendingTransferTxParams) (db.CreatePendingTransferTxResult, error) {
+						require.Equal(t, db.VerificationMethodTOTP, arg.VerificationMethod)
+						require.Equal(t, int64(testStepUpThreshold+1), arg.Amount)
+						require.Nil(t, arg.Idempotency)
+						pendingTransfer := db.PendingTransfer{ID: 1, VerificationMethod: arg.VerificationMethod, ExpiresAt: arg.ExpiresAt}
+						return db.CreatePendingTransferTxResult{PendingTransfer: pendingTransfer}, nil
+					})
+				distributor.EXPECT().DistributeTaskSendTransferCode(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+
+				var rsp pendingTransferResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, db.VerificationMethodTOTP, rsp.VerificationMethod)
+				require.NotContains(t, recorder.Body.String(), "code_hash")
+			},
+		},
+		{
+			name:   "Email",
+			amount: testStepUpThreshold + 1,
+			buildStubs: func(store *mockdb.MockStore, distributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					CreatePendingTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreatePendingTransferTxResult{
+						PendingTransfer: db.PendingTransfer{ID: 7, VerificationMethod: db.VerificationMethodEmail},
+					}, nil)
+				distributor.EXPECT().
+					DistributeTaskSendTransferCode(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, recorder.Header().Get(idempotentReplayedHeader))
+			},
+		},
+		{
+			name:           "IdempotentReplay",
+			amount:         testStepUpThreshold + 1,
+			idempotencyKey: "step-up-key",
+			buildStubs: func(store *mockdb.MockStore, distributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					CreatePendingTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreatePendingTransferTxParams) (db.CreatePendingTransferTxResult, error) {
+						require.Equal(t, &db.IdempotencyParams{Username: user.Username, Key: "step-up-key"}, arg.Idempotency)
+						return db.CreatePendingTransferTxResult{
+							PendingTransfer: db.PendingTransfer{ID: 7, VerificationMethod: db.VerificationMethodEmail},
+							Replayed:        true,
+						}, nil
+					})
+				distributor.EXPECT().DistributeTaskSendTransferCode(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Equal(t, "true", recorder.Header().Get(idempotentReplayedHeader))
+
+				var rsp pendingTransferResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, int64(7), rsp.ID)
+			},
+		},
+		{
+			name:           "IdempotencyKeyConflict",
+			amount:         testStepUpThreshold + 1,
+			idempotencyKey: "step-up-key",
+			buildStubs: func(store *mockdb.MockStore, distributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					CreatePendingTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreatePendingTransferTxResult{}, db.ErrIdempotencyKeyConflict)
+				distributor.EXPECT().DistributeTaskSendTransferCode(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:   "AtThreshold",
+			amount: testStepUpThreshold,
+			buildStubs: func(store *mockdb.MockStore, distributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().CreatePendingTransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().GetAccountAlert(gomock.Any(), gomock.Any()).AnyTimes().Return(db.AccountAlert{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			distributor := mockwk.NewMockTaskDistributor(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+			tc.buildStubs(store, distributor)
+
+			server := newTestServer(t, store)
+			server.config.StepUpTransferThreshold = testStepUpThreshold
+			server.taskDistributor = distributor
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          tc.amount,
+				"currency":        account1.Currency,
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+			if tc.idempotencyKey != "" {
+				request.Header.Set(idempotencyKeyHeader, tc.idempotencyKey)
+			}
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestConfirmPendingTransferAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account1 := randomAccount(user.Username)
+	account2 := randomAccount(util.RandomOwner())
+	account2.ID = account1.ID + 1
+	account2.Currency = account1.Currency
+
+	code := "123456"
+	pendingTransfer := db.PendingTransfer{
+		ID:                 util.RandomInt(1, 1000),
+		Owner:              user.Username,
+		FromAccountID:      account1.ID,
+		ToAccountID:        account2.ID,
+		Amount:             testStepUpThreshold + 1,
+		Currency:           account1.Currency,
+		VerificationMethod: db.VerificationMethodEmail,
+		CodeHash:           pgtype.Text{String: util.HashOTPCode(code), Valid: true},
+		Status:             db.PendingTransferStatusPending,
+		ExpiresAt:          time.Now().Add(time.Minute),
+	}
+
+	attempted := pendingTransfer
+	attempted.Attempts = 1
+
+	expired := pendingTransfer
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+
+	confirmed := pendingTransfer
+	confirmed.Status = db.PendingTransferStatusConfirmed
+
+	exhausted := pendingTransfer
+	exhausted.Attempts = util.MaxStepUpAttempts + 1
+
+	testCases := []struct {
+		name          string
+		code          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			code:     code,
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(pendingTransfer, nil)
+				store.EXPECT().AddPendingTransferAttemptTx(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(attempted, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				arg := db.ConfirmPendingTransferTxParams{
+					PendingTransferID: pendingTransfer.ID,
+					Transfer: db.TransferTxParams{
+						FromAccountID:     account1.ID,
+						ToAccountID:       account2.ID,
+						Amount:            pendingTransfer.Amount,
+						EnforceDailyLimit: true,
+					},
+				}
+				store.EXPECT().ConfirmPendingTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "IncorrectCode",
+			code:     "654321",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(pendingTransfer, nil)
+				store.EXPECT().AddPendingTransferAttemptTx(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(attempted, nil)
+				store.EXPECT().ConfirmPendingTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "TooManyAttempts",
+			code:     code,
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(pendingTransfer, nil)
+				store.EXPECT().AddPendingTransferAttemptTx(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(exhausted, nil)
+				store.EXPECT().ConfirmPendingTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+		{
+			name:     "Expired",
+			code:     code,
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(expired, nil)
+				store.EXPECT().AddPendingTransferAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:     "AlreadyConfirmed",
+			code:     code,
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(confirmed, nil)
+				store.EXPECT().AddPendingTransferAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			code:     code,
+			username: "unauthorized_user",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(pendingTransfer, nil)
+				store.EXPECT().AddPendingTransferAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.StepUpTransferThreshold = testStepUpThreshold
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"code": tc.code})
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/transfers/pending/%d/confirm", pendingTransfer.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
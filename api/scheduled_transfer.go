@@ -0,0 +1,309 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/transfer"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type createScheduledTransferRequest struct {
+	FromAccountID   int64      `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID     int64      `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	Amount          int64      `json:"amount" binding:"required,gt=0"`
+	Currency        string     `json:"currency" binding:"required,currency"`
+	Recurrence      string     `json:"recurrence" binding:"required,recurrence"`
+	CronExpression  string     `json:"cron_expression"`
+	IntervalSeconds int64      `json:"interval_seconds" binding:"min=0"`
+	StartAt         *time.Time `json:"start_at"`
+	EndAt           *time.Time `json:"end_at"`
+	MaxOccurrences  *int32     `json:"max_occurrences" binding:"omitempty,min=1"`
+}
+
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	interval := time.Duration(req.IntervalSeconds) * time.Second
+	if err := util.ValidateRecurrence(req.Recurrence, req.CronExpression, interval); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	startAt := time.Now()
+	if req.StartAt != nil {
+		startAt = *req.StartAt
+	}
+
+	nextRunAt, err := util.FirstRunTime(req.Recurrence, req.CronExpression, startAt)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.EndAt != nil && req.EndAt.Before(nextRunAt) {
+		err := fmt.Errorf("end_at must not be before the first run at %s", nextRunAt.Format(time.RFC3339))
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	fromAccount, err := transfer.ValidAccount(ctx, server.store, req.FromAccountID, req.Currency)
+	if err != nil {
+		transferErrorResponse(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		transferErrorResponse(ctx, transfer.ErrNotAccountOwner)
+		return
+	}
+
+	if !server.requireVerifiedEmail(ctx, authPayload.Username) {
+		return
+	}
+
+	if _, err := transfer.ValidAccount(ctx, server.store, req.ToAccountID, req.Currency); err != nil {
+		transferErrorResponse(ctx, err)
+		return
+	}
+
+	if server.config.RequiresStepUp(req.Amount) {
+		ctx.JSON(http.StatusForbidden, errorResponse(util.ErrStepUpNotScheduled))
+		return
+	}
+
+	arg := db.CreateScheduledTransferParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Recurrence:    req.Recurrence,
+		NextRunAt:     nextRunAt,
+	}
+	switch req.Recurrence {
+	case util.RecurrenceCron:
+		arg.CronExpression = pgtype.Text{String: req.CronExpression, Valid: true}
+	case util.RecurrenceInterval:
+		arg.IntervalSeconds = pgtype.Int8{Int64: req.IntervalSeconds, Valid: true}
+	}
+	if req.EndAt != nil {
+		arg.EndAt = pgtype.Timestamptz{Time: *req.EndAt, Valid: true}
+	}
+	if req.MaxOccurrences != nil {
+		arg.MaxOccurrences = pgtype.Int4{Int32: *req.MaxOccurrences, Valid: true}
+	}
+
+	scheduledTransfer, err := server.store.CreateScheduledTransferTx(auditContext(ctx, authPayload.Username, authPayload.Role), arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+}
+
+type scheduledTransferUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduledTransfer, ok := server.getOwnedScheduledTransfer(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+}
+
+type listScheduledTransfersQuery struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var uri accountIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var q listScheduledTransfersQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getOwnedAccount(ctx, uri.AccountID); !ok {
+		return
+	}
+
+	scheduledTransfers, err := server.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		FromAccountID: uri.AccountID,
+		Limit:         q.PageSize,
+		Offset:        (q.PageID - 1) * q.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfers)
+}
+
+type updateScheduledTransferRequest struct {
+	Amount         *int64     `json:"amount" binding:"omitempty,gt=0"`
+	EndAt          *time.Time `json:"end_at"`
+	MaxOccurrences *int32     `json:"max_occurrences" binding:"omitempty,min=1"`
+}
+
+func (server *Server) updateScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduledTransfer, ok := server.getOwnedScheduledTransfer(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	if scheduledTransfer.Status != db.ScheduledTransferStatusActive {
+		err := fmt.Errorf("scheduled transfer is %s", scheduledTransfer.Status)
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+		return
+	}
+
+	if req.Amount != nil && server.config.RequiresStepUp(*req.Amount) {
+		ctx.JSON(http.StatusForbidden, errorResponse(util.ErrStepUpNotScheduled))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.UpdateScheduledTransferParams{
+		ID: uri.ID,
+	}
+	if req.Amount != nil {
+		arg.Amount = pgtype.Int8{Int64: *req.Amount, Valid: true}
+	}
+	if req.EndAt != nil {
+		arg.EndAt = pgtype.Timestamptz{Time: *req.EndAt, Valid: true}
+	}
+	if req.MaxOccurrences != nil {
+		arg.MaxOccurrences = pgtype.Int4{Int32: *req.MaxOccurrences, Valid: true}
+	}
+
+	scheduledTransfer, err := server.store.UpdateScheduledTransferTx(auditContext(ctx, authPayload.Username, authPayload.Role), arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+}
+
+func (server *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduledTransfer, ok := server.getOwnedScheduledTransfer(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	if scheduledTransfer.Status != db.ScheduledTransferStatusActive {
+		err := fmt.Errorf("scheduled transfer is %s", scheduledTransfer.Status)
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduledTransfer, err := server.store.UpdateScheduledTransferStatusTx(auditContext(ctx, authPayload.Username, authPayload.Role), db.UpdateScheduledTransferStatusParams{
+		ID:     uri.ID,
+		Status: db.ScheduledTransferStatusCancelled,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+}
+
+func (server *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	var uri scheduledTransferUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var q listScheduledTransfersQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getOwnedScheduledTransfer(ctx, uri.ID); !ok {
+		return
+	}
+
+	runs, err := server.store.ListScheduledTransferRuns(ctx, db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: uri.ID,
+		Limit:               q.PageSize,
+		Offset:              (q.PageID - 1) * q.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, runs)
+}
+
+func (server *Server) getOwnedScheduledTransfer(ctx *gin.Context, id int64) (db.ScheduledTransfer, bool) {
+	scheduledTransfer, err := server.store.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return scheduledTransfer, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return scheduledTransfer, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if scheduledTransfer.Owner != authPayload.Username {
+		err := errors.New("scheduled transfer doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return scheduledTransfer, false
+	}
+
+	return scheduledTransfer, true
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 
 	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/lockout"
+	"github.com/Ian-Balijawa/simplebank/ratelimit"
+	"github.com/Ian-Balijawa/simplebank/revocation"
 	"github.com/Ian-Balijawa/simplebank/token"
 	"github.com/Ian-Balijawa/simplebank/util"
 	"github.com/Ian-Balijawa/simplebank/worker"
@@ -17,7 +20,12 @@ type Server struct {
 	config          util.Config
 	store           db.Store
 	tokenMaker      token.Maker
+	tokenChecker    revocation.Checker
+	loginGuard      lockout.Guard
+	rateLimiter     ratelimit.Limiter
+	rateLimitPolicy ratelimit.Policy
 	taskDistributor worker.TaskDistributor
+	fxRateProvider  util.FXRateProvider
 	router          *gin.Engine
 }
 
@@ -28,41 +36,93 @@ func NewServer(config util.Config, store db.Store) (*Server, error) {
 
 // NewServerWithTaskDistributor creates a new HTTP server with optional task distributor.
 func NewServerWithTaskDistributor(config util.Config, store db.Store, taskDistributor worker.TaskDistributor) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	tokenMaker, err := token.NewMakerFromConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	fxRateProvider, err := util.LoadStaticFXRateProvider(config.FXRatesFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create exchange rate provider: %w", err)
+	}
+
+	rateLimiter, err := ratelimit.NewLimiterFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limiter: %w", err)
+	}
+
+	rateLimitPolicy, err := ratelimit.NewPolicyFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limit policy: %w", err)
+	}
+
 	server := &Server{
 		config:          config,
 		store:           store,
 		tokenMaker:      tokenMaker,
+		tokenChecker:    revocation.NewCache(store, config.TokenRevocationCacheTTL),
+		loginGuard:      lockout.NewTracker(store),
+		rateLimiter:     rateLimiter,
+		rateLimitPolicy: rateLimitPolicy,
 		taskDistributor: taskDistributor,
+		fxRateProvider:  fxRateProvider,
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("recurrence", validRecurrence)
 	}
 
-	server.setupRouter()
+	err = server.setupRouter()
+	if err != nil {
+		return nil, fmt.Errorf("cannot set up router: %w", err)
+	}
 	return server, nil
 }
 
-func (server *Server) setupRouter() {
+func (server *Server) setupRouter() error {
 	router := gin.Default()
 
+	// without trusted proxies, ctx.ClientIP is the address of the connection and X-Forwarded-For is ignored
+	err := router.SetTrustedProxies(server.config.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	router.Use(rateLimitMiddleware(server.rateLimiter, server.rateLimitPolicy, server.tokenMaker))
+
 	router.POST("/users", server.createUser)
 	router.POST("/users/login", server.loginUser)
+	router.POST("/users/login/otp", server.verifyLoginOTP)
+	router.POST("/users/logout", server.logoutUser)
+	router.POST("/users/password_reset/request", server.requestPasswordReset)
+	router.POST("/users/password_reset", server.resetPassword)
 	router.POST("/tokens/renew_access", server.renewAccessToken)
 
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.tokenChecker))
 	authRoutes.POST("/accounts", server.createAccount)
 	authRoutes.GET("/accounts/:id", server.getAccount)
 	authRoutes.GET("/accounts", server.listAccounts)
+	authRoutes.POST("/accounts/:id/close", server.closeAccount)
+	authRoutes.POST("/accounts/:id/freeze", server.freezeAccount)
+	authRoutes.POST("/accounts/:id/unfreeze", server.unfreezeAccount)
+
+	authRoutes.POST("/users/verify_email/resend", server.resendVerifyEmail)
+
+	authRoutes.POST("/users/totp/enroll", server.enrollTOTP)
+	authRoutes.POST("/users/totp/confirm", server.confirmTOTP)
+	authRoutes.POST("/users/totp/disable", server.disableTOTP)
+
+	authRoutes.GET("/sessions", server.listSessions)
+	authRoutes.DELETE("/sessions/:id", server.revokeSession)
+	authRoutes.POST("/sessions/revoke_all", server.revokeAllSessions)
 
 	authRoutes.POST("/transfers", server.createTransfer)
+	authRoutes.POST("/transfers/pending/:id/confirm", server.confirmPendingTransfer)
+	authRoutes.POST("/transfers/:id/reverse", server.reverseTransfer)
 	authRoutes.GET("/accounts/:id/entries", server.listEntries)
 	authRoutes.GET("/accounts/:id/transfers", server.listTransfers)
+	authRoutes.GET("/accounts/:id/statement", server.getAccountStatement)
 
 	authRoutes.GET("/accounts/:id/limits", server.getAccountLimit)
 	authRoutes.PUT("/accounts/:id/limits", server.upsertAccountLimit)
@@ -70,7 +130,15 @@ func (server *Server) setupRouter() {
 	authRoutes.GET("/accounts/:id/alerts", server.getAccountAlert)
 	authRoutes.PUT("/accounts/:id/alerts", server.upsertAccountAlert)
 
+	authRoutes.POST("/scheduled_transfers", server.createScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers/:id", server.getScheduledTransfer)
+	authRoutes.PATCH("/scheduled_transfers/:id", server.updateScheduledTransfer)
+	authRoutes.DELETE("/scheduled_transfers/:id", server.cancelScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers/:id/runs", server.listScheduledTransferRuns)
+	authRoutes.GET("/accounts/:id/scheduled_transfers", server.listScheduledTransfers)
+
 	server.router = router
+	return nil
 }
 
 // Start runs the HTTP server on a specific address.
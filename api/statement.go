@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/statement"
+	"github.com/gin-gonic/gin"
+)
+
+// maxStatementPeriod bounds how many entries a single on-demand statement has to load
+const maxStatementPeriod = 366 * 24 * time.Hour
+
+var errStatementPeriodTooLong = errors.New("statement period must not be longer than a year")
+
+type statementQuery struct {
+	From   string `form:"from" binding:"required"`
+	To     string `form:"to" binding:"required"`
+	Format string `form:"format"`
+}
+
+func (server *Server) getAccountStatement(ctx *gin.Context) {
+	var uri accountIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var q statementQuery
+	if err := ctx.ShouldBindQuery(&q); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	from, err := parseOptionalTime(q.From, time.Time{})
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	to, err := parseOptionalTime(q.To, time.Time{})
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !from.Before(to) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidDateRange))
+		return
+	}
+	if to.Sub(from) > maxStatementPeriod {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errStatementPeriodTooLong))
+		return
+	}
+
+	format := q.Format
+	if format == "" {
+		format = statement.FormatCSV
+	}
+	if !statement.IsSupportedFormat(format) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("unsupported statement format: %s", format)))
+		return
+	}
+
+	account, ok := server.getOwnedAccount(ctx, uri.AccountID)
+	if !ok {
+		return
+	}
+
+	accountStatement, err := statement.Generate(ctx, server.store, account, from, to)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := accountStatement.Render(&buf, format); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", accountStatement.FileName(format)))
+	ctx.Data(http.StatusOK, statement.ContentType(format), buf.Bytes())
+}
@@ -0,0 +1,295 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/gin-gonic/gin"
+)
+
+type verifyLoginOTPRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required,min=6,max=20"`
+}
+
+// verifyLoginOTP exchanges the challenge token returned by loginUser
+// and a TOTP or recovery code for the tokens of a new session
+func (server *Server) verifyLoginOTP(ctx *gin.Context) {
+	var req verifyLoginOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	challengePayload, err := server.tokenMaker.VerifyToken(req.ChallengeToken, token.TokenTypeLoginChallenge)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	reservation, ok := server.reserveOTPAttempt(ctx, challengePayload)
+	if !ok {
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, challengePayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	userTOTP, ok := server.getConfirmedTOTP(ctx, user.Username)
+	if !ok {
+		return
+	}
+
+	ok, err = server.verifySecondFactor(ctx, userTOTP, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok {
+		server.notifyLoginLockout(ctx, user.Username, reservation)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errIncorrectCode))
+		return
+	}
+
+	err = server.loginGuard.Release(ctx, reservation)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// the failed logins are only forgotten once both factors are proven
+	err = server.loginGuard.RecordSuccess(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.createLoginSession(ctx, user)
+}
+
+type enrollTOTPResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// enrollTOTP generates a new TOTP secret for the authenticated user,
+// it is only used for logging in once confirmed with a first code
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	secret, err := util.RandomTOTPSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	userTOTP, err := server.store.UpsertUserTOTPTx(auditContext(ctx, authPayload.Username, authPayload.Role), db.UpsertUserTOTPParams{
+		Username: authPayload.Username,
+		Secret:   secret,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(errTOTPAlreadyEnabled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := enrollTOTPResponse{
+		Secret:     userTOTP.Secret,
+		OTPAuthURI: util.TOTPURI(util.TOTPIssuer, userTOTP.Username, userTOTP.Secret),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type totpCodeRequest struct {
+	Code string `json:"code" binding:"required,min=6,max=20"`
+}
+
+type confirmTOTPResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmTOTP enables two-factor authentication once the user proves their
+// authenticator works, the recovery codes are only ever returned here
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var req totpCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	userTOTP, err := server.store.GetUserTOTP(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(errTOTPNotEnrolled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if userTOTP.ConfirmedAt.Valid {
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(errTOTPAlreadyEnabled))
+		return
+	}
+
+	if !server.reserveCodeAttempt(ctx, authPayload.Username) {
+		return
+	}
+
+	step, ok := util.ValidateTOTP(userTOTP.Secret, req.Code, time.Now())
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errIncorrectCode))
+		return
+	}
+
+	err = server.loginGuard.RecordCodeSuccess(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	recoveryCodes, err := util.RandomRecoveryCodes(util.RecoveryCodeCount)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.ConfirmTOTPTxParams{
+		Username:           authPayload.Username,
+		Step:               step,
+		RecoveryCodeHashes: make([]string, 0, len(recoveryCodes)),
+	}
+	for _, code := range recoveryCodes {
+		arg.RecoveryCodeHashes = append(arg.RecoveryCodeHashes, util.HashRecoveryCode(code))
+	}
+
+	_, err = server.store.ConfirmTOTPTx(auditContext(ctx, authPayload.Username, authPayload.Role), arg)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(errTOTPAlreadyEnabled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, confirmTOTPResponse{RecoveryCodes: recoveryCodes})
+}
+
+// disableTOTP turns two-factor authentication off,
+// it requires a TOTP or recovery code so a stolen session cannot do it alone
+func (server *Server) disableTOTP(ctx *gin.Context) {
+	var req totpCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	userTOTP, ok := server.getConfirmedTOTP(ctx, authPayload.Username)
+	if !ok {
+		return
+	}
+
+	if !server.reserveCodeAttempt(ctx, authPayload.Username) {
+		return
+	}
+
+	ok, err := server.verifySecondFactor(ctx, userTOTP, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errIncorrectCode))
+		return
+	}
+
+	err = server.loginGuard.RecordCodeSuccess(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.DisableTOTPTx(auditContext(ctx, authPayload.Username, authPayload.Role), authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// getConfirmedTOTP loads the TOTP of the user and writes the error response
+// when two-factor authentication is not enabled
+func (server *Server) getConfirmedTOTP(ctx *gin.Context, username string) (db.UserTotp, bool) {
+	userTOTP, err := server.store.GetUserTOTP(ctx, username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return userTOTP, false
+	}
+	if err != nil || !userTOTP.ConfirmedAt.Valid {
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(errTOTPNotEnabled))
+		return userTOTP, false
+	}
+	return userTOTP, true
+}
+
+// isTOTPEnabled tells whether the user must send a one-time password to log in
+func (server *Server) isTOTPEnabled(ctx context.Context, username string) (bool, error) {
+	userTOTP, err := server.store.GetUserTOTP(ctx, username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return userTOTP.ConfirmedAt.Valid, nil
+}
+
+// verifySecondFactor checks a TOTP code or a recovery code of the user,
+// and marks it as used so it cannot be sent again
+func (server *Server) verifySecondFactor(ctx context.Context, userTOTP db.UserTotp, code string) (bool, error) {
+	var err error
+	if util.IsTOTPCode(code) {
+		step, ok := util.ValidateTOTP(userTOTP.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+
+		_, err = server.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+			Username: userTOTP.Username,
+			Step:     step,
+		})
+	} else {
+		_, err = server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			Username: userTOTP.Username,
+			CodeHash: util.HashRecoveryCode(code),
+		})
+	}
+
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,467 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/lockout"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func randomUserTOTP(t *testing.T, username string, confirmed bool) db.UserTotp {
+	secret, err := util.RandomTOTPSecret()
+	require.NoError(t, err)
+
+	return db.UserTotp{
+		Username:    username,
+		Secret:      secret,
+		ConfirmedAt: pgtype.Timestamptz{Time: time.Now(), Valid: confirmed},
+	}
+}
+
+// incorrectTOTPCode returns a code the secret does not give at the moment
+func incorrectTOTPCode(secret string) string {
+	for i := 0; ; i++ {
+		code := fmt.Sprintf("%06d", i)
+		if _, ok := util.ValidateTOTP(secret, code, time.Now()); !ok {
+			return code
+		}
+	}
+}
+
+func TestVerifyLoginOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	userTOTP := randomUserTOTP(t, user.Username, true)
+
+	code, err := util.TOTPCode(userTOTP.Secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	recoveryCode := "abcde-fghij"
+
+	testCases := []struct {
+		name          string
+		code          string
+		tokenType     token.TokenType
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			code:      code,
+			tokenType: token.TokenTypeLoginChallenge,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOTPAttempt(store, 1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				arg := db.UseTOTPStepParams{Username: user.Username, Step: util.TOTPStep(time.Now())}
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Eq(arg)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().
+					DeleteLoginFailure(gomock.Any(), gomock.Eq(db.DeleteLoginFailureParams{
+						Kind:    lockout.KindUsername,
+						Subject: user.Username,
+					})).
+					Times(1).
+					Return(nil)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+			},
+		},
+		{
+			name:      "RecoveryCode",
+			code:      recoveryCode,
+			tokenType: token.TokenTypeLoginChallenge,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOTPAttempt(store, 1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().DeleteLoginFailure(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				arg := db.UseRecoveryCodeParams{Username: user.Username, CodeHash: util.HashRecoveryCode(recoveryCode)}
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TotpRecoveryCode{}, nil)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "ReplayedCode",
+			code:      code,
+			tokenType: token.TokenTypeLoginChallenge,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOTPAttempt(store, 1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().DeleteLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "IncorrectCode",
+			code:      incorrectTOTPCode(userTOTP.Secret),
+			tokenType: token.TokenTypeLoginChallenge,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOTPAttempt(store, 1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "AccessTokenAsChallenge",
+			code:      code,
+			tokenType: token.TokenTypeAccessToken,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "NotEnabled",
+			code:      code,
+			tokenType: token.TokenTypeLoginChallenge,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOTPAttempt(store, 1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:      "ChallengeSpent",
+			code:      code,
+			tokenType: token.TokenTypeLoginChallenge,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOTPAttempt(store, lockout.ChallengeAttempts+1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), lockout.ErrChallengeSpent.Error())
+			},
+		},
+		{
+			name:      "TooManyAttempts",
+			code:      code,
+			tokenType: token.TokenTypeLoginChallenge,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(db.ReserveLoginAttemptTxResult{
+						Failure:          db.LoginFailure{FailedCount: lockout.UsernameLockoutThreshold + 1},
+						PreviousFailedAt: time.Now(),
+					}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			challengeToken, _, err := server.tokenMaker.CreateToken(user.Username, user.Role, uuid.Nil, time.Minute, tc.tokenType)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(gin.H{"challenge_token": challengeToken, "code": tc.code})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login/otp", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// expectOTPAttempt expects a one-time password to be counted against the username
+// and the login challenge, which then has challengeFailedCount failures
+func expectOTPAttempt(store *mockdb.MockStore, challengeFailedCount int32) {
+	store.EXPECT().
+		ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureParams) (db.ReserveLoginAttemptTxResult, error) {
+			if arg.Kind == lockout.KindLoginChallenge {
+				return db.ReserveLoginAttemptTxResult{Failure: db.LoginFailure{FailedCount: challengeFailedCount}}, nil
+			}
+			return db.ReserveLoginAttemptTxResult{Failure: db.LoginFailure{FailedCount: 2}}, nil
+		})
+}
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	pending := randomUserTOTP(t, user.Username, false)
+
+	code, err := util.TOTPCode(pending.Secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		code          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(pending, nil)
+				expectCodeAttempt(store, user.Username, 1)
+				expectCodeSuccess(store, user.Username)
+				store.EXPECT().
+					ConfirmTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ConfirmTOTPTxParams) (db.ConfirmTOTPTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Len(t, arg.RecoveryCodeHashes, util.RecoveryCodeCount)
+						return db.ConfirmTOTPTxResult{}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp confirmTOTPResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.RecoveryCodes, util.RecoveryCodeCount)
+			},
+		},
+		{
+			name: "IncorrectCode",
+			code: incorrectTOTPCode(pending.Secret),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(pending, nil)
+				expectCodeAttempt(store, user.Username, 1)
+				store.EXPECT().DeleteLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ConfirmTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "TooManyAttempts",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(pending, nil)
+				expectCodeAttempt(store, user.Username, lockout.UsernameLockoutThreshold+1)
+				store.EXPECT().ConfirmTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(randomUserTOTP(t, user.Username, true), nil)
+				store.EXPECT().ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ConfirmTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"code": tc.code})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/totp/confirm", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestDisableTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	userTOTP := randomUserTOTP(t, user.Username, true)
+
+	code, err := util.TOTPCode(userTOTP.Secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		code          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				expectCodeAttempt(store, user.Username, 1)
+				arg := db.UseTOTPStepParams{Username: user.Username, Step: util.TOTPStep(time.Now())}
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Eq(arg)).Times(1).Return(userTOTP, nil)
+				expectCodeSuccess(store, user.Username)
+				store.EXPECT().DisableTOTPTx(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "IncorrectCode",
+			code: incorrectTOTPCode(userTOTP.Secret),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				expectCodeAttempt(store, user.Username, 1)
+				store.EXPECT().DeleteLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DisableTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "TooManyAttempts",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				expectCodeAttempt(store, user.Username, lockout.UsernameLockoutThreshold+1)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DisableTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnabled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DisableTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"code": tc.code})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/totp/disable", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// expectCodeAttempt expects a code sent to change the two-factor authentication of the username to be counted,
+// which then has failedCount incorrect codes
+func expectCodeAttempt(store *mockdb.MockStore, username string, failedCount int32) {
+	store.EXPECT().
+		ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureParams) (db.ReserveLoginAttemptTxResult, error) {
+			if arg.Kind != lockout.KindTOTPCode || arg.Subject != username {
+				return db.ReserveLoginAttemptTxResult{}, fmt.Errorf("unexpected login failure %s %s", arg.Kind, arg.Subject)
+			}
+			return db.ReserveLoginAttemptTxResult{
+				Failure: db.LoginFailure{FailedCount: failedCount},
+				// the previous code was sent a moment ago, so only a locked out user has to wait
+				PreviousFailedAt: time.Now().Add(-time.Second),
+			}, nil
+		})
+}
+
+// expectCodeSuccess expects the incorrect codes of the username to be forgotten
+func expectCodeSuccess(store *mockdb.MockStore, username string) {
+	store.EXPECT().
+		DeleteLoginFailure(gomock.Any(), gomock.Eq(db.DeleteLoginFailureParams{
+			Kind:    lockout.KindTOTPCode,
+			Subject: username,
+		})).
+		Times(1).
+		Return(nil)
+}
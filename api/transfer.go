@@ -4,19 +4,26 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
 	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
 	"github.com/Ian-Balijawa/simplebank/token"
-	"github.com/Ian-Balijawa/simplebank/worker"
+	"github.com/Ian-Balijawa/simplebank/transfer"
+	"github.com/Ian-Balijawa/simplebank/util"
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
 type transferRequest struct {
-	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
-	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
-	Amount        int64  `json:"amount" binding:"required,gt=0"`
-	Currency      string `json:"currency" binding:"required,currency"`
+	FromAccountID   int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID     int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount          int64  `json:"amount" binding:"required,gt=0"`
+	Currency        string `json:"currency" binding:"required,currency"`
+	ConvertCurrency bool   `json:"convert_currency"`
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
@@ -26,139 +33,87 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		return
 	}
 
-	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
-	if !valid {
+	idempotencyKey := ctx.GetHeader(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		err := fmt.Errorf("%s header must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if fromAccount.Owner != authPayload.Username {
-		err := errors.New("from account doesn't belong to the authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	if !server.requireVerifiedEmail(ctx, authPayload.Username) {
 		return
 	}
 
-	toAccount, valid := server.validAccount(ctx, req.ToAccountID, req.Currency)
+	prepared, valid := server.prepareTransfer(ctx, req, authPayload.Username)
 	if !valid {
 		return
 	}
 
-	if err := server.checkDailyTransferLimit(ctx, req.FromAccountID, req.Amount); err != nil {
-		if errors.Is(err, db.ErrDailyTransferLimitExceeded) {
-			ctx.JSON(http.StatusForbidden, errorResponse(err))
-			return
+	var idempotency *db.IdempotencyParams
+	if idempotencyKey != "" {
+		idempotency = &db.IdempotencyParams{
+			Username: authPayload.Username,
+			Key:      idempotencyKey,
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
-	}
-
-	arg := db.TransferTxParams{
-		FromAccountID: req.FromAccountID,
-		ToAccountID:   req.ToAccountID,
-		Amount:        req.Amount,
 	}
 
-	result, err := server.store.TransferTx(ctx, arg)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	if server.config.RequiresStepUp(req.Amount) {
+		server.createPendingTransfer(ctx, req, authPayload, idempotency)
 		return
 	}
 
-	server.sendBalanceAlerts(ctx, fromAccount, result.FromAccount)
-	server.sendBalanceAlerts(ctx, toAccount, result.ToAccount)
+	arg := prepared.Arg
+	arg.Idempotency = idempotency
 
-	ctx.JSON(http.StatusOK, result)
-}
-
-func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
-	account, err := server.store.GetAccount(ctx, accountID)
+	result, err := server.store.TransferTx(auditContext(ctx, authPayload.Username, authPayload.Role), arg)
 	if err != nil {
-		if errors.Is(err, db.ErrRecordNotFound) {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return account, false
-		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return account, false
+		transferErrorResponse(ctx, err)
+		return
 	}
 
-	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return account, false
+	if result.Replayed {
+		ctx.Header(idempotentReplayedHeader, "true")
+	} else {
+		transfer.SendBalanceAlerts(ctx, server.store, server.taskDistributor, prepared.FromAccount, result.FromAccount)
+		transfer.SendBalanceAlerts(ctx, server.store, server.taskDistributor, prepared.ToAccount, result.ToAccount)
 	}
 
-	return account, true
+	ctx.JSON(http.StatusOK, result)
 }
 
-func (server *Server) checkDailyTransferLimit(ctx *gin.Context, accountID int64, amount int64) error {
-	limit, err := server.store.GetAccountLimit(ctx, accountID)
-	if err != nil {
-		if errors.Is(err, db.ErrRecordNotFound) {
-			return nil
-		}
-		return err
-	}
-	if limit.DailyTransferLimit <= 0 {
-		return nil
-	}
-
-	now := time.Now().UTC()
-	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-	dayEnd := dayStart.Add(24 * time.Hour)
-
-	total, err := server.store.GetDailyTransferTotal(ctx, db.GetDailyTransferTotalParams{
-		FromAccountID: accountID,
-		CreatedAt:     dayStart,
-		CreatedAt_2:   dayEnd,
-	})
+// prepareTransfer checks the accounts of a transfer request made by username,
+// and writes the error response when it cannot be made
+func (server *Server) prepareTransfer(ctx *gin.Context, req transferRequest, username string) (transfer.Prepared, bool) {
+	prepared, err := transfer.Prepare(ctx, server.store, server.fxRateProvider, transfer.Request(req), username)
 	if err != nil {
-		return err
+		transferErrorResponse(ctx, err)
+		return prepared, false
 	}
-
-	if total+amount > limit.DailyTransferLimit {
-		return db.ErrDailyTransferLimitExceeded
-	}
-
-	return nil
+	return prepared, true
 }
 
-func (server *Server) sendBalanceAlerts(ctx *gin.Context, before db.Account, after db.Account) {
-	if server.taskDistributor == nil {
-		return
-	}
-
-	alert, err := server.store.GetAccountAlert(ctx, after.ID)
-	if err != nil {
-		if errors.Is(err, db.ErrRecordNotFound) {
-			return
-		}
-		return
-	}
-
-	if alert.LowBalanceThreshold > 0 &&
-		before.Balance > alert.LowBalanceThreshold &&
-		after.Balance <= alert.LowBalanceThreshold {
-		_ = server.taskDistributor.DistributeTaskSendAccountAlert(ctx, &worker.PayloadAccountAlert{
-			Username:  after.Owner,
-			AccountID: after.ID,
-			Balance:   after.Balance,
-			Threshold: alert.LowBalanceThreshold,
-			Direction: worker.AlertDirectionLow,
-			Currency:  after.Currency,
-		})
-	}
-
-	if alert.HighBalanceThreshold > 0 &&
-		before.Balance < alert.HighBalanceThreshold &&
-		after.Balance >= alert.HighBalanceThreshold {
-		_ = server.taskDistributor.DistributeTaskSendAccountAlert(ctx, &worker.PayloadAccountAlert{
-			Username:  after.Owner,
-			AccountID: after.ID,
-			Balance:   after.Balance,
-			Threshold: alert.HighBalanceThreshold,
-			Direction: worker.AlertDirectionHigh,
-			Currency:  after.Currency,
-		})
+// transferErrorResponse writes the response of an error of transfer.Prepare or of a transfer transaction
+func transferErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+	case errors.Is(err, transfer.ErrNotAccountOwner):
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	case errors.Is(err, transfer.ErrSameAccount),
+		errors.Is(err, transfer.ErrCurrencyMismatch),
+		errors.Is(err, transfer.ErrAmountTooSmall):
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+	case errors.Is(err, db.ErrIdempotencyKeyConflict):
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+	case errors.Is(err, db.ErrDailyTransferLimitExceeded):
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	case errors.Is(err, util.ErrFXRateNotFound),
+		errors.Is(err, db.ErrPendingTransferNotPending),
+		errors.Is(err, db.ErrPendingTransferExpired),
+		db.IsTransferRejected(err):
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,7 +14,8 @@ type listTransfersUri struct {
 }
 
 type listTransfersQuery struct {
-	PageID    int32  `form:"page_id" binding:"required,min=1"`
+	PageID    int32  `form:"page_id" binding:"omitempty,min=1"`
+	PageToken string `form:"page_token"`
 	PageSize  int32  `form:"page_size" binding:"required,min=5,max=50"`
 	Direction string `form:"direction"`
 	Min       string `form:"min_amount"`
@@ -23,6 +25,11 @@ type listTransfersQuery struct {
 	Sort      string `form:"sort"`
 }
 
+type listTransfersResponse struct {
+	Transfers     []db.Transfer `json:"transfers"`
+	NextPageToken string        `json:"next_page_token,omitempty"`
+}
+
 func (server *Server) listTransfers(ctx *gin.Context) {
 	var uri listTransfersUri
 	if err := ctx.ShouldBindUri(&uri); err != nil {
@@ -36,6 +43,11 @@ func (server *Server) listTransfers(ctx *gin.Context) {
 		return
 	}
 
+	if q.PageID > 0 && q.PageToken != "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errPageIDWithPageToken))
+		return
+	}
+
 	if _, ok := server.getOwnedAccount(ctx, uri.AccountID); !ok {
 		return
 	}
@@ -82,19 +94,24 @@ func (server *Server) listTransfers(ctx *gin.Context) {
 		return
 	}
 
+	if q.PageID == 0 {
+		server.listTransfersPage(ctx, uri.AccountID, q, direction, minAmount, maxAmount, fromTime, toTime, sortOrder == "asc")
+		return
+	}
+
 	limit := q.PageSize
 	offset := (q.PageID - 1) * q.PageSize
 
 	if sortOrder == "asc" {
 		transfers, err := server.store.ListTransfersFilteredAsc(ctx, db.ListTransfersFilteredAscParams{
-			AccountID:   uri.AccountID,
-			Direction:   direction,
-			Amount:      minAmount,
-			Amount_2:    maxAmount,
-			CreatedAt:   fromTime,
-			CreatedAt_2: toTime,
-			Limit:       limit,
-			Offset:      offset,
+			AccountID: uri.AccountID,
+			Direction: direction,
+			MinAmount: minAmount,
+			MaxAmount: maxAmount,
+			FromTime:  fromTime,
+			ToTime:    toTime,
+			Limit:     limit,
+			Offset:    offset,
 		})
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -105,14 +122,14 @@ func (server *Server) listTransfers(ctx *gin.Context) {
 	}
 
 	transfers, err := server.store.ListTransfersFilteredDesc(ctx, db.ListTransfersFilteredDescParams{
-		AccountID:   uri.AccountID,
-		Direction:   direction,
-		Amount:      minAmount,
-		Amount_2:    maxAmount,
-		CreatedAt:   fromTime,
-		CreatedAt_2: toTime,
-		Limit:       limit,
-		Offset:      offset,
+		AccountID: uri.AccountID,
+		Direction: direction,
+		MinAmount: minAmount,
+		MaxAmount: maxAmount,
+		FromTime:  fromTime,
+		ToTime:    toTime,
+		Limit:     limit,
+		Offset:    offset,
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -121,3 +138,63 @@ func (server *Server) listTransfers(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, transfers)
 }
+
+// listTransfersPage lists transfers after the position in the page token, ordered by (created_at, id),
+// so rows added while paging never shift the following pages
+func (server *Server) listTransfersPage(
+	ctx *gin.Context,
+	accountID int64,
+	q listTransfersQuery,
+	direction string,
+	minAmount int64,
+	maxAmount int64,
+	fromTime time.Time,
+	toTime time.Time,
+	ascending bool,
+) {
+	cursor, err := util.PageCursor(q.PageToken, ascending, fromTime, toTime)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// one more row than the page size tells whether there is a next page
+	arg := db.ListTransfersKeysetDescParams{
+		AccountID:       accountID,
+		Direction:       direction,
+		MinAmount:       minAmount,
+		MaxAmount:       maxAmount,
+		FromTime:        fromTime,
+		ToTime:          toTime,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		Limit:           q.PageSize + 1,
+	}
+
+	var transfers []db.Transfer
+	if ascending {
+		transfers, err = server.store.ListTransfersKeysetAsc(ctx, db.ListTransfersKeysetAscParams(arg))
+	} else {
+		transfers, err = server.store.ListTransfersKeysetDesc(ctx, arg)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := listTransfersResponse{
+		Transfers: make([]db.Transfer, 0, len(transfers)),
+	}
+	if len(transfers) > int(q.PageSize) {
+		transfers = transfers[:q.PageSize]
+		last := transfers[len(transfers)-1]
+		rsp.NextPageToken = util.EncodePageToken(util.PageToken{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+			Ascending: ascending,
+		})
+	}
+	rsp.Transfers = append(rsp.Transfers, transfers...)
+
+	ctx.JSON(http.StatusOK, rsp)
+}
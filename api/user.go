@@ -50,14 +50,16 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	arg := db.CreateUserParams{
-		Username:       req.Username,
-		HashedPassword: hashedPassword,
-		FullName:       req.FullName,
-		Email:          req.Email,
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.Username,
+			HashedPassword: hashedPassword,
+			FullName:       req.FullName,
+			Email:          req.Email,
+		},
 	}
 
-	user, err := server.store.CreateUser(ctx, arg)
+	result, err := server.store.CreateUserTx(auditContext(ctx, "", ""), arg)
 	if err != nil {
 		if db.ErrorCode(err) == db.UniqueViolation {
 			ctx.JSON(http.StatusForbidden, errorResponse(err))
@@ -67,7 +69,7 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	rsp := newUserResponse(user)
+	rsp := newUserResponse(result.User)
 	ctx.JSON(http.StatusOK, rsp)
 }
 
@@ -85,6 +87,14 @@ type loginUserResponse struct {
 	User                  userResponse `json:"user"`
 }
 
+// loginChallengeResponse is sent instead of the tokens when the user
+// has to send a one-time password to /users/login/otp
+type loginChallengeResponse struct {
+	OTPRequired             bool      `json:"otp_required"`
+	ChallengeToken          string    `json:"challenge_token"`
+	ChallengeTokenExpiresAt time.Time `json:"challenge_token_expires_at"`
+}
+
 func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -92,10 +102,16 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
+	reservation, ok := server.reserveLoginAttempt(ctx, req.Username)
+	if !ok {
+		return
+	}
+
 	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
 		if errors.Is(err, db.ErrRecordNotFound) {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			util.FakeCheckPassword(req.Password)
+			server.rejectLogin(ctx, req.Username, reservation)
 			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -104,13 +120,63 @@ func (server *Server) loginUser(ctx *gin.Context) {
 
 	err = util.CheckPassword(req.Password, user.HashedPassword)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		server.rejectLogin(ctx, req.Username, reservation)
+		return
+	}
+
+	// a correct password is not held against the client IP, the users behind one address would lock each other out
+	err = server.loginGuard.Release(ctx, reservation)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	otpRequired, err := server.isTOTPEnabled(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	// with two-factor authentication, the attempt against the username stays counted until the one-time password is sent
+	if otpRequired {
+		challengeToken, challengePayload, err := server.tokenMaker.CreateToken(
+			user.Username,
+			user.Role,
+			uuid.Nil,
+			util.TOTPLoginChallengeDuration,
+			token.TokenTypeLoginChallenge,
+		)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		rsp := loginChallengeResponse{
+			OTPRequired:             true,
+			ChallengeToken:          challengeToken,
+			ChallengeTokenExpiresAt: challengePayload.ExpiredAt,
+		}
+		ctx.JSON(http.StatusOK, rsp)
+		return
+	}
+
+	err = server.loginGuard.RecordSuccess(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.createLoginSession(ctx, user)
+}
+
+// createLoginSession creates the session of a user who has proven who they are,
+// and responds with the access and refresh tokens of the session
+func (server *Server) createLoginSession(ctx *gin.Context, user db.User) {
+	sessionID := uuid.New()
 	accessToken, accessPayload, err := server.tokenMaker.CreateToken(
 		user.Username,
 		user.Role,
+		sessionID,
 		server.config.AccessTokenDuration,
 		token.TokenTypeAccessToken,
 	)
@@ -122,6 +188,7 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(
 		user.Username,
 		user.Role,
+		sessionID,
 		server.config.RefreshTokenDuration,
 		token.TokenTypeRefreshToken,
 	)
@@ -130,14 +197,15 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
-	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
-		ID:           refreshPayload.ID,
+	session, err := server.store.CreateSessionTx(auditContext(ctx, user.Username, user.Role), db.CreateSessionParams{
+		ID:           sessionID,
 		Username:     user.Username,
 		RefreshToken: refreshToken,
 		UserAgent:    ctx.Request.UserAgent(),
 		ClientIp:     ctx.ClientIP(),
 		IsBlocked:    false,
 		ExpiresAt:    refreshPayload.ExpiredAt,
+		FamilyID:     sessionID,
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
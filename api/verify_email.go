@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/ratelimit"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/worker"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+)
+
+func (server *Server) resendVerifyEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.IsEmailVerified {
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(errEmailAlreadyVerified))
+		return
+	}
+
+	cooldown := server.config.VerifyEmailCooldown()
+	lastVerifyEmail, err := server.store.GetLastVerifyEmail(ctx, user.Username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil {
+		if wait := time.Until(lastVerifyEmail.CreatedAt.Add(cooldown)); wait > 0 {
+			respondVerifyEmailCooldown(ctx, wait)
+			return
+		}
+	}
+
+	if server.taskDistributor == nil {
+		err := errors.New("verification emails cannot be sent")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// the task is unique during the cooldown, so resends cannot pile up before its email is created
+	err = server.taskDistributor.DistributeTaskSendVerifyEmail(
+		ctx,
+		&worker.PayloadSendVerifyEmail{Username: user.Username},
+		asynq.MaxRetry(10),
+		asynq.Queue(worker.QueueCritical),
+		asynq.Unique(cooldown),
+	)
+	if err != nil {
+		if errors.Is(err, asynq.ErrDuplicateTask) {
+			respondVerifyEmailCooldown(ctx, cooldown)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+func respondVerifyEmailCooldown(ctx *gin.Context, wait time.Duration) {
+	ctx.Header("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(wait)))
+	ctx.JSON(http.StatusTooManyRequests, errorResponse(errVerifyEmailCooldown))
+}
+
+// requireVerifiedEmail writes a 403 response and returns false if the policy requires a verified email
+// and the email address of the user is not verified yet
+func (server *Server) requireVerifiedEmail(ctx *gin.Context, username string) bool {
+	if !server.config.RequireVerifiedEmail {
+		return true
+	}
+
+	user, err := server.store.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if !user.IsEmailVerified {
+		ctx.JSON(http.StatusForbidden, errorResponse(errEmailNotVerified))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/ledger"
+	"github.com/rs/zerolog/log"
+)
+
+// Exit codes of the commands
+const (
+	exitOK           = 0
+	exitInconsistent = 1
+	exitFailure      = 2
+)
+
+const commandUsage = `usage: simplebank [command]
+
+Without a command, simplebank runs the servers and the background workers.
+
+Commands:
+  ledger verify [-batch-size n]    check that the balances, entries and transfers agree
+`
+
+// runCommand runs a one-off command instead of the servers and returns its exit code
+func runCommand(ctx context.Context, store db.Store, args []string, stdout io.Writer) int {
+	if len(args) >= 2 && args[0] == "ledger" && args[1] == "verify" {
+		return runLedgerVerify(ctx, store, args[2:], stdout)
+	}
+
+	fmt.Fprint(os.Stderr, commandUsage)
+	return exitFailure
+}
+
+// runLedgerVerify writes the report of the ledger as JSON,
+// and exits with exitInconsistent if anything is wrong so it can run from cron
+func runLedgerVerify(ctx context.Context, store db.Store, args []string, stdout io.Writer) int {
+	flags := flag.NewFlagSet("ledger verify", flag.ContinueOnError)
+	batchSize := flags.Int("batch-size", ledger.DefaultBatchSize, "number of accounts checked per batch")
+	if err := flags.Parse(args); err != nil {
+		return exitFailure
+	}
+
+	report, err := ledger.Verify(ctx, store, int32(*batchSize))
+	if err != nil {
+		log.Error().Err(err).Msg("cannot verify ledger")
+		return exitFailure
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Error().Err(err).Msg("cannot write ledger report")
+		return exitFailure
+	}
+
+	if !report.Consistent() {
+		return exitInconsistent
+	}
+	return exitOK
+}
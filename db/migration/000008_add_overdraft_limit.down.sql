@@ -0,0 +1 @@
+ALTER TABLE "account_limits" DROP COLUMN IF EXISTS "overdraft_limit";
@@ -0,0 +1 @@
+ALTER TABLE "account_limits" ADD COLUMN "overdraft_limit" bigint NOT NULL DEFAULT 0;
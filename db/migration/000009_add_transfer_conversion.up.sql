@@ -0,0 +1,10 @@
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" numeric(20,8) NOT NULL DEFAULT 1;
+
+UPDATE "transfers" SET "to_amount" = "amount";
+
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+
+COMMENT ON COLUMN "transfers"."amount" IS 'must be positive, in the currency of the from account';
+COMMENT ON COLUMN "transfers"."to_amount" IS 'must be positive, in the currency of the to account';
+COMMENT ON COLUMN "transfers"."exchange_rate" IS 'to_amount per unit of amount';
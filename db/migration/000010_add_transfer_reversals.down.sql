@@ -0,0 +1,3 @@
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reason";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reversal_of";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "status";
@@ -0,0 +1,8 @@
+ALTER TABLE "transfers" ADD COLUMN "status" varchar NOT NULL DEFAULT 'completed';
+ALTER TABLE "transfers" ADD COLUMN "reversal_of" bigint UNIQUE;
+ALTER TABLE "transfers" ADD COLUMN "reason" varchar;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reversal_of") REFERENCES "transfers" ("id");
+
+COMMENT ON COLUMN "transfers"."status" IS 'completed or reversed';
+COMMENT ON COLUMN "transfers"."reversal_of" IS 'the transfer that this one reverses';
@@ -0,0 +1,9 @@
+CREATE TABLE "login_failures" (
+  "kind" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "failed_count" integer NOT NULL DEFAULT 0,
+  "last_failed_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("kind", "subject")
+);
+
+COMMENT ON COLUMN "login_failures"."kind" IS 'username or client_ip';
@@ -0,0 +1,18 @@
+CREATE TABLE "audit_events" (
+  "id" bigserial PRIMARY KEY,
+  "actor_username" varchar NOT NULL,
+  "actor_role" varchar NOT NULL,
+  "action" varchar NOT NULL,
+  "target_type" varchar NOT NULL,
+  "target_id" varchar NOT NULL,
+  "reason" varchar NOT NULL DEFAULT '',
+  "before" jsonb,
+  "after" jsonb,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "audit_events" ADD FOREIGN KEY ("actor_username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "audit_events" ("target_type", "target_id", "created_at");
+
+CREATE INDEX ON "audit_events" ("actor_username", "created_at");
@@ -0,0 +1,25 @@
+DROP TRIGGER IF EXISTS "audit_events_no_truncate" ON "audit_events";
+
+DROP TRIGGER IF EXISTS "audit_events_append_only" ON "audit_events";
+
+DROP TRIGGER IF EXISTS "audit_events_chain" ON "audit_events";
+
+DROP FUNCTION IF EXISTS audit_events_append_only();
+
+DROP FUNCTION IF EXISTS audit_events_chain();
+
+DROP FUNCTION IF EXISTS audit_event_hash(bytea, bigint, varchar, varchar, varchar, varchar, varchar, varchar, varchar, varchar, jsonb, jsonb, timestamptz);
+
+DROP INDEX IF EXISTS "audit_events_action_created_at_idx";
+
+ALTER TABLE "audit_events" DROP COLUMN IF EXISTS "hash";
+
+ALTER TABLE "audit_events" DROP COLUMN IF EXISTS "prev_hash";
+
+ALTER TABLE "audit_events" DROP COLUMN IF EXISTS "user_agent";
+
+ALTER TABLE "audit_events" DROP COLUMN IF EXISTS "client_ip";
+
+DELETE FROM "audit_events" WHERE "actor_username" IS NULL;
+
+ALTER TABLE "audit_events" ALTER COLUMN "actor_username" SET NOT NULL;
@@ -0,0 +1,85 @@
+-- events of the system itself, such as scheduled transfers, have no actor
+ALTER TABLE "audit_events" ALTER COLUMN "actor_username" DROP NOT NULL;
+
+ALTER TABLE "audit_events" ADD COLUMN "client_ip" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "audit_events" ADD COLUMN "user_agent" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "audit_events" ADD COLUMN "prev_hash" bytea;
+
+ALTER TABLE "audit_events" ADD COLUMN "hash" bytea;
+
+CREATE INDEX ON "audit_events" ("action", "created_at");
+
+-- audit_event_hash chains an event to the one before it,
+-- so changing, inserting or removing an event breaks the hashes of all the events after it
+CREATE FUNCTION audit_event_hash(
+  prev_hash bytea,
+  id bigint,
+  actor_username varchar,
+  actor_role varchar,
+  client_ip varchar,
+  user_agent varchar,
+  action varchar,
+  target_type varchar,
+  target_id varchar,
+  reason varchar,
+  before jsonb,
+  after jsonb,
+  created_at timestamptz
+) RETURNS bytea LANGUAGE sql IMMUTABLE AS $$
+  SELECT sha256(COALESCE(prev_hash, ''::bytea) || convert_to(jsonb_build_array(
+    id, actor_username, actor_role, client_ip, user_agent, action,
+    target_type, target_id, reason, before, after, created_at AT TIME ZONE 'UTC'
+  )::text, 'UTF8'))
+$$;
+
+DO $$
+DECLARE
+  event record;
+  last_hash bytea;
+BEGIN
+  FOR event IN SELECT * FROM "audit_events" ORDER BY "id" LOOP
+    UPDATE "audit_events"
+    SET "prev_hash" = last_hash,
+        "hash" = audit_event_hash(
+          last_hash, event.id, event.actor_username, event.actor_role, event.client_ip, event.user_agent,
+          event.action, event.target_type, event.target_id, event.reason, event.before, event.after, event.created_at
+        )
+    WHERE "id" = event.id
+    RETURNING "hash" INTO last_hash;
+  END LOOP;
+END $$;
+
+ALTER TABLE "audit_events" ALTER COLUMN "hash" SET NOT NULL;
+
+-- audit_events_chain links each new event to the last one.
+-- Inserts are serialized until the transaction commits, and the ID is assigned under the lock,
+-- so the chain follows the order of the IDs.
+CREATE FUNCTION audit_events_chain() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtext('audit_events'));
+
+  NEW.id := nextval(pg_get_serial_sequence('audit_events', 'id'));
+  SELECT "hash" INTO NEW.prev_hash FROM "audit_events" ORDER BY "id" DESC LIMIT 1;
+  NEW.hash := audit_event_hash(
+    NEW.prev_hash, NEW.id, NEW.actor_username, NEW.actor_role, NEW.client_ip, NEW.user_agent,
+    NEW.action, NEW.target_type, NEW.target_id, NEW.reason, NEW.before, NEW.after, NEW.created_at
+  );
+  RETURN NEW;
+END $$;
+
+CREATE TRIGGER "audit_events_chain" BEFORE INSERT ON "audit_events"
+FOR EACH ROW EXECUTE FUNCTION audit_events_chain();
+
+-- audit_events_append_only keeps the audit log immutable
+CREATE FUNCTION audit_events_append_only() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  RAISE EXCEPTION 'audit events are append-only';
+END $$;
+
+CREATE TRIGGER "audit_events_append_only" BEFORE UPDATE OR DELETE ON "audit_events"
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER "audit_events_no_truncate" BEFORE TRUNCATE ON "audit_events"
+FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
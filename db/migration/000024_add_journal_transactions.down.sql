@@ -0,0 +1,23 @@
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+COMMENT ON COLUMN "entries"."transfer_id" IS 'the transfer that made the entry';
+
+UPDATE "entries" AS e
+SET "transfer_id" = j."transfer_id"
+FROM "journal_transactions" AS j
+WHERE e."journal_transaction_id" = j."id";
+
+DELETE FROM "entries"
+WHERE "account_id" IN (SELECT "id" FROM "accounts" WHERE "owner" = 'simplebank.exchange');
+
+DELETE FROM "accounts" WHERE "owner" = 'simplebank.exchange';
+
+DELETE FROM "users" WHERE "username" = 'simplebank.exchange';
+
+ALTER TABLE "entries" DROP COLUMN "journal_transaction_id";
+
+DROP TABLE IF EXISTS "journal_transactions";
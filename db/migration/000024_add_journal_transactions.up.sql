@@ -0,0 +1,65 @@
+CREATE TABLE "journal_transactions" (
+  "id" bigserial PRIMARY KEY,
+  "kind" varchar NOT NULL,
+  "transfer_id" bigint UNIQUE,
+  "description" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "journal_transactions" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+COMMENT ON COLUMN "journal_transactions"."kind" IS 'transfer, reversal, deposit, fee...';
+
+COMMENT ON COLUMN "journal_transactions"."transfer_id" IS 'the transfer that the journal transaction records';
+
+ALTER TABLE "entries" ADD COLUMN "journal_transaction_id" bigint;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("journal_transaction_id") REFERENCES "journal_transactions" ("id");
+
+CREATE INDEX ON "entries" ("journal_transaction_id");
+
+COMMENT ON COLUMN "entries"."journal_transaction_id" IS 'the entries of a journal transaction sum to zero per currency';
+
+-- the exchange desk holds one account per currency,
+-- so converted transfers balance in each currency with legs on its accounts.
+-- It has no password and a role without access, so it can't log in.
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email", "is_email_verified", "role")
+VALUES ('simplebank.exchange', '', 'Currency exchange', 'exchange@simplebank.internal', true, 'system');
+
+INSERT INTO "accounts" ("owner", "balance", "currency")
+SELECT 'simplebank.exchange', 0, "currency"
+FROM (
+  VALUES ('USD'), ('EUR'), ('CAD'), ('GBP')
+  UNION
+  SELECT "currency" FROM "accounts"
+) AS c ("currency");
+
+INSERT INTO "journal_transactions" ("kind", "transfer_id", "created_at")
+SELECT CASE WHEN "reversal_of" IS NULL THEN 'transfer' ELSE 'reversal' END, "id", "created_at"
+FROM "transfers"
+ORDER BY "id";
+
+UPDATE "entries" AS e
+SET "journal_transaction_id" = j."id"
+FROM "journal_transactions" AS j
+WHERE e."transfer_id" = j."transfer_id";
+
+-- converted transfers get the legs on the exchange accounts they were missing
+INSERT INTO "entries" ("account_id", "amount", "journal_transaction_id", "created_at")
+SELECT x."id", leg."amount", j."id", j."created_at"
+FROM "transfers" AS t
+JOIN "journal_transactions" AS j ON j."transfer_id" = t."id"
+JOIN "accounts" AS f ON f."id" = t."from_account_id"
+JOIN "accounts" AS r ON r."id" = t."to_account_id"
+CROSS JOIN LATERAL (
+  VALUES (f."currency", t."amount"), (r."currency", -t."to_amount")
+) AS leg ("currency", "amount")
+JOIN "accounts" AS x ON x."owner" = 'simplebank.exchange' AND x."currency" = leg."currency"
+WHERE f."currency" <> r."currency";
+
+UPDATE "accounts" AS a
+SET "balance" = (SELECT COALESCE(SUM(e."amount"), 0) FROM "entries" AS e WHERE e."account_id" = a."id")
+WHERE a."owner" = 'simplebank.exchange';
+
+-- the journal transaction now links the entries of a transfer
+ALTER TABLE "entries" DROP COLUMN "transfer_id";
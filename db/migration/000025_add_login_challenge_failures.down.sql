@@ -0,0 +1,3 @@
+DELETE FROM "login_failures" WHERE "kind" = 'login_challenge';
+
+COMMENT ON COLUMN "login_failures"."kind" IS 'username or client_ip';
@@ -0,0 +1 @@
+COMMENT ON COLUMN "login_failures"."kind" IS 'username, client_ip or login_challenge';
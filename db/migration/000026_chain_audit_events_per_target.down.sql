@@ -0,0 +1,37 @@
+CREATE OR REPLACE FUNCTION audit_events_chain() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtext('audit_events'));
+
+  NEW.id := nextval(pg_get_serial_sequence('audit_events', 'id'));
+  SELECT "hash" INTO NEW.prev_hash FROM "audit_events" ORDER BY "id" DESC LIMIT 1;
+  NEW.hash := audit_event_hash(
+    NEW.prev_hash, NEW.id, NEW.actor_username, NEW.actor_role, NEW.client_ip, NEW.user_agent,
+    NEW.action, NEW.target_type, NEW.target_id, NEW.reason, NEW.before, NEW.after, NEW.created_at
+  );
+  RETURN NEW;
+END $$;
+
+LOCK TABLE "audit_events" IN EXCLUSIVE MODE;
+
+ALTER TABLE "audit_events" DISABLE TRIGGER "audit_events_append_only";
+
+DO $$
+DECLARE
+  event record;
+  last_hash bytea;
+BEGIN
+  FOR event IN SELECT * FROM "audit_events" ORDER BY "id" LOOP
+    UPDATE "audit_events"
+    SET "prev_hash" = last_hash,
+        "hash" = audit_event_hash(
+          last_hash, event.id, event.actor_username, event.actor_role, event.client_ip, event.user_agent,
+          event.action, event.target_type, event.target_id, event.reason, event.before, event.after, event.created_at
+        )
+    WHERE "id" = event.id
+    RETURNING "hash" INTO last_hash;
+  END LOOP;
+END $$;
+
+ALTER TABLE "audit_events" ENABLE TRIGGER "audit_events_append_only";
+
+DROP INDEX IF EXISTS "audit_events_target_type_target_id_id_idx";
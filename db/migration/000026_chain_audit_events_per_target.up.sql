@@ -0,0 +1,50 @@
+-- each target has a chain of its own, so the events of different targets are not written one at a time
+CREATE INDEX ON "audit_events" ("target_type", "target_id", "id");
+
+-- audit_events_chain links each new event to the last one of its target.
+-- Inserts for the same target are serialized until the transaction commits, and the ID is assigned under the lock,
+-- so the chain of a target follows the order of its IDs.
+CREATE OR REPLACE FUNCTION audit_events_chain() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtextextended(NEW.target_type || ':' || NEW.target_id, 0));
+
+  NEW.id := nextval(pg_get_serial_sequence('audit_events', 'id'));
+  SELECT "hash" INTO NEW.prev_hash FROM "audit_events"
+  WHERE "target_type" = NEW.target_type AND "target_id" = NEW.target_id
+  ORDER BY "id" DESC LIMIT 1;
+  NEW.hash := audit_event_hash(
+    NEW.prev_hash, NEW.id, NEW.actor_username, NEW.actor_role, NEW.client_ip, NEW.user_agent,
+    NEW.action, NEW.target_type, NEW.target_id, NEW.reason, NEW.before, NEW.after, NEW.created_at
+  );
+  RETURN NEW;
+END $$;
+
+-- the existing events are chained again by target, the only rewrite the append-only log allows
+LOCK TABLE "audit_events" IN EXCLUSIVE MODE;
+
+ALTER TABLE "audit_events" DISABLE TRIGGER "audit_events_append_only";
+
+DO $$
+DECLARE
+  event record;
+  last_hash bytea;
+  last_target varchar;
+BEGIN
+  FOR event IN SELECT * FROM "audit_events" ORDER BY "target_type", "target_id", "id" LOOP
+    IF last_target IS DISTINCT FROM event.target_type || ':' || event.target_id THEN
+      last_hash := NULL;
+      last_target := event.target_type || ':' || event.target_id;
+    END IF;
+
+    UPDATE "audit_events"
+    SET "prev_hash" = last_hash,
+        "hash" = audit_event_hash(
+          last_hash, event.id, event.actor_username, event.actor_role, event.client_ip, event.user_agent,
+          event.action, event.target_type, event.target_id, event.reason, event.before, event.after, event.created_at
+        )
+    WHERE "id" = event.id
+    RETURNING "hash" INTO last_hash;
+  END LOOP;
+END $$;
+
+ALTER TABLE "audit_events" ENABLE TRIGGER "audit_events_append_only";
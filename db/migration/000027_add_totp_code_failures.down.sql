@@ -0,0 +1,3 @@
+DELETE FROM "login_failures" WHERE "kind" = 'totp_code';
+
+COMMENT ON COLUMN "login_failures"."kind" IS 'username, client_ip or login_challenge';
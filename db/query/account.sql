@@ -23,18 +23,39 @@ ORDER BY id
 LIMIT $2
 OFFSET $3;
 
--- name: UpdateAccount :one
+-- name: AddEntryToAccountBalance :one
+-- balances only move by the entries of journal transactions
 UPDATE accounts
-SET balance = $2
-WHERE id = $1
-RETURNING *;
+SET balance = accounts.balance + entries.amount
+FROM entries
+WHERE entries.id = sqlc.arg(entry_id)
+  AND accounts.id = entries.account_id
+  AND entries.journal_transaction_id IS NOT NULL
+RETURNING accounts.*;
+
+-- name: ListAllAccounts :many
+SELECT * FROM accounts
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
 
--- name: AddAccountBalance :one
+-- name: UpdateAccountStatus :one
 UPDATE accounts
-SET balance = balance + sqlc.arg(amount)
+SET status = sqlc.arg(status)
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
--- name: DeleteAccount :exec
-DELETE FROM accounts
-WHERE id = $1;
+-- name: GetAccountByOwnerCurrency :one
+SELECT * FROM accounts
+WHERE owner = $1 AND currency = $2 AND status <> 'closed'
+LIMIT 1;
+
+-- name: CreateAccountIfNotExists :exec
+-- creates an empty account, unless the owner already has an open one in the currency
+INSERT INTO accounts (
+  owner,
+  balance,
+  currency
+) VALUES (
+  $1, 0, $2
+) ON CONFLICT (owner, currency) WHERE status <> 'closed' DO NOTHING;
@@ -1,13 +1,15 @@
 -- name: UpsertAccountLimit :one
 INSERT INTO account_limits (
   account_id,
-  daily_transfer_limit
+  daily_transfer_limit,
+  overdraft_limit
 ) VALUES (
-  $1, $2
+  sqlc.arg(account_id), sqlc.arg(daily_transfer_limit), COALESCE(sqlc.narg(overdraft_limit)::bigint, 0)
 )
 ON CONFLICT (account_id)
 DO UPDATE SET
   daily_transfer_limit = EXCLUDED.daily_transfer_limit,
+  overdraft_limit = COALESCE(sqlc.narg(overdraft_limit)::bigint, account_limits.overdraft_limit),
   updated_at = now()
 RETURNING *;
 
@@ -0,0 +1,48 @@
+-- name: CreateAuditEvent :one
+-- the hash chain columns are filled in by the audit_events_chain trigger
+INSERT INTO audit_events (
+  actor_username,
+  actor_role,
+  client_ip,
+  user_agent,
+  action,
+  target_type,
+  target_id,
+  reason,
+  before,
+  after
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING *;
+
+-- name: ListAuditEvents :many
+SELECT * FROM audit_events
+WHERE (sqlc.narg(actor_username)::varchar IS NULL OR actor_username = sqlc.narg(actor_username))
+  AND (sqlc.narg(action)::varchar IS NULL OR action = sqlc.narg(action))
+  AND (sqlc.narg(target_type)::varchar IS NULL OR target_type = sqlc.narg(target_type))
+  AND (sqlc.narg(target_id)::varchar IS NULL OR target_id = sqlc.narg(target_id))
+  AND created_at >= sqlc.arg(from_time)
+  AND created_at <= sqlc.arg(to_time)
+ORDER BY id DESC
+LIMIT sqlc.arg(page_limit)
+OFFSET sqlc.arg(page_offset);
+
+-- name: ListBrokenAuditEvents :many
+-- returns the events whose hash doesn't match their content,
+-- or whose previous hash doesn't match the hash of the event of the same target before them
+SELECT id FROM (
+  SELECT
+    id,
+    hash,
+    prev_hash,
+    lag(hash) OVER (PARTITION BY target_type, target_id ORDER BY id) AS expected_prev_hash,
+    audit_event_hash(
+      prev_hash, id, actor_username, actor_role, client_ip, user_agent,
+      action, target_type, target_id, reason, before, after, created_at
+    ) AS expected_hash
+  FROM audit_events
+) AS chain
+WHERE hash <> expected_hash
+   OR prev_hash IS DISTINCT FROM expected_prev_hash
+ORDER BY id
+LIMIT sqlc.arg(max_results);
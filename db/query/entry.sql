@@ -1,9 +1,10 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  journal_transaction_id
 ) VALUES (
-  $1, $2
+  $1, $2, $3
 ) RETURNING *;
 
 -- name: GetEntry :one
@@ -38,3 +39,31 @@ WHERE account_id = $1
 ORDER BY created_at ASC
 LIMIT $6
 OFFSET $7;
+
+-- name: ListEntriesKeysetDesc :many
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id)
+  AND amount >= sqlc.arg(min_amount)
+  AND amount <= sqlc.arg(max_amount)
+  AND created_at >= sqlc.arg(from_time)
+  AND created_at <= sqlc.arg(to_time)
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListEntriesKeysetAsc :many
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id)
+  AND amount >= sqlc.arg(min_amount)
+  AND amount <= sqlc.arg(max_amount)
+  AND created_at >= sqlc.arg(from_time)
+  AND created_at <= sqlc.arg(to_time)
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: GetEntriesTotalSince :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total
+FROM entries
+WHERE account_id = $1
+  AND created_at >= $2;
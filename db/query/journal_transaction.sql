@@ -0,0 +1,22 @@
+-- name: CreateJournalTransaction :one
+INSERT INTO journal_transactions (
+  kind,
+  transfer_id,
+  description
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetJournalTransaction :one
+SELECT * FROM journal_transactions
+WHERE id = $1 LIMIT 1;
+
+-- name: ListJournalTransactionsByIDs :many
+SELECT * FROM journal_transactions
+WHERE id = ANY(sqlc.arg(ids)::bigint[])
+ORDER BY id;
+
+-- name: ListEntriesByJournalTransactions :many
+SELECT * FROM entries
+WHERE journal_transaction_id = ANY(sqlc.arg(ids)::bigint[])
+ORDER BY id;
@@ -0,0 +1,58 @@
+-- name: ListAccountLedgerTotals :many
+-- returns the balance of each account along with the sum of its entries, which must be equal
+SELECT
+  a.id,
+  a.currency,
+  a.balance,
+  (SELECT COALESCE(SUM(e.amount), 0) FROM entries AS e WHERE e.account_id = a.id)::bigint AS entries_total
+FROM accounts AS a
+WHERE a.id > sqlc.arg(after_id)
+ORDER BY a.id
+LIMIT sqlc.arg(batch_size);
+
+-- name: ListOrphanedEntries :many
+-- returns the entries of the accounts in the ID range that aren't part of a journal transaction
+SELECT * FROM entries
+WHERE account_id >= sqlc.arg(from_account_id)
+  AND account_id <= sqlc.arg(to_account_id)
+  AND journal_transaction_id IS NULL
+ORDER BY id;
+
+-- name: ListUnbalancedTransfers :many
+-- returns the transfers from the accounts in the ID range whose journal transaction doesn't
+-- debit amount from the from account and credit to_amount to the to account
+SELECT
+  t.id,
+  t.from_account_id,
+  t.to_account_id,
+  t.amount,
+  t.to_amount,
+  COUNT(e.id)::int AS entry_count,
+  COALESCE(SUM(e.amount) FILTER (WHERE e.account_id = t.from_account_id), 0)::bigint AS from_entries_total,
+  COALESCE(SUM(e.amount) FILTER (WHERE e.account_id = t.to_account_id), 0)::bigint AS to_entries_total
+FROM transfers AS t
+LEFT JOIN journal_transactions AS j ON j.transfer_id = t.id
+LEFT JOIN entries AS e ON e.journal_transaction_id = j.id
+WHERE t.from_account_id >= sqlc.arg(from_account_id)
+  AND t.from_account_id <= sqlc.arg(to_account_id)
+GROUP BY t.id
+HAVING COALESCE(SUM(e.amount) FILTER (WHERE e.account_id = t.from_account_id), 0) <> -t.amount
+  OR COALESCE(SUM(e.amount) FILTER (WHERE e.account_id = t.to_account_id), 0) <> t.to_amount
+ORDER BY t.id;
+
+-- name: GetLastJournalTransactionID :one
+SELECT COALESCE(MAX(id), 0)::bigint FROM journal_transactions;
+
+-- name: ListUnbalancedJournalTransactions :many
+-- returns the currencies in which the journal transactions in the ID range don't sum to zero
+SELECT
+  e.journal_transaction_id::bigint AS journal_transaction_id,
+  a.currency,
+  SUM(e.amount)::bigint AS total
+FROM entries AS e
+JOIN accounts AS a ON a.id = e.account_id
+WHERE e.journal_transaction_id >= sqlc.arg(from_id)::bigint
+  AND e.journal_transaction_id <= sqlc.arg(to_id)::bigint
+GROUP BY e.journal_transaction_id, a.currency
+HAVING SUM(e.amount) <> 0
+ORDER BY e.journal_transaction_id, a.currency;
@@ -0,0 +1,47 @@
+-- name: GetLoginFailure :one
+SELECT * FROM login_failures
+WHERE kind = $1
+  AND subject = $2
+LIMIT 1;
+
+-- name: GetLoginFailureForUpdate :one
+SELECT * FROM login_failures
+WHERE kind = $1
+  AND subject = $2
+LIMIT 1
+FOR UPDATE;
+
+-- name: RecordLoginFailure :one
+-- counts a failed login, starting over when the last failure is older than reset_before
+INSERT INTO login_failures (
+  kind,
+  subject,
+  failed_count,
+  last_failed_at
+) VALUES (
+  sqlc.arg(kind), sqlc.arg(subject), 1, now()
+)
+ON CONFLICT (kind, subject) DO UPDATE
+SET failed_count = CASE
+      WHEN login_failures.last_failed_at < sqlc.arg(reset_before) THEN 1
+      ELSE login_failures.failed_count + 1
+    END,
+    last_failed_at = now()
+RETURNING *;
+
+-- name: DeleteLoginFailure :exec
+DELETE FROM login_failures
+WHERE kind = $1
+  AND subject = $2;
+
+-- name: RefundLoginFailure :exec
+-- takes back an attempt counted as failed once it succeeded, restoring when the failure before it was
+-- unless another attempt was counted after it
+UPDATE login_failures
+SET failed_count = GREATEST(failed_count - 1, 0),
+    last_failed_at = CASE
+      WHEN last_failed_at = sqlc.arg(reserved_at) THEN sqlc.arg(previous_failed_at)
+      ELSE last_failed_at
+    END
+WHERE kind = sqlc.arg(kind)
+  AND subject = sqlc.arg(subject);
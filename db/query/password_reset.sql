@@ -0,0 +1,33 @@
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (
+    username,
+    email,
+    code_hash
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: UsePasswordReset :one
+UPDATE password_resets
+SET
+    is_used = TRUE
+WHERE
+    id = @id
+    AND code_hash = @code_hash
+    AND is_used = FALSE
+    AND expired_at > now()
+RETURNING *;
+
+-- name: InvalidatePasswordResets :exec
+UPDATE password_resets
+SET
+    is_used = TRUE
+WHERE
+    username = $1
+    AND is_used = FALSE;
+
+-- name: GetLastPasswordReset :one
+SELECT * FROM password_resets
+WHERE username = $1
+ORDER BY created_at DESC
+LIMIT 1;
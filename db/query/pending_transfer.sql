@@ -0,0 +1,52 @@
+-- name: CreatePendingTransfer :one
+INSERT INTO pending_transfers (
+  owner,
+  from_account_id,
+  to_account_id,
+  amount,
+  currency,
+  convert_currency,
+  verification_method,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: GetPendingTransfer :one
+SELECT * FROM pending_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: GetPendingTransferForUpdate :one
+SELECT * FROM pending_transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: SetPendingTransferCode :one
+UPDATE pending_transfers
+SET code_hash = $2
+WHERE id = $1
+  AND status = 'pending'
+RETURNING *;
+
+-- name: AddPendingTransferAttempt :one
+-- counts a confirmation attempt before its code is checked
+UPDATE pending_transfers
+SET attempts = attempts + 1
+WHERE id = $1
+  AND status = 'pending'
+RETURNING *;
+
+-- name: ConfirmPendingTransfer :one
+UPDATE pending_transfers
+SET status = 'confirmed',
+    transfer_id = $2,
+    confirmed_at = now()
+WHERE id = $1
+  AND status = 'pending'
+RETURNING *;
+
+-- name: ExpirePendingTransfers :execrows
+UPDATE pending_transfers
+SET status = 'expired'
+WHERE status = 'pending'
+  AND expires_at <= $1;
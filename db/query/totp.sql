@@ -0,0 +1,58 @@
+-- name: UpsertUserTOTP :one
+-- replaces a pending enrollment, but never a confirmed one
+INSERT INTO user_totps (
+  username,
+  secret
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (username) DO UPDATE
+SET secret = EXCLUDED.secret,
+    last_used_step = 0,
+    created_at = now()
+WHERE user_totps.confirmed_at IS NULL
+RETURNING *;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totps
+WHERE username = $1 LIMIT 1;
+
+-- name: ConfirmUserTOTP :one
+UPDATE user_totps
+SET confirmed_at = now(),
+    last_used_step = sqlc.arg(last_used_step)
+WHERE username = sqlc.arg(username)
+  AND confirmed_at IS NULL
+RETURNING *;
+
+-- name: UseTOTPStep :one
+-- a code is accepted once, so its step must be after the last used one
+UPDATE user_totps
+SET last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username)
+  AND last_used_step < sqlc.arg(step)
+RETURNING *;
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM user_totps
+WHERE username = $1;
+
+-- name: CreateRecoveryCode :one
+INSERT INTO totp_recovery_codes (
+  username,
+  code_hash
+) VALUES (
+  $1, $2
+) RETURNING *;
+
+-- name: UseRecoveryCode :one
+UPDATE totp_recovery_codes
+SET used_at = now()
+WHERE username = $1
+  AND code_hash = $2
+  AND used_at IS NULL
+RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM totp_recovery_codes
+WHERE username = $1;
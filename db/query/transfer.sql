@@ -2,15 +2,50 @@
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  to_amount,
+  exchange_rate
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5
 ) RETURNING *;
 
 -- name: GetTransfer :one
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: CreateReversalTransfer :one
+INSERT INTO transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  to_amount,
+  exchange_rate,
+  reversal_of,
+  reason
+)
+SELECT
+  original.to_account_id,
+  original.from_account_id,
+  original.to_amount,
+  original.amount,
+  ROUND(original.amount::numeric / original.to_amount, 8),
+  original.id,
+  sqlc.arg(reason)::varchar
+FROM transfers AS original
+WHERE original.id = sqlc.arg(transfer_id)
+RETURNING *;
+
+-- name: UpdateTransferStatus :one
+UPDATE transfers
+SET status = $2
+WHERE id = $1
+RETURNING *;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 WHERE 
@@ -23,36 +58,63 @@ OFFSET $4;
 -- name: ListTransfersFilteredDesc :many
 SELECT * FROM transfers
 WHERE (
-    ($2 = 'any' AND (from_account_id = $1 OR to_account_id = $1)) OR
-    ($2 = 'out' AND from_account_id = $1) OR
-    ($2 = 'in' AND to_account_id = $1)
+    (from_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::text IN ('any', 'out')) OR
+    (to_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::text IN ('any', 'in'))
   )
-  AND amount >= $3
-  AND amount <= $4
-  AND created_at >= $5
-  AND created_at <= $6
+  AND amount >= sqlc.arg(min_amount)
+  AND amount <= sqlc.arg(max_amount)
+  AND created_at >= sqlc.arg(from_time)
+  AND created_at <= sqlc.arg(to_time)
 ORDER BY created_at DESC
-LIMIT $7
-OFFSET $8;
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
 
 -- name: ListTransfersFilteredAsc :many
 SELECT * FROM transfers
 WHERE (
-    ($2 = 'any' AND (from_account_id = $1 OR to_account_id = $1)) OR
-    ($2 = 'out' AND from_account_id = $1) OR
-    ($2 = 'in' AND to_account_id = $1)
+    (from_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::text IN ('any', 'out')) OR
+    (to_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::text IN ('any', 'in'))
   )
-  AND amount >= $3
-  AND amount <= $4
-  AND created_at >= $5
-  AND created_at <= $6
+  AND amount >= sqlc.arg(min_amount)
+  AND amount <= sqlc.arg(max_amount)
+  AND created_at >= sqlc.arg(from_time)
+  AND created_at <= sqlc.arg(to_time)
 ORDER BY created_at ASC
-LIMIT $7
-OFFSET $8;
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: ListTransfersKeysetDesc :many
+SELECT * FROM transfers
+WHERE (
+    (from_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::text IN ('any', 'out')) OR
+    (to_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::text IN ('any', 'in'))
+  )
+  AND amount >= sqlc.arg(min_amount)
+  AND amount <= sqlc.arg(max_amount)
+  AND created_at >= sqlc.arg(from_time)
+  AND created_at <= sqlc.arg(to_time)
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListTransfersKeysetAsc :many
+SELECT * FROM transfers
+WHERE (
+    (from_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::text IN ('any', 'out')) OR
+    (to_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::text IN ('any', 'in'))
+  )
+  AND amount >= sqlc.arg(min_amount)
+  AND amount <= sqlc.arg(max_amount)
+  AND created_at >= sqlc.arg(from_time)
+  AND created_at <= sqlc.arg(to_time)
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
 
 -- name: GetDailyTransferTotal :one
 SELECT COALESCE(SUM(amount), 0)::bigint AS total
 FROM transfers
 WHERE from_account_id = $1
+  AND reversal_of IS NULL
   AND created_at >= $2
   AND created_at < $3;
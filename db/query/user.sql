@@ -12,6 +12,10 @@ INSERT INTO users (
 SELECT * FROM users
 WHERE username = $1 LIMIT 1;
 
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
 -- name: UpdateUser :one
 UPDATE users
 SET
@@ -23,3 +27,24 @@ SET
 WHERE
   username = sqlc.arg(username)
 RETURNING *;
+
+-- name: GetUserForUpdate :one
+SELECT * FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = sqlc.arg(role)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: SearchUsers :many
+-- matches the users whose username, email or full name contain the pattern
+SELECT * FROM users
+WHERE username ILIKE sqlc.arg(pattern)
+   OR email ILIKE sqlc.arg(pattern)
+   OR full_name ILIKE sqlc.arg(pattern)
+ORDER BY username
+LIMIT sqlc.arg(page_limit)
+OFFSET sqlc.arg(page_offset);
@@ -17,3 +17,16 @@ WHERE
     AND is_used = FALSE
     AND expired_at > now()
 RETURNING *;
+
+-- name: GetLastVerifyEmail :one
+SELECT * FROM verify_emails
+WHERE username = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: InvalidateVerifyEmails :exec
+UPDATE verify_emails
+SET expired_at = now()
+WHERE username = $1
+  AND is_used = FALSE
+  AND expired_at > now();
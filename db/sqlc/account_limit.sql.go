@@ -7,10 +7,12 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const getAccountLimit = `-- name: GetAccountLimit :one
-SELECT account_id, daily_transfer_limit, created_at, updated_at FROM account_limits
+SELECT account_id, daily_transfer_limit, created_at, updated_at, overdraft_limit FROM account_limits
 WHERE account_id = $1 LIMIT 1
 `
 
@@ -22,6 +24,7 @@ func (q *Queries) GetAccountLimit(ctx context.Context, accountID int64) (Account
 		&i.DailyTransferLimit,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OverdraftLimit,
 	)
 	return i, err
 }
@@ -29,30 +32,34 @@ func (q *Queries) GetAccountLimit(ctx context.Context, accountID int64) (Account
 const upsertAccountLimit = `-- name: UpsertAccountLimit :one
 INSERT INTO account_limits (
   account_id,
-  daily_transfer_limit
+  daily_transfer_limit,
+  overdraft_limit
 ) VALUES (
-  $1, $2
+  $1, $2, COALESCE($3::bigint, 0)
 )
 ON CONFLICT (account_id)
 DO UPDATE SET
   daily_transfer_limit = EXCLUDED.daily_transfer_limit,
+  overdraft_limit = COALESCE($3::bigint, account_limits.overdraft_limit),
   updated_at = now()
-RETURNING account_id, daily_transfer_limit, created_at, updated_at
+RETURNING account_id, daily_transfer_limit, created_at, updated_at, overdraft_limit
 `
 
 type UpsertAccountLimitParams struct {
-	AccountID          int64 `json:"account_id"`
-	DailyTransferLimit int64 `json:"daily_transfer_limit"`
+	AccountID          int64       `json:"account_id"`
+	DailyTransferLimit int64       `json:"daily_transfer_limit"`
+	OverdraftLimit     pgtype.Int8 `json:"overdraft_limit"`
 }
 
 func (q *Queries) UpsertAccountLimit(ctx context.Context, arg UpsertAccountLimitParams) (AccountLimit, error) {
-	row := q.db.QueryRow(ctx, upsertAccountLimit, arg.AccountID, arg.DailyTransferLimit)
+	row := q.db.QueryRow(ctx, upsertAccountLimit, arg.AccountID, arg.DailyTransferLimit, arg.OverdraftLimit)
 	var i AccountLimit
 	err := row.Scan(
 		&i.AccountID,
 		&i.DailyTransferLimit,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OverdraftLimit,
 	)
 	return i, err
 }
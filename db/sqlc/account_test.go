@@ -10,15 +10,23 @@ import (
 )
 
 func createRandomAccount(t *testing.T) Account {
+	return createRandomAccountWithBalance(t, util.RandomMoney())
+}
+
+func createRandomAccountWithBalance(t *testing.T, balance int64) Account {
+	return createRandomAccountInCurrency(t, balance, util.RandomCurrency())
+}
+
+func createRandomAccountInCurrency(t *testing.T, balance int64, currency string) Account {
 	user := createRandomUser(t)
 
 	arg := CreateAccountParams{
 		Owner:    user.Username,
-		Balance:  util.RandomMoney(),
-		Currency: util.RandomCurrency(),
+		Balance:  balance,
+		Currency: currency,
 	}
 
-	account, err := testStore.CreateAccount(context.Background(), arg)
+	account, err := testStore.CreateAccountTx(context.Background(), arg)
 	require.NoError(t, err)
 	require.NotEmpty(t, account)
 
@@ -49,34 +57,54 @@ func TestGetAccount(t *testing.T) {
 	require.WithinDuration(t, account1.CreatedAt, account2.CreatedAt, time.Second)
 }
 
-func TestUpdateAccount(t *testing.T) {
-	account1 := createRandomAccount(t)
+func TestCloseAccount(t *testing.T) {
+	account1 := createRandomAccountWithBalance(t, 10)
+	account2 := createRandomAccount(t)
+	ctx := WithAuditActor(context.Background(), AuditActor{Username: account1.Owner, Role: util.DepositorRole})
 
-	arg := UpdateAccountParams{
-		ID:      account1.ID,
-		Balance: util.RandomMoney(),
+	arg := UpdateAccountStatusTxParams{
+		AccountID: account1.ID,
+		Status:    AccountStatusClosed,
+		Action:    AuditActionAccountClose,
 	}
 
-	account2, err := testStore.UpdateAccount(context.Background(), arg)
-	require.NoError(t, err)
-	require.NotEmpty(t, account2)
+	_, err := testStore.UpdateAccountStatusTx(ctx, arg)
+	require.ErrorIs(t, err, ErrAccountBalanceNotZero)
 
-	require.Equal(t, account1.ID, account2.ID)
-	require.Equal(t, account1.Owner, account2.Owner)
-	require.Equal(t, arg.Balance, account2.Balance)
-	require.Equal(t, account1.Currency, account2.Currency)
-	require.WithinDuration(t, account1.CreatedAt, account2.CreatedAt, time.Second)
-}
+	_, err = testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
 
-func TestDeleteAccount(t *testing.T) {
-	account1 := createRandomAccount(t)
-	err := testStore.DeleteAccount(context.Background(), account1.ID)
+	result, err := testStore.UpdateAccountStatusTx(ctx, arg)
 	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, result.Account.Status)
 
-	account2, err := testStore.GetAccount(context.Background(), account1.ID)
-	require.Error(t, err)
-	require.EqualError(t, err, ErrRecordNotFound.Error())
-	require.Empty(t, account2)
+	// the account is kept for its entries and transfers
+	account, err := testStore.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, account.Status)
+
+	_, err = testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account1.ID,
+		Amount:        1,
+	})
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	arg.Status = AccountStatusActive
+	_, err = testStore.UpdateAccountStatusTx(ctx, arg)
+	require.ErrorIs(t, err, ErrInvalidAccountStatusTransition)
+
+	// the owner can open a new account in the currency of the closed one
+	reopened, err := testStore.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    account1.Owner,
+		Currency: account1.Currency,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusActive, reopened.Status)
 }
 
 func TestListAccounts(t *testing.T) {
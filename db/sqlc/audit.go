@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Audited actions
+const (
+	AuditActionUserCreate         = "user.create"
+	AuditActionUserUpdate         = "user.update"
+	AuditActionUserLogin          = "user.login"
+	AuditActionUserPasswordReset  = "user.password_reset"
+	AuditActionUserEmailVerify    = "user.email_verify"
+	AuditActionUserTOTPEnroll     = "user.totp_enroll"
+	AuditActionUserTOTPEnable     = "user.totp_enable"
+	AuditActionUserTOTPDisable    = "user.totp_disable"
+	AuditActionUserSearch         = "user.search"
+	AuditActionUserRoleChange     = "user.role_change"
+	AuditActionSessionRevoke      = "session.revoke"
+	AuditActionSessionRevokeAll   = "session.revoke_all"
+	AuditActionSessionRefresh     = "session.refresh"
+	AuditActionAccountCreate      = "account.create"
+	AuditActionAccountView        = "account.view"
+	AuditActionAccountFreeze      = "account.freeze"
+	AuditActionAccountUnfreeze    = "account.unfreeze"
+	AuditActionAccountClose       = "account.close"
+	AuditActionAccountLimitSet    = "account.limit_set"
+	AuditActionAccountAlertSet    = "account.alert_set"
+	AuditActionEntriesView        = "account.entries_view"
+	AuditActionTransfersView      = "account.transfers_view"
+	AuditActionTransferCreate     = "transfer.create"
+	AuditActionTransferReverse    = "transfer.reverse"
+	AuditActionPendingCreate      = "pending_transfer.create"
+	AuditActionPendingAttempt     = "pending_transfer.failed_attempt"
+	AuditActionPendingConfirm     = "pending_transfer.confirm"
+	AuditActionScheduledCreate    = "scheduled_transfer.create"
+	AuditActionScheduledUpdate    = "scheduled_transfer.update"
+	AuditActionScheduledSetStatus = "scheduled_transfer.status_change"
+	AuditActionScheduledRun       = "scheduled_transfer.run"
+	AuditActionAuditLogView       = "audit_log.view"
+	AuditActionAuditLogVerify     = "audit_log.verify"
+	AuditActionJournalPost        = "journal.post"
+)
+
+// Types of audited targets
+const (
+	AuditTargetUser              = "user"
+	AuditTargetSession           = "session"
+	AuditTargetAccount           = "account"
+	AuditTargetTransfer          = "transfer"
+	AuditTargetPendingTransfer   = "pending_transfer"
+	AuditTargetScheduledTransfer = "scheduled_transfer"
+	AuditTargetAuditLog          = "audit_log"
+	AuditTargetJournal           = "journal_transaction"
+)
+
+// AuditRoleSystem is the role of the actor of events without a user, such as scheduled transfer runs
+const AuditRoleSystem = "system"
+
+// AuditActor is the user performing an audited action, and where the request came from
+type AuditActor struct {
+	Username  string
+	Role      string
+	ClientIP  string
+	UserAgent string
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor returns a copy of ctx carrying the actor of the audit events recorded with it
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditActorFromContext returns the actor carried by ctx, if any
+func AuditActorFromContext(ctx context.Context) (AuditActor, bool) {
+	actor, ok := ctx.Value(auditActorKey{}).(AuditActor)
+	return actor, ok
+}
+
+// AuditRecord describes an audited action.
+// Before and After are the states of the target around the action, encoded as JSON;
+// they are left empty for actions that change nothing.
+type AuditRecord struct {
+	Actor      AuditActor
+	Action     string
+	TargetType string
+	TargetID   string
+	Reason     string
+	Before     any
+	After      any
+}
+
+// Params returns the parameters to insert the record as an audit event.
+// A record without an actor username is recorded as an action of the system.
+func (record AuditRecord) Params() (CreateAuditEventParams, error) {
+	before, err := marshalAuditState(record.Before)
+	if err != nil {
+		return CreateAuditEventParams{}, err
+	}
+
+	after, err := marshalAuditState(record.After)
+	if err != nil {
+		return CreateAuditEventParams{}, err
+	}
+
+	actor := record.Actor
+	if actor.Username == "" {
+		actor.Role = AuditRoleSystem
+	}
+
+	return CreateAuditEventParams{
+		ActorUsername: pgtype.Text{String: actor.Username, Valid: actor.Username != ""},
+		ActorRole:     actor.Role,
+		ClientIp:      actor.ClientIP,
+		UserAgent:     actor.UserAgent,
+		Action:        record.Action,
+		TargetType:    record.TargetType,
+		TargetID:      record.TargetID,
+		Reason:        record.Reason,
+		Before:        before,
+		After:         after,
+	}, nil
+}
+
+func marshalAuditState(state any) ([]byte, error) {
+	if state == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit state: %w", err)
+	}
+
+	// a nil pointer means there was no state, e.g. before a limit is first set
+	if string(data) == "null" {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// recordAuditEvent inserts the record within the transaction of q,
+// so the event is only kept if the audited change is committed.
+// The fields of the actor left empty in the record are taken from the actor carried by ctx.
+func recordAuditEvent(ctx context.Context, q *Queries, record AuditRecord) (AuditEvent, error) {
+	if actor, ok := AuditActorFromContext(ctx); ok {
+		if record.Actor.Username == "" {
+			record.Actor.Username = actor.Username
+			record.Actor.Role = actor.Role
+		}
+		if record.Actor.ClientIP == "" {
+			record.Actor.ClientIP = actor.ClientIP
+		}
+		if record.Actor.UserAgent == "" {
+			record.Actor.UserAgent = actor.UserAgent
+		}
+	}
+
+	arg, err := record.Params()
+	if err != nil {
+		return AuditEvent{}, err
+	}
+
+	return q.CreateAuditEvent(ctx, arg)
+}
+
+// formatAuditID formats a numeric ID as the target ID of an audit event
+func formatAuditID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// auditUser is the audited state of a user, without any secret
+type auditUser struct {
+	Username          string `json:"username"`
+	FullName          string `json:"full_name"`
+	Email             string `json:"email"`
+	Role              string `json:"role"`
+	IsEmailVerified   bool   `json:"is_email_verified"`
+	PasswordChangedAt string `json:"password_changed_at"`
+}
+
+func newAuditUser(user User) auditUser {
+	return auditUser{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
+		PasswordChangedAt: user.PasswordChangedAt.UTC().Format(time.RFC3339Nano),
+	}
+}
@@ -0,0 +1,186 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: audit_event.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createAuditEvent = `-- name: CreateAuditEvent :one
+INSERT INTO audit_events (
+  actor_username,
+  actor_role,
+  client_ip,
+  user_agent,
+  action,
+  target_type,
+  target_id,
+  reason,
+  before,
+  after
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING id, actor_username, actor_role, action, target_type, target_id, reason, before, after, created_at, client_ip, user_agent, prev_hash, hash
+`
+
+type CreateAuditEventParams struct {
+	ActorUsername pgtype.Text `json:"actor_username"`
+	ActorRole     string      `json:"actor_role"`
+	ClientIp      string      `json:"client_ip"`
+	UserAgent     string      `json:"user_agent"`
+	Action        string      `json:"action"`
+	TargetType    string      `json:"target_type"`
+	TargetID      string      `json:"target_id"`
+	Reason        string      `json:"reason"`
+	Before        []byte      `json:"before"`
+	After         []byte      `json:"after"`
+}
+
+// the hash chain columns are filled in by the audit_events_chain trigger
+func (q *Queries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error) {
+	row := q.db.QueryRow(ctx, createAuditEvent,
+		arg.ActorUsername,
+		arg.ActorRole,
+		arg.ClientIp,
+		arg.UserAgent,
+		arg.Action,
+		arg.TargetType,
+		arg.TargetID,
+		arg.Reason,
+		arg.Before,
+		arg.After,
+	)
+	var i AuditEvent
+	err := row.Scan(
+		&i.ID,
+		&i.ActorUsername,
+		&i.ActorRole,
+		&i.Action,
+		&i.TargetType,
+		&i.TargetID,
+		&i.Reason,
+		&i.Before,
+		&i.After,
+		&i.CreatedAt,
+		&i.ClientIp,
+		&i.UserAgent,
+		&i.PrevHash,
+		&i.Hash,
+	)
+	return i, err
+}
+
+const listAuditEvents = `-- name: ListAuditEvents :many
+SELECT id, actor_username, actor_role, action, target_type, target_id, reason, before, after, created_at, client_ip, user_agent, prev_hash, hash FROM audit_events
+WHERE ($1::varchar IS NULL OR actor_username = $1)
+  AND ($2::varchar IS NULL OR action = $2)
+  AND ($3::varchar IS NULL OR target_type = $3)
+  AND ($4::varchar IS NULL OR target_id = $4)
+  AND created_at >= $5
+  AND created_at <= $6
+ORDER BY id DESC
+LIMIT $8
+OFFSET $7
+`
+
+type ListAuditEventsParams struct {
+	ActorUsername pgtype.Text `json:"actor_username"`
+	Action        pgtype.Text `json:"action"`
+	TargetType    pgtype.Text `json:"target_type"`
+	TargetID      pgtype.Text `json:"target_id"`
+	FromTime      time.Time   `json:"from_time"`
+	ToTime        time.Time   `json:"to_time"`
+	PageOffset    int32       `json:"page_offset"`
+	PageLimit     int32       `json:"page_limit"`
+}
+
+func (q *Queries) ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error) {
+	rows, err := q.db.Query(ctx, listAuditEvents,
+		arg.ActorUsername,
+		arg.Action,
+		arg.TargetType,
+		arg.TargetID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditEvent{}
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.ActorUsername,
+			&i.ActorRole,
+			&i.Action,
+			&i.TargetType,
+			&i.TargetID,
+			&i.Reason,
+			&i.Before,
+			&i.After,
+			&i.CreatedAt,
+			&i.ClientIp,
+			&i.UserAgent,
+			&i.PrevHash,
+			&i.Hash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBrokenAuditEvents = `-- name: ListBrokenAuditEvents :many
+SELECT id FROM (
+  SELECT
+    id,
+    hash,
+    prev_hash,
+    lag(hash) OVER (PARTITION BY target_type, target_id ORDER BY id) AS expected_prev_hash,
+    audit_event_hash(
+      prev_hash, id, actor_username, actor_role, client_ip, user_agent,
+      action, target_type, target_id, reason, before, after, created_at
+    ) AS expected_hash
+  FROM audit_events
+) AS chain
+WHERE hash <> expected_hash
+   OR prev_hash IS DISTINCT FROM expected_prev_hash
+ORDER BY id
+LIMIT $1
+`
+
+// returns the events whose hash doesn't match their content,
+// or whose previous hash doesn't match the hash of the event of the same target before them
+func (q *Queries) ListBrokenAuditEvents(ctx context.Context, maxResults int32) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listBrokenAuditEvents, maxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateAccountStatusTx(t *testing.T) {
+	banker := createRandomUser(t)
+	ctx := WithAuditActor(context.Background(), AuditActor{Username: banker.Username, Role: util.BankerRole})
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	result, err := testStore.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{
+		AccountID: account1.ID,
+		Status:    AccountStatusFrozen,
+		Action:    AuditActionAccountFreeze,
+		Reason:    "suspected fraud",
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusFrozen, result.Account.Status)
+
+	event := result.AuditEvent
+	require.Equal(t, banker.Username, event.ActorUsername.String)
+	require.Equal(t, util.BankerRole, event.ActorRole)
+	require.Equal(t, AuditActionAccountFreeze, event.Action)
+	require.Equal(t, AuditTargetAccount, event.TargetType)
+	require.Equal(t, formatAuditID(account1.ID), event.TargetID)
+	require.Equal(t, "suspected fraud", event.Reason)
+	require.JSONEq(t, `{"status":"active"}`, string(event.Before))
+	require.JSONEq(t, `{"status":"frozen"}`, string(event.After))
+
+	_, err = testStore.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{
+		AccountID: account1.ID,
+		Status:    AccountStatusFrozen,
+		Action:    AuditActionAccountFreeze,
+	})
+	require.ErrorIs(t, err, ErrAccountStatusUnchanged)
+
+	for _, arg := range []TransferTxParams{
+		{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 1},
+		{FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 1},
+	} {
+		_, err = testStore.TransferTx(context.Background(), arg)
+		require.ErrorIs(t, err, ErrAccountFrozen)
+	}
+
+	_, err = testStore.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{
+		AccountID: account1.ID,
+		Status:    AccountStatusActive,
+		Action:    AuditActionAccountUnfreeze,
+	})
+	require.NoError(t, err)
+
+	_, err = testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1,
+	})
+	require.NoError(t, err)
+
+	events, err := testStore.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		ActorUsername: pgtype.Text{String: banker.Username, Valid: true},
+		TargetType:    pgtype.Text{String: AuditTargetAccount, Valid: true},
+		TargetID:      pgtype.Text{String: formatAuditID(account1.ID), Valid: true},
+		FromTime:      time.Now().Add(-time.Minute),
+		ToTime:        time.Now().Add(time.Minute),
+		PageLimit:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, AuditActionAccountUnfreeze, events[0].Action)
+	require.Equal(t, AuditActionAccountFreeze, events[1].Action)
+}
+
+func TestChangeUserRoleTx(t *testing.T) {
+	banker := createRandomUser(t)
+	ctx := WithAuditActor(context.Background(), AuditActor{Username: banker.Username, Role: util.BankerRole})
+	user := createRandomUser(t)
+	createRandomSession(t, user)
+
+	result, err := testStore.ChangeUserRoleTx(ctx, ChangeUserRoleTxParams{
+		Username: user.Username,
+		Role:     util.BankerRole,
+		Reason:   "joined the branch",
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.BankerRole, result.User.Role)
+	require.Equal(t, int64(1), result.BlockedSessions)
+
+	var before, after map[string]string
+	require.NoError(t, json.Unmarshal(result.AuditEvent.Before, &before))
+	require.NoError(t, json.Unmarshal(result.AuditEvent.After, &after))
+	require.Equal(t, map[string]string{"role": util.DepositorRole}, before)
+	require.Equal(t, map[string]string{"role": util.BankerRole}, after)
+	require.Equal(t, AuditTargetUser, result.AuditEvent.TargetType)
+	require.Equal(t, user.Username, result.AuditEvent.TargetID)
+
+	_, err = testStore.ChangeUserRoleTx(ctx, ChangeUserRoleTxParams{
+		Username: user.Username,
+		Role:     util.BankerRole,
+	})
+	require.ErrorIs(t, err, ErrUserRoleUnchanged)
+}
+
+func TestAuditEventChain(t *testing.T) {
+	user := createRandomUser(t)
+	ctx := WithAuditActor(context.Background(), AuditActor{
+		Username:  user.Username,
+		Role:      user.Role,
+		ClientIP:  "127.0.0.1",
+		UserAgent: "test",
+	})
+
+	account := createRandomAccount(t)
+	limit, err := testStore.UpsertAccountLimitTx(ctx, UpsertAccountLimitParams{
+		AccountID:          account.ID,
+		DailyTransferLimit: 100,
+	})
+	require.NoError(t, err)
+
+	events, err := testStore.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		Action:     pgtype.Text{String: AuditActionAccountLimitSet, Valid: true},
+		TargetType: pgtype.Text{String: AuditTargetAccount, Valid: true},
+		TargetID:   pgtype.Text{String: formatAuditID(limit.AccountID), Valid: true},
+		FromTime:   time.Now().Add(-time.Minute),
+		ToTime:     time.Now().Add(time.Minute),
+		PageLimit:  5,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	event := events[0]
+	require.Equal(t, user.Username, event.ActorUsername.String)
+	require.Equal(t, "127.0.0.1", event.ClientIp)
+	require.Equal(t, "test", event.UserAgent)
+	require.Nil(t, event.Before)
+	require.Len(t, event.Hash, 32)
+	require.Len(t, event.PrevHash, 32)
+
+	broken, err := testStore.ListBrokenAuditEvents(context.Background(), 10)
+	require.NoError(t, err)
+	require.Empty(t, broken)
+
+	// the log is append-only
+	_, err = testConnPool.Exec(context.Background(),
+		"UPDATE audit_events SET reason = 'tampered' WHERE id = $1", event.ID)
+	require.Error(t, err)
+
+	_, err = testConnPool.Exec(context.Background(),
+		"DELETE FROM audit_events WHERE id = $1", event.ID)
+	require.Error(t, err)
+}
+
+func TestAuditEventChainPerTarget(t *testing.T) {
+	banker := createRandomUser(t)
+	ctx := WithAuditActor(context.Background(), AuditActor{Username: banker.Username, Role: util.BankerRole})
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	updateStatus := func(account Account, status string, action string) AuditEvent {
+		result, err := testStore.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{
+			AccountID: account.ID,
+			Status:    status,
+			Action:    action,
+		})
+		require.NoError(t, err)
+		return result.AuditEvent
+	}
+
+	// the events of the two accounts are interleaved, but each one follows the last event of its own account
+	freeze1 := updateStatus(account1, AccountStatusFrozen, AuditActionAccountFreeze)
+	freeze2 := updateStatus(account2, AccountStatusFrozen, AuditActionAccountFreeze)
+	unfreeze1 := updateStatus(account1, AccountStatusActive, AuditActionAccountUnfreeze)
+	unfreeze2 := updateStatus(account2, AccountStatusActive, AuditActionAccountUnfreeze)
+
+	require.Equal(t, freeze1.Hash, unfreeze1.PrevHash)
+	require.Equal(t, freeze2.Hash, unfreeze2.PrevHash)
+	require.NotEqual(t, freeze2.Hash, unfreeze1.PrevHash)
+
+	broken, err := testStore.ListBrokenAuditEvents(context.Background(), 10)
+	require.NoError(t, err)
+	require.Empty(t, broken)
+}
+
+func TestUpdateAccountStatusTxSystemAccount(t *testing.T) {
+	banker := createRandomUser(t)
+	ctx := WithAuditActor(context.Background(), AuditActor{Username: banker.Username, Role: util.BankerRole})
+
+	exchangeAccount, err := testStore.GetAccountByOwnerCurrency(context.Background(), GetAccountByOwnerCurrencyParams{
+		Owner:    ExchangeUsername,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+
+	_, err = testStore.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{
+		AccountID: exchangeAccount.ID,
+		Status:    AccountStatusFrozen,
+		Action:    AuditActionAccountFreeze,
+	})
+	require.ErrorIs(t, err, ErrSystemAccount)
+}
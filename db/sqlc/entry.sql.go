@@ -8,36 +8,60 @@ package db
 import (
 	"context"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  journal_transaction_id
 ) VALUES (
-  $1, $2
-) RETURNING id, account_id, amount, created_at
+  $1, $2, $3
+) RETURNING id, account_id, amount, created_at, journal_transaction_id
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID            int64       `json:"account_id"`
+	Amount               int64       `json:"amount"`
+	JournalTransactionID pgtype.Int8 `json:"journal_transaction_id"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRow(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createEntry, arg.AccountID, arg.Amount, arg.JournalTransactionID)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.JournalTransactionID,
 	)
 	return i, err
 }
 
+const getEntriesTotalSince = `-- name: GetEntriesTotalSince :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total
+FROM entries
+WHERE account_id = $1
+  AND created_at >= $2
+`
+
+type GetEntriesTotalSinceParams struct {
+	AccountID int64     `json:"account_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) GetEntriesTotalSince(ctx context.Context, arg GetEntriesTotalSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, getEntriesTotalSince, arg.AccountID, arg.CreatedAt)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, journal_transaction_id FROM entries
 WHERE id = $1 LIMIT 1
 `
 
@@ -49,12 +73,13 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.JournalTransactionID,
 	)
 	return i, err
 }
 
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, journal_transaction_id FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
@@ -81,6 +106,7 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.JournalTransactionID,
 		); err != nil {
 			return nil, err
 		}
@@ -93,7 +119,7 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 }
 
 const listEntriesFilteredAsc = `-- name: ListEntriesFilteredAsc :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, journal_transaction_id FROM entries
 WHERE account_id = $1
   AND amount >= $2
   AND amount <= $3
@@ -136,6 +162,7 @@ func (q *Queries) ListEntriesFilteredAsc(ctx context.Context, arg ListEntriesFil
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.JournalTransactionID,
 		); err != nil {
 			return nil, err
 		}
@@ -148,7 +175,7 @@ func (q *Queries) ListEntriesFilteredAsc(ctx context.Context, arg ListEntriesFil
 }
 
 const listEntriesFilteredDesc = `-- name: ListEntriesFilteredDesc :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, journal_transaction_id FROM entries
 WHERE account_id = $1
   AND amount >= $2
   AND amount <= $3
@@ -191,6 +218,123 @@ func (q *Queries) ListEntriesFilteredDesc(ctx context.Context, arg ListEntriesFi
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.JournalTransactionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesKeysetAsc = `-- name: ListEntriesKeysetAsc :many
+SELECT id, account_id, amount, created_at, journal_transaction_id FROM entries
+WHERE account_id = $1
+  AND amount >= $2
+  AND amount <= $3
+  AND created_at >= $4
+  AND created_at <= $5
+  AND (created_at, id) > ($6::timestamptz, $7::bigint)
+ORDER BY created_at ASC, id ASC
+LIMIT $8
+`
+
+type ListEntriesKeysetAscParams struct {
+	AccountID       int64     `json:"account_id"`
+	MinAmount       int64     `json:"min_amount"`
+	MaxAmount       int64     `json:"max_amount"`
+	FromTime        time.Time `json:"from_time"`
+	ToTime          time.Time `json:"to_time"`
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	Limit           int32     `json:"limit"`
+}
+
+func (q *Queries) ListEntriesKeysetAsc(ctx context.Context, arg ListEntriesKeysetAscParams) ([]Entry, error) {
+	rows, err := q.db.Query(ctx, listEntriesKeysetAsc,
+		arg.AccountID,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.FromTime,
+		arg.ToTime,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.JournalTransactionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesKeysetDesc = `-- name: ListEntriesKeysetDesc :many
+SELECT id, account_id, amount, created_at, journal_transaction_id FROM entries
+WHERE account_id = $1
+  AND amount >= $2
+  AND amount <= $3
+  AND created_at >= $4
+  AND created_at <= $5
+  AND (created_at, id) < ($6::timestamptz, $7::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT $8
+`
+
+type ListEntriesKeysetDescParams struct {
+	AccountID       int64     `json:"account_id"`
+	MinAmount       int64     `json:"min_amount"`
+	MaxAmount       int64     `json:"max_amount"`
+	FromTime        time.Time `json:"from_time"`
+	ToTime          time.Time `json:"to_time"`
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	Limit           int32     `json:"limit"`
+}
+
+func (q *Queries) ListEntriesKeysetDesc(ctx context.Context, arg ListEntriesKeysetDescParams) ([]Entry, error) {
+	rows, err := q.db.Query(ctx, listEntriesKeysetDesc,
+		arg.AccountID,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.FromTime,
+		arg.ToTime,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.JournalTransactionID,
 		); err != nil {
 			return nil, err
 		}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"math"
 	"testing"
 	"time"
 
@@ -67,3 +68,38 @@ func TestListEntries(t *testing.T) {
 		require.Equal(t, arg.AccountID, entry.AccountID)
 	}
 }
+
+func TestListEntriesKeyset(t *testing.T) {
+	account := createRandomAccount(t)
+	for i := 0; i < 10; i++ {
+		createRandomEntry(t, account)
+	}
+
+	arg := ListEntriesKeysetDescParams{
+		AccountID:       account.ID,
+		MinAmount:       math.MinInt64,
+		MaxAmount:       math.MaxInt64,
+		FromTime:        time.Now().Add(-time.Minute),
+		ToTime:          time.Now(),
+		CursorCreatedAt: time.Now(),
+		CursorID:        math.MaxInt64,
+		Limit:           6,
+	}
+
+	// every entry is seen exactly once across both pages
+	seen := make(map[int64]bool)
+	for page := 0; page < 2; page++ {
+		entries, err := testStore.ListEntriesKeysetDesc(context.Background(), arg)
+		require.NoError(t, err)
+
+		for _, entry := range entries {
+			require.False(t, seen[entry.ID])
+			seen[entry.ID] = true
+		}
+
+		last := entries[len(entries)-1]
+		arg.CursorCreatedAt = last.CreatedAt
+		arg.CursorID = last.ID
+	}
+	require.Len(t, seen, 10)
+}
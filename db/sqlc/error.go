@@ -16,10 +16,55 @@ var ErrRecordNotFound = pgx.ErrNoRows
 
 var ErrDailyTransferLimitExceeded = errors.New("daily transfer limit exceeded")
 
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+var ErrTransferAlreadyReversed = errors.New("transfer has already been reversed")
+
+var ErrTransferNotReversible = errors.New("a reversal transfer cannot be reversed")
+
+var ErrIdempotencyKeyConflict = errors.New("idempotency key was already used for a different request")
+
+var ErrSessionBlocked = errors.New("blocked session")
+
+var ErrSessionMismatch = errors.New("mismatched session token")
+
+var ErrSessionExpired = errors.New("expired session")
+
+var ErrRefreshTokenReused = errors.New("refresh token was already used, all sessions of its family are revoked")
+
+var ErrPendingTransferNotPending = errors.New("pending transfer is no longer pending")
+
+var ErrPendingTransferExpired = errors.New("pending transfer has expired")
+
+var ErrAccountFrozen = errors.New("account is frozen")
+
+var ErrAccountClosed = errors.New("account is closed")
+
+var ErrAccountBalanceNotZero = errors.New("account balance must be zero to close it")
+
+var ErrInvalidAccountStatusTransition = errors.New("invalid account status transition")
+
+var ErrAccountStatusUnchanged = errors.New("account already has this status")
+
+var ErrSystemAccount = errors.New("account is owned by the bank and its status cannot change")
+
+var ErrUserRoleUnchanged = errors.New("user already has this role")
+
+var ErrUnbalancedJournal = errors.New("journal transaction entries must sum to zero in each currency")
+
 var ErrUniqueViolation = &pgconn.PgError{
 	Code: UniqueViolation,
 }
 
+// IsTransferRejected reports whether a transfer transaction was refused by a rule of the accounts,
+// which retrying the same transfer won't change
+func IsTransferRejected(err error) bool {
+	return errors.Is(err, ErrInsufficientFunds) ||
+		errors.Is(err, ErrDailyTransferLimitExceeded) ||
+		errors.Is(err, ErrAccountFrozen) ||
+		errors.Is(err, ErrAccountClosed)
+}
+
 func ErrorCode(err error) string {
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
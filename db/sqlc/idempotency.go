@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// IdempotencyParams identifies a client supplied idempotency key.
+// Keys are scoped to the user who sent the request.
+type IdempotencyParams struct {
+	Username string
+	Key      string
+}
+
+// requestHash returns a fingerprint of a request body,
+// used to detect a key being reused for a different request.
+func requestHash(request interface{}) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal request: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// claimIdempotencyKey reserves the key for the current transaction.
+// If the key was already used, it loads the stored response into result and returns true.
+// A concurrent transaction holding the same key blocks the insert until it commits or rolls back.
+func claimIdempotencyKey(ctx context.Context, q *Queries, key IdempotencyParams, request interface{}, result interface{}) (bool, error) {
+	hash, err := requestHash(request)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+		Username:       key.Username,
+		IdempotencyKey: key.Key,
+		RequestHash:    hash,
+	})
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, ErrRecordNotFound) {
+		return false, err
+	}
+
+	existing, err := q.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{
+		Username:       key.Username,
+		IdempotencyKey: key.Key,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if existing.RequestHash != hash || len(existing.Response) == 0 {
+		return false, ErrIdempotencyKeyConflict
+	}
+
+	if err := json.Unmarshal(existing.Response, result); err != nil {
+		return false, fmt.Errorf("cannot unmarshal stored response: %w", err)
+	}
+
+	return true, nil
+}
+
+func saveIdempotentResponse(ctx context.Context, q *Queries, key IdempotencyParams, result interface{}) error {
+	response, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("cannot marshal response: %w", err)
+	}
+
+	return q.UpdateIdempotencyKeyResponse(ctx, UpdateIdempotencyKeyResponseParams{
+		Username:       key.Username,
+		IdempotencyKey: key.Key,
+		Response:       response,
+	})
+}
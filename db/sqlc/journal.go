@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+)
+
+// Journal is a journal transaction with all of its entries
+type Journal struct {
+	JournalTransaction
+	Entries []Entry `json:"entries"`
+}
+
+// ListJournals returns the journal transactions of the given IDs with their entries, in ID order.
+// IDs that don't exist are skipped.
+func ListJournals(ctx context.Context, q Querier, ids []int64) ([]Journal, error) {
+	if len(ids) == 0 {
+		return []Journal{}, nil
+	}
+
+	transactions, err := q.ListJournalTransactionsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := q.ListEntriesByJournalTransactions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64][]Entry, len(transactions))
+	for _, entry := range entries {
+		byID[entry.JournalTransactionID.Int64] = append(byID[entry.JournalTransactionID.Int64], entry)
+	}
+
+	journals := make([]Journal, 0, len(transactions))
+	for _, transaction := range transactions {
+		journals = append(journals, Journal{
+			JournalTransaction: transaction,
+			Entries:            byID[transaction.ID],
+		})
+	}
+	return journals, nil
+}
+
+// ListAccountJournals is ListJournals keeping only the entries of the account in each journal transaction,
+// so that the entries of an account do not disclose the accounts on the other side of its transactions
+func ListAccountJournals(ctx context.Context, q Querier, accountID int64, ids []int64) ([]Journal, error) {
+	journals, err := ListJournals(ctx, q, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, journal := range journals {
+		entries := make([]Entry, 0, len(journal.Entries))
+		for _, entry := range journal.Entries {
+			if entry.AccountID == accountID {
+				entries = append(entries, entry)
+			}
+		}
+		journals[i].Entries = entries
+	}
+	return journals, nil
+}
+
+// EntryJournalIDs returns the distinct journal transaction IDs of the entries
+func EntryJournalIDs(entries []Entry) []int64 {
+	seen := make(map[int64]bool, len(entries))
+	ids := make([]int64, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.JournalTransactionID.Valid || seen[entry.JournalTransactionID.Int64] {
+			continue
+		}
+		seen[entry.JournalTransactionID.Int64] = true
+		ids = append(ids, entry.JournalTransactionID.Int64)
+	}
+	return ids
+}
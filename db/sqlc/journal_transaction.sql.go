@@ -0,0 +1,123 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: journal_transaction.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createJournalTransaction = `-- name: CreateJournalTransaction :one
+INSERT INTO journal_transactions (
+  kind,
+  transfer_id,
+  description
+) VALUES (
+  $1, $2, $3
+) RETURNING id, kind, transfer_id, description, created_at
+`
+
+type CreateJournalTransactionParams struct {
+	Kind        string      `json:"kind"`
+	TransferID  pgtype.Int8 `json:"transfer_id"`
+	Description string      `json:"description"`
+}
+
+func (q *Queries) CreateJournalTransaction(ctx context.Context, arg CreateJournalTransactionParams) (JournalTransaction, error) {
+	row := q.db.QueryRow(ctx, createJournalTransaction, arg.Kind, arg.TransferID, arg.Description)
+	var i JournalTransaction
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.TransferID,
+		&i.Description,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getJournalTransaction = `-- name: GetJournalTransaction :one
+SELECT id, kind, transfer_id, description, created_at FROM journal_transactions
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetJournalTransaction(ctx context.Context, id int64) (JournalTransaction, error) {
+	row := q.db.QueryRow(ctx, getJournalTransaction, id)
+	var i JournalTransaction
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.TransferID,
+		&i.Description,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listEntriesByJournalTransactions = `-- name: ListEntriesByJournalTransactions :many
+SELECT id, account_id, amount, created_at, journal_transaction_id FROM entries
+WHERE journal_transaction_id = ANY($1::bigint[])
+ORDER BY id
+`
+
+func (q *Queries) ListEntriesByJournalTransactions(ctx context.Context, ids []int64) ([]Entry, error) {
+	rows, err := q.db.Query(ctx, listEntriesByJournalTransactions, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.JournalTransactionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listJournalTransactionsByIDs = `-- name: ListJournalTransactionsByIDs :many
+SELECT id, kind, transfer_id, description, created_at FROM journal_transactions
+WHERE id = ANY($1::bigint[])
+ORDER BY id
+`
+
+func (q *Queries) ListJournalTransactionsByIDs(ctx context.Context, ids []int64) ([]JournalTransaction, error) {
+	rows, err := q.db.Query(ctx, listJournalTransactionsByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []JournalTransaction{}
+	for rows.Next() {
+		var i JournalTransaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.TransferID,
+			&i.Description,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,225 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: ledger.sql
+
+package db
+
+import (
+	"context"
+)
+
+const getLastJournalTransactionID = `-- name: GetLastJournalTransactionID :one
+SELECT COALESCE(MAX(id), 0)::bigint FROM journal_transactions
+`
+
+func (q *Queries) GetLastJournalTransactionID(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, getLastJournalTransactionID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const listAccountLedgerTotals = `-- name: ListAccountLedgerTotals :many
+SELECT
+  a.id,
+  a.currency,
+  a.balance,
+  (SELECT COALESCE(SUM(e.amount), 0) FROM entries AS e WHERE e.account_id = a.id)::bigint AS entries_total
+FROM accounts AS a
+WHERE a.id > $1
+ORDER BY a.id
+LIMIT $2
+`
+
+type ListAccountLedgerTotalsParams struct {
+	AfterID   int64 `json:"after_id"`
+	BatchSize int32 `json:"batch_size"`
+}
+
+type ListAccountLedgerTotalsRow struct {
+	ID           int64  `json:"id"`
+	Currency     string `json:"currency"`
+	Balance      int64  `json:"balance"`
+	EntriesTotal int64  `json:"entries_total"`
+}
+
+// returns the balance of each account along with the sum of its entries, which must be equal
+func (q *Queries) ListAccountLedgerTotals(ctx context.Context, arg ListAccountLedgerTotalsParams) ([]ListAccountLedgerTotalsRow, error) {
+	rows, err := q.db.Query(ctx, listAccountLedgerTotals, arg.AfterID, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountLedgerTotalsRow{}
+	for rows.Next() {
+		var i ListAccountLedgerTotalsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Currency,
+			&i.Balance,
+			&i.EntriesTotal,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrphanedEntries = `-- name: ListOrphanedEntries :many
+SELECT id, account_id, amount, created_at, journal_transaction_id FROM entries
+WHERE account_id >= $1
+  AND account_id <= $2
+  AND journal_transaction_id IS NULL
+ORDER BY id
+`
+
+type ListOrphanedEntriesParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+}
+
+// returns the entries of the accounts in the ID range that aren't part of a journal transaction
+func (q *Queries) ListOrphanedEntries(ctx context.Context, arg ListOrphanedEntriesParams) ([]Entry, error) {
+	rows, err := q.db.Query(ctx, listOrphanedEntries, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.JournalTransactionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnbalancedJournalTransactions = `-- name: ListUnbalancedJournalTransactions :many
+SELECT
+  e.journal_transaction_id::bigint AS journal_transaction_id,
+  a.currency,
+  SUM(e.amount)::bigint AS total
+FROM entries AS e
+JOIN accounts AS a ON a.id = e.account_id
+WHERE e.journal_transaction_id >= $1::bigint
+  AND e.journal_transaction_id <= $2::bigint
+GROUP BY e.journal_transaction_id, a.currency
+HAVING SUM(e.amount) <> 0
+ORDER BY e.journal_transaction_id, a.currency
+`
+
+type ListUnbalancedJournalTransactionsParams struct {
+	FromID int64 `json:"from_id"`
+	ToID   int64 `json:"to_id"`
+}
+
+type ListUnbalancedJournalTransactionsRow struct {
+	JournalTransactionID int64  `json:"journal_transaction_id"`
+	Currency             string `json:"currency"`
+	Total                int64  `json:"total"`
+}
+
+// returns the currencies in which the journal transactions in the ID range don't sum to zero
+func (q *Queries) ListUnbalancedJournalTransactions(ctx context.Context, arg ListUnbalancedJournalTransactionsParams) ([]ListUnbalancedJournalTransactionsRow, error) {
+	rows, err := q.db.Query(ctx, listUnbalancedJournalTransactions, arg.FromID, arg.ToID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUnbalancedJournalTransactionsRow{}
+	for rows.Next() {
+		var i ListUnbalancedJournalTransactionsRow
+		if err := rows.Scan(&i.JournalTransactionID, &i.Currency, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnbalancedTransfers = `-- name: ListUnbalancedTransfers :many
+SELECT
+  t.id,
+  t.from_account_id,
+  t.to_account_id,
+  t.amount,
+  t.to_amount,
+  COUNT(e.id)::int AS entry_count,
+  COALESCE(SUM(e.amount) FILTER (WHERE e.account_id = t.from_account_id), 0)::bigint AS from_entries_total,
+  COALESCE(SUM(e.amount) FILTER (WHERE e.account_id = t.to_account_id), 0)::bigint AS to_entries_total
+FROM transfers AS t
+LEFT JOIN journal_transactions AS j ON j.transfer_id = t.id
+LEFT JOIN entries AS e ON e.journal_transaction_id = j.id
+WHERE t.from_account_id >= $1
+  AND t.from_account_id <= $2
+GROUP BY t.id
+HAVING COALESCE(SUM(e.amount) FILTER (WHERE e.account_id = t.from_account_id), 0) <> -t.amount
+  OR COALESCE(SUM(e.amount) FILTER (WHERE e.account_id = t.to_account_id), 0) <> t.to_amount
+ORDER BY t.id
+`
+
+type ListUnbalancedTransfersParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+}
+
+type ListUnbalancedTransfersRow struct {
+	ID               int64 `json:"id"`
+	FromAccountID    int64 `json:"from_account_id"`
+	ToAccountID      int64 `json:"to_account_id"`
+	Amount           int64 `json:"amount"`
+	ToAmount         int64 `json:"to_amount"`
+	EntryCount       int32 `json:"entry_count"`
+	FromEntriesTotal int64 `json:"from_entries_total"`
+	ToEntriesTotal   int64 `json:"to_entries_total"`
+}
+
+// returns the transfers from the accounts in the ID range whose journal transaction doesn't
+// debit amount from the from account and credit to_amount to the to account
+func (q *Queries) ListUnbalancedTransfers(ctx context.Context, arg ListUnbalancedTransfersParams) ([]ListUnbalancedTransfersRow, error) {
+	rows, err := q.db.Query(ctx, listUnbalancedTransfers, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUnbalancedTransfersRow{}
+	for rows.Next() {
+		var i ListUnbalancedTransfersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.ToAmount,
+			&i.EntryCount,
+			&i.FromEntriesTotal,
+			&i.ToEntriesTotal,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
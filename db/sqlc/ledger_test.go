@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedgerQueries(t *testing.T) {
+	account1 := createRandomAccountWithBalance(t, 0)
+	account2 := createRandomAccountWithBalance(t, 0)
+
+	// an entry and a transfer made without each other
+	orphan := createRandomEntry(t, account1)
+	transfer := createRandomTransfer(t, account1, account2)
+
+	totals, err := testStore.ListAccountLedgerTotals(context.Background(), ListAccountLedgerTotalsParams{
+		AfterID:   account1.ID - 1,
+		BatchSize: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, totals, 1)
+	require.Equal(t, account1.ID, totals[0].ID)
+	require.Equal(t, int64(0), totals[0].Balance)
+	require.Equal(t, orphan.Amount, totals[0].EntriesTotal)
+
+	entries, err := testStore.ListOrphanedEntries(context.Background(), ListOrphanedEntriesParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account1.ID,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, orphan.ID, entries[0].ID)
+
+	transfers, err := testStore.ListUnbalancedTransfers(context.Background(), ListUnbalancedTransfersParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account1.ID,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, transfer.ID, transfers[0].ID)
+	require.Zero(t, transfers[0].EntryCount)
+
+	// the transfers of the store are made with their entries
+	account3 := createRandomAccountWithBalance(t, 100)
+	account4 := createRandomAccountWithBalance(t, 0)
+	_, err = testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account3.ID,
+		ToAccountID:   account4.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	transfers, err = testStore.ListUnbalancedTransfers(context.Background(), ListUnbalancedTransfersParams{
+		FromAccountID: account3.ID,
+		ToAccountID:   account3.ID,
+	})
+	require.NoError(t, err)
+	require.Empty(t, transfers)
+
+	// a journal transaction with a single entry doesn't balance
+	journal, err := testStore.CreateJournalTransaction(context.Background(), CreateJournalTransactionParams{
+		Kind: "adjustment",
+	})
+	require.NoError(t, err)
+	entry, err := testStore.CreateEntry(context.Background(), CreateEntryParams{
+		AccountID:            account4.ID,
+		Amount:               7,
+		JournalTransactionID: pgtype.Int8{Int64: journal.ID, Valid: true},
+	})
+	require.NoError(t, err)
+
+	journals, err := testStore.ListUnbalancedJournalTransactions(context.Background(), ListUnbalancedJournalTransactionsParams{
+		FromID: journal.ID,
+		ToID:   journal.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []ListUnbalancedJournalTransactionsRow{
+		{JournalTransactionID: journal.ID, Currency: account4.Currency, Total: entry.Amount},
+	}, journals)
+
+	lastID, err := testStore.GetLastJournalTransactionID(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, lastID, journal.ID)
+}
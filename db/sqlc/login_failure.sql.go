@@ -0,0 +1,143 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: login_failure.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const deleteLoginFailure = `-- name: DeleteLoginFailure :exec
+DELETE FROM login_failures
+WHERE kind = $1
+  AND subject = $2
+`
+
+type DeleteLoginFailureParams struct {
+	Kind    string `json:"kind"`
+	Subject string `json:"subject"`
+}
+
+func (q *Queries) DeleteLoginFailure(ctx context.Context, arg DeleteLoginFailureParams) error {
+	_, err := q.db.Exec(ctx, deleteLoginFailure, arg.Kind, arg.Subject)
+	return err
+}
+
+const getLoginFailure = `-- name: GetLoginFailure :one
+SELECT kind, subject, failed_count, last_failed_at FROM login_failures
+WHERE kind = $1
+  AND subject = $2
+LIMIT 1
+`
+
+type GetLoginFailureParams struct {
+	Kind    string `json:"kind"`
+	Subject string `json:"subject"`
+}
+
+func (q *Queries) GetLoginFailure(ctx context.Context, arg GetLoginFailureParams) (LoginFailure, error) {
+	row := q.db.QueryRow(ctx, getLoginFailure, arg.Kind, arg.Subject)
+	var i LoginFailure
+	err := row.Scan(
+		&i.Kind,
+		&i.Subject,
+		&i.FailedCount,
+		&i.LastFailedAt,
+	)
+	return i, err
+}
+
+const getLoginFailureForUpdate = `-- name: GetLoginFailureForUpdate :one
+SELECT kind, subject, failed_count, last_failed_at FROM login_failures
+WHERE kind = $1
+  AND subject = $2
+LIMIT 1
+FOR UPDATE
+`
+
+type GetLoginFailureForUpdateParams struct {
+	Kind    string `json:"kind"`
+	Subject string `json:"subject"`
+}
+
+func (q *Queries) GetLoginFailureForUpdate(ctx context.Context, arg GetLoginFailureForUpdateParams) (LoginFailure, error) {
+	row := q.db.QueryRow(ctx, getLoginFailureForUpdate, arg.Kind, arg.Subject)
+	var i LoginFailure
+	err := row.Scan(
+		&i.Kind,
+		&i.Subject,
+		&i.FailedCount,
+		&i.LastFailedAt,
+	)
+	return i, err
+}
+
+const recordLoginFailure = `-- name: RecordLoginFailure :one
+INSERT INTO login_failures (
+  kind,
+  subject,
+  failed_count,
+  last_failed_at
+) VALUES (
+  $1, $2, 1, now()
+)
+ON CONFLICT (kind, subject) DO UPDATE
+SET failed_count = CASE
+      WHEN login_failures.last_failed_at < $3 THEN 1
+      ELSE login_failures.failed_count + 1
+    END,
+    last_failed_at = now()
+RETURNING kind, subject, failed_count, last_failed_at
+`
+
+type RecordLoginFailureParams struct {
+	Kind        string    `json:"kind"`
+	Subject     string    `json:"subject"`
+	ResetBefore time.Time `json:"reset_before"`
+}
+
+// counts a failed login, starting over when the last failure is older than reset_before
+func (q *Queries) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginFailure, error) {
+	row := q.db.QueryRow(ctx, recordLoginFailure, arg.Kind, arg.Subject, arg.ResetBefore)
+	var i LoginFailure
+	err := row.Scan(
+		&i.Kind,
+		&i.Subject,
+		&i.FailedCount,
+		&i.LastFailedAt,
+	)
+	return i, err
+}
+
+const refundLoginFailure = `-- name: RefundLoginFailure :exec
+UPDATE login_failures
+SET failed_count = GREATEST(failed_count - 1, 0),
+    last_failed_at = CASE
+      WHEN last_failed_at = $1 THEN $2
+      ELSE last_failed_at
+    END
+WHERE kind = $3
+  AND subject = $4
+`
+
+type RefundLoginFailureParams struct {
+	ReservedAt       time.Time `json:"reserved_at"`
+	PreviousFailedAt time.Time `json:"previous_failed_at"`
+	Kind             string    `json:"kind"`
+	Subject          string    `json:"subject"`
+}
+
+// takes back an attempt counted as failed once it succeeded, restoring when the failure before it was
+// unless another attempt was counted after it
+func (q *Queries) RefundLoginFailure(ctx context.Context, arg RefundLoginFailureParams) error {
+	_, err := q.db.Exec(ctx, refundLoginFailure,
+		arg.ReservedAt,
+		arg.PreviousFailedAt,
+		arg.Kind,
+		arg.Subject,
+	)
+	return err
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordLoginFailure(t *testing.T) {
+	subject := util.RandomOwner()
+	arg := RecordLoginFailureParams{
+		Kind:        "username",
+		Subject:     subject,
+		ResetBefore: time.Now().Add(-time.Hour),
+	}
+
+	for i := int32(1); i <= 3; i++ {
+		failure, err := testStore.RecordLoginFailure(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, i, failure.FailedCount)
+		require.WithinDuration(t, time.Now(), failure.LastFailedAt, time.Second)
+	}
+
+	got, err := testStore.GetLoginFailure(context.Background(), GetLoginFailureParams{Kind: arg.Kind, Subject: subject})
+	require.NoError(t, err)
+	require.Equal(t, int32(3), got.FailedCount)
+
+	// failures older than reset_before are forgotten
+	arg.ResetBefore = time.Now().Add(time.Second)
+	failure, err := testStore.RecordLoginFailure(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), failure.FailedCount)
+
+	err = testStore.DeleteLoginFailure(context.Background(), DeleteLoginFailureParams{Kind: arg.Kind, Subject: subject})
+	require.NoError(t, err)
+
+	_, err = testStore.GetLoginFailure(context.Background(), GetLoginFailureParams{Kind: arg.Kind, Subject: subject})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestReserveLoginAttemptTxConcurrent(t *testing.T) {
+	subject := util.RandomOwner()
+	arg := RecordLoginFailureParams{
+		Kind:        "username",
+		Subject:     subject,
+		ResetBefore: time.Now().Add(-time.Hour),
+	}
+
+	// the first attempt makes sure the row exists, so the others queue up on its lock
+	first, err := testStore.ReserveLoginAttemptTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), first.Failure.FailedCount)
+	require.True(t, first.PreviousFailedAt.IsZero())
+
+	n := 10
+	errs := make(chan error)
+	results := make(chan ReserveLoginAttemptTxResult)
+
+	// run n concurrent attempts of the same subject
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := testStore.ReserveLoginAttemptTx(context.Background(), arg)
+
+			errs <- err
+			results <- result
+		}()
+	}
+
+	counted := make(map[int32]bool)
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+
+		result := <-results
+		require.False(t, result.PreviousFailedAt.IsZero())
+		require.False(t, result.PreviousFailedAt.After(result.Failure.LastFailedAt))
+
+		// every attempt is counted on top of the one before it
+		require.False(t, counted[result.Failure.FailedCount])
+		counted[result.Failure.FailedCount] = true
+	}
+
+	for i := int32(2); i <= int32(n)+1; i++ {
+		require.True(t, counted[i], "failed count %d", i)
+	}
+}
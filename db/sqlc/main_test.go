@@ -10,6 +10,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var testConnPool *pgxpool.Pool
 var testStore Store
 
 func TestMain(m *testing.M) {
@@ -18,11 +19,11 @@ func TestMain(m *testing.M) {
 		log.Fatal("cannot load config:", err)
 	}
 
-	connPool, err := pgxpool.New(context.Background(), config.DBSource)
+	testConnPool, err = pgxpool.New(context.Background(), config.DBSource)
 	if err != nil {
 		log.Fatal("cannot connect to db:", err)
 	}
 
-	testStore = NewStore(connPool)
+	testStore = NewStore(testConnPool)
 	os.Exit(m.Run())
 }
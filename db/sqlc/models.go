@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Account struct {
@@ -16,6 +17,7 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	Status    string    `json:"status"`
 }
 
 type AccountAlert struct {
@@ -31,6 +33,24 @@ type AccountLimit struct {
 	DailyTransferLimit int64     `json:"daily_transfer_limit"`
 	CreatedAt          time.Time `json:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at"`
+	OverdraftLimit     int64     `json:"overdraft_limit"`
+}
+
+type AuditEvent struct {
+	ID            int64       `json:"id"`
+	ActorUsername pgtype.Text `json:"actor_username"`
+	ActorRole     string      `json:"actor_role"`
+	Action        string      `json:"action"`
+	TargetType    string      `json:"target_type"`
+	TargetID      string      `json:"target_id"`
+	Reason        string      `json:"reason"`
+	Before        []byte      `json:"before"`
+	After         []byte      `json:"after"`
+	CreatedAt     time.Time   `json:"created_at"`
+	ClientIp      string      `json:"client_ip"`
+	UserAgent     string      `json:"user_agent"`
+	PrevHash      []byte      `json:"prev_hash"`
+	Hash          []byte      `json:"hash"`
 }
 
 type Entry struct {
@@ -39,26 +59,135 @@ type Entry struct {
 	// can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// the entries of a journal transaction sum to zero per currency
+	JournalTransactionID pgtype.Int8 `json:"journal_transaction_id"`
+}
+
+type IdempotencyKey struct {
+	Username       string    `json:"username"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	RequestHash    string    `json:"request_hash"`
+	Response       []byte    `json:"response"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type JournalTransaction struct {
+	ID int64 `json:"id"`
+	// transfer, reversal, deposit, fee...
+	Kind string `json:"kind"`
+	// the transfer that the journal transaction records
+	TransferID  pgtype.Int8 `json:"transfer_id"`
+	Description string      `json:"description"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+type LoginFailure struct {
+	// username, client_ip or login_challenge
+	Kind         string    `json:"kind"`
+	Subject      string    `json:"subject"`
+	FailedCount  int32     `json:"failed_count"`
+	LastFailedAt time.Time `json:"last_failed_at"`
+}
+
+type PasswordReset struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	CodeHash  string    `json:"code_hash"`
+	IsUsed    bool      `json:"is_used"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+type PendingTransfer struct {
+	ID              int64  `json:"id"`
+	Owner           string `json:"owner"`
+	FromAccountID   int64  `json:"from_account_id"`
+	ToAccountID     int64  `json:"to_account_id"`
+	Amount          int64  `json:"amount"`
+	Currency        string `json:"currency"`
+	ConvertCurrency bool   `json:"convert_currency"`
+	// totp or email
+	VerificationMethod string      `json:"verification_method"`
+	CodeHash           pgtype.Text `json:"code_hash"`
+	Attempts           int32       `json:"attempts"`
+	// pending, confirmed or expired
+	Status      string             `json:"status"`
+	TransferID  pgtype.Int8        `json:"transfer_id"`
+	ExpiresAt   time.Time          `json:"expires_at"`
+	ConfirmedAt pgtype.Timestamptz `json:"confirmed_at"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	// once, interval or cron
+	Recurrence      string             `json:"recurrence"`
+	CronExpression  pgtype.Text        `json:"cron_expression"`
+	IntervalSeconds pgtype.Int8        `json:"interval_seconds"`
+	NextRunAt       time.Time          `json:"next_run_at"`
+	EndAt           pgtype.Timestamptz `json:"end_at"`
+	MaxOccurrences  pgtype.Int4        `json:"max_occurrences"`
+	Occurrences     int32              `json:"occurrences"`
+	// active, completed or cancelled
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64       `json:"id"`
+	ScheduledTransferID int64       `json:"scheduled_transfer_id"`
+	TransferID          pgtype.Int8 `json:"transfer_id"`
+	// succeeded or failed
+	Status      string      `json:"status"`
+	Error       pgtype.Text `json:"error"`
+	ScheduledAt time.Time   `json:"scheduled_at"`
+	CreatedAt   time.Time   `json:"created_at"`
 }
 
 type Session struct {
-	ID           uuid.UUID `json:"id"`
-	Username     string    `json:"username"`
-	RefreshToken string    `json:"refresh_token"`
-	UserAgent    string    `json:"user_agent"`
-	ClientIp     string    `json:"client_ip"`
-	IsBlocked    bool      `json:"is_blocked"`
-	ExpiresAt    time.Time `json:"expires_at"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID           uuid.UUID          `json:"id"`
+	Username     string             `json:"username"`
+	RefreshToken string             `json:"refresh_token"`
+	UserAgent    string             `json:"user_agent"`
+	ClientIp     string             `json:"client_ip"`
+	IsBlocked    bool               `json:"is_blocked"`
+	ExpiresAt    time.Time          `json:"expires_at"`
+	CreatedAt    time.Time          `json:"created_at"`
+	FamilyID     uuid.UUID          `json:"family_id"`
+	ParentID     pgtype.UUID        `json:"parent_id"`
+	RotatedAt    pgtype.Timestamptz `json:"rotated_at"`
+}
+
+type TotpRecoveryCode struct {
+	ID        int64              `json:"id"`
+	Username  string             `json:"username"`
+	CodeHash  string             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
 }
 
 type Transfer struct {
 	ID            int64 `json:"id"`
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
-	// must be positive
+	// must be positive, in the currency of the from account
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// must be positive, in the currency of the to account
+	ToAmount int64 `json:"to_amount"`
+	// to_amount per unit of amount
+	ExchangeRate pgtype.Numeric `json:"exchange_rate"`
+	// completed or reversed
+	Status string `json:"status"`
+	// the transfer that this one reverses
+	ReversalOf pgtype.Int8 `json:"reversal_of"`
+	Reason     pgtype.Text `json:"reason"`
 }
 
 type User struct {
@@ -72,6 +201,14 @@ type User struct {
 	Role              string    `json:"role"`
 }
 
+type UserTotp struct {
+	Username     string             `json:"username"`
+	Secret       string             `json:"secret"`
+	LastUsedStep int64              `json:"last_used_step"`
+	ConfirmedAt  pgtype.Timestamptz `json:"confirmed_at"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
 type VerifyEmail struct {
 	ID         int64     `json:"id"`
 	Username   string    `json:"username"`
@@ -0,0 +1,109 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: password_reset.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createPasswordReset = `-- name: CreatePasswordReset :one
+INSERT INTO password_resets (
+    username,
+    email,
+    code_hash
+) VALUES (
+    $1, $2, $3
+) RETURNING id, username, email, code_hash, is_used, created_at, expired_at
+`
+
+type CreatePasswordResetParams struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	CodeHash string `json:"code_hash"`
+}
+
+func (q *Queries) CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error) {
+	row := q.db.QueryRow(ctx, createPasswordReset, arg.Username, arg.Email, arg.CodeHash)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.CodeHash,
+		&i.IsUsed,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+	)
+	return i, err
+}
+
+const getLastPasswordReset = `-- name: GetLastPasswordReset :one
+SELECT id, username, email, code_hash, is_used, created_at, expired_at FROM password_resets
+WHERE username = $1
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLastPasswordReset(ctx context.Context, username string) (PasswordReset, error) {
+	row := q.db.QueryRow(ctx, getLastPasswordReset, username)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.CodeHash,
+		&i.IsUsed,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+	)
+	return i, err
+}
+
+const invalidatePasswordResets = `-- name: InvalidatePasswordResets :exec
+UPDATE password_resets
+SET
+    is_used = TRUE
+WHERE
+    username = $1
+    AND is_used = FALSE
+`
+
+func (q *Queries) InvalidatePasswordResets(ctx context.Context, username string) error {
+	_, err := q.db.Exec(ctx, invalidatePasswordResets, username)
+	return err
+}
+
+const usePasswordReset = `-- name: UsePasswordReset :one
+UPDATE password_resets
+SET
+    is_used = TRUE
+WHERE
+    id = $1
+    AND code_hash = $2
+    AND is_used = FALSE
+    AND expired_at > now()
+RETURNING id, username, email, code_hash, is_used, created_at, expired_at
+`
+
+type UsePasswordResetParams struct {
+	ID       int64  `json:"id"`
+	CodeHash string `json:"code_hash"`
+}
+
+func (q *Queries) UsePasswordReset(ctx context.Context, arg UsePasswordResetParams) (PasswordReset, error) {
+	row := q.db.QueryRow(ctx, usePasswordReset, arg.ID, arg.CodeHash)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.CodeHash,
+		&i.IsUsed,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+	)
+	return i, err
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPasswordReset(t *testing.T, user User, codeHash string) PasswordReset {
+	passwordReset, err := testStore.CreatePasswordReset(context.Background(), CreatePasswordResetParams{
+		Username: user.Username,
+		Email:    user.Email,
+		CodeHash: codeHash,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, passwordReset.Username)
+	require.False(t, passwordReset.IsUsed)
+	require.True(t, passwordReset.ExpiredAt.After(time.Now()))
+
+	return passwordReset
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomSession(t, user)
+
+	codeHash := util.HashOTPCode(util.RandomString(32))
+	passwordReset := createRandomPasswordReset(t, user, codeHash)
+	otherReset := createRandomPasswordReset(t, user, util.HashOTPCode(util.RandomString(32)))
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	arg := ResetPasswordTxParams{
+		ResetID:        passwordReset.ID,
+		CodeHash:       codeHash,
+		HashedPassword: hashedPassword,
+	}
+	result, err := testStore.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	require.WithinDuration(t, time.Now(), result.User.PasswordChangedAt, time.Second)
+	require.Equal(t, int64(1), result.BlockedSessions)
+
+	// the code is single use, and resetting invalidates the other codes of the user
+	_, err = testStore.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = testStore.UsePasswordReset(context.Background(), UsePasswordResetParams{
+		ID:       otherReset.ID,
+		CodeHash: otherReset.CodeHash,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestResetPasswordTxWrongCode(t *testing.T) {
+	user := createRandomUser(t)
+	passwordReset := createRandomPasswordReset(t, user, util.HashOTPCode(util.RandomString(32)))
+
+	_, err := testStore.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		ResetID:        passwordReset.ID,
+		CodeHash:       util.HashOTPCode(util.RandomString(32)),
+		HashedPassword: "unused",
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	got, err := testStore.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.HashedPassword, got.HashedPassword)
+}
@@ -0,0 +1,249 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: pending_transfer.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const addPendingTransferAttempt = `-- name: AddPendingTransferAttempt :one
+UPDATE pending_transfers
+SET attempts = attempts + 1
+WHERE id = $1
+  AND status = 'pending'
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, convert_currency, verification_method, code_hash, attempts, status, transfer_id, expires_at, confirmed_at, created_at
+`
+
+// counts a confirmation attempt before its code is checked
+func (q *Queries) AddPendingTransferAttempt(ctx context.Context, id int64) (PendingTransfer, error) {
+	row := q.db.QueryRow(ctx, addPendingTransferAttempt, id)
+	var i PendingTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.ConvertCurrency,
+		&i.VerificationMethod,
+		&i.CodeHash,
+		&i.Attempts,
+		&i.Status,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.ConfirmedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const confirmPendingTransfer = `-- name: ConfirmPendingTransfer :one
+UPDATE pending_transfers
+SET status = 'confirmed',
+    transfer_id = $2,
+    confirmed_at = now()
+WHERE id = $1
+  AND status = 'pending'
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, convert_currency, verification_method, code_hash, attempts, status, transfer_id, expires_at, confirmed_at, created_at
+`
+
+type ConfirmPendingTransferParams struct {
+	ID         int64       `json:"id"`
+	TransferID pgtype.Int8 `json:"transfer_id"`
+}
+
+func (q *Queries) ConfirmPendingTransfer(ctx context.Context, arg ConfirmPendingTransferParams) (PendingTransfer, error) {
+	row := q.db.QueryRow(ctx, confirmPendingTransfer, arg.ID, arg.TransferID)
+	var i PendingTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.ConvertCurrency,
+		&i.VerificationMethod,
+		&i.CodeHash,
+		&i.Attempts,
+		&i.Status,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.ConfirmedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPendingTransfer = `-- name: CreatePendingTransfer :one
+INSERT INTO pending_transfers (
+  owner,
+  from_account_id,
+  to_account_id,
+  amount,
+  currency,
+  convert_currency,
+  verification_method,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING id, owner, from_account_id, to_account_id, amount, currency, convert_currency, verification_method, code_hash, attempts, status, transfer_id, expires_at, confirmed_at, created_at
+`
+
+type CreatePendingTransferParams struct {
+	Owner              string    `json:"owner"`
+	FromAccountID      int64     `json:"from_account_id"`
+	ToAccountID        int64     `json:"to_account_id"`
+	Amount             int64     `json:"amount"`
+	Currency           string    `json:"currency"`
+	ConvertCurrency    bool      `json:"convert_currency"`
+	VerificationMethod string    `json:"verification_method"`
+	ExpiresAt          time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreatePendingTransfer(ctx context.Context, arg CreatePendingTransferParams) (PendingTransfer, error) {
+	row := q.db.QueryRow(ctx, createPendingTransfer,
+		arg.Owner,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Currency,
+		arg.ConvertCurrency,
+		arg.VerificationMethod,
+		arg.ExpiresAt,
+	)
+	var i PendingTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.ConvertCurrency,
+		&i.VerificationMethod,
+		&i.CodeHash,
+		&i.Attempts,
+		&i.Status,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.ConfirmedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const expirePendingTransfers = `-- name: ExpirePendingTransfers :execrows
+UPDATE pending_transfers
+SET status = 'expired'
+WHERE status = 'pending'
+  AND expires_at <= $1
+`
+
+func (q *Queries) ExpirePendingTransfers(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, expirePendingTransfers, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getPendingTransfer = `-- name: GetPendingTransfer :one
+SELECT id, owner, from_account_id, to_account_id, amount, currency, convert_currency, verification_method, code_hash, attempts, status, transfer_id, expires_at, confirmed_at, created_at FROM pending_transfers
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetPendingTransfer(ctx context.Context, id int64) (PendingTransfer, error) {
+	row := q.db.QueryRow(ctx, getPendingTransfer, id)
+	var i PendingTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.ConvertCurrency,
+		&i.VerificationMethod,
+		&i.CodeHash,
+		&i.Attempts,
+		&i.Status,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.ConfirmedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPendingTransferForUpdate = `-- name: GetPendingTransferForUpdate :one
+SELECT id, owner, from_account_id, to_account_id, amount, currency, convert_currency, verification_method, code_hash, attempts, status, transfer_id, expires_at, confirmed_at, created_at FROM pending_transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetPendingTransferForUpdate(ctx context.Context, id int64) (PendingTransfer, error) {
+	row := q.db.QueryRow(ctx, getPendingTransferForUpdate, id)
+	var i PendingTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.ConvertCurrency,
+		&i.VerificationMethod,
+		&i.CodeHash,
+		&i.Attempts,
+		&i.Status,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.ConfirmedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const setPendingTransferCode = `-- name: SetPendingTransferCode :one
+UPDATE pending_transfers
+SET code_hash = $2
+WHERE id = $1
+  AND status = 'pending'
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, convert_currency, verification_method, code_hash, attempts, status, transfer_id, expires_at, confirmed_at, created_at
+`
+
+type SetPendingTransferCodeParams struct {
+	ID       int64       `json:"id"`
+	CodeHash pgtype.Text `json:"code_hash"`
+}
+
+func (q *Queries) SetPendingTransferCode(ctx context.Context, arg SetPendingTransferCodeParams) (PendingTransfer, error) {
+	row := q.db.QueryRow(ctx, setPendingTransferCode, arg.ID, arg.CodeHash)
+	var i PendingTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.ConvertCurrency,
+		&i.VerificationMethod,
+		&i.CodeHash,
+		&i.Attempts,
+		&i.Status,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.ConfirmedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPendingTransfer(t *testing.T, account1, account2 Account, expiresAt time.Time) PendingTransfer {
+	arg := CreatePendingTransferParams{
+		Owner:              account1.Owner,
+		FromAccountID:      account1.ID,
+		ToAccountID:        account2.ID,
+		Amount:             10,
+		Currency:           account1.Currency,
+		VerificationMethod: VerificationMethodEmail,
+		ExpiresAt:          expiresAt,
+	}
+
+	result, err := testStore.CreatePendingTransferTx(context.Background(), CreatePendingTransferTxParams{
+		CreatePendingTransferParams: arg,
+	})
+	require.NoError(t, err)
+	require.False(t, result.Replayed)
+
+	pendingTransfer := result.PendingTransfer
+	require.Equal(t, arg.Owner, pendingTransfer.Owner)
+	require.Equal(t, arg.Amount, pendingTransfer.Amount)
+	require.Equal(t, PendingTransferStatusPending, pendingTransfer.Status)
+	require.Zero(t, pendingTransfer.Attempts)
+	require.False(t, pendingTransfer.TransferID.Valid)
+
+	return pendingTransfer
+}
+
+func TestCreatePendingTransferTxIdempotent(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	arg := CreatePendingTransferTxParams{
+		CreatePendingTransferParams: CreatePendingTransferParams{
+			Owner:              account1.Owner,
+			FromAccountID:      account1.ID,
+			ToAccountID:        account2.ID,
+			Amount:             10,
+			Currency:           account1.Currency,
+			VerificationMethod: VerificationMethodEmail,
+			ExpiresAt:          time.Now().Add(time.Minute),
+		},
+		Idempotency: &IdempotencyParams{Username: account1.Owner, Key: util.RandomString(16)},
+	}
+
+	result1, err := testStore.CreatePendingTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, result1.Replayed)
+
+	// a retry is held by the same pending transfer, even with a later expiry
+	arg.ExpiresAt = time.Now().Add(2 * time.Minute)
+	result2, err := testStore.CreatePendingTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result2.Replayed)
+	require.Equal(t, result1.PendingTransfer.ID, result2.PendingTransfer.ID)
+
+	arg.Amount++
+	_, err = testStore.CreatePendingTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyConflict)
+}
+
+func TestConfirmPendingTransferTx(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	pendingTransfer := createRandomPendingTransfer(t, account1, account2, time.Now().Add(time.Minute))
+
+	arg := ConfirmPendingTransferTxParams{
+		PendingTransferID: pendingTransfer.ID,
+		Transfer: TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        pendingTransfer.Amount,
+		},
+	}
+
+	result, err := testStore.ConfirmPendingTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, PendingTransferStatusConfirmed, result.PendingTransfer.Status)
+	require.True(t, result.PendingTransfer.ConfirmedAt.Valid)
+	require.Equal(t, result.Transfer.Transfer.ID, result.PendingTransfer.TransferID.Int64)
+	require.Equal(t, account1.Balance-pendingTransfer.Amount, result.Transfer.FromAccount.Balance)
+
+	// a pending transfer moves money only once
+	_, err = testStore.ConfirmPendingTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrPendingTransferNotPending)
+}
+
+func TestExpirePendingTransfers(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	pendingTransfer := createRandomPendingTransfer(t, account1, account2, time.Now().Add(-time.Second))
+
+	_, err := testStore.ConfirmPendingTransferTx(context.Background(), ConfirmPendingTransferTxParams{
+		PendingTransferID: pendingTransfer.ID,
+		Transfer: TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        pendingTransfer.Amount,
+		},
+	})
+	require.ErrorIs(t, err, ErrPendingTransferExpired)
+
+	expired, err := testStore.ExpirePendingTransfers(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, expired, int64(1))
+
+	pendingTransfer, err = testStore.GetPendingTransfer(context.Background(), pendingTransfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, PendingTransferStatusExpired, pendingTransfer.Status)
+}
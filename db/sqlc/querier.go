@@ -6,40 +6,130 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
-	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	// balances only move by the entries of journal transactions
+	AddEntryToAccountBalance(ctx context.Context, entryID int64) (Account, error)
+	// counts a confirmation attempt before its code is checked
+	AddPendingTransferAttempt(ctx context.Context, id int64) (PendingTransfer, error)
+	AdvanceScheduledTransfer(ctx context.Context, arg AdvanceScheduledTransferParams) (ScheduledTransfer, error)
+	BlockSession(ctx context.Context, arg BlockSessionParams) (Session, error)
+	BlockSessionFamily(ctx context.Context, familyID uuid.UUID) (int64, error)
+	BlockUserSessions(ctx context.Context, username string) (int64, error)
+	ConfirmPendingTransfer(ctx context.Context, arg ConfirmPendingTransferParams) (PendingTransfer, error)
+	ConfirmUserTOTP(ctx context.Context, arg ConfirmUserTOTPParams) (UserTotp, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	// creates an empty account, unless the owner already has an open one in the currency
+	CreateAccountIfNotExists(ctx context.Context, arg CreateAccountIfNotExistsParams) error
+	// the hash chain columns are filled in by the audit_events_chain trigger
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	CreateJournalTransaction(ctx context.Context, arg CreateJournalTransactionParams) (JournalTransaction, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
+	CreatePendingTransfer(ctx context.Context, arg CreatePendingTransferParams) (PendingTransfer, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (TotpRecoveryCode, error)
+	CreateReversalTransfer(ctx context.Context, arg CreateReversalTransferParams) (Transfer, error)
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
-	DeleteAccount(ctx context.Context, id int64) error
+	DeleteLoginFailure(ctx context.Context, arg DeleteLoginFailureParams) error
+	DeleteRecoveryCodes(ctx context.Context, username string) error
+	DeleteUserTOTP(ctx context.Context, username string) error
+	ExpirePendingTransfers(ctx context.Context, expiresAt time.Time) (int64, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
 	GetAccountAlert(ctx context.Context, accountID int64) (AccountAlert, error)
+	GetAccountByOwnerCurrency(ctx context.Context, arg GetAccountByOwnerCurrencyParams) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
 	GetAccountLimit(ctx context.Context, accountID int64) (AccountLimit, error)
 	GetDailyTransferTotal(ctx context.Context, arg GetDailyTransferTotalParams) (int64, error)
+	GetEntriesTotalSince(ctx context.Context, arg GetEntriesTotalSinceParams) (int64, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetJournalTransaction(ctx context.Context, id int64) (JournalTransaction, error)
+	GetLastJournalTransactionID(ctx context.Context) (int64, error)
+	GetLastPasswordReset(ctx context.Context, username string) (PasswordReset, error)
+	GetLastVerifyEmail(ctx context.Context, username string) (VerifyEmail, error)
+	GetLoginFailure(ctx context.Context, arg GetLoginFailureParams) (LoginFailure, error)
+	GetLoginFailureForUpdate(ctx context.Context, arg GetLoginFailureForUpdateParams) (LoginFailure, error)
+	GetPendingTransfer(ctx context.Context, id int64) (PendingTransfer, error)
+	GetPendingTransferForUpdate(ctx context.Context, id int64) (PendingTransfer, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetScheduledTransferForUpdate(ctx context.Context, id int64) (ScheduledTransfer, error)
 	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	GetSessionForUpdate(ctx context.Context, id uuid.UUID) (Session, error)
+	GetSessionStatus(ctx context.Context, id uuid.UUID) (GetSessionStatusRow, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserForUpdate(ctx context.Context, username string) (User, error)
+	GetUserTOTP(ctx context.Context, username string) (UserTotp, error)
+	InvalidatePasswordResets(ctx context.Context, username string) error
+	InvalidateVerifyEmails(ctx context.Context, username string) error
+	// returns the balance of each account along with the sum of its entries, which must be equal
+	ListAccountLedgerTotals(ctx context.Context, arg ListAccountLedgerTotalsParams) ([]ListAccountLedgerTotalsRow, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListActiveSessions(ctx context.Context, username string) ([]Session, error)
+	ListAllAccounts(ctx context.Context, arg ListAllAccountsParams) ([]Account, error)
+	ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error)
+	// returns the events whose hash doesn't match their content,
+	// or whose previous hash doesn't match the hash of the event of the same target before them
+	ListBrokenAuditEvents(ctx context.Context, maxResults int32) ([]int64, error)
+	ListDueScheduledTransfers(ctx context.Context, arg ListDueScheduledTransfersParams) ([]ScheduledTransfer, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListEntriesByJournalTransactions(ctx context.Context, ids []int64) ([]Entry, error)
 	ListEntriesFilteredAsc(ctx context.Context, arg ListEntriesFilteredAscParams) ([]Entry, error)
 	ListEntriesFilteredDesc(ctx context.Context, arg ListEntriesFilteredDescParams) ([]Entry, error)
+	ListEntriesKeysetAsc(ctx context.Context, arg ListEntriesKeysetAscParams) ([]Entry, error)
+	ListEntriesKeysetDesc(ctx context.Context, arg ListEntriesKeysetDescParams) ([]Entry, error)
+	ListJournalTransactionsByIDs(ctx context.Context, ids []int64) ([]JournalTransaction, error)
+	// returns the entries of the accounts in the ID range that aren't part of a journal transaction
+	ListOrphanedEntries(ctx context.Context, arg ListOrphanedEntriesParams) ([]Entry, error)
+	ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error)
+	ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
 	ListTransfersFilteredAsc(ctx context.Context, arg ListTransfersFilteredAscParams) ([]Transfer, error)
 	ListTransfersFilteredDesc(ctx context.Context, arg ListTransfersFilteredDescParams) ([]Transfer, error)
-	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	ListTransfersKeysetAsc(ctx context.Context, arg ListTransfersKeysetAscParams) ([]Transfer, error)
+	ListTransfersKeysetDesc(ctx context.Context, arg ListTransfersKeysetDescParams) ([]Transfer, error)
+	// returns the currencies in which the journal transactions in the ID range don't sum to zero
+	ListUnbalancedJournalTransactions(ctx context.Context, arg ListUnbalancedJournalTransactionsParams) ([]ListUnbalancedJournalTransactionsRow, error)
+	// returns the transfers from the accounts in the ID range whose journal transaction doesn't
+	// debit amount from the from account and credit to_amount to the to account
+	ListUnbalancedTransfers(ctx context.Context, arg ListUnbalancedTransfersParams) ([]ListUnbalancedTransfersRow, error)
+	MarkSessionRotated(ctx context.Context, id uuid.UUID) (Session, error)
+	// counts a failed login, starting over when the last failure is older than reset_before
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginFailure, error)
+	// takes back an attempt counted as failed once it succeeded, restoring when the failure before it was
+	// unless another attempt was counted after it
+	RefundLoginFailure(ctx context.Context, arg RefundLoginFailureParams) error
+	// matches the users whose username, email or full name contain the pattern
+	SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error)
+	SetPendingTransferCode(ctx context.Context, arg SetPendingTransferCodeParams) (PendingTransfer, error)
+	UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error)
+	UpdateIdempotencyKeyResponse(ctx context.Context, arg UpdateIdempotencyKeyResponseParams) error
+	UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error)
+	UpdateScheduledTransferStatus(ctx context.Context, arg UpdateScheduledTransferStatusParams) (ScheduledTransfer, error)
+	UpdateTransferStatus(ctx context.Context, arg UpdateTransferStatusParams) (Transfer, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
 	UpdateVerifyEmail(ctx context.Context, arg UpdateVerifyEmailParams) (VerifyEmail, error)
 	UpsertAccountAlert(ctx context.Context, arg UpsertAccountAlertParams) (AccountAlert, error)
 	UpsertAccountLimit(ctx context.Context, arg UpsertAccountLimitParams) (AccountLimit, error)
+	// replaces a pending enrollment, but never a confirmed one
+	UpsertUserTOTP(ctx context.Context, arg UpsertUserTOTPParams) (UserTotp, error)
+	UsePasswordReset(ctx context.Context, arg UsePasswordResetParams) (PasswordReset, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (TotpRecoveryCode, error)
+	// a code is accepted once, so its step must be after the last used one
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (UserTotp, error)
 }
 
 var _ Querier = (*Queries)(nil)
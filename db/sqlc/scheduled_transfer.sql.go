@@ -0,0 +1,448 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: scheduled_transfer.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const advanceScheduledTransfer = `-- name: AdvanceScheduledTransfer :one
+UPDATE scheduled_transfers
+SET
+  occurrences = occurrences + 1,
+  next_run_at = $1,
+  status = $2,
+  updated_at = now()
+WHERE id = $3
+  AND next_run_at = $4
+  AND status = 'active'
+RETURNING id, owner, from_account_id, to_account_id, amount, recurrence, cron_expression, interval_seconds, next_run_at, end_at, max_occurrences, occurrences, status, created_at, updated_at
+`
+
+type AdvanceScheduledTransferParams struct {
+	NextRunAt   time.Time `json:"next_run_at"`
+	Status      string    `json:"status"`
+	ID          int64     `json:"id"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
+
+func (q *Queries) AdvanceScheduledTransfer(ctx context.Context, arg AdvanceScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRow(ctx, advanceScheduledTransfer,
+		arg.NextRunAt,
+		arg.Status,
+		arg.ID,
+		arg.ScheduledAt,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Recurrence,
+		&i.CronExpression,
+		&i.IntervalSeconds,
+		&i.NextRunAt,
+		&i.EndAt,
+		&i.MaxOccurrences,
+		&i.Occurrences,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createScheduledTransfer = `-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+  owner,
+  from_account_id,
+  to_account_id,
+  amount,
+  recurrence,
+  cron_expression,
+  interval_seconds,
+  next_run_at,
+  end_at,
+  max_occurrences
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING id, owner, from_account_id, to_account_id, amount, recurrence, cron_expression, interval_seconds, next_run_at, end_at, max_occurrences, occurrences, status, created_at, updated_at
+`
+
+type CreateScheduledTransferParams struct {
+	Owner           string             `json:"owner"`
+	FromAccountID   int64              `json:"from_account_id"`
+	ToAccountID     int64              `json:"to_account_id"`
+	Amount          int64              `json:"amount"`
+	Recurrence      string             `json:"recurrence"`
+	CronExpression  pgtype.Text        `json:"cron_expression"`
+	IntervalSeconds pgtype.Int8        `json:"interval_seconds"`
+	NextRunAt       time.Time          `json:"next_run_at"`
+	EndAt           pgtype.Timestamptz `json:"end_at"`
+	MaxOccurrences  pgtype.Int4        `json:"max_occurrences"`
+}
+
+func (q *Queries) CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRow(ctx, createScheduledTransfer,
+		arg.Owner,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Recurrence,
+		arg.CronExpression,
+		arg.IntervalSeconds,
+		arg.NextRunAt,
+		arg.EndAt,
+		arg.MaxOccurrences,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Recurrence,
+		&i.CronExpression,
+		&i.IntervalSeconds,
+		&i.NextRunAt,
+		&i.EndAt,
+		&i.MaxOccurrences,
+		&i.Occurrences,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createScheduledTransferRun = `-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+  scheduled_transfer_id,
+  transfer_id,
+  status,
+  error,
+  scheduled_at
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING id, scheduled_transfer_id, transfer_id, status, error, scheduled_at, created_at
+`
+
+type CreateScheduledTransferRunParams struct {
+	ScheduledTransferID int64       `json:"scheduled_transfer_id"`
+	TransferID          pgtype.Int8 `json:"transfer_id"`
+	Status              string      `json:"status"`
+	Error               pgtype.Text `json:"error"`
+	ScheduledAt         time.Time   `json:"scheduled_at"`
+}
+
+func (q *Queries) CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	row := q.db.QueryRow(ctx, createScheduledTransferRun,
+		arg.ScheduledTransferID,
+		arg.TransferID,
+		arg.Status,
+		arg.Error,
+		arg.ScheduledAt,
+	)
+	var i ScheduledTransferRun
+	err := row.Scan(
+		&i.ID,
+		&i.ScheduledTransferID,
+		&i.TransferID,
+		&i.Status,
+		&i.Error,
+		&i.ScheduledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getScheduledTransfer = `-- name: GetScheduledTransfer :one
+SELECT id, owner, from_account_id, to_account_id, amount, recurrence, cron_expression, interval_seconds, next_run_at, end_at, max_occurrences, occurrences, status, created_at, updated_at FROM scheduled_transfers
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	row := q.db.QueryRow(ctx, getScheduledTransfer, id)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Recurrence,
+		&i.CronExpression,
+		&i.IntervalSeconds,
+		&i.NextRunAt,
+		&i.EndAt,
+		&i.MaxOccurrences,
+		&i.Occurrences,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getScheduledTransferForUpdate = `-- name: GetScheduledTransferForUpdate :one
+SELECT id, owner, from_account_id, to_account_id, amount, recurrence, cron_expression, interval_seconds, next_run_at, end_at, max_occurrences, occurrences, status, created_at, updated_at FROM scheduled_transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetScheduledTransferForUpdate(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	row := q.db.QueryRow(ctx, getScheduledTransferForUpdate, id)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Recurrence,
+		&i.CronExpression,
+		&i.IntervalSeconds,
+		&i.NextRunAt,
+		&i.EndAt,
+		&i.MaxOccurrences,
+		&i.Occurrences,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listDueScheduledTransfers = `-- name: ListDueScheduledTransfers :many
+SELECT id, owner, from_account_id, to_account_id, amount, recurrence, cron_expression, interval_seconds, next_run_at, end_at, max_occurrences, occurrences, status, created_at, updated_at FROM scheduled_transfers
+WHERE status = 'active'
+  AND next_run_at <= $1
+ORDER BY next_run_at
+LIMIT $2
+`
+
+type ListDueScheduledTransfersParams struct {
+	NextRunAt time.Time `json:"next_run_at"`
+	Limit     int32     `json:"limit"`
+}
+
+func (q *Queries) ListDueScheduledTransfers(ctx context.Context, arg ListDueScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	rows, err := q.db.Query(ctx, listDueScheduledTransfers, arg.NextRunAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransfer{}
+	for rows.Next() {
+		var i ScheduledTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Recurrence,
+			&i.CronExpression,
+			&i.IntervalSeconds,
+			&i.NextRunAt,
+			&i.EndAt,
+			&i.MaxOccurrences,
+			&i.Occurrences,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledTransferRuns = `-- name: ListScheduledTransferRuns :many
+SELECT id, scheduled_transfer_id, transfer_id, status, error, scheduled_at, created_at FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3
+`
+
+type ListScheduledTransferRunsParams struct {
+	ScheduledTransferID int64 `json:"scheduled_transfer_id"`
+	Limit               int32 `json:"limit"`
+	Offset              int32 `json:"offset"`
+}
+
+func (q *Queries) ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error) {
+	rows, err := q.db.Query(ctx, listScheduledTransferRuns, arg.ScheduledTransferID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransferRun{}
+	for rows.Next() {
+		var i ScheduledTransferRun
+		if err := rows.Scan(
+			&i.ID,
+			&i.ScheduledTransferID,
+			&i.TransferID,
+			&i.Status,
+			&i.Error,
+			&i.ScheduledAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledTransfers = `-- name: ListScheduledTransfers :many
+SELECT id, owner, from_account_id, to_account_id, amount, recurrence, cron_expression, interval_seconds, next_run_at, end_at, max_occurrences, occurrences, status, created_at, updated_at FROM scheduled_transfers
+WHERE from_account_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3
+`
+
+type ListScheduledTransfersParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	Limit         int32 `json:"limit"`
+	Offset        int32 `json:"offset"`
+}
+
+func (q *Queries) ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	rows, err := q.db.Query(ctx, listScheduledTransfers, arg.FromAccountID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransfer{}
+	for rows.Next() {
+		var i ScheduledTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Recurrence,
+			&i.CronExpression,
+			&i.IntervalSeconds,
+			&i.NextRunAt,
+			&i.EndAt,
+			&i.MaxOccurrences,
+			&i.Occurrences,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateScheduledTransfer = `-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+SET
+  amount = COALESCE($1, amount),
+  end_at = COALESCE($2, end_at),
+  max_occurrences = COALESCE($3, max_occurrences),
+  updated_at = now()
+WHERE
+  id = $4
+RETURNING id, owner, from_account_id, to_account_id, amount, recurrence, cron_expression, interval_seconds, next_run_at, end_at, max_occurrences, occurrences, status, created_at, updated_at
+`
+
+type UpdateScheduledTransferParams struct {
+	Amount         pgtype.Int8        `json:"amount"`
+	EndAt          pgtype.Timestamptz `json:"end_at"`
+	MaxOccurrences pgtype.Int4        `json:"max_occurrences"`
+	ID             int64              `json:"id"`
+}
+
+func (q *Queries) UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRow(ctx, updateScheduledTransfer,
+		arg.Amount,
+		arg.EndAt,
+		arg.MaxOccurrences,
+		arg.ID,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Recurrence,
+		&i.CronExpression,
+		&i.IntervalSeconds,
+		&i.NextRunAt,
+		&i.EndAt,
+		&i.MaxOccurrences,
+		&i.Occurrences,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateScheduledTransferStatus = `-- name: UpdateScheduledTransferStatus :one
+UPDATE scheduled_transfers
+SET
+  status = $2,
+  updated_at = now()
+WHERE id = $1
+RETURNING id, owner, from_account_id, to_account_id, amount, recurrence, cron_expression, interval_seconds, next_run_at, end_at, max_occurrences, occurrences, status, created_at, updated_at
+`
+
+type UpdateScheduledTransferStatusParams struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+func (q *Queries) UpdateScheduledTransferStatus(ctx context.Context, arg UpdateScheduledTransferStatusParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRow(ctx, updateScheduledTransferStatus, arg.ID, arg.Status)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Recurrence,
+		&i.CronExpression,
+		&i.IntervalSeconds,
+		&i.NextRunAt,
+		&i.EndAt,
+		&i.MaxOccurrences,
+		&i.Occurrences,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
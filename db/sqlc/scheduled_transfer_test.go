@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomScheduledTransfer(t *testing.T, account1, account2 Account) ScheduledTransfer {
+	arg := CreateScheduledTransferParams{
+		Owner:           account1.Owner,
+		FromAccountID:   account1.ID,
+		ToAccountID:     account2.ID,
+		Amount:          util.RandomMoney(),
+		Recurrence:      util.RecurrenceInterval,
+		IntervalSeconds: pgtype.Int8{Int64: 3600, Valid: true},
+		NextRunAt:       time.Now().UTC().Truncate(time.Second),
+		MaxOccurrences:  pgtype.Int4{Int32: 2, Valid: true},
+	}
+
+	scheduledTransfer, err := testStore.CreateScheduledTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, scheduledTransfer)
+
+	require.Equal(t, arg.Owner, scheduledTransfer.Owner)
+	require.Equal(t, arg.FromAccountID, scheduledTransfer.FromAccountID)
+	require.Equal(t, arg.ToAccountID, scheduledTransfer.ToAccountID)
+	require.Equal(t, arg.Amount, scheduledTransfer.Amount)
+	require.Equal(t, arg.IntervalSeconds, scheduledTransfer.IntervalSeconds)
+	require.WithinDuration(t, arg.NextRunAt, scheduledTransfer.NextRunAt, time.Second)
+	require.Equal(t, ScheduledTransferStatusActive, scheduledTransfer.Status)
+	require.Zero(t, scheduledTransfer.Occurrences)
+
+	return scheduledTransfer
+}
+
+func TestCreateScheduledTransfer(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	createRandomScheduledTransfer(t, account1, account2)
+}
+
+func TestRecordScheduledTransferRunTx(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	scheduledTransfer := createRandomScheduledTransfer(t, account1, account2)
+	transfer := createRandomTransfer(t, account1, account2)
+
+	nextRunAt := scheduledTransfer.NextRunAt.Add(time.Hour)
+	result, err := testStore.RecordScheduledTransferRunTx(context.Background(), RecordScheduledTransferRunTxParams{
+		ScheduledTransfer: scheduledTransfer,
+		TransferID:        transfer.ID,
+		NextRunAt:         nextRunAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), result.ScheduledTransfer.Occurrences)
+	require.Equal(t, ScheduledTransferStatusActive, result.ScheduledTransfer.Status)
+	require.WithinDuration(t, nextRunAt, result.ScheduledTransfer.NextRunAt, time.Second)
+	require.Equal(t, ScheduledTransferRunSucceeded, result.Run.Status)
+	require.Equal(t, transfer.ID, result.Run.TransferID.Int64)
+
+	// the same run cannot be recorded twice
+	_, err = testStore.RecordScheduledTransferRunTx(context.Background(), RecordScheduledTransferRunTxParams{
+		ScheduledTransfer: scheduledTransfer,
+		TransferID:        transfer.ID,
+		NextRunAt:         nextRunAt,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	result, err = testStore.RecordScheduledTransferRunTx(context.Background(), RecordScheduledTransferRunTxParams{
+		ScheduledTransfer: result.ScheduledTransfer,
+		Error:             ErrInsufficientFunds.Error(),
+		Finished:          true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), result.ScheduledTransfer.Occurrences)
+	require.Equal(t, ScheduledTransferStatusCompleted, result.ScheduledTransfer.Status)
+	require.Equal(t, ScheduledTransferRunFailed, result.Run.Status)
+	require.False(t, result.Run.TransferID.Valid)
+	require.Equal(t, ErrInsufficientFunds.Error(), result.Run.Error.String)
+
+	runs, err := testStore.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		Limit:               5,
+		Offset:              0,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T, user User) Session {
+	id := uuid.New()
+	arg := CreateSessionParams{
+		ID:           id,
+		Username:     user.Username,
+		RefreshToken: util.RandomString(32),
+		UserAgent:    "test",
+		ClientIp:     "127.0.0.1",
+		ExpiresAt:    time.Now().Add(time.Hour),
+		FamilyID:     id,
+	}
+
+	session, err := testStore.CreateSessionTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, session.ID)
+	require.False(t, session.IsBlocked)
+
+	return session
+}
+
+func TestBlockSession(t *testing.T) {
+	user := createRandomUser(t)
+	session1 := createRandomSession(t, user)
+	session2 := createRandomSession(t, user)
+
+	sessions, err := testStore.ListActiveSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+
+	// another user cannot block the session
+	other := createRandomUser(t)
+	_, err = testStore.BlockSessionTx(context.Background(), BlockSessionParams{ID: session1.ID, Username: other.Username})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	blocked, err := testStore.BlockSessionTx(context.Background(), BlockSessionParams{ID: session1.ID, Username: user.Username})
+	require.NoError(t, err)
+	require.True(t, blocked.IsBlocked)
+
+	sessions, err = testStore.ListActiveSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, session2.ID, sessions[0].ID)
+}
+
+func TestChangePasswordTx(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomSession(t, user)
+	createRandomSession(t, user)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	result, err := testStore.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username:          user.Username,
+			HashedPassword:    pgtype.Text{String: hashedPassword, Valid: true},
+			PasswordChangedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	require.Equal(t, int64(2), result.BlockedSessions)
+
+	sessions, err := testStore.ListActiveSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
+
+func rotateRandomSession(t *testing.T, session Session) RotateSessionTxResult {
+	result, err := testStore.RotateSessionTx(context.Background(), RotateSessionTxParams{
+		SessionID:    session.ID,
+		Username:     session.Username,
+		RefreshToken: session.RefreshToken,
+		NewSession: CreateSessionParams{
+			ID:           uuid.New(),
+			Username:     session.Username,
+			RefreshToken: util.RandomString(32),
+			UserAgent:    "test",
+			ClientIp:     "127.0.0.1",
+			ExpiresAt:    time.Now().Add(time.Hour),
+		},
+	})
+	require.NoError(t, err)
+	return result
+}
+
+func TestRotateSessionTx(t *testing.T) {
+	user := createRandomUser(t)
+	session1 := createRandomSession(t, user)
+
+	result := rotateRandomSession(t, session1)
+	session2 := result.Session
+	require.Equal(t, session1.FamilyID, session2.FamilyID)
+	require.True(t, session2.ParentID.Valid)
+	require.Equal(t, session1.ID, uuid.UUID(session2.ParentID.Bytes))
+	require.False(t, session2.RotatedAt.Valid)
+
+	rotated, err := testStore.GetSession(context.Background(), session1.ID)
+	require.NoError(t, err)
+	require.True(t, rotated.RotatedAt.Valid)
+
+	session3 := rotateRandomSession(t, session2).Session
+	require.Equal(t, session1.FamilyID, session3.FamilyID)
+
+	sessions, err := testStore.ListActiveSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, session3.ID, sessions[0].ID)
+}
+
+func TestRotateSessionTxMismatch(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+
+	_, err := testStore.RotateSessionTx(context.Background(), RotateSessionTxParams{
+		SessionID:    session.ID,
+		Username:     user.Username,
+		RefreshToken: util.RandomString(32),
+	})
+	require.ErrorIs(t, err, ErrSessionMismatch)
+}
+
+func TestRotateSessionTxReuse(t *testing.T) {
+	user := createRandomUser(t)
+	session1 := createRandomSession(t, user)
+	session2 := rotateRandomSession(t, session1).Session
+	other := createRandomSession(t, user)
+
+	// replaying the already rotated refresh token revokes the whole family
+	result, err := testStore.RotateSessionTx(context.Background(), RotateSessionTxParams{
+		SessionID:    session1.ID,
+		Username:     user.Username,
+		RefreshToken: session1.RefreshToken,
+	})
+	require.ErrorIs(t, err, ErrRefreshTokenReused)
+	require.True(t, result.ReuseDetected)
+	require.Equal(t, int64(2), result.BlockedSessions)
+
+	blocked, err := testStore.GetSession(context.Background(), session2.ID)
+	require.NoError(t, err)
+	require.True(t, blocked.IsBlocked)
+
+	sessions, err := testStore.ListActiveSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, other.ID, sessions[0].ID)
+
+	_, err = testStore.RotateSessionTx(context.Background(), RotateSessionTxParams{
+		SessionID:    session2.ID,
+		Username:     user.Username,
+		RefreshToken: session2.RefreshToken,
+	})
+	require.ErrorIs(t, err, ErrSessionBlocked)
+}
+
+func TestGetSessionStatus(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+
+	status, err := testStore.GetSessionStatus(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.Equal(t, session.ID, status.ID)
+	require.Equal(t, user.Username, status.Username)
+	require.Equal(t, user.Role, status.Role)
+	require.False(t, status.IsBlocked)
+}
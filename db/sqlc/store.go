@@ -10,8 +10,34 @@ import (
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error)
+	PostJournalTx(ctx context.Context, arg PostJournalTxParams) (PostJournalTxResult, error)
+	RecordScheduledTransferRunTx(ctx context.Context, arg RecordScheduledTransferRunTxParams) (RecordScheduledTransferRunTxResult, error)
+	CreatePendingTransferTx(ctx context.Context, arg CreatePendingTransferTxParams) (CreatePendingTransferTxResult, error)
+	ConfirmPendingTransferTx(ctx context.Context, arg ConfirmPendingTransferTxParams) (ConfirmPendingTransferTxResult, error)
 	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (ChangePasswordTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error)
+	RotateSessionTx(ctx context.Context, arg RotateSessionTxParams) (RotateSessionTxResult, error)
+	ConfirmTOTPTx(ctx context.Context, arg ConfirmTOTPTxParams) (ConfirmTOTPTxResult, error)
+	DisableTOTPTx(ctx context.Context, username string) error
 	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (UpdateAccountStatusTxResult, error)
+	ChangeUserRoleTx(ctx context.Context, arg ChangeUserRoleTxParams) (ChangeUserRoleTxResult, error)
+	ReserveLoginAttemptTx(ctx context.Context, arg RecordLoginFailureParams) (ReserveLoginAttemptTxResult, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserParams) (User, error)
+	CreateSessionTx(ctx context.Context, arg CreateSessionParams) (Session, error)
+	BlockSessionTx(ctx context.Context, arg BlockSessionParams) (Session, error)
+	BlockUserSessionsTx(ctx context.Context, username string) (int64, error)
+	UpsertUserTOTPTx(ctx context.Context, arg UpsertUserTOTPParams) (UserTotp, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
+	UpsertAccountLimitTx(ctx context.Context, arg UpsertAccountLimitParams) (AccountLimit, error)
+	UpsertAccountAlertTx(ctx context.Context, arg UpsertAccountAlertParams) (AccountAlert, error)
+	AddPendingTransferAttemptTx(ctx context.Context, id int64) (PendingTransfer, error)
+	CreateScheduledTransferTx(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	UpdateScheduledTransferTx(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error)
+	UpdateScheduledTransferStatusTx(ctx context.Context, arg UpdateScheduledTransferStatusParams) (ScheduledTransfer, error)
+	ReadTx(ctx context.Context, fn func(Querier) error) error
 }
 
 // SQLStore provides all functions to execute SQL queries and transactions
@@ -3,14 +3,17 @@ package db
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"testing"
 
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
 func TestTransferTx(t *testing.T) {
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithBalance(t, 1000)
 	fmt.Println(">> before:", account1.Balance, account2.Balance)
 
 	n := 5
@@ -60,6 +63,7 @@ func TestTransferTx(t *testing.T) {
 		require.NotEmpty(t, fromEntry)
 		require.Equal(t, account1.ID, fromEntry.AccountID)
 		require.Equal(t, -amount, fromEntry.Amount)
+		require.Equal(t, result.JournalTransaction.ID, fromEntry.JournalTransactionID.Int64)
 		require.NotZero(t, fromEntry.ID)
 		require.NotZero(t, fromEntry.CreatedAt)
 
@@ -70,6 +74,7 @@ func TestTransferTx(t *testing.T) {
 		require.NotEmpty(t, toEntry)
 		require.Equal(t, account2.ID, toEntry.AccountID)
 		require.Equal(t, amount, toEntry.Amount)
+		require.Equal(t, result.JournalTransaction.ID, toEntry.JournalTransactionID.Int64)
 		require.NotZero(t, toEntry.ID)
 		require.NotZero(t, toEntry.CreatedAt)
 
@@ -114,8 +119,8 @@ func TestTransferTx(t *testing.T) {
 }
 
 func TestTransferTxDeadlock(t *testing.T) {
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithBalance(t, 1000)
 	fmt.Println(">> before:", account1.Balance, account2.Balance)
 
 	n := 10
@@ -158,3 +163,318 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxIdempotency(t *testing.T) {
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithBalance(t, 1000)
+
+	n := 5
+	amount := int64(10)
+	idempotency := &IdempotencyParams{
+		Username: account1.Owner,
+		Key:      util.RandomString(16),
+	}
+
+	errs := make(chan error)
+	results := make(chan TransferTxResult)
+
+	// run n concurrent transfers sharing the same idempotency key
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := testStore.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        amount,
+				Idempotency:   idempotency,
+			})
+
+			errs <- err
+			results <- result
+		}()
+	}
+
+	var transferID int64
+	executed := 0
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+
+		result := <-results
+		if transferID == 0 {
+			transferID = result.Transfer.ID
+		}
+		require.Equal(t, transferID, result.Transfer.ID)
+
+		if !result.Replayed {
+			executed++
+		}
+	}
+	require.Equal(t, 1, executed)
+
+	// the money should only move once
+	updatedAccount1, err := testStore.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-amount, updatedAccount1.Balance)
+
+	// reusing the key for a different request must fail
+	_, err = testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount + 1,
+		Idempotency:   idempotency,
+	})
+	require.ErrorIs(t, err, ErrIdempotencyKeyConflict)
+}
+
+func TestTransferTxDailyLimit(t *testing.T) {
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithBalance(t, 1000)
+
+	n := 10
+	allowed := 3
+	amount := int64(10)
+
+	_, err := testStore.UpsertAccountLimitTx(context.Background(), UpsertAccountLimitParams{
+		AccountID:          account1.ID,
+		DailyTransferLimit: int64(allowed) * amount,
+	})
+	require.NoError(t, err)
+
+	errs := make(chan error)
+
+	// run n concurrent transfers that together exceed the daily limit,
+	// half of them in the opposite direction to check for deadlocks
+	for i := 0; i < n; i++ {
+		fromAccountID := account1.ID
+		toAccountID := account2.ID
+
+		if i%2 == 1 {
+			fromAccountID = account2.ID
+			toAccountID = account1.ID
+		}
+
+		go func() {
+			_, err := testStore.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID:     fromAccountID,
+				ToAccountID:       toAccountID,
+				Amount:            amount,
+				EnforceDailyLimit: true,
+			})
+
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err != nil {
+			require.ErrorIs(t, err, ErrDailyTransferLimitExceeded)
+			continue
+		}
+		succeeded++
+	}
+
+	// every transfer out of account2 succeeds, but only the allowed number out of account1
+	require.Equal(t, n/2+allowed, succeeded)
+
+	updatedAccount1, err := testStore.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-int64(allowed)*amount+int64(n/2)*amount, updatedAccount1.Balance)
+}
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccountWithBalance(t, 100)
+
+	n := 5
+	amount := int64(30)
+	errs := make(chan error)
+
+	// only 3 of the concurrent transfers fit into the balance
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := testStore.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        amount,
+			})
+
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err != nil {
+			require.ErrorIs(t, err, ErrInsufficientFunds)
+			continue
+		}
+		succeeded++
+	}
+	require.Equal(t, 3, succeeded)
+
+	updatedAccount1, err := testStore.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), updatedAccount1.Balance)
+
+	// an overdraft allowance lets the balance go below zero, but not past it
+	_, err = testStore.UpsertAccountLimitTx(context.Background(), UpsertAccountLimitParams{
+		AccountID:      account1.ID,
+		OverdraftLimit: pgtype.Int8{Int64: 50, Valid: true},
+	})
+	require.NoError(t, err)
+
+	result, err := testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(-20), result.FromAccount.Balance)
+
+	_, err = testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount + 1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestTransferTxWithConversion(t *testing.T) {
+	account1 := createRandomAccountInCurrency(t, 1000, util.USD)
+	account2 := createRandomAccountInCurrency(t, 1000, util.EUR)
+
+	amount := int64(100)
+	toAmount, exchangeRate := util.ConvertAmount(amount, big.NewRat(4, 5))
+
+	result, err := testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+		Conversion: &CurrencyConversion{
+			ToAmount:     toAmount,
+			ExchangeRate: exchangeRate,
+		},
+	})
+	require.NoError(t, err)
+
+	// each side is recorded in its own account's currency
+	require.Equal(t, amount, result.Transfer.Amount)
+	require.Equal(t, int64(80), result.Transfer.ToAmount)
+
+	rate, err := result.Transfer.ExchangeRate.Float64Value()
+	require.NoError(t, err)
+	require.Equal(t, 0.8, rate.Float64)
+
+	require.Equal(t, -amount, result.FromEntry.Amount)
+	require.Equal(t, toAmount, result.ToEntry.Amount)
+
+	require.Equal(t, account1.Balance-amount, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+toAmount, result.ToAccount.Balance)
+
+	// the exchange desk takes the dollars and pays out the euros
+	journal := result.JournalTransaction
+	require.Equal(t, JournalKindTransfer, journal.Kind)
+	require.Equal(t, result.Transfer.ID, journal.TransferID.Int64)
+
+	journals, err := ListJournals(context.Background(), testStore, []int64{journal.ID})
+	require.NoError(t, err)
+	require.Len(t, journals, 1)
+	require.Len(t, journals[0].Entries, 4)
+
+	totals := make(map[string]int64)
+	for _, entry := range journals[0].Entries {
+		account, err := testStore.GetAccount(context.Background(), entry.AccountID)
+		require.NoError(t, err)
+		totals[account.Currency] += entry.Amount
+
+		if account.Owner == ExchangeUsername {
+			if account.Currency == util.USD {
+				require.Equal(t, amount, entry.Amount)
+			} else {
+				require.Equal(t, util.EUR, account.Currency)
+				require.Equal(t, -toAmount, entry.Amount)
+			}
+		}
+	}
+	require.Equal(t, map[string]int64{util.USD: 0, util.EUR: 0}, totals)
+}
+
+func TestReverseTransferTx(t *testing.T) {
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithBalance(t, 1000)
+	amount := int64(10)
+
+	transferResult, err := testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+	original := transferResult.Transfer
+	require.Equal(t, TransferStatusCompleted, original.Status)
+
+	n := 5
+	reason := util.RandomString(12)
+	errs := make(chan error)
+	results := make(chan ReverseTransferTxResult)
+
+	// run n concurrent reversals of the same transfer
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := testStore.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+				TransferID: original.ID,
+				Reason:     reason,
+			})
+
+			errs <- err
+			results <- result
+		}()
+	}
+
+	var reversalID int64
+	reversed := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		result := <-results
+		if err != nil {
+			require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+			continue
+		}
+		reversed++
+		reversalID = result.ReversalTransfer.ID
+
+		reversal := result.ReversalTransfer
+		require.Equal(t, account2.ID, reversal.FromAccountID)
+		require.Equal(t, account1.ID, reversal.ToAccountID)
+		require.Equal(t, amount, reversal.Amount)
+		require.Equal(t, original.ID, reversal.ReversalOf.Int64)
+		require.Equal(t, reason, reversal.Reason.String)
+
+		require.Equal(t, TransferStatusReversed, result.OriginalTransfer.Status)
+		require.Equal(t, -amount, result.FromEntry.Amount)
+		require.Equal(t, amount, result.ToEntry.Amount)
+		require.Equal(t, JournalKindReversal, result.JournalTransaction.Kind)
+		require.Equal(t, reversal.ID, result.JournalTransaction.TransferID.Int64)
+	}
+	require.Equal(t, 1, reversed)
+
+	// the balances are back where they started
+	updatedAccount1, err := testStore.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := testStore.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+
+	// the reversal itself cannot be reversed
+	_, err = testStore.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: reversalID,
+		Reason:     reason,
+	})
+	require.ErrorIs(t, err, ErrTransferNotReversible)
+}
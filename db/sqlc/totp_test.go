@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPEnrollment(t *testing.T) {
+	user := createRandomUser(t)
+
+	pending, err := testStore.UpsertUserTOTPTx(context.Background(), UpsertUserTOTPParams{
+		Username: user.Username,
+		Secret:   util.RandomString(32),
+	})
+	require.NoError(t, err)
+	require.False(t, pending.ConfirmedAt.Valid)
+
+	// enrolling again replaces the pending secret
+	secret := util.RandomString(32)
+	pending, err = testStore.UpsertUserTOTPTx(context.Background(), UpsertUserTOTPParams{
+		Username: user.Username,
+		Secret:   secret,
+	})
+	require.NoError(t, err)
+	require.Equal(t, secret, pending.Secret)
+
+	codes, err := util.RandomRecoveryCodes(3)
+	require.NoError(t, err)
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, util.HashRecoveryCode(code))
+	}
+
+	result, err := testStore.ConfirmTOTPTx(context.Background(), ConfirmTOTPTxParams{
+		Username:           user.Username,
+		Step:               100,
+		RecoveryCodeHashes: hashes,
+	})
+	require.NoError(t, err)
+	require.True(t, result.UserTOTP.ConfirmedAt.Valid)
+	require.Equal(t, int64(100), result.UserTOTP.LastUsedStep)
+
+	// a confirmed secret cannot be replaced or confirmed again
+	_, err = testStore.UpsertUserTOTPTx(context.Background(), UpsertUserTOTPParams{
+		Username: user.Username,
+		Secret:   util.RandomString(32),
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = testStore.ConfirmTOTPTx(context.Background(), ConfirmTOTPTxParams{Username: user.Username, Step: 101})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// a step is only accepted once
+	_, err = testStore.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: 100})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	_, err = testStore.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: 101})
+	require.NoError(t, err)
+
+	// a recovery code is only accepted once
+	arg := UseRecoveryCodeParams{Username: user.Username, CodeHash: hashes[0]}
+	_, err = testStore.UseRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+	_, err = testStore.UseRecoveryCode(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	err = testStore.DisableTOTPTx(context.Background(), user.Username)
+	require.NoError(t, err)
+
+	_, err = testStore.GetUserTOTP(context.Background(), user.Username)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	_, err = testStore.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{Username: user.Username, CodeHash: hashes[1]})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
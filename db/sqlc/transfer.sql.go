@@ -8,26 +8,84 @@ package db
 import (
 	"context"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const createReversalTransfer = `-- name: CreateReversalTransfer :one
+INSERT INTO transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  to_amount,
+  exchange_rate,
+  reversal_of,
+  reason
+)
+SELECT
+  original.to_account_id,
+  original.from_account_id,
+  original.to_amount,
+  original.amount,
+  ROUND(original.amount::numeric / original.to_amount, 8),
+  original.id,
+  $1::varchar
+FROM transfers AS original
+WHERE original.id = $2
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of, reason
+`
+
+type CreateReversalTransferParams struct {
+	Reason     string `json:"reason"`
+	TransferID int64  `json:"transfer_id"`
+}
+
+func (q *Queries) CreateReversalTransfer(ctx context.Context, arg CreateReversalTransferParams) (Transfer, error) {
+	row := q.db.QueryRow(ctx, createReversalTransfer, arg.Reason, arg.TransferID)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.Status,
+		&i.ReversalOf,
+		&i.Reason,
+	)
+	return i, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  to_amount,
+  exchange_rate
 ) VALUES (
-  $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+  $1, $2, $3, $4, $5
+) RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of, reason
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64          `json:"from_account_id"`
+	ToAccountID   int64          `json:"to_account_id"`
+	Amount        int64          `json:"amount"`
+	ToAmount      int64          `json:"to_amount"`
+	ExchangeRate  pgtype.Numeric `json:"exchange_rate"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRow(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.ExchangeRate,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -35,6 +93,11 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.Status,
+		&i.ReversalOf,
+		&i.Reason,
 	)
 	return i, err
 }
@@ -43,6 +106,7 @@ const getDailyTransferTotal = `-- name: GetDailyTransferTotal :one
 SELECT COALESCE(SUM(amount), 0)::bigint AS total
 FROM transfers
 WHERE from_account_id = $1
+  AND reversal_of IS NULL
   AND created_at >= $2
   AND created_at < $3
 `
@@ -61,7 +125,7 @@ func (q *Queries) GetDailyTransferTotal(ctx context.Context, arg GetDailyTransfe
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of, reason FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -74,12 +138,41 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.Status,
+		&i.ReversalOf,
+		&i.Reason,
+	)
+	return i, err
+}
+
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of, reason FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRow(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.Status,
+		&i.ReversalOf,
+		&i.Reason,
 	)
 	return i, err
 }
 
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of, reason FROM transfers
 WHERE 
     from_account_id = $1 OR
     to_account_id = $2
@@ -115,6 +208,11 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.Status,
+			&i.ReversalOf,
+			&i.Reason,
 		); err != nil {
 			return nil, err
 		}
@@ -127,44 +225,41 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 }
 
 const listTransfersFilteredAsc = `-- name: ListTransfersFilteredAsc :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of, reason FROM transfers
 WHERE (
-    ($2 = 'any' AND (from_account_id = $1 OR to_account_id = $1)) OR
-    ($2 = 'out' AND from_account_id = $1) OR
-    ($2 = 'in' AND to_account_id = $1)
+    (from_account_id = $1 AND $2::text IN ('any', 'out')) OR
+    (to_account_id = $1 AND $2::text IN ('any', 'in'))
   )
   AND amount >= $3
   AND amount <= $4
   AND created_at >= $5
   AND created_at <= $6
 ORDER BY created_at ASC
-LIMIT $7
-OFFSET $8
+LIMIT $8
+OFFSET $7
 `
 
 type ListTransfersFilteredAscParams struct {
-	AccountID     int64       `json:"account_id"`
-	Direction     interface{} `json:"direction"`
-	FromAccountID int64       `json:"from_account_id"`
-	Column2       interface{} `json:"column_2"`
-	Amount        int64       `json:"amount"`
-	Amount_2      int64       `json:"amount_2"`
-	CreatedAt     time.Time   `json:"created_at"`
-	CreatedAt_2   time.Time   `json:"created_at_2"`
-	Limit         int32       `json:"limit"`
-	Offset        int32       `json:"offset"`
+	AccountID int64     `json:"account_id"`
+	Direction string    `json:"direction"`
+	MinAmount int64     `json:"min_amount"`
+	MaxAmount int64     `json:"max_amount"`
+	FromTime  time.Time `json:"from_time"`
+	ToTime    time.Time `json:"to_time"`
+	Offset    int32     `json:"offset"`
+	Limit     int32     `json:"limit"`
 }
 
 func (q *Queries) ListTransfersFilteredAsc(ctx context.Context, arg ListTransfersFilteredAscParams) ([]Transfer, error) {
 	rows, err := q.db.Query(ctx, listTransfersFilteredAsc,
-		arg.FromAccountID,
-		arg.Column2,
-		arg.Amount,
-		arg.Amount_2,
-		arg.CreatedAt,
-		arg.CreatedAt_2,
-		arg.Limit,
+		arg.AccountID,
+		arg.Direction,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.FromTime,
+		arg.ToTime,
 		arg.Offset,
+		arg.Limit,
 	)
 	if err != nil {
 		return nil, err
@@ -179,6 +274,11 @@ func (q *Queries) ListTransfersFilteredAsc(ctx context.Context, arg ListTransfer
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.Status,
+			&i.ReversalOf,
+			&i.Reason,
 		); err != nil {
 			return nil, err
 		}
@@ -191,44 +291,41 @@ func (q *Queries) ListTransfersFilteredAsc(ctx context.Context, arg ListTransfer
 }
 
 const listTransfersFilteredDesc = `-- name: ListTransfersFilteredDesc :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of, reason FROM transfers
 WHERE (
-    ($2 = 'any' AND (from_account_id = $1 OR to_account_id = $1)) OR
-    ($2 = 'out' AND from_account_id = $1) OR
-    ($2 = 'in' AND to_account_id = $1)
+    (from_account_id = $1 AND $2::text IN ('any', 'out')) OR
+    (to_account_id = $1 AND $2::text IN ('any', 'in'))
   )
   AND amount >= $3
   AND amount <= $4
   AND created_at >= $5
   AND created_at <= $6
 ORDER BY created_at DESC
-LIMIT $7
-OFFSET $8
+LIMIT $8
+OFFSET $7
 `
 
 type ListTransfersFilteredDescParams struct {
-	AccountID     int64       `json:"account_id"`
-	Direction     interface{} `json:"direction"`
-	FromAccountID int64       `json:"from_account_id"`
-	Column2       interface{} `json:"column_2"`
-	Amount        int64       `json:"amount"`
-	Amount_2      int64       `json:"amount_2"`
-	CreatedAt     time.Time   `json:"created_at"`
-	CreatedAt_2   time.Time   `json:"created_at_2"`
-	Limit         int32       `json:"limit"`
-	Offset        int32       `json:"offset"`
+	AccountID int64     `json:"account_id"`
+	Direction string    `json:"direction"`
+	MinAmount int64     `json:"min_amount"`
+	MaxAmount int64     `json:"max_amount"`
+	FromTime  time.Time `json:"from_time"`
+	ToTime    time.Time `json:"to_time"`
+	Offset    int32     `json:"offset"`
+	Limit     int32     `json:"limit"`
 }
 
 func (q *Queries) ListTransfersFilteredDesc(ctx context.Context, arg ListTransfersFilteredDescParams) ([]Transfer, error) {
 	rows, err := q.db.Query(ctx, listTransfersFilteredDesc,
-		arg.FromAccountID,
-		arg.Column2,
-		arg.Amount,
-		arg.Amount_2,
-		arg.CreatedAt,
-		arg.CreatedAt_2,
-		arg.Limit,
+		arg.AccountID,
+		arg.Direction,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.FromTime,
+		arg.ToTime,
 		arg.Offset,
+		arg.Limit,
 	)
 	if err != nil {
 		return nil, err
@@ -243,6 +340,11 @@ func (q *Queries) ListTransfersFilteredDesc(ctx context.Context, arg ListTransfe
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.Status,
+			&i.ReversalOf,
+			&i.Reason,
 		); err != nil {
 			return nil, err
 		}
@@ -253,3 +355,169 @@ func (q *Queries) ListTransfersFilteredDesc(ctx context.Context, arg ListTransfe
 	}
 	return items, nil
 }
+
+const listTransfersKeysetAsc = `-- name: ListTransfersKeysetAsc :many
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of, reason FROM transfers
+WHERE (
+    (from_account_id = $1 AND $2::text IN ('any', 'out')) OR
+    (to_account_id = $1 AND $2::text IN ('any', 'in'))
+  )
+  AND amount >= $3
+  AND amount <= $4
+  AND created_at >= $5
+  AND created_at <= $6
+  AND (created_at, id) > ($7::timestamptz, $8::bigint)
+ORDER BY created_at ASC, id ASC
+LIMIT $9
+`
+
+type ListTransfersKeysetAscParams struct {
+	AccountID       int64     `json:"account_id"`
+	Direction       string    `json:"direction"`
+	MinAmount       int64     `json:"min_amount"`
+	MaxAmount       int64     `json:"max_amount"`
+	FromTime        time.Time `json:"from_time"`
+	ToTime          time.Time `json:"to_time"`
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	Limit           int32     `json:"limit"`
+}
+
+func (q *Queries) ListTransfersKeysetAsc(ctx context.Context, arg ListTransfersKeysetAscParams) ([]Transfer, error) {
+	rows, err := q.db.Query(ctx, listTransfersKeysetAsc,
+		arg.AccountID,
+		arg.Direction,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.FromTime,
+		arg.ToTime,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.Status,
+			&i.ReversalOf,
+			&i.Reason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransfersKeysetDesc = `-- name: ListTransfersKeysetDesc :many
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of, reason FROM transfers
+WHERE (
+    (from_account_id = $1 AND $2::text IN ('any', 'out')) OR
+    (to_account_id = $1 AND $2::text IN ('any', 'in'))
+  )
+  AND amount >= $3
+  AND amount <= $4
+  AND created_at >= $5
+  AND created_at <= $6
+  AND (created_at, id) < ($7::timestamptz, $8::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT $9
+`
+
+type ListTransfersKeysetDescParams struct {
+	AccountID       int64     `json:"account_id"`
+	Direction       string    `json:"direction"`
+	MinAmount       int64     `json:"min_amount"`
+	MaxAmount       int64     `json:"max_amount"`
+	FromTime        time.Time `json:"from_time"`
+	ToTime          time.Time `json:"to_time"`
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	Limit           int32     `json:"limit"`
+}
+
+func (q *Queries) ListTransfersKeysetDesc(ctx context.Context, arg ListTransfersKeysetDescParams) ([]Transfer, error) {
+	rows, err := q.db.Query(ctx, listTransfersKeysetDesc,
+		arg.AccountID,
+		arg.Direction,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.FromTime,
+		arg.ToTime,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.Status,
+			&i.ReversalOf,
+			&i.Reason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateTransferStatus = `-- name: UpdateTransferStatus :one
+UPDATE transfers
+SET status = $2
+WHERE id = $1
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, status, reversal_of, reason
+`
+
+type UpdateTransferStatusParams struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+func (q *Queries) UpdateTransferStatus(ctx context.Context, arg UpdateTransferStatusParams) (Transfer, error) {
+	row := q.db.QueryRow(ctx, updateTransferStatus, arg.ID, arg.Status)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.Status,
+		&i.ReversalOf,
+		&i.Reason,
+	)
+	return i, err
+}
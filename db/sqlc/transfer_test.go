@@ -2,18 +2,23 @@ package db
 
 import (
 	"context"
+	"math/big"
 	"testing"
 	"time"
 
 	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
 func createRandomTransfer(t *testing.T, account1, account2 Account) Transfer {
+	amount := util.RandomMoney()
 	arg := CreateTransferParams{
 		FromAccountID: account1.ID,
 		ToAccountID:   account2.ID,
-		Amount:        util.RandomMoney(),
+		Amount:        amount,
+		ToAmount:      amount,
+		ExchangeRate:  pgtype.Numeric{Int: big.NewInt(1), Valid: true},
 	}
 
 	transfer, err := testStore.CreateTransfer(context.Background(), arg)
@@ -23,6 +28,7 @@ func createRandomTransfer(t *testing.T, account1, account2 Account) Transfer {
 	require.Equal(t, arg.FromAccountID, transfer.FromAccountID)
 	require.Equal(t, arg.ToAccountID, transfer.ToAccountID)
 	require.Equal(t, arg.Amount, transfer.Amount)
+	require.Equal(t, arg.ToAmount, transfer.ToAmount)
 
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
@@ -77,3 +83,57 @@ func TestListTransfer(t *testing.T) {
 		require.True(t, transfer.FromAccountID == account1.ID || transfer.ToAccountID == account1.ID)
 	}
 }
+
+func TestListTransfersFilteredDirection(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	account3 := createRandomAccount(t)
+
+	out := createRandomTransfer(t, account1, account2)
+	in := createRandomTransfer(t, account2, account1)
+	// a transfer between two other accounts must never be listed
+	createRandomTransfer(t, account2, account3)
+
+	testCases := []struct {
+		direction string
+		want      []int64
+	}{
+		{direction: "out", want: []int64{out.ID}},
+		{direction: "in", want: []int64{in.ID}},
+		{direction: "any", want: []int64{out.ID, in.ID}},
+	}
+
+	for _, tc := range testCases {
+		transfers, err := testStore.ListTransfersFilteredAsc(context.Background(), ListTransfersFilteredAscParams{
+			AccountID: account1.ID,
+			Direction: tc.direction,
+			MinAmount: 0,
+			MaxAmount: 1000,
+			FromTime:  time.Now().Add(-time.Minute),
+			ToTime:    time.Now().Add(time.Minute),
+			Limit:     10,
+			Offset:    0,
+		})
+		require.NoError(t, err)
+
+		ids := make([]int64, 0, len(transfers))
+		for _, transfer := range transfers {
+			ids = append(ids, transfer.ID)
+		}
+		require.ElementsMatch(t, tc.want, ids, tc.direction)
+	}
+
+	// the limit and offset are bound to their own parameters
+	transfers, err := testStore.ListTransfersFilteredDesc(context.Background(), ListTransfersFilteredDescParams{
+		AccountID: account1.ID,
+		Direction: "any",
+		MinAmount: 0,
+		MaxAmount: 1000,
+		FromTime:  time.Now().Add(-time.Minute),
+		ToTime:    time.Now().Add(time.Minute),
+		Limit:     1,
+		Offset:    1,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+}
@@ -0,0 +1,368 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The state-changing queries the servers make have a transaction of their own,
+// which records an audit event of the actor carried by ctx along with the change.
+// The generated queries they wrap are not audited, so the servers always call the transactions.
+
+// auditSession is the audited state of a session, without its refresh token
+type auditSession struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	FamilyID  uuid.UUID `json:"family_id"`
+	UserAgent string    `json:"user_agent"`
+	ClientIP  string    `json:"client_ip"`
+	IsBlocked bool      `json:"is_blocked"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func newAuditSession(session Session) auditSession {
+	return auditSession{
+		ID:        session.ID,
+		Username:  session.Username,
+		FamilyID:  session.FamilyID,
+		UserAgent: session.UserAgent,
+		ClientIP:  session.ClientIp,
+		IsBlocked: session.IsBlocked,
+		ExpiresAt: session.ExpiresAt,
+	}
+}
+
+// auditPendingTransfer is the audited state of a pending transfer, without its code
+type auditPendingTransfer struct {
+	ID                 int64  `json:"id"`
+	FromAccountID      int64  `json:"from_account_id"`
+	ToAccountID        int64  `json:"to_account_id"`
+	Amount             int64  `json:"amount"`
+	Currency           string `json:"currency"`
+	VerificationMethod string `json:"verification_method"`
+	Attempts           int32  `json:"attempts"`
+	Status             string `json:"status"`
+}
+
+func newAuditPendingTransfer(pendingTransfer PendingTransfer) auditPendingTransfer {
+	return auditPendingTransfer{
+		ID:                 pendingTransfer.ID,
+		FromAccountID:      pendingTransfer.FromAccountID,
+		ToAccountID:        pendingTransfer.ToAccountID,
+		Amount:             pendingTransfer.Amount,
+		Currency:           pendingTransfer.Currency,
+		VerificationMethod: pendingTransfer.VerificationMethod,
+		Attempts:           pendingTransfer.Attempts,
+		Status:             pendingTransfer.Status,
+	}
+}
+
+// auditTOTP is the audited state of the two-factor authentication of a user, without its secret
+type auditTOTP struct {
+	Enabled bool `json:"enabled"`
+}
+
+// auditSessionCount is the audited result of revoking several sessions
+type auditSessionCount struct {
+	BlockedSessions int64 `json:"blocked_sessions"`
+}
+
+func recordUserCreated(ctx context.Context, q *Queries, user User) error {
+	_, err := recordAuditEvent(ctx, q, AuditRecord{
+		Actor:      AuditActor{Username: user.Username, Role: user.Role},
+		Action:     AuditActionUserCreate,
+		TargetType: AuditTargetUser,
+		TargetID:   user.Username,
+		After:      newAuditUser(user),
+	})
+	return err
+}
+
+// auditedUpdateUser updates the locked user and records the change within the transaction of q
+func auditedUpdateUser(ctx context.Context, q *Queries, arg UpdateUserParams) (User, error) {
+	before, err := q.GetUserForUpdate(ctx, arg.Username)
+	if err != nil {
+		return before, err
+	}
+
+	user, err := q.UpdateUser(ctx, arg)
+	if err != nil {
+		return user, err
+	}
+
+	_, err = recordAuditEvent(ctx, q, AuditRecord{
+		Action:     AuditActionUserUpdate,
+		TargetType: AuditTargetUser,
+		TargetID:   user.Username,
+		Before:     newAuditUser(before),
+		After:      newAuditUser(user),
+	})
+	return user, err
+}
+
+// UpdateUserTx updates the locked user and records the change as an audit event
+func (store *SQLStore) UpdateUserTx(ctx context.Context, arg UpdateUserParams) (user User, err error) {
+	err = store.execTx(ctx, func(q *Queries) error {
+		user, err = auditedUpdateUser(ctx, q, arg)
+		return err
+	})
+	return
+}
+
+// CreateSessionTx creates the session of a login and records the login as an audit event
+func (store *SQLStore) CreateSessionTx(ctx context.Context, arg CreateSessionParams) (session Session, err error) {
+	err = store.execTx(ctx, func(q *Queries) error {
+		session, err = q.CreateSession(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionUserLogin,
+			TargetType: AuditTargetUser,
+			TargetID:   session.Username,
+			After:      newAuditSession(session),
+		})
+		return err
+	})
+	return
+}
+
+// BlockSessionTx revokes the locked session and records the change as an audit event
+func (store *SQLStore) BlockSessionTx(ctx context.Context, arg BlockSessionParams) (session Session, err error) {
+	err = store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetSessionForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		session, err = q.BlockSession(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionSessionRevoke,
+			TargetType: AuditTargetSession,
+			TargetID:   session.ID.String(),
+			Before:     newAuditSession(before),
+			After:      newAuditSession(session),
+		})
+		return err
+	})
+	return
+}
+
+// BlockUserSessionsTx revokes all the sessions of a user and records how many as an audit event
+func (store *SQLStore) BlockUserSessionsTx(ctx context.Context, username string) (count int64, err error) {
+	err = store.execTx(ctx, func(q *Queries) error {
+		count, err = q.BlockUserSessions(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionSessionRevokeAll,
+			TargetType: AuditTargetUser,
+			TargetID:   username,
+			After:      auditSessionCount{BlockedSessions: count},
+		})
+		return err
+	})
+	return
+}
+
+// UpsertUserTOTPTx stores a TOTP enrollment, which is pending until it is confirmed, and records it as an audit event
+func (store *SQLStore) UpsertUserTOTPTx(ctx context.Context, arg UpsertUserTOTPParams) (userTOTP UserTotp, err error) {
+	err = store.execTx(ctx, func(q *Queries) error {
+		userTOTP, err = q.UpsertUserTOTP(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionUserTOTPEnroll,
+			TargetType: AuditTargetUser,
+			TargetID:   userTOTP.Username,
+			After:      auditTOTP{Enabled: userTOTP.ConfirmedAt.Valid},
+		})
+		return err
+	})
+	return
+}
+
+// CreateAccountTx creates an account and records it as an audit event
+func (store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (account Account, err error) {
+	err = store.execTx(ctx, func(q *Queries) error {
+		account, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionAccountCreate,
+			TargetType: AuditTargetAccount,
+			TargetID:   formatAuditID(account.ID),
+			After:      account,
+		})
+		return err
+	})
+	return
+}
+
+// UpsertAccountLimitTx sets the limits of the locked account and records the change as an audit event
+func (store *SQLStore) UpsertAccountLimitTx(ctx context.Context, arg UpsertAccountLimitParams) (limit AccountLimit, err error) {
+	err = store.execTx(ctx, func(q *Queries) error {
+		_, err = q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		var before *AccountLimit
+		current, err := q.GetAccountLimit(ctx, arg.AccountID)
+		if err == nil {
+			before = &current
+		} else if !errors.Is(err, ErrRecordNotFound) {
+			return err
+		}
+
+		limit, err = q.UpsertAccountLimit(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionAccountLimitSet,
+			TargetType: AuditTargetAccount,
+			TargetID:   formatAuditID(arg.AccountID),
+			Before:     before,
+			After:      limit,
+		})
+		return err
+	})
+	return
+}
+
+// UpsertAccountAlertTx sets the balance alerts of the locked account and records the change as an audit event
+func (store *SQLStore) UpsertAccountAlertTx(ctx context.Context, arg UpsertAccountAlertParams) (alert AccountAlert, err error) {
+	err = store.execTx(ctx, func(q *Queries) error {
+		_, err = q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		var before *AccountAlert
+		current, err := q.GetAccountAlert(ctx, arg.AccountID)
+		if err == nil {
+			before = &current
+		} else if !errors.Is(err, ErrRecordNotFound) {
+			return err
+		}
+
+		alert, err = q.UpsertAccountAlert(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionAccountAlertSet,
+			TargetType: AuditTargetAccount,
+			TargetID:   formatAuditID(arg.AccountID),
+			Before:     before,
+			After:      alert,
+		})
+		return err
+	})
+	return
+}
+
+// AddPendingTransferAttemptTx counts a code entered for the pending transfer and records it as an audit event
+func (store *SQLStore) AddPendingTransferAttemptTx(ctx context.Context, id int64) (pendingTransfer PendingTransfer, err error) {
+	err = store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetPendingTransferForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		pendingTransfer, err = q.AddPendingTransferAttempt(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionPendingAttempt,
+			TargetType: AuditTargetPendingTransfer,
+			TargetID:   formatAuditID(id),
+			Before:     newAuditPendingTransfer(before),
+			After:      newAuditPendingTransfer(pendingTransfer),
+		})
+		return err
+	})
+	return
+}
+
+// CreateScheduledTransferTx creates a scheduled transfer and records it as an audit event
+func (store *SQLStore) CreateScheduledTransferTx(ctx context.Context, arg CreateScheduledTransferParams) (schedule ScheduledTransfer, err error) {
+	err = store.execTx(ctx, func(q *Queries) error {
+		schedule, err = q.CreateScheduledTransfer(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionScheduledCreate,
+			TargetType: AuditTargetScheduledTransfer,
+			TargetID:   formatAuditID(schedule.ID),
+			After:      schedule,
+		})
+		return err
+	})
+	return
+}
+
+// UpdateScheduledTransferTx updates the locked scheduled transfer and records the change as an audit event
+func (store *SQLStore) UpdateScheduledTransferTx(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error) {
+	return store.updateScheduledTransfer(ctx, arg.ID, AuditActionScheduledUpdate, func(q *Queries) (ScheduledTransfer, error) {
+		return q.UpdateScheduledTransfer(ctx, arg)
+	})
+}
+
+// UpdateScheduledTransferStatusTx changes the status of the locked scheduled transfer and records the change as an audit event
+func (store *SQLStore) UpdateScheduledTransferStatusTx(ctx context.Context, arg UpdateScheduledTransferStatusParams) (ScheduledTransfer, error) {
+	return store.updateScheduledTransfer(ctx, arg.ID, AuditActionScheduledSetStatus, func(q *Queries) (ScheduledTransfer, error) {
+		return q.UpdateScheduledTransferStatus(ctx, arg)
+	})
+}
+
+// updateScheduledTransfer locks the scheduled transfer, applies update to it and records the change
+func (store *SQLStore) updateScheduledTransfer(
+	ctx context.Context,
+	id int64,
+	action string,
+	update func(q *Queries) (ScheduledTransfer, error),
+) (schedule ScheduledTransfer, err error) {
+	err = store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetScheduledTransferForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		schedule, err = update(q)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     action,
+			TargetType: AuditTargetScheduledTransfer,
+			TargetID:   formatAuditID(id),
+			Before:     before,
+			After:      schedule,
+		})
+		return err
+	})
+	return
+}
@@ -0,0 +1,34 @@
+package db
+
+import "context"
+
+type ChangePasswordTxParams struct {
+	UpdateUserParams
+}
+
+type ChangePasswordTxResult struct {
+	User User
+	// BlockedSessions is the number of sessions that were revoked
+	BlockedSessions int64
+}
+
+// ChangePasswordTx updates the user, who must be given a new password,
+// and revokes all of the user's sessions within the same database transaction,
+// so no refresh token issued for the old password can be used afterwards.
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (ChangePasswordTxResult, error) {
+	var result ChangePasswordTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = auditedUpdateUser(ctx, q, arg.UpdateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.BlockedSessions, err = q.BlockUserSessions(ctx, result.User.Username)
+		return err
+	})
+
+	return result, err
+}
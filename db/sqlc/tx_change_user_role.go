@@ -0,0 +1,67 @@
+package db
+
+import "context"
+
+// ChangeUserRoleTxParams contains the input parameters of the change user role transaction
+type ChangeUserRoleTxParams struct {
+	Username string
+	Role     string
+	Reason   string
+}
+
+// ChangeUserRoleTxResult is the result of the change user role transaction
+type ChangeUserRoleTxResult struct {
+	User       User
+	AuditEvent AuditEvent
+	// BlockedSessions is the number of sessions that were revoked
+	BlockedSessions int64
+}
+
+// userRoleState is the audited state of a user role change
+type userRoleState struct {
+	Role string `json:"role"`
+}
+
+// ChangeUserRoleTx sets the role of the locked user, revokes the user's sessions
+// so no token carrying the old role can be refreshed, and records the change
+// as an audit event within the same database transaction.
+// It fails with ErrUserRoleUnchanged if the user already has the role.
+func (store *SQLStore) ChangeUserRoleTx(ctx context.Context, arg ChangeUserRoleTxParams) (ChangeUserRoleTxResult, error) {
+	var result ChangeUserRoleTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		user, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		if user.Role == arg.Role {
+			return ErrUserRoleUnchanged
+		}
+
+		result.User, err = q.UpdateUserRole(ctx, UpdateUserRoleParams{
+			Username: arg.Username,
+			Role:     arg.Role,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.BlockedSessions, err = q.BlockUserSessions(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.AuditEvent, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionUserRoleChange,
+			TargetType: AuditTargetUser,
+			TargetID:   arg.Username,
+			Reason:     arg.Reason,
+			Before:     userRoleState{Role: user.Role},
+			After:      userRoleState{Role: result.User.Role},
+		})
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Statuses of a transfer waiting for a second factor
+const (
+	PendingTransferStatusPending   = "pending"
+	PendingTransferStatusConfirmed = "confirmed"
+	PendingTransferStatusExpired   = "expired"
+)
+
+// Methods a pending transfer is confirmed with
+const (
+	VerificationMethodTOTP  = "totp"
+	VerificationMethodEmail = "email"
+)
+
+// ConfirmPendingTransferTxParams contains the input parameters of the confirm pending transfer transaction
+type ConfirmPendingTransferTxParams struct {
+	PendingTransferID int64
+	// Transfer is made once the pending transfer is locked and still pending
+	Transfer TransferTxParams
+}
+
+// ConfirmPendingTransferTxResult is the result of the confirm pending transfer transaction
+type ConfirmPendingTransferTxResult struct {
+	PendingTransfer PendingTransfer  `json:"pending_transfer"`
+	Transfer        TransferTxResult `json:"transfer"`
+}
+
+// ConfirmPendingTransferTx makes the transfer of a pending transfer and marks it as confirmed
+// within a database transaction, so a pending transfer moves money at most once.
+// It returns ErrPendingTransferNotPending if it was already confirmed or expired,
+// and ErrPendingTransferExpired if its expiry has passed but the worker has not expired it yet.
+func (store *SQLStore) ConfirmPendingTransferTx(ctx context.Context, arg ConfirmPendingTransferTxParams) (ConfirmPendingTransferTxResult, error) {
+	var result ConfirmPendingTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		pendingTransfer, err := q.GetPendingTransferForUpdate(ctx, arg.PendingTransferID)
+		if err != nil {
+			return err
+		}
+
+		if pendingTransfer.Status != PendingTransferStatusPending {
+			return ErrPendingTransferNotPending
+		}
+		if !pendingTransfer.ExpiresAt.After(time.Now()) {
+			return ErrPendingTransferExpired
+		}
+
+		result.Transfer, err = transferMoney(ctx, q, arg.Transfer)
+		if err != nil {
+			return err
+		}
+
+		result.PendingTransfer, err = q.ConfirmPendingTransfer(ctx, ConfirmPendingTransferParams{
+			ID:         pendingTransfer.ID,
+			TransferID: pgtype.Int8{Int64: result.Transfer.Transfer.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionPendingConfirm,
+			TargetType: AuditTargetPendingTransfer,
+			TargetID:   formatAuditID(pendingTransfer.ID),
+			Before:     newAuditPendingTransfer(pendingTransfer),
+			After:      newAuditPendingTransfer(result.PendingTransfer),
+		})
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+)
+
+// CreatePendingTransferTxParams contains the input parameters of the create pending transfer transaction
+type CreatePendingTransferTxParams struct {
+	CreatePendingTransferParams
+	// Idempotency makes the request safe to retry when set,
+	// so a retry returns the pending transfer of the first request instead of holding another one.
+	Idempotency *IdempotencyParams
+}
+
+// CreatePendingTransferTxResult is the result of the create pending transfer transaction
+type CreatePendingTransferTxResult struct {
+	PendingTransfer PendingTransfer
+	// Replayed is true when the pending transfer was created by a previous request with the same idempotency key
+	Replayed bool
+}
+
+// pendingTransferRequest is the fingerprint of a transfer held for a second factor.
+// The expiry and verification method are left out, they are chosen by the server, not the client.
+type pendingTransferRequest struct {
+	FromAccountID   int64  `json:"from_account_id"`
+	ToAccountID     int64  `json:"to_account_id"`
+	Amount          int64  `json:"amount"`
+	Currency        string `json:"currency"`
+	ConvertCurrency bool   `json:"convert_currency"`
+}
+
+// pendingTransferResponse is the stored response of a pending transfer,
+// it is loaded again when replayed so that the retry sees its current status
+type pendingTransferResponse struct {
+	PendingTransferID int64 `json:"pending_transfer_id"`
+}
+
+// CreatePendingTransferTx holds a transfer until its owner confirms it with a second factor,
+// and records it as an audit event within a database transaction.
+func (store *SQLStore) CreatePendingTransferTx(ctx context.Context, arg CreatePendingTransferTxParams) (CreatePendingTransferTxResult, error) {
+	var result CreatePendingTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		if arg.Idempotency != nil {
+			request := pendingTransferRequest{
+				FromAccountID:   arg.FromAccountID,
+				ToAccountID:     arg.ToAccountID,
+				Amount:          arg.Amount,
+				Currency:        arg.Currency,
+				ConvertCurrency: arg.ConvertCurrency,
+			}
+
+			var response pendingTransferResponse
+			result.Replayed, err = claimIdempotencyKey(ctx, q, *arg.Idempotency, request, &response)
+			if err != nil {
+				return err
+			}
+			if result.Replayed {
+				result.PendingTransfer, err = q.GetPendingTransfer(ctx, response.PendingTransferID)
+				return err
+			}
+		}
+
+		result.PendingTransfer, err = q.CreatePendingTransfer(ctx, arg.CreatePendingTransferParams)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionPendingCreate,
+			TargetType: AuditTargetPendingTransfer,
+			TargetID:   formatAuditID(result.PendingTransfer.ID),
+			After:      newAuditPendingTransfer(result.PendingTransfer),
+		})
+		if err != nil {
+			return err
+		}
+
+		if arg.Idempotency != nil {
+			return saveIdempotentResponse(ctx, q, *arg.Idempotency, pendingTransferResponse{
+				PendingTransferID: result.PendingTransfer.ID,
+			})
+		}
+
+		return nil
+	})
+
+	return result, err
+}
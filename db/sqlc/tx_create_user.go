@@ -4,6 +4,7 @@ import "context"
 
 type CreateUserTxParams struct {
 	CreateUserParams
+	// AfterCreate is called with the new user before the transaction commits, when set
 	AfterCreate func(user User) error
 }
 
@@ -22,6 +23,14 @@ func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams)
 			return err
 		}
 
+		err = recordUserCreated(ctx, q, result.User)
+		if err != nil {
+			return err
+		}
+
+		if arg.AfterCreate == nil {
+			return nil
+		}
 		return arg.AfterCreate(result.User)
 	})
 
@@ -0,0 +1,261 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Kinds of journal transactions
+const (
+	JournalKindTransfer = "transfer"
+	JournalKindReversal = "reversal"
+)
+
+// ExchangeUsername owns the accounts of the currency exchange desk, one per currency.
+// A converted transfer pays into the desk in one currency and out of it in the other,
+// so its journal transaction balances in both.
+const ExchangeUsername = "simplebank.exchange"
+
+// IsSystemAccount reports whether the account is owned by the bank itself, such as an account of the exchange desk.
+// Its status never changes, or the transfers that need it would fail.
+func IsSystemAccount(account Account) bool {
+	return account.Owner == ExchangeUsername
+}
+
+// JournalEntryParams is an entry of a journal transaction,
+// which credits the account when positive and debits it when negative
+type JournalEntryParams struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"`
+}
+
+// PostJournalTxParams contains the input parameters of the post journal transaction
+type PostJournalTxParams struct {
+	Kind        string               `json:"kind"`
+	Description string               `json:"description"`
+	Entries     []JournalEntryParams `json:"entries"`
+}
+
+// PostJournalTxResult is the result of the post journal transaction
+type PostJournalTxResult struct {
+	JournalTransaction JournalTransaction `json:"journal_transaction"`
+	// Entries are in the order of the params
+	Entries []Entry `json:"entries"`
+	// Accounts are the accounts of the entries after the journal transaction, in ID order
+	Accounts []Account `json:"accounts"`
+}
+
+// PostJournalTx posts a journal transaction of any number of entries, such as a deposit
+// from a settlement account or a transfer with a fee, within a database transaction.
+// The entries must sum to zero in each currency, otherwise ErrUnbalancedJournal is returned.
+// Every account is locked and must be active; balances are not checked,
+// so the caller decides whether an account may go negative.
+func (store *SQLStore) PostJournalTx(ctx context.Context, arg PostJournalTxParams) (PostJournalTxResult, error) {
+	var result PostJournalTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		accounts, err := lockJournalAccounts(ctx, q, arg.Entries)
+		if err != nil {
+			return err
+		}
+
+		for _, account := range accounts {
+			if err = checkAccountStatus(account); err != nil {
+				return err
+			}
+		}
+
+		result.JournalTransaction, result.Entries, err = postJournal(ctx, q, CreateJournalTransactionParams{
+			Kind:        arg.Kind,
+			Description: arg.Description,
+		}, arg.Entries, accounts)
+		if err != nil {
+			return err
+		}
+
+		result.Accounts = sortedAccounts(accounts)
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionJournalPost,
+			TargetType: AuditTargetJournal,
+			TargetID:   formatAuditID(result.JournalTransaction.ID),
+			Reason:     arg.Description,
+			After:      result,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// postJournal records a journal transaction and its entries, and adds the entries to the balances of their accounts.
+// The accounts must be locked by the caller, and are updated in place.
+func postJournal(
+	ctx context.Context,
+	q *Queries,
+	arg CreateJournalTransactionParams,
+	entries []JournalEntryParams,
+	accounts map[int64]Account,
+) (JournalTransaction, []Entry, error) {
+	if err := checkJournalBalanced(entries, accounts); err != nil {
+		return JournalTransaction{}, nil, err
+	}
+
+	journal, err := q.CreateJournalTransaction(ctx, arg)
+	if err != nil {
+		return journal, nil, err
+	}
+
+	journalID := pgtype.Int8{Int64: journal.ID, Valid: true}
+	created := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		createdEntry, err := q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:            entry.AccountID,
+			Amount:               entry.Amount,
+			JournalTransactionID: journalID,
+		})
+		if err != nil {
+			return journal, nil, err
+		}
+		created = append(created, createdEntry)
+
+		accounts[entry.AccountID], err = q.AddEntryToAccountBalance(ctx, createdEntry.ID)
+		if err != nil {
+			return journal, nil, err
+		}
+	}
+
+	return journal, created, nil
+}
+
+// checkJournalBalanced returns ErrUnbalancedJournal unless there are at least two entries,
+// none of them empty, and they sum to zero in each currency
+func checkJournalBalanced(entries []JournalEntryParams, accounts map[int64]Account) error {
+	if len(entries) < 2 {
+		return fmt.Errorf("%w: it needs at least two entries", ErrUnbalancedJournal)
+	}
+
+	totals := make(map[string]int64)
+	for _, entry := range entries {
+		if entry.Amount == 0 {
+			return fmt.Errorf("%w: the entry of account %d has no amount", ErrUnbalancedJournal, entry.AccountID)
+		}
+		totals[accounts[entry.AccountID].Currency] += entry.Amount
+	}
+
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	for _, currency := range currencies {
+		if totals[currency] != 0 {
+			return fmt.Errorf("%w: the %s entries sum to %d", ErrUnbalancedJournal, currency, totals[currency])
+		}
+	}
+	return nil
+}
+
+// lockJournalAccounts locks the accounts of the entries in ID order, to avoid deadlocks,
+// and returns them by ID
+func lockJournalAccounts(ctx context.Context, q *Queries, entries []JournalEntryParams) (map[int64]Account, error) {
+	accountIDs := make([]int64, 0, len(entries))
+	for _, entry := range entries {
+		accountIDs = append(accountIDs, entry.AccountID)
+	}
+	sort.Slice(accountIDs, func(i, j int) bool { return accountIDs[i] < accountIDs[j] })
+
+	accounts := make(map[int64]Account, len(accountIDs))
+	for _, accountID := range accountIDs {
+		if _, ok := accounts[accountID]; ok {
+			continue
+		}
+
+		account, err := q.GetAccountForUpdate(ctx, accountID)
+		if err != nil {
+			return nil, err
+		}
+		accounts[accountID] = account
+	}
+	return accounts, nil
+}
+
+func sortedAccounts(accounts map[int64]Account) []Account {
+	sorted := make([]Account, 0, len(accounts))
+	for _, account := range accounts {
+		sorted = append(sorted, account)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return sorted
+}
+
+// transferEntries returns the entries of a transfer that debits amount from one account
+// and credits toAmount to the other.
+// Between currencies, the exchange desk is credited amount in the from currency
+// and debited toAmount in the to currency.
+// Currencies never change, so the accounts may be read before they are locked.
+func transferEntries(ctx context.Context, q *Queries, fromAccountID int64, toAccountID int64, amount int64, toAmount int64) ([]JournalEntryParams, error) {
+	fromAccount, err := q.GetAccount(ctx, fromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	toAccount, err := q.GetAccount(ctx, toAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []JournalEntryParams{
+		{AccountID: fromAccountID, Amount: -amount},
+		{AccountID: toAccountID, Amount: toAmount},
+	}
+	if fromAccount.Currency == toAccount.Currency {
+		return entries, nil
+	}
+
+	// an exchange account opened by a concurrent transfer is waited for,
+	// so they are looked up in currency order to avoid deadlocks
+	currencies := []string{fromAccount.Currency, toAccount.Currency}
+	sort.Strings(currencies)
+
+	exchangeIDs := make(map[string]int64, len(currencies))
+	for _, currency := range currencies {
+		exchangeIDs[currency], err = getExchangeAccountID(ctx, q, currency)
+		if err != nil {
+			return nil, err
+		}
+	}
+	fromExchangeID := exchangeIDs[fromAccount.Currency]
+	toExchangeID := exchangeIDs[toAccount.Currency]
+
+	return append(entries,
+		JournalEntryParams{AccountID: fromExchangeID, Amount: amount},
+		JournalEntryParams{AccountID: toExchangeID, Amount: -toAmount},
+	), nil
+}
+
+// getExchangeAccountID returns the ID of the exchange desk's account in the currency,
+// opening it the first time a currency added after the migration is exchanged
+func getExchangeAccountID(ctx context.Context, q *Queries, currency string) (int64, error) {
+	arg := GetAccountByOwnerCurrencyParams{
+		Owner:    ExchangeUsername,
+		Currency: currency,
+	}
+
+	account, err := q.GetAccountByOwnerCurrency(ctx, arg)
+	if errors.Is(err, ErrRecordNotFound) {
+		err = q.CreateAccountIfNotExists(ctx, CreateAccountIfNotExistsParams(arg))
+		if err != nil {
+			return 0, err
+		}
+		account, err = q.GetAccountByOwnerCurrency(ctx, arg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return account.ID, nil
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostJournalTx(t *testing.T) {
+	settlement := createRandomAccountInCurrency(t, 1000, util.USD)
+	customer := createRandomAccountInCurrency(t, 0, util.USD)
+	fees := createRandomAccountInCurrency(t, 0, util.USD)
+
+	// a deposit of 100 from the settlement account, less a fee of 2
+	arg := PostJournalTxParams{
+		Kind:        "deposit",
+		Description: util.RandomString(12),
+		Entries: []JournalEntryParams{
+			{AccountID: settlement.ID, Amount: -100},
+			{AccountID: customer.ID, Amount: 98},
+			{AccountID: fees.ID, Amount: 2},
+		},
+	}
+
+	result, err := testStore.PostJournalTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, result.JournalTransaction.ID)
+	require.Equal(t, arg.Kind, result.JournalTransaction.Kind)
+	require.Equal(t, arg.Description, result.JournalTransaction.Description)
+	require.False(t, result.JournalTransaction.TransferID.Valid)
+
+	require.Len(t, result.Entries, 3)
+	for i, entry := range result.Entries {
+		require.Equal(t, arg.Entries[i].AccountID, entry.AccountID)
+		require.Equal(t, arg.Entries[i].Amount, entry.Amount)
+		require.Equal(t, result.JournalTransaction.ID, entry.JournalTransactionID.Int64)
+	}
+
+	require.Len(t, result.Accounts, 3)
+	balances := make(map[int64]int64)
+	for _, account := range result.Accounts {
+		balances[account.ID] = account.Balance
+	}
+	require.Equal(t, map[int64]int64{settlement.ID: 900, customer.ID: 98, fees.ID: 2}, balances)
+
+	journals, err := ListJournals(context.Background(), testStore, EntryJournalIDs(result.Entries))
+	require.NoError(t, err)
+	require.Len(t, journals, 1)
+	require.Equal(t, result.JournalTransaction, journals[0].JournalTransaction)
+	require.Equal(t, result.Entries, journals[0].Entries)
+}
+
+func TestPostJournalTxUnbalanced(t *testing.T) {
+	usd := createRandomAccountInCurrency(t, 1000, util.USD)
+	eur := createRandomAccountInCurrency(t, 0, util.EUR)
+
+	testCases := []struct {
+		name    string
+		entries []JournalEntryParams
+	}{
+		{
+			name:    "SingleEntry",
+			entries: []JournalEntryParams{{AccountID: usd.ID, Amount: 10}},
+		},
+		{
+			name: "NonZeroSum",
+			entries: []JournalEntryParams{
+				{AccountID: usd.ID, Amount: -10},
+				{AccountID: usd.ID, Amount: 9},
+			},
+		},
+		{
+			name: "AcrossCurrencies",
+			entries: []JournalEntryParams{
+				{AccountID: usd.ID, Amount: -10},
+				{AccountID: eur.ID, Amount: 10},
+			},
+		},
+		{
+			name: "EmptyEntry",
+			entries: []JournalEntryParams{
+				{AccountID: usd.ID, Amount: 0},
+				{AccountID: eur.ID, Amount: 0},
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := testStore.PostJournalTx(context.Background(), PostJournalTxParams{
+				Kind:    "adjustment",
+				Entries: tc.entries,
+			})
+			require.ErrorIs(t, err, ErrUnbalancedJournal)
+		})
+	}
+
+	// nothing was posted
+	account, err := testStore.GetAccount(context.Background(), usd.ID)
+	require.NoError(t, err)
+	require.Equal(t, usd.Balance, account.Balance)
+}
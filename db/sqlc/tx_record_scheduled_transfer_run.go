@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Statuses of a scheduled transfer
+const (
+	ScheduledTransferStatusActive    = "active"
+	ScheduledTransferStatusCompleted = "completed"
+	ScheduledTransferStatusCancelled = "cancelled"
+)
+
+// Statuses of a single run of a scheduled transfer
+const (
+	ScheduledTransferRunSucceeded = "succeeded"
+	ScheduledTransferRunFailed    = "failed"
+)
+
+// RecordScheduledTransferRunTxParams contains the input parameters of the record scheduled transfer run transaction
+type RecordScheduledTransferRunTxParams struct {
+	ScheduledTransfer ScheduledTransfer
+	// TransferID is the transfer made by a successful run
+	TransferID int64
+	// Error is the reason a failed run did not make a transfer
+	Error string
+	// NextRunAt is when the schedule runs next, ignored when Finished is set
+	NextRunAt time.Time
+	// Finished marks the schedule as completed after this run
+	Finished bool
+}
+
+// RecordScheduledTransferRunTxResult is the result of the record scheduled transfer run transaction
+type RecordScheduledTransferRunTxResult struct {
+	ScheduledTransfer ScheduledTransfer    `json:"scheduled_transfer"`
+	Run               ScheduledTransferRun `json:"run"`
+}
+
+// RecordScheduledTransferRunTx records the outcome of a due run and advances the schedule to its next run,
+// within a database transaction.
+// The schedule only advances if it is still active and due at the same time, so each run is recorded once;
+// otherwise ErrRecordNotFound is returned.
+func (store *SQLStore) RecordScheduledTransferRunTx(ctx context.Context, arg RecordScheduledTransferRunTxParams) (RecordScheduledTransferRunTxResult, error) {
+	var result RecordScheduledTransferRunTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		schedule := arg.ScheduledTransfer
+		advanceArg := AdvanceScheduledTransferParams{
+			ID:          schedule.ID,
+			ScheduledAt: schedule.NextRunAt,
+			NextRunAt:   arg.NextRunAt,
+			Status:      ScheduledTransferStatusActive,
+		}
+		if arg.Finished {
+			advanceArg.NextRunAt = schedule.NextRunAt
+			advanceArg.Status = ScheduledTransferStatusCompleted
+		}
+
+		result.ScheduledTransfer, err = q.AdvanceScheduledTransfer(ctx, advanceArg)
+		if err != nil {
+			return err
+		}
+
+		runArg := CreateScheduledTransferRunParams{
+			ScheduledTransferID: schedule.ID,
+			Status:              ScheduledTransferRunSucceeded,
+			ScheduledAt:         schedule.NextRunAt,
+		}
+		if arg.Error != "" {
+			runArg.Status = ScheduledTransferRunFailed
+			runArg.Error = pgtype.Text{String: arg.Error, Valid: true}
+		} else {
+			runArg.TransferID = pgtype.Int8{Int64: arg.TransferID, Valid: true}
+		}
+
+		result.Run, err = q.CreateScheduledTransferRun(ctx, runArg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionScheduledRun,
+			TargetType: AuditTargetScheduledTransfer,
+			TargetID:   formatAuditID(schedule.ID),
+			Before:     schedule,
+			After:      result.Run,
+		})
+		return err
+	})
+
+	return result, err
+}
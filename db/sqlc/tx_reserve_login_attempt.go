@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type ReserveLoginAttemptTxResult struct {
+	// Failure has the attempt counted in
+	Failure LoginFailure
+	// PreviousFailedAt is when the failure before the attempt was, or zero if there was none
+	PreviousFailedAt time.Time
+}
+
+// ReserveLoginAttemptTx counts a login attempt as failed before its password is checked,
+// within a database transaction that locks the failures of the subject first,
+// so that concurrent attempts are counted one after the other
+// and each of them sees the failure counted just before it
+func (store *SQLStore) ReserveLoginAttemptTx(ctx context.Context, arg RecordLoginFailureParams) (ReserveLoginAttemptTxResult, error) {
+	var result ReserveLoginAttemptTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		previous, err := q.GetLoginFailureForUpdate(ctx, GetLoginFailureForUpdateParams{
+			Kind:    arg.Kind,
+			Subject: arg.Subject,
+		})
+		if err != nil && !errors.Is(err, ErrRecordNotFound) {
+			return err
+		}
+		result.PreviousFailedAt = previous.LastFailedAt
+
+		result.Failure, err = q.RecordLoginFailure(ctx, arg)
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ResetPasswordTxParams struct {
+	ResetID        int64
+	CodeHash       string
+	HashedPassword string
+}
+
+type ResetPasswordTxResult struct {
+	User User
+	// BlockedSessions is the number of sessions that were revoked
+	BlockedSessions int64
+}
+
+// ResetPasswordTx uses a password reset code to give its user a new password,
+// then revokes all of the user's sessions and other reset codes within the same database transaction.
+// It returns ErrRecordNotFound if the code is wrong, used or expired.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error) {
+	var result ResetPasswordTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		passwordReset, err := q.UsePasswordReset(ctx, UsePasswordResetParams{
+			ID:       arg.ResetID,
+			CodeHash: arg.CodeHash,
+		})
+		if err != nil {
+			return err
+		}
+
+		user, err := q.GetUserForUpdate(ctx, passwordReset.Username)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.UpdateUser(ctx, UpdateUserParams{
+			Username: passwordReset.Username,
+			HashedPassword: pgtype.Text{
+				String: arg.HashedPassword,
+				Valid:  true,
+			},
+			PasswordChangedAt: pgtype.Timestamptz{
+				Time:  time.Now(),
+				Valid: true,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.InvalidatePasswordResets(ctx, passwordReset.Username)
+		if err != nil {
+			return err
+		}
+
+		result.BlockedSessions, err = q.BlockUserSessions(ctx, passwordReset.Username)
+		if err != nil {
+			return err
+		}
+
+		// the reset code identifies the user, who is not logged in
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Actor:      AuditActor{Username: user.Username, Role: user.Role},
+			Action:     AuditActionUserPasswordReset,
+			TargetType: AuditTargetUser,
+			TargetID:   user.Username,
+			Before:     newAuditUser(user),
+			After:      newAuditUser(result.User),
+		})
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Statuses of a transfer
+const (
+	TransferStatusCompleted = "completed"
+	TransferStatusReversed  = "reversed"
+)
+
+// ReverseTransferTxParams contains the input parameters of the reverse transfer transaction
+type ReverseTransferTxParams struct {
+	TransferID int64  `json:"transfer_id"`
+	Reason     string `json:"reason"`
+}
+
+// ReverseTransferTxResult is the result of the reverse transfer transaction
+type ReverseTransferTxResult struct {
+	OriginalTransfer Transfer `json:"original_transfer"`
+	ReversalTransfer Transfer `json:"reversal_transfer"`
+	FromAccount      Account  `json:"from_account"`
+	ToAccount        Account  `json:"to_account"`
+	FromEntry        Entry    `json:"from_entry"`
+	ToEntry          Entry    `json:"to_entry"`
+	// JournalTransaction groups the entries of the reversal
+	JournalTransaction JournalTransaction `json:"journal_transaction"`
+}
+
+// ReverseTransferTx undoes a completed transfer.
+// It creates a compensating transfer in the opposite direction linked to the original one,
+// posts its journal transaction and marks the original transfer as reversed, within a database transaction.
+// A transfer can only be reversed once; later attempts fail with ErrTransferAlreadyReversed.
+// The balance of the account that received the original transfer is allowed to go negative.
+// Transfers of frozen accounts can be reversed, but not those of closed accounts.
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	var result ReverseTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		original, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		if original.ReversalOf.Valid {
+			return ErrTransferNotReversible
+		}
+		if original.Status == TransferStatusReversed {
+			return ErrTransferAlreadyReversed
+		}
+
+		// the reversal moves the converted amount back, so it balances the exchange desk too
+		entries, err := transferEntries(ctx, q, original.ToAccountID, original.FromAccountID, original.ToAmount, original.Amount)
+		if err != nil {
+			return err
+		}
+
+		accounts, err := lockJournalAccounts(ctx, q, entries)
+		if err != nil {
+			return err
+		}
+		fromAccount := accounts[original.ToAccountID]
+		toAccount := accounts[original.FromAccountID]
+
+		if fromAccount.Status == AccountStatusClosed || toAccount.Status == AccountStatusClosed {
+			return ErrAccountClosed
+		}
+
+		result.ReversalTransfer, err = q.CreateReversalTransfer(ctx, CreateReversalTransferParams{
+			TransferID: original.ID,
+			Reason:     arg.Reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		journal, journalEntries, err := postJournal(ctx, q, CreateJournalTransactionParams{
+			Kind:        JournalKindReversal,
+			TransferID:  pgtype.Int8{Int64: result.ReversalTransfer.ID, Valid: true},
+			Description: arg.Reason,
+		}, entries, accounts)
+		if err != nil {
+			return err
+		}
+
+		result.JournalTransaction = journal
+		result.FromEntry = journalEntries[0]
+		result.ToEntry = journalEntries[1]
+		result.FromAccount = accounts[original.ToAccountID]
+		result.ToAccount = accounts[original.FromAccountID]
+
+		result.OriginalTransfer, err = q.UpdateTransferStatus(ctx, UpdateTransferStatusParams{
+			ID:     original.ID,
+			Status: TransferStatusReversed,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionTransferReverse,
+			TargetType: AuditTargetTransfer,
+			TargetID:   formatAuditID(original.ID),
+			Reason:     arg.Reason,
+			Before: auditTransfer{
+				Transfer:    &original,
+				FromBalance: fromAccount.Balance,
+				ToBalance:   toAccount.Balance,
+			},
+			After: auditTransfer{
+				Transfer:    &result.ReversalTransfer,
+				FromBalance: result.FromAccount.Balance,
+				ToBalance:   result.ToAccount.Balance,
+			},
+		})
+		return err
+	})
+
+	return result, err
+}
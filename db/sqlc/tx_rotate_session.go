@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type RotateSessionTxParams struct {
+	// SessionID, Username and RefreshToken identify the refresh token being renewed
+	SessionID    uuid.UUID
+	Username     string
+	RefreshToken string
+	// NewSession is the session of the refresh token issued in its place,
+	// its family and parent are filled in from the rotated session
+	NewSession CreateSessionParams
+}
+
+type RotateSessionTxResult struct {
+	Session Session
+	// ReuseDetected is true when the refresh token had already been rotated
+	ReuseDetected bool
+	// BlockedSessions is the number of sessions of the family revoked on reuse
+	BlockedSessions int64
+}
+
+// RotateSessionTx replaces a session with a new one of the same family.
+// Presenting a refresh token that was already rotated means it has leaked,
+// so every session of its family is blocked and ErrRefreshTokenReused is returned.
+func (store *SQLStore) RotateSessionTx(ctx context.Context, arg RotateSessionTxParams) (RotateSessionTxResult, error) {
+	var result RotateSessionTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		session, err := q.GetSessionForUpdate(ctx, arg.SessionID)
+		if err != nil {
+			return err
+		}
+
+		if session.IsBlocked {
+			return ErrSessionBlocked
+		}
+
+		if session.Username != arg.Username || session.RefreshToken != arg.RefreshToken {
+			return ErrSessionMismatch
+		}
+
+		if session.RotatedAt.Valid {
+			// the family must stay blocked, so the transaction is committed
+			result.ReuseDetected = true
+			result.BlockedSessions, err = q.BlockSessionFamily(ctx, session.FamilyID)
+			return err
+		}
+
+		if time.Now().After(session.ExpiresAt) {
+			return ErrSessionExpired
+		}
+
+		_, err = q.MarkSessionRotated(ctx, session.ID)
+		if err != nil {
+			return err
+		}
+
+		newSession := arg.NewSession
+		newSession.FamilyID = session.FamilyID
+		newSession.ParentID = pgtype.UUID{Bytes: session.ID, Valid: true}
+
+		result.Session, err = q.CreateSession(ctx, newSession)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionSessionRefresh,
+			TargetType: AuditTargetSession,
+			TargetID:   result.Session.ID.String(),
+			Before:     newAuditSession(session),
+			After:      newAuditSession(result.Session),
+		})
+		return err
+	})
+	if err == nil && result.ReuseDetected {
+		err = ErrRefreshTokenReused
+	}
+
+	return result, err
+}
@@ -0,0 +1,84 @@
+package db
+
+import "context"
+
+type ConfirmTOTPTxParams struct {
+	Username string
+	// Step is the time step of the code that confirmed the enrollment
+	Step int64
+	// RecoveryCodeHashes replace every recovery code of the user
+	RecoveryCodeHashes []string
+}
+
+type ConfirmTOTPTxResult struct {
+	UserTOTP UserTotp
+}
+
+// ConfirmTOTPTx enables the pending TOTP enrollment of a user
+// and stores a new set of recovery codes within a single database transaction.
+// ErrRecordNotFound is returned when there is no pending enrollment.
+func (store *SQLStore) ConfirmTOTPTx(ctx context.Context, arg ConfirmTOTPTxParams) (ConfirmTOTPTxResult, error) {
+	var result ConfirmTOTPTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.UserTOTP, err = q.ConfirmUserTOTP(ctx, ConfirmUserTOTPParams{
+			Username:     arg.Username,
+			LastUsedStep: arg.Step,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecoveryCodes(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		for _, codeHash := range arg.RecoveryCodeHashes {
+			_, err = q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username: arg.Username,
+				CodeHash: codeHash,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionUserTOTPEnable,
+			TargetType: AuditTargetUser,
+			TargetID:   arg.Username,
+			Before:     auditTOTP{Enabled: false},
+			After:      auditTOTP{Enabled: true},
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// DisableTOTPTx removes the TOTP secret and the recovery codes of a user
+func (store *SQLStore) DisableTOTPTx(ctx context.Context, username string) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		err := q.DeleteRecoveryCodes(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteUserTOTP(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     AuditActionUserTOTPDisable,
+			TargetType: AuditTargetUser,
+			TargetID:   username,
+			Before:     auditTOTP{Enabled: true},
+			After:      auditTOTP{Enabled: false},
+		})
+		return err
+	})
+}
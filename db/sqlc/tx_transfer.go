@@ -1,12 +1,40 @@
 package db
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
 
 // TransferTxParams contains the input parameters of the transfer transaction
 type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	// Idempotency makes the transfer safe to retry when set.
+	// It is not part of the request fingerprint.
+	Idempotency *IdempotencyParams `json:"-"`
+	// EnforceDailyLimit rejects the transfer with ErrDailyTransferLimitExceeded
+	// when it would push the source account over its daily transfer limit.
+	EnforceDailyLimit bool `json:"-"`
+	// Conversion credits the to account in its own currency when it differs from the from account's.
+	// It is not part of the request fingerprint, so a retry with a changed rate replays the original transfer.
+	Conversion *CurrencyConversion `json:"-"`
+}
+
+// auditTransfer is the audited state of a transfer and the balances of its accounts
+type auditTransfer struct {
+	Transfer    *Transfer `json:"transfer,omitempty"`
+	FromBalance int64     `json:"from_balance"`
+	ToBalance   int64     `json:"to_balance"`
+}
+
+// CurrencyConversion contains the converted amount of a cross-currency transfer
+type CurrencyConversion struct {
+	ToAmount     int64  `json:"to_amount"`
+	ExchangeRate string `json:"exchange_rate"`
 }
 
 // TransferTxResult is the result of the transfer transaction
@@ -16,72 +44,187 @@ type TransferTxResult struct {
 	ToAccount   Account  `json:"to_account"`
 	FromEntry   Entry    `json:"from_entry"`
 	ToEntry     Entry    `json:"to_entry"`
+	// JournalTransaction groups the entries of the transfer,
+	// which include the exchange desk's when the currencies differ
+	JournalTransaction JournalTransaction `json:"journal_transaction"`
+	// Replayed is true when the result was loaded from a previous
+	// transfer with the same idempotency key instead of being executed.
+	Replayed bool `json:"-"`
 }
 
 // TransferTx performs a money transfer from one account to the other.
-// It creates the transfer, add account entries, and update accounts' balance within a database transaction
+// It creates the transfer, posts its journal transaction, and update accounts' balance within a database transaction.
+// Both accounts are locked before the balance and limit checks, so concurrent transfers cannot overdraw
+// the source account or exceed its daily limit together.
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
-
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
-		if err != nil {
-			return err
+		if arg.Idempotency != nil {
+			result.Replayed, err = claimIdempotencyKey(ctx, q, *arg.Idempotency, arg, &result)
+			if err != nil || result.Replayed {
+				return err
+			}
 		}
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
+		result, err = transferMoney(ctx, q, arg)
 		if err != nil {
 			return err
 		}
 
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+		if arg.Idempotency != nil {
+			return saveIdempotentResponse(ctx, q, *arg.Idempotency, result)
 		}
 
-		return err
+		return nil
 	})
 
 	return result, err
 }
 
-func addMoney(
-	ctx context.Context,
-	q *Queries,
-	accountID1 int64,
-	amount1 int64,
-	accountID2 int64,
-	amount2 int64,
-) (account1 Account, account2 Account, err error) {
-	account1, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID1,
-		Amount: amount1,
+// transferMoney posts the journal transaction of a transfer and records it as an audit event within the transaction of q,
+// after checking that both accounts are active and the balance and limits of the locked source account
+func transferMoney(ctx context.Context, q *Queries, arg TransferTxParams) (result TransferTxResult, err error) {
+	toAmount := arg.Amount
+	exchangeRate := "1"
+	if arg.Conversion != nil {
+		toAmount = arg.Conversion.ToAmount
+		exchangeRate = arg.Conversion.ExchangeRate
+	}
+
+	entries, err := transferEntries(ctx, q, arg.FromAccountID, arg.ToAccountID, arg.Amount, toAmount)
+	if err != nil {
+		return result, err
+	}
+
+	accounts, err := lockJournalAccounts(ctx, q, entries)
+	if err != nil {
+		return result, err
+	}
+	fromAccount := accounts[arg.FromAccountID]
+	toAccount := accounts[arg.ToAccountID]
+
+	for _, account := range []Account{fromAccount, toAccount} {
+		if err = checkAccountStatus(account); err != nil {
+			return result, err
+		}
+	}
+
+	limit, err := getTransferLimit(ctx, q, arg.FromAccountID)
+	if err != nil {
+		return result, err
+	}
+
+	if fromAccount.Balance-arg.Amount < -limit.OverdraftLimit {
+		return result, ErrInsufficientFunds
+	}
+
+	if arg.EnforceDailyLimit {
+		err = checkDailyTransferLimit(ctx, q, limit, arg.Amount)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	var rate pgtype.Numeric
+	if err = rate.Scan(exchangeRate); err != nil {
+		return result, err
+	}
+
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		ToAmount:      toAmount,
+		ExchangeRate:  rate,
 	})
 	if err != nil {
-		return
+		return result, err
+	}
+
+	journal, journalEntries, err := postJournal(ctx, q, CreateJournalTransactionParams{
+		Kind:       JournalKindTransfer,
+		TransferID: pgtype.Int8{Int64: result.Transfer.ID, Valid: true},
+	}, entries, accounts)
+	if err != nil {
+		return result, err
+	}
+
+	result.JournalTransaction = journal
+	result.FromEntry = journalEntries[0]
+	result.ToEntry = journalEntries[1]
+	result.FromAccount = accounts[arg.FromAccountID]
+	result.ToAccount = accounts[arg.ToAccountID]
+
+	_, err = recordAuditEvent(ctx, q, AuditRecord{
+		Action:     AuditActionTransferCreate,
+		TargetType: AuditTargetTransfer,
+		TargetID:   formatAuditID(result.Transfer.ID),
+		Before:     auditTransfer{FromBalance: fromAccount.Balance, ToBalance: toAccount.Balance},
+		After: auditTransfer{
+			Transfer:    &result.Transfer,
+			FromBalance: result.FromAccount.Balance,
+			ToBalance:   result.ToAccount.Balance,
+		},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// checkAccountStatus returns ErrAccountClosed or ErrAccountFrozen
+// if money cannot be sent from or to the account
+func checkAccountStatus(account Account) error {
+	switch account.Status {
+	case AccountStatusClosed:
+		return ErrAccountClosed
+	case AccountStatusFrozen:
+		return ErrAccountFrozen
+	}
+	return nil
+}
+
+// getTransferLimit returns the limits of the account,
+// or an empty limit that restricts nothing if none have been set
+func getTransferLimit(ctx context.Context, q *Queries, accountID int64) (AccountLimit, error) {
+	limit, err := q.GetAccountLimit(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return AccountLimit{AccountID: accountID}, nil
+		}
+		return limit, err
+	}
+	return limit, nil
+}
+
+// checkDailyTransferLimit returns ErrDailyTransferLimitExceeded if sending amount
+// from the account would exceed its limit for the current UTC day.
+// The account must be locked by the caller.
+func checkDailyTransferLimit(ctx context.Context, q *Queries, limit AccountLimit, amount int64) error {
+	if limit.DailyTransferLimit <= 0 {
+		return nil
 	}
 
-	account2, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID2,
-		Amount: amount2,
+	now := time.Now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	dayEnd := dayStart.Add(24 * time.Hour)
+
+	total, err := q.GetDailyTransferTotal(ctx, GetDailyTransferTotalParams{
+		FromAccountID: limit.AccountID,
+		CreatedAt:     dayStart,
+		CreatedAt_2:   dayEnd,
 	})
-	return
+	if err != nil {
+		return err
+	}
+
+	if total+amount > limit.DailyTransferLimit {
+		return ErrDailyTransferLimitExceeded
+	}
+
+	return nil
 }
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Statuses of an account
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+	AccountStatusClosed = "closed"
+)
+
+// accountStatusTransitions lists the statuses an account can change to from each status.
+// A closed account never changes again.
+var accountStatusTransitions = map[string][]string{
+	AccountStatusActive: {AccountStatusFrozen, AccountStatusClosed},
+	AccountStatusFrozen: {AccountStatusActive},
+}
+
+// UpdateAccountStatusTxParams contains the input parameters of the update account status transaction
+type UpdateAccountStatusTxParams struct {
+	AccountID int64
+	Status    string
+	// Action is the audited action, such as AuditActionAccountFreeze
+	Action string
+	Reason string
+}
+
+// UpdateAccountStatusTxResult is the result of the update account status transaction
+type UpdateAccountStatusTxResult struct {
+	Account    Account
+	AuditEvent AuditEvent
+}
+
+// accountStatusState is the audited state of an account status change
+type accountStatusState struct {
+	Status string `json:"status"`
+}
+
+// UpdateAccountStatusTx sets the status of the locked account and records the change
+// as an audit event of the actor carried by ctx within the same database transaction.
+// It fails with ErrSystemAccount for the accounts of the bank itself,
+// with ErrAccountStatusUnchanged if the account already has the status,
+// with ErrInvalidAccountStatusTransition if the account cannot change to the status,
+// and with ErrAccountBalanceNotZero when closing an account that still holds or owes money.
+func (store *SQLStore) UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (UpdateAccountStatusTxResult, error) {
+	var result UpdateAccountStatusTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if IsSystemAccount(account) {
+			return ErrSystemAccount
+		}
+		if account.Status == arg.Status {
+			return ErrAccountStatusUnchanged
+		}
+		if !slices.Contains(accountStatusTransitions[account.Status], arg.Status) {
+			return fmt.Errorf("%w: from %s to %s", ErrInvalidAccountStatusTransition, account.Status, arg.Status)
+		}
+		if arg.Status == AccountStatusClosed && account.Balance != 0 {
+			return ErrAccountBalanceNotZero
+		}
+
+		result.Account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			ID:     arg.AccountID,
+			Status: arg.Status,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.AuditEvent, err = recordAuditEvent(ctx, q, AuditRecord{
+			Action:     arg.Action,
+			TargetType: AuditTargetAccount,
+			TargetID:   formatAuditID(arg.AccountID),
+			Reason:     arg.Reason,
+			Before:     accountStatusState{Status: account.Status},
+			After:      accountStatusState{Status: result.Account.Status},
+		})
+		return err
+	})
+
+	return result, err
+}
@@ -30,6 +30,11 @@ func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParam
 			return err
 		}
 
+		user, err := q.GetUserForUpdate(ctx, result.VerifyEmail.Username)
+		if err != nil {
+			return err
+		}
+
 		result.User, err = q.UpdateUser(ctx, UpdateUserParams{
 			Username: result.VerifyEmail.Username,
 			IsEmailVerified: pgtype.Bool{
@@ -37,6 +42,19 @@ func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParam
 				Valid: true,
 			},
 		})
+		if err != nil {
+			return err
+		}
+
+		// the secret code identifies the user, who is not logged in
+		_, err = recordAuditEvent(ctx, q, AuditRecord{
+			Actor:      AuditActor{Username: user.Username, Role: user.Role},
+			Action:     AuditActionUserEmailVerify,
+			TargetType: AuditTargetUser,
+			TargetID:   user.Username,
+			Before:     newAuditUser(user),
+			After:      newAuditUser(result.User),
+		})
 		return err
 	})
 
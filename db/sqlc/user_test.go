@@ -57,7 +57,7 @@ func TestUpdateUserOnlyFullName(t *testing.T) {
 	oldUser := createRandomUser(t)
 
 	newFullName := util.RandomOwner()
-	updatedUser, err := testStore.UpdateUser(context.Background(), UpdateUserParams{
+	updatedUser, err := testStore.UpdateUserTx(context.Background(), UpdateUserParams{
 		Username: oldUser.Username,
 		FullName: pgtype.Text{
 			String: newFullName,
@@ -76,7 +76,7 @@ func TestUpdateUserOnlyEmail(t *testing.T) {
 	oldUser := createRandomUser(t)
 
 	newEmail := util.RandomEmail()
-	updatedUser, err := testStore.UpdateUser(context.Background(), UpdateUserParams{
+	updatedUser, err := testStore.UpdateUserTx(context.Background(), UpdateUserParams{
 		Username: oldUser.Username,
 		Email: pgtype.Text{
 			String: newEmail,
@@ -98,7 +98,7 @@ func TestUpdateUserOnlyPassword(t *testing.T) {
 	newHashedPassword, err := util.HashPassword(newPassword)
 	require.NoError(t, err)
 
-	updatedUser, err := testStore.UpdateUser(context.Background(), UpdateUserParams{
+	updatedUser, err := testStore.UpdateUserTx(context.Background(), UpdateUserParams{
 		Username: oldUser.Username,
 		HashedPassword: pgtype.Text{
 			String: newHashedPassword,
@@ -122,7 +122,7 @@ func TestUpdateUserAllFields(t *testing.T) {
 	newHashedPassword, err := util.HashPassword(newPassword)
 	require.NoError(t, err)
 
-	updatedUser, err := testStore.UpdateUser(context.Background(), UpdateUserParams{
+	updatedUser, err := testStore.UpdateUserTx(context.Background(), UpdateUserParams{
 		Username: oldUser.Username,
 		FullName: pgtype.Text{
 			String: newFullName,
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomVerifyEmail(t *testing.T, user User) VerifyEmail {
+	verifyEmail, err := testStore.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, verifyEmail.Username)
+	require.False(t, verifyEmail.IsUsed)
+
+	return verifyEmail
+}
+
+func TestGetLastVerifyEmail(t *testing.T) {
+	user := createRandomUser(t)
+
+	_, err := testStore.GetLastVerifyEmail(context.Background(), user.Username)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	createRandomVerifyEmail(t, user)
+	lastVerifyEmail := createRandomVerifyEmail(t, user)
+
+	got, err := testStore.GetLastVerifyEmail(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, lastVerifyEmail.ID, got.ID)
+}
+
+func TestInvalidateVerifyEmails(t *testing.T) {
+	user := createRandomUser(t)
+	verifyEmail := createRandomVerifyEmail(t, user)
+
+	err := testStore.InvalidateVerifyEmails(context.Background(), user.Username)
+	require.NoError(t, err)
+
+	got, err := testStore.GetLastVerifyEmail(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), got.ExpiredAt, time.Second)
+
+	// the code of an invalidated email can no longer be used
+	_, err = testStore.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailId:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -2,10 +2,13 @@ package gapi
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
 	"github.com/Ian-Balijawa/simplebank/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (server *Server) getOwnedAccount(ctx context.Context, accountID int64, payload *token.Payload) (db.Account, error) {
@@ -20,3 +23,19 @@ func (server *Server) getOwnedAccount(ctx context.Context, accountID int64, payl
 
 	return account, nil
 }
+
+// accountStatusError converts an error of UpdateAccountStatusTx to a gRPC status error
+func accountStatusError(err error) error {
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return status.Errorf(codes.NotFound, "account not found")
+	}
+	if errors.Is(err, db.ErrSystemAccount) {
+		return status.Errorf(codes.PermissionDenied, "%s", err)
+	}
+	if errors.Is(err, db.ErrAccountStatusUnchanged) ||
+		errors.Is(err, db.ErrInvalidAccountStatusTransition) ||
+		errors.Is(err, db.ErrAccountBalanceNotZero) {
+		return status.Errorf(codes.FailedPrecondition, "%s", err)
+	}
+	return status.Errorf(codes.Internal, "failed to update account status: %s", err)
+}
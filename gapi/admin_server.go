@@ -0,0 +1,50 @@
+package gapi
+
+import (
+	"context"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/util"
+)
+
+// AdminServer serves gRPC requests for the back office of our banking service.
+// It shares the dependencies of Server, only accepts bankers
+// and records every action as an audit event.
+type AdminServer struct {
+	pb.UnimplementedSimpleBankAdminServer
+	*Server
+}
+
+// NewAdminServer creates a new gRPC back office server on top of server.
+func NewAdminServer(server *Server) *AdminServer {
+	return &AdminServer{Server: server}
+}
+
+// authorizeBanker authorizes the caller as a banker and returns the caller as an audit actor
+func (server *AdminServer) authorizeBanker(ctx context.Context) (db.AuditActor, error) {
+	authPayload, err := server.authorizeUser(ctx, []string{util.BankerRole})
+	if err != nil {
+		return db.AuditActor{}, err
+	}
+
+	mtdt := server.extractMetadata(ctx)
+	return db.AuditActor{
+		Username:  authPayload.Username,
+		Role:      authPayload.Role,
+		ClientIP:  mtdt.ClientIP,
+		UserAgent: mtdt.UserAgent,
+	}, nil
+}
+
+// recordAuditEvent records an action that changes nothing, such as viewing an account.
+// Actions that change data record their event within their own transaction instead.
+func (server *AdminServer) recordAuditEvent(ctx context.Context, record db.AuditRecord) error {
+	arg, err := record.Params()
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.CreateAuditEvent(ctx, arg)
+	return err
+}
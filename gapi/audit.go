@@ -0,0 +1,20 @@
+package gapi
+
+import (
+	"context"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+)
+
+// withAuditActor returns a copy of ctx carrying the user and the client of the request
+// as the actor of the audit events the store records.
+// An empty username leaves the actor to the store, for requests made before logging in.
+func (server *Server) withAuditActor(ctx context.Context, username string, role string) context.Context {
+	mtdt := server.extractMetadata(ctx)
+	return db.WithAuditActor(ctx, db.AuditActor{
+		Username:  username,
+		Role:      role,
+		ClientIP:  mtdt.ClientIP,
+		UserAgent: mtdt.UserAgent,
+	})
+}
@@ -42,6 +42,11 @@ func (server *Server) authorizeUser(ctx context.Context, accessibleRoles []strin
 		return nil, fmt.Errorf("invalid access token: %s", err)
 	}
 
+	err = server.tokenChecker.Check(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access token: %s", err)
+	}
+
 	if !hasPermission(payload.Role, accessibleRoles) {
 		return nil, fmt.Errorf("permission denied")
 	}
@@ -0,0 +1,66 @@
+package gapi
+
+import (
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/revocation"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorizeUserRevocation(t *testing.T) {
+	user, _ := randomUser(t, util.DepositorRole)
+
+	testCases := []struct {
+		name       string
+		status     db.GetSessionStatusRow
+		err        error
+		authorized bool
+	}{
+		{
+			name:       "OK",
+			status:     db.GetSessionStatusRow{Username: user.Username, Role: user.Role, ExpiresAt: time.Now().Add(time.Hour)},
+			authorized: true,
+		},
+		{
+			name:   "BlockedSession",
+			status: db.GetSessionStatusRow{Username: user.Username, Role: user.Role, IsBlocked: true, ExpiresAt: time.Now().Add(time.Hour)},
+		},
+		{
+			name:   "RoleChanged",
+			status: db.GetSessionStatusRow{Username: user.Username, Role: util.BankerRole, ExpiresAt: time.Now().Add(time.Hour)},
+		},
+		{
+			name: "SessionNotFound",
+			err:  db.ErrRecordNotFound,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+			store.EXPECT().GetSessionStatus(gomock.Any(), gomock.Any()).Times(1).Return(tc.status, tc.err)
+
+			server := newTestServer(t, store, nil)
+			server.tokenChecker = revocation.NewCache(store, time.Minute)
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, user.Role, time.Minute, token.TokenTypeAccessToken)
+			payload, err := server.authorizeUser(ctx, []string{util.BankerRole, util.DepositorRole})
+			if tc.authorized {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, payload.Username)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
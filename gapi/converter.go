@@ -1,8 +1,11 @@
 package gapi
 
 import (
+	"encoding/hex"
+
 	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
 	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/jackc/pgx/v5/pgtype"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -13,6 +16,7 @@ func convertUser(user db.User) *pb.User {
 		Email:             user.Email,
 		PasswordChangedAt: timestamppb.New(user.PasswordChangedAt),
 		CreatedAt:         timestamppb.New(user.CreatedAt),
+		Role:              user.Role,
 	}
 }
 
@@ -23,32 +27,73 @@ func convertAccount(account db.Account) *pb.Account {
 		Balance:   account.Balance,
 		Currency:  account.Currency,
 		CreatedAt: timestamppb.New(account.CreatedAt),
+		Status:    account.Status,
 	}
 }
 
 func convertEntry(entry db.Entry) *pb.Entry {
-	return &pb.Entry{
+	pbEntry := &pb.Entry{
 		Id:        entry.ID,
 		AccountId: entry.AccountID,
 		Amount:    entry.Amount,
 		CreatedAt: timestamppb.New(entry.CreatedAt),
 	}
+	if entry.JournalTransactionID.Valid {
+		pbEntry.JournalTransactionId = &entry.JournalTransactionID.Int64
+	}
+	return pbEntry
+}
+
+func convertJournal(journal db.Journal) *pb.JournalTransaction {
+	pbJournal := &pb.JournalTransaction{
+		Id:          journal.ID,
+		Kind:        journal.Kind,
+		Description: journal.Description,
+		CreatedAt:   timestamppb.New(journal.CreatedAt),
+		Entries:     make([]*pb.Entry, 0, len(journal.Entries)),
+	}
+	if journal.TransferID.Valid {
+		pbJournal.TransferId = &journal.TransferID.Int64
+	}
+	for _, entry := range journal.Entries {
+		pbJournal.Entries = append(pbJournal.Entries, convertEntry(entry))
+	}
+	return pbJournal
 }
 
 func convertTransfer(transfer db.Transfer) *pb.Transfer {
-	return &pb.Transfer{
+	pbTransfer := &pb.Transfer{
 		Id:            transfer.ID,
 		FromAccountId: transfer.FromAccountID,
 		ToAccountId:   transfer.ToAccountID,
 		Amount:        transfer.Amount,
+		ToAmount:      transfer.ToAmount,
+		ExchangeRate:  convertNumeric(transfer.ExchangeRate),
+		Status:        transfer.Status,
 		CreatedAt:     timestamppb.New(transfer.CreatedAt),
 	}
+	if transfer.ReversalOf.Valid {
+		pbTransfer.ReversalOf = &transfer.ReversalOf.Int64
+	}
+	if transfer.Reason.Valid {
+		pbTransfer.Reason = &transfer.Reason.String
+	}
+	return pbTransfer
+}
+
+func convertNumeric(n pgtype.Numeric) string {
+	value, err := n.Value()
+	if err != nil || value == nil {
+		return ""
+	}
+	return value.(string)
 }
 
 func convertAccountLimit(limit db.AccountLimit) *pb.AccountLimit {
 	return &pb.AccountLimit{
 		AccountId:          limit.AccountID,
 		DailyTransferLimit: limit.DailyTransferLimit,
+		OverdraftLimit:     limit.OverdraftLimit,
 		CreatedAt:          timestamppb.New(limit.CreatedAt),
 		UpdatedAt:          timestamppb.New(limit.UpdatedAt),
 	}
@@ -63,3 +108,95 @@ func convertAccountAlert(alert db.AccountAlert) *pb.AccountAlert {
 		UpdatedAt:            timestamppb.New(alert.UpdatedAt),
 	}
 }
+
+func convertScheduledTransfer(scheduledTransfer db.ScheduledTransfer) *pb.ScheduledTransfer {
+	pbScheduledTransfer := &pb.ScheduledTransfer{
+		Id:            scheduledTransfer.ID,
+		Owner:         scheduledTransfer.Owner,
+		FromAccountId: scheduledTransfer.FromAccountID,
+		ToAccountId:   scheduledTransfer.ToAccountID,
+		Amount:        scheduledTransfer.Amount,
+		Recurrence:    scheduledTransfer.Recurrence,
+		NextRunAt:     timestamppb.New(scheduledTransfer.NextRunAt),
+		Occurrences:   scheduledTransfer.Occurrences,
+		Status:        scheduledTransfer.Status,
+		CreatedAt:     timestamppb.New(scheduledTransfer.CreatedAt),
+		UpdatedAt:     timestamppb.New(scheduledTransfer.UpdatedAt),
+	}
+	if scheduledTransfer.CronExpression.Valid {
+		pbScheduledTransfer.CronExpression = &scheduledTransfer.CronExpression.String
+	}
+	if scheduledTransfer.IntervalSeconds.Valid {
+		pbScheduledTransfer.IntervalSeconds = &scheduledTransfer.IntervalSeconds.Int64
+	}
+	if scheduledTransfer.EndAt.Valid {
+		pbScheduledTransfer.EndAt = timestamppb.New(scheduledTransfer.EndAt.Time)
+	}
+	if scheduledTransfer.MaxOccurrences.Valid {
+		pbScheduledTransfer.MaxOccurrences = &scheduledTransfer.MaxOccurrences.Int32
+	}
+	return pbScheduledTransfer
+}
+
+func convertScheduledTransferRun(run db.ScheduledTransferRun) *pb.ScheduledTransferRun {
+	pbRun := &pb.ScheduledTransferRun{
+		Id:                  run.ID,
+		ScheduledTransferId: run.ScheduledTransferID,
+		Status:              run.Status,
+		ScheduledAt:         timestamppb.New(run.ScheduledAt),
+		CreatedAt:           timestamppb.New(run.CreatedAt),
+	}
+	if run.TransferID.Valid {
+		pbRun.TransferId = &run.TransferID.Int64
+	}
+	if run.Error.Valid {
+		pbRun.Error = &run.Error.String
+	}
+	return pbRun
+}
+
+func convertSession(session db.Session) *pb.Session {
+	return &pb.Session{
+		Id:        session.ID.String(),
+		Username:  session.Username,
+		UserAgent: session.UserAgent,
+		ClientIp:  session.ClientIp,
+		IsBlocked: session.IsBlocked,
+		ExpiresAt: timestamppb.New(session.ExpiresAt),
+		CreatedAt: timestamppb.New(session.CreatedAt),
+	}
+}
+
+func convertPendingTransfer(pendingTransfer db.PendingTransfer) *pb.PendingTransfer {
+	return &pb.PendingTransfer{
+		Id:                 pendingTransfer.ID,
+		FromAccountId:      pendingTransfer.FromAccountID,
+		ToAccountId:        pendingTransfer.ToAccountID,
+		Amount:             pendingTransfer.Amount,
+		Currency:           pendingTransfer.Currency,
+		ConvertCurrency:    pendingTransfer.ConvertCurrency,
+		VerificationMethod: pendingTransfer.VerificationMethod,
+		Status:             pendingTransfer.Status,
+		ExpiresAt:          timestamppb.New(pendingTransfer.ExpiresAt),
+		CreatedAt:          timestamppb.New(pendingTransfer.CreatedAt),
+	}
+}
+
+func convertAuditEvent(event db.AuditEvent) *pb.AuditEvent {
+	return &pb.AuditEvent{
+		Id:            event.ID,
+		ActorUsername: event.ActorUsername.String,
+		ActorRole:     event.ActorRole,
+		ClientIp:      event.ClientIp,
+		UserAgent:     event.UserAgent,
+		Action:        event.Action,
+		TargetType:    event.TargetType,
+		TargetId:      event.TargetID,
+		Reason:        event.Reason,
+		Before:        string(event.Before),
+		After:         string(event.After),
+		PrevHash:      hex.EncodeToString(event.PrevHash),
+		Hash:          hex.EncodeToString(event.Hash),
+		CreatedAt:     timestamppb.New(event.CreatedAt),
+	}
+}
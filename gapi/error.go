@@ -2,10 +2,12 @@ package gapi
 
 import (
 	"errors"
+	"time"
 
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 var errPermissionDenied = errors.New("permission denied")
@@ -32,3 +34,16 @@ func invalidArgumentError(violations []*errdetails.BadRequest_FieldViolation) er
 func unauthenticatedError(err error) error {
 	return status.Errorf(codes.Unauthenticated, "unauthorized: %s", err)
 }
+
+// resourceExhaustedStatus tells the client how long to wait before retrying
+func resourceExhaustedStatus(err error, retryAfter time.Duration) *status.Status {
+	retryInfo := &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}
+	statusExhausted := status.New(codes.ResourceExhausted, err.Error())
+
+	statusDetails, detailsErr := statusExhausted.WithDetails(retryInfo)
+	if detailsErr != nil {
+		return statusExhausted
+	}
+
+	return statusDetails
+}
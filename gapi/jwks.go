@@ -0,0 +1,38 @@
+package gapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Ian-Balijawa/simplebank/token"
+)
+
+// JWKSPath is where the gateway publishes the public keys of the access tokens
+const JWKSPath = "/.well-known/jwks.json"
+
+// JWKSHandler serves the public keys access tokens are verified with as a JSON Web Key Set,
+// so partner services can verify the tokens on their own
+func (server *Server) JWKSHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		res.Header().Set("Allow", "GET, HEAD")
+		http.Error(res, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	publisher, ok := server.tokenMaker.(token.KeySetPublisher)
+	if !ok {
+		http.NotFound(res, req)
+		return
+	}
+
+	body, err := json.Marshal(publisher.JWKS())
+	if err != nil {
+		http.Error(res, "cannot encode key set", http.StatusInternalServerError)
+		return
+	}
+
+	// verifiers may cache the keys, new keys are published before they sign any token
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Cache-Control", "public, max-age=300")
+	res.Write(body)
+}
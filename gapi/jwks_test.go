@@ -0,0 +1,88 @@
+package gapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKSHandler(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	keySet := token.NewJWTKeySet()
+	err = keySet.SetSigningKey("key-1", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	jwtMaker, err := token.NewAsymmetricJWTMaker(keySet)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		method        string
+		setupMaker    func(server *Server)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			method: http.MethodGet,
+			setupMaker: func(server *Server) {
+				server.tokenMaker = jwtMaker
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+				var jwks token.JSONWebKeySet
+				require.NoError(t, json.NewDecoder(recorder.Body).Decode(&jwks))
+				require.Len(t, jwks.Keys, 1)
+				require.Equal(t, "key-1", jwks.Keys[0].Kid)
+				require.Equal(t, "ES256", jwks.Keys[0].Alg)
+				require.Empty(t, jwks.Keys[0].N)
+			},
+		},
+		{
+			name:       "SymmetricMaker",
+			method:     http.MethodGet,
+			setupMaker: func(server *Server) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "MethodNotAllowed",
+			method: http.MethodPost,
+			setupMaker: func(server *Server) {
+				server.tokenMaker = jwtMaker
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil, nil)
+			tc.setupMaker(server)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(tc.method, JWKSPath, nil)
+
+			server.JWKSHandler(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
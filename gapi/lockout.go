@@ -0,0 +1,101 @@
+package gapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/lockout"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/worker"
+	"github.com/hibiken/asynq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// reserveLoginAttempt counts the login attempt as failed until it succeeds.
+// It returns a ResourceExhausted error telling when to retry
+// if the username or client IP must wait before trying to log in again.
+func (server *Server) reserveLoginAttempt(ctx context.Context, username string, clientIP string) (lockout.Reservation, error) {
+	reservation, err := server.loginGuard.Reserve(ctx, username, clientIP)
+	if err != nil {
+		return reservation, status.Errorf(codes.Internal, "failed to count login attempt: %s", err)
+	}
+
+	return reservation, server.checkReservation(ctx, username, clientIP, reservation)
+}
+
+// reserveOTPAttempt counts the one-time password sent with a login challenge as failed until it succeeds.
+// It returns a ResourceExhausted error if the username or client IP must wait before trying again,
+// or an Unauthenticated error if the challenge has no attempts left.
+func (server *Server) reserveOTPAttempt(ctx context.Context, challengePayload *token.Payload, clientIP string) (lockout.Reservation, error) {
+	username := challengePayload.Username
+	reservation, err := server.loginGuard.ReserveChallenge(ctx, username, challengePayload.ID.String(), clientIP)
+	if err != nil {
+		return reservation, status.Errorf(codes.Internal, "failed to count login attempt: %s", err)
+	}
+
+	if err := server.checkReservation(ctx, username, clientIP, reservation); err != nil {
+		return reservation, err
+	}
+
+	if reservation.ChallengeSpent {
+		server.notifyLoginLockout(ctx, username, clientIP, reservation)
+		return reservation, unauthenticatedError(lockout.ErrChallengeSpent)
+	}
+	return reservation, nil
+}
+
+// checkReservation returns a ResourceExhausted error telling when to retry if the reserved attempt must wait
+func (server *Server) checkReservation(ctx context.Context, username string, clientIP string, reservation lockout.Reservation) error {
+	if reservation.Wait <= 0 {
+		return nil
+	}
+
+	// the attempt is counted even though it is turned away, so it may be the one that locks out the user
+	server.notifyLoginLockout(ctx, username, clientIP, reservation)
+	return resourceExhaustedStatus(lockout.ErrTooManyAttempts, reservation.Wait).Err()
+}
+
+// reserveCodeAttempt counts a code sent by the user to change their two-factor authentication as incorrect until it is correct.
+// It returns a ResourceExhausted error telling when to retry if the user must wait before sending another.
+func (server *Server) reserveCodeAttempt(ctx context.Context, username string) error {
+	reservation, err := server.loginGuard.ReserveCode(ctx, username)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to count code attempt: %s", err)
+	}
+
+	if reservation.Wait > 0 {
+		return resourceExhaustedStatus(lockout.ErrTooManyAttempts, reservation.Wait).Err()
+	}
+	return nil
+}
+
+// rejectLogin notifies an existing user if the failed attempt locks them out,
+// and returns the same error whether the username exists or not
+func (server *Server) rejectLogin(ctx context.Context, username string, clientIP string, reservation lockout.Reservation) error {
+	server.notifyLoginLockout(ctx, username, clientIP, reservation)
+	return status.Errorf(codes.Unauthenticated, "incorrect username or password")
+}
+
+// notifyLoginLockout tells the user when their failed login attempt locked them out.
+// Nobody is told about a username that doesn't exist.
+func (server *Server) notifyLoginLockout(ctx context.Context, username string, clientIP string, reservation lockout.Reservation) {
+	if !reservation.LockedOut || server.taskDistributor == nil {
+		return
+	}
+
+	if _, err := server.store.GetUser(ctx, username); err != nil {
+		return
+	}
+
+	_ = server.taskDistributor.DistributeTaskSendLoginLockout(
+		ctx,
+		&worker.PayloadSendLoginLockout{
+			Username:    username,
+			ClientIP:    clientIP,
+			LockedUntil: time.Now().Add(lockout.LockoutDuration),
+		},
+		asynq.MaxRetry(10),
+		asynq.Queue(worker.QueueCritical),
+	)
+}
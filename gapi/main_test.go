@@ -10,6 +10,8 @@ import (
 	"github.com/Ian-Balijawa/simplebank/token"
 	"github.com/Ian-Balijawa/simplebank/util"
 	"github.com/Ian-Balijawa/simplebank/worker"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/metadata"
 )
@@ -23,11 +25,21 @@ func newTestServer(t *testing.T, store db.Store, taskDistributor worker.TaskDist
 	server, err := NewServer(config, store, taskDistributor)
 	require.NoError(t, err)
 
+	server.tokenChecker = allowAllTokens{}
+
 	return server
 }
 
+// allowAllTokens skips the revocation check of access tokens,
+// which is covered by the tests of authorizeUser
+type allowAllTokens struct{}
+
+func (allowAllTokens) Check(ctx context.Context, payload *token.Payload) error {
+	return nil
+}
+
 func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, username string, role string, duration time.Duration, tokenType token.TokenType) context.Context {
-	accessToken, _, err := tokenMaker.CreateToken(username, role, duration, tokenType)
+	accessToken, _, err := tokenMaker.CreateToken(username, role, uuid.New(), duration, tokenType)
 	require.NoError(t, err)
 
 	bearerToken := fmt.Sprintf("%s %s", authorizationBearer, accessToken)
@@ -39,3 +51,27 @@ func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, username st
 
 	return metadata.NewIncomingContext(context.Background(), md)
 }
+
+type eqAuditActorMatcher struct {
+	username string
+	role     string
+}
+
+func (expected eqAuditActorMatcher) Matches(x interface{}) bool {
+	ctx, ok := x.(context.Context)
+	if !ok {
+		return false
+	}
+
+	actor, ok := db.AuditActorFromContext(ctx)
+	return ok && actor.Username == expected.username && actor.Role == expected.role
+}
+
+func (expected eqAuditActorMatcher) String() string {
+	return fmt.Sprintf("context with audit actor %s (%s)", expected.username, expected.role)
+}
+
+// eqAuditActor matches a context carrying the given user as the actor of its audit events
+func eqAuditActor(username string, role string) gomock.Matcher {
+	return eqAuditActorMatcher{username, role}
+}
@@ -2,6 +2,9 @@ package gapi
 
 import (
 	"context"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
@@ -11,15 +14,27 @@ const (
 	grpcGatewayUserAgentHeader = "grpcgateway-user-agent"
 	userAgentHeader            = "user-agent"
 	xForwardedForHeader        = "x-forwarded-for"
+	idempotencyKeyHeader       = "idempotency-key"
 )
 
 type Metadata struct {
-	UserAgent string
-	ClientIP  string
+	UserAgent      string
+	ClientIP       string
+	IdempotencyKey string
+}
+
+// IncomingHeaderMatcher forwards the HTTP headers the gRPC handlers rely on
+// from the gateway into the incoming gRPC metadata.
+func IncomingHeaderMatcher(key string) (string, bool) {
+	if strings.ToLower(key) == idempotencyKeyHeader {
+		return idempotencyKeyHeader, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
 }
 
 func (server *Server) extractMetadata(ctx context.Context) *Metadata {
 	mtdt := &Metadata{}
+	var forwardedFor string
 
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		if userAgents := md.Get(grpcGatewayUserAgentHeader); len(userAgents) > 0 {
@@ -30,14 +45,30 @@ func (server *Server) extractMetadata(ctx context.Context) *Metadata {
 			mtdt.UserAgent = userAgents[0]
 		}
 
-		if clientIPs := md.Get(xForwardedForHeader); len(clientIPs) > 0 {
-			mtdt.ClientIP = clientIPs[0]
+		forwardedFor = strings.Join(md.Get(xForwardedForHeader), ",")
+
+		if keys := md.Get(idempotencyKeyHeader); len(keys) > 0 {
+			mtdt.IdempotencyKey = keys[0]
 		}
 	}
 
 	if p, ok := peer.FromContext(ctx); ok {
-		mtdt.ClientIP = p.Addr.String()
+		mtdt.ClientIP = server.trustedProxies.ClientIP(p.Addr.String(), forwardedFor)
+	} else {
+		// the gateway calls the server in process, with the address its HTTP request came from
+		// appended to the X-Forwarded-For header it was sent, like a proxy would
+		forwarded, remoteAddr := splitLastHop(forwardedFor)
+		mtdt.ClientIP = server.trustedProxies.ClientIP(remoteAddr, forwarded)
 	}
 
 	return mtdt
 }
+
+// splitLastHop splits the address of the last hop from an X-Forwarded-For header
+func splitLastHop(forwardedFor string) (string, string) {
+	i := strings.LastIndex(forwardedFor, ",")
+	if i < 0 {
+		return "", strings.TrimSpace(forwardedFor)
+	}
+	return forwardedFor[:i], strings.TrimSpace(forwardedFor[i+1:])
+}
@@ -0,0 +1,73 @@
+package gapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestExtractMetadataClientIP(t *testing.T) {
+	trustedProxies, err := util.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	withPeer := func(ctx context.Context, ip string) context.Context {
+		return peer.NewContext(ctx, &peer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 54321},
+		})
+	}
+	withForwardedFor := func(forwardedFor string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(xForwardedForHeader, forwardedFor))
+	}
+
+	testCases := []struct {
+		name           string
+		trustedProxies util.TrustedProxies
+		ctx            context.Context
+		clientIP       string
+	}{
+		{
+			name:     "Peer",
+			ctx:      withPeer(withForwardedFor("198.51.100.1"), "203.0.113.7"),
+			clientIP: "203.0.113.7",
+		},
+		{
+			name:           "PeerTrustedProxy",
+			trustedProxies: trustedProxies,
+			ctx:            withPeer(withForwardedFor("198.51.100.1"), "10.0.0.2"),
+			clientIP:       "198.51.100.1",
+		},
+		{
+			// the gateway appends the address of its HTTP request to the header it was sent
+			name:     "GatewaySpoofed",
+			ctx:      withForwardedFor("198.51.100.1, 203.0.113.7"),
+			clientIP: "203.0.113.7",
+		},
+		{
+			name:           "GatewayTrustedProxy",
+			trustedProxies: trustedProxies,
+			ctx:            withForwardedFor("192.0.2.66, 198.51.100.1, 10.0.0.2"),
+			clientIP:       "198.51.100.1",
+		},
+		{
+			name:     "GatewayWithoutHeader",
+			ctx:      withForwardedFor("203.0.113.7"),
+			clientIP: "203.0.113.7",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil, nil)
+			server.trustedProxies = tc.trustedProxies
+
+			require.Equal(t, tc.clientIP, server.extractMetadata(tc.ctx).ClientIP)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package gapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Ian-Balijawa/simplebank/ratelimit"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// rateLimitClasses are the RPCs with stricter limits than the default
+var rateLimitClasses = map[string]string{
+	"/pb.SimpleBank/LoginUser":              ratelimit.ClassLogin,
+	"/pb.SimpleBank/VerifyLoginOTP":         ratelimit.ClassLogin,
+	"/pb.SimpleBank/RequestPasswordReset":   ratelimit.ClassLogin,
+	"/pb.SimpleBank/ResetPassword":          ratelimit.ClassLogin,
+	"/pb.SimpleBank/ResendVerifyEmail":      ratelimit.ClassLogin,
+	"/pb.SimpleBank/CreateTransfer":         ratelimit.ClassTransfer,
+	"/pb.SimpleBank/ConfirmPendingTransfer": ratelimit.ClassTransfer,
+	"/pb.SimpleBank/ReverseTransfer":        ratelimit.ClassTransfer,
+}
+
+// gatewayRateLimitClass returns the class of a request to the gateway,
+// matching the HTTP paths of the RPCs in rateLimitClasses
+func gatewayRateLimitClass(path string) string {
+	switch path {
+	case "/v1/login_user", "/v1/login_user/otp", "/v1/password_reset/request", "/v1/password_reset", "/v1/verify_email/resend":
+		return ratelimit.ClassLogin
+	case "/v1/transfers":
+		return ratelimit.ClassTransfer
+	}
+
+	if strings.HasPrefix(path, "/v1/pending_transfers/") && strings.HasSuffix(path, "/confirm") ||
+		strings.HasPrefix(path, "/v1/transfers/") && strings.HasSuffix(path, "/reverse") {
+		return ratelimit.ClassTransfer
+	}
+
+	return ratelimit.ClassDefault
+}
+
+// RateLimiter is a gRPC interceptor limiting the requests of each user,
+// or of each client IP for the requests without a valid access token
+func (server *Server) RateLimiter(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp interface{}, err error) {
+	class, ok := rateLimitClasses[info.FullMethod]
+	if !ok {
+		class = ratelimit.ClassDefault
+	}
+
+	var authHeader string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(authorizationHeader); len(values) > 0 {
+			authHeader = values[0]
+		}
+	}
+
+	clientIP := server.extractMetadata(ctx).ClientIP
+	result, allowed := server.allowRequest(ctx, class, authHeader, clientIP)
+	if !allowed {
+		return nil, resourceExhaustedStatus(ratelimit.ErrRateLimited, result.RetryAfter).Err()
+	}
+
+	return handler(ctx, req)
+}
+
+// HttpRateLimiter limits the requests to the gateway like RateLimiter,
+// since the gateway calls the server without going through the gRPC interceptors
+func (server *Server) HttpRateLimiter(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		class := gatewayRateLimitClass(req.URL.Path)
+		clientIP := server.trustedProxies.ClientIP(req.RemoteAddr, req.Header.Get(xForwardedForHeader))
+		result, allowed := server.allowRequest(req.Context(), class, req.Header.Get(authorizationHeader), clientIP)
+		if !allowed {
+			body, err := protojson.Marshal(resourceExhaustedStatus(ratelimit.ErrRateLimited, result.RetryAfter).Proto())
+			if err != nil {
+				http.Error(res, ratelimit.ErrRateLimited.Error(), http.StatusTooManyRequests)
+				return
+			}
+
+			res.Header().Set("Content-Type", "application/json")
+			res.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(result.RetryAfter)))
+			res.WriteHeader(http.StatusTooManyRequests)
+			res.Write(body)
+			return
+		}
+
+		handler.ServeHTTP(res, req)
+	})
+}
+
+// allowRequest takes a token from the bucket of the user of the access token in authHeader,
+// or of the client IP if there is no valid one
+func (server *Server) allowRequest(ctx context.Context, class string, authHeader string, clientIP string) (ratelimit.Result, bool) {
+	var username string
+	fields := strings.Fields(authHeader)
+	if len(fields) == 2 && strings.ToLower(fields[0]) == authorizationBearer {
+		if payload, err := server.tokenMaker.VerifyToken(fields[1], token.TokenTypeAccessToken); err == nil {
+			username = payload.Username
+		}
+	}
+
+	key := ratelimit.Key(class, username, clientIP)
+	result, err := server.rateLimiter.Allow(ctx, key, server.rateLimitPolicy.Rule(class))
+	if err != nil {
+		// the requests are let through rather than failing them all while the backend is down
+		log.Error().Err(err).Str("key", key).Msg("failed to check rate limit")
+		return ratelimit.Result{Allowed: true}, true
+	}
+
+	return result, result.Allowed
+}
@@ -0,0 +1,106 @@
+package gapi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/ratelimit"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimiter(t *testing.T) {
+	server := newTestServer(t, nil, nil)
+	server.rateLimitPolicy = ratelimit.Policy{
+		ratelimit.ClassDefault: {Limit: 2, Period: time.Minute},
+		ratelimit.ClassLogin:   {Limit: 1, Period: time.Minute},
+	}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	call := func(ctx context.Context, method string) error {
+		ctx = peer.NewContext(ctx, &peer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 54321},
+		})
+		_, err := server.RateLimiter(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, call(context.Background(), "/pb.SimpleBank/ListAccounts"))
+	}
+
+	err := call(context.Background(), "/pb.SimpleBank/ListAccounts")
+	requireStatusCode(t, err, codes.ResourceExhausted)
+	st, _ := status.FromError(err)
+	require.Len(t, st.Details(), 1)
+	retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	require.InDelta(t, 30*time.Second, retryInfo.GetRetryDelay().AsDuration(), float64(time.Second))
+
+	// an authenticated user has a bucket of their own
+	ctx := newContextWithBearerToken(t, server.tokenMaker, util.RandomOwner(), util.DepositorRole, time.Minute, token.TokenTypeAccessToken)
+	require.NoError(t, call(ctx, "/pb.SimpleBank/ListAccounts"))
+
+	// the login RPCs have a stricter limit
+	require.NoError(t, call(context.Background(), "/pb.SimpleBank/LoginUser"))
+	requireStatusCode(t, call(context.Background(), "/pb.SimpleBank/VerifyLoginOTP"), codes.ResourceExhausted)
+}
+
+func TestHttpRateLimiter(t *testing.T) {
+	server := newTestServer(t, nil, nil)
+	server.rateLimitPolicy = ratelimit.Policy{
+		ratelimit.ClassDefault:  {Limit: 5, Period: time.Minute},
+		ratelimit.ClassTransfer: {Limit: 1, Period: time.Minute},
+	}
+
+	handler := server.HttpRateLimiter(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	}))
+	serve := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, path, nil)
+		request.RemoteAddr = "10.0.0.1:54321"
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	require.Equal(t, http.StatusOK, serve("/v1/transfers").Code)
+
+	// the transfer routes share the stricter limit
+	recorder := serve("/v1/pending_transfers/42/confirm")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "60", recorder.Header().Get("Retry-After"))
+	require.Contains(t, recorder.Body.String(), ratelimit.ErrRateLimited.Error())
+
+	// a client claiming another IP is still limited, as no proxy is trusted
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/v1/transfers", nil)
+	request.RemoteAddr = "10.0.0.1:54321"
+	request.Header.Set("X-Forwarded-For", "198.51.100.1")
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+	require.Equal(t, http.StatusOK, serve("/v1/accounts").Code)
+}
+
+func TestGatewayRateLimitClass(t *testing.T) {
+	require.Equal(t, ratelimit.ClassLogin, gatewayRateLimitClass("/v1/login_user"))
+	require.Equal(t, ratelimit.ClassLogin, gatewayRateLimitClass("/v1/password_reset/request"))
+	require.Equal(t, ratelimit.ClassTransfer, gatewayRateLimitClass("/v1/transfers"))
+	require.Equal(t, ratelimit.ClassTransfer, gatewayRateLimitClass("/v1/transfers/7/reverse"))
+	require.Equal(t, ratelimit.ClassTransfer, gatewayRateLimitClass("/v1/pending_transfers/7/confirm"))
+	require.Equal(t, ratelimit.ClassDefault, gatewayRateLimitClass("/v1/pending_transfers/7"))
+	require.Equal(t, ratelimit.ClassDefault, gatewayRateLimitClass("/v1/accounts"))
+}
@@ -49,6 +49,8 @@ func (server *Server) UpsertAccountAlert(ctx context.Context, req *pb.UpsertAcco
 		return nil, unauthenticatedError(err)
 	}
 
+	ctx = server.withAuditActor(ctx, authPayload.Username, authPayload.Role)
+
 	violations := validateUpsertAccountAlertRequest(req)
 	if violations != nil {
 		return nil, invalidArgumentError(violations)
@@ -64,7 +66,7 @@ func (server *Server) UpsertAccountAlert(ctx context.Context, req *pb.UpsertAcco
 		return nil, status.Errorf(codes.Internal, "failed to get account: %s", err)
 	}
 
-	alert, err := server.store.UpsertAccountAlert(ctx, db.UpsertAccountAlertParams{
+	alert, err := server.store.UpsertAccountAlertTx(ctx, db.UpsertAccountAlertParams{
 		AccountID:            req.GetAccountId(),
 		LowBalanceThreshold:  req.GetLowBalanceThreshold(),
 		HighBalanceThreshold: req.GetHighBalanceThreshold(),
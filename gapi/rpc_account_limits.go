@@ -7,6 +7,7 @@ import (
 	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
 	"github.com/Ian-Balijawa/simplebank/pb"
 	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/jackc/pgx/v5/pgtype"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -49,12 +50,20 @@ func (server *Server) UpsertAccountLimit(ctx context.Context, req *pb.UpsertAcco
 		return nil, unauthenticatedError(err)
 	}
 
+	ctx = server.withAuditActor(ctx, authPayload.Username, authPayload.Role)
+
 	violations := validateUpsertAccountLimitRequest(req)
 	if violations != nil {
 		return nil, invalidArgumentError(violations)
 	}
 
-	if _, err := server.getOwnedAccount(ctx, req.GetAccountId(), authPayload); err != nil {
+	// bankers manage the limits of any account, depositors only those of their own
+	if authPayload.Role == util.BankerRole {
+		_, err = server.store.GetAccount(ctx, req.GetAccountId())
+	} else {
+		_, err = server.getOwnedAccount(ctx, req.GetAccountId(), authPayload)
+	}
+	if err != nil {
 		if errors.Is(err, db.ErrRecordNotFound) {
 			return nil, status.Errorf(codes.NotFound, "account not found")
 		}
@@ -64,10 +73,24 @@ func (server *Server) UpsertAccountLimit(ctx context.Context, req *pb.UpsertAcco
 		return nil, status.Errorf(codes.Internal, "failed to get account: %s", err)
 	}
 
-	limit, err := server.store.UpsertAccountLimit(ctx, db.UpsertAccountLimitParams{
+	arg := db.UpsertAccountLimitParams{
 		AccountID:          req.GetAccountId(),
 		DailyTransferLimit: req.GetDailyTransferLimit(),
-	})
+	}
+
+	// an overdraft is credit extended by the bank, so only bankers may grant it
+	if req.OverdraftLimit != nil {
+		if authPayload.Role != util.BankerRole {
+			return nil, status.Errorf(codes.PermissionDenied, "only bankers can set the overdraft limit")
+		}
+
+		arg.OverdraftLimit = pgtype.Int8{
+			Int64: req.GetOverdraftLimit(),
+			Valid: true,
+		}
+	}
+
+	limit, err := server.store.UpsertAccountLimitTx(ctx, arg)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to upsert account limit: %s", err)
 	}
@@ -87,5 +110,8 @@ func validateUpsertAccountLimitRequest(req *pb.UpsertAccountLimitRequest) (viola
 	if req.GetDailyTransferLimit() < 0 {
 		violations = append(violations, fieldViolation("daily_transfer_limit", errors.New("must be greater than or equal to 0")))
 	}
+	if req.OverdraftLimit != nil && req.GetOverdraftLimit() < 0 {
+		violations = append(violations, fieldViolation("overdraft_limit", errors.New("must be greater than or equal to 0")))
+	}
 	return violations
 }
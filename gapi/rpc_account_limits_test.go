@@ -0,0 +1,146 @@
+package gapi
+
+import (
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestUpsertAccountLimitAPI(t *testing.T) {
+	banker, _ := randomUser(t, util.BankerRole)
+	depositor, _ := randomUser(t, util.DepositorRole)
+	account := randomAccount(depositor.Username, util.USD)
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		req           *pb.UpsertAccountLimitRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.UpsertAccountLimitResponse, err error)
+	}{
+		{
+			name: "OK",
+			user: depositor,
+			req: &pb.UpsertAccountLimitRequest{
+				AccountId:          account.ID,
+				DailyTransferLimit: 500,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.UpsertAccountLimitParams{
+					AccountID:          account.ID,
+					DailyTransferLimit: 500,
+				}
+				store.EXPECT().
+					UpsertAccountLimitTx(eqAuditActor(depositor.Username, util.DepositorRole), gomock.Eq(arg)).
+					Times(1).
+					Return(db.AccountLimit{AccountID: account.ID, DailyTransferLimit: 500}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpsertAccountLimitResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, int64(500), res.GetLimit().GetDailyTransferLimit())
+			},
+		},
+		{
+			name: "BankerSetsOverdraftOnDepositorAccount",
+			user: banker,
+			req: &pb.UpsertAccountLimitRequest{
+				AccountId:          account.ID,
+				DailyTransferLimit: 500,
+				OverdraftLimit:     proto.Int64(200),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.UpsertAccountLimitParams{
+					AccountID:          account.ID,
+					DailyTransferLimit: 500,
+					OverdraftLimit:     pgtype.Int8{Int64: 200, Valid: true},
+				}
+				store.EXPECT().
+					UpsertAccountLimitTx(eqAuditActor(banker.Username, util.BankerRole), gomock.Eq(arg)).
+					Times(1).
+					Return(db.AccountLimit{AccountID: account.ID, DailyTransferLimit: 500, OverdraftLimit: 200}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpsertAccountLimitResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, int64(200), res.GetLimit().GetOverdraftLimit())
+			},
+		},
+		{
+			name: "BankerAccountNotFound",
+			user: banker,
+			req: &pb.UpsertAccountLimitRequest{
+				AccountId:          account.ID,
+				DailyTransferLimit: 500,
+				OverdraftLimit:     proto.Int64(200),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().UpsertAccountLimitTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpsertAccountLimitResponse, err error) {
+				requireStatusCode(t, err, codes.NotFound)
+			},
+		},
+		{
+			name: "DepositorCannotSetOverdraft",
+			user: depositor,
+			req: &pb.UpsertAccountLimitRequest{
+				AccountId:          account.ID,
+				DailyTransferLimit: 500,
+				OverdraftLimit:     proto.Int64(200),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().UpsertAccountLimitTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpsertAccountLimitResponse, err error) {
+				requireStatusCode(t, err, codes.PermissionDenied)
+			},
+		},
+		{
+			name: "DepositorOtherAccount",
+			user: depositor,
+			req: &pb.UpsertAccountLimitRequest{
+				AccountId:          account.ID,
+				DailyTransferLimit: 500,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				other := account
+				other.Owner = util.RandomOwner()
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(other, nil)
+				store.EXPECT().UpsertAccountLimitTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpsertAccountLimitResponse, err error) {
+				requireStatusCode(t, err, codes.PermissionDenied)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := newTestServer(t, store, nil)
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, tc.user.Username, tc.user.Role, time.Minute, token.TokenTypeAccessToken)
+			res, err := server.UpsertAccountLimit(ctx, tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *AdminServer) GetCustomerAccount(ctx context.Context, req *pb.GetCustomerAccountRequest) (*pb.GetCustomerAccountResponse, error) {
+	actor, err := server.authorizeBanker(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if req.GetAccountId() <= 0 {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{
+			fieldViolation("account_id", errors.New("must be a positive integer")),
+		})
+	}
+
+	account, err := server.viewAccount(ctx, actor, db.AuditActionAccountView, req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := &pb.GetCustomerAccountResponse{
+		Account: convertAccount(account),
+	}
+	return rsp, nil
+}
+
+func (server *AdminServer) ListCustomerEntries(ctx context.Context, req *pb.ListEntriesRequest) (*pb.ListEntriesResponse, error) {
+	actor, err := server.authorizeBanker(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	violations := validateListEntriesRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	_, err = server.viewAccount(ctx, actor, db.AuditActionEntriesView, req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+
+	return server.listEntries(ctx, req)
+}
+
+func (server *AdminServer) ListCustomerTransfers(ctx context.Context, req *pb.ListTransfersRequest) (*pb.ListTransfersResponse, error) {
+	actor, err := server.authorizeBanker(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	violations := validateListTransfersRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	_, err = server.viewAccount(ctx, actor, db.AuditActionTransfersView, req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+
+	return server.listTransfers(ctx, req)
+}
+
+// viewAccount gets any account for the banker and records the view as an audit event.
+// It returns a gRPC status error.
+func (server *AdminServer) viewAccount(ctx context.Context, actor db.AuditActor, action string, accountID int64) (db.Account, error) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return account, status.Errorf(codes.NotFound, "account not found")
+		}
+		return account, status.Errorf(codes.Internal, "failed to get account: %s", err)
+	}
+
+	err = server.recordAuditEvent(ctx, db.AuditRecord{
+		Actor:      actor,
+		Action:     action,
+		TargetType: db.AuditTargetAccount,
+		TargetID:   strconv.FormatInt(accountID, 10),
+	})
+	if err != nil {
+		return account, status.Errorf(codes.Internal, "failed to record audit event: %s", err)
+	}
+
+	return account, nil
+}
+
+func (server *AdminServer) FreezeAccount(ctx context.Context, req *pb.UpdateAccountStatusRequest) (*pb.UpdateAccountStatusResponse, error) {
+	return server.updateAccountStatus(ctx, req, db.AccountStatusFrozen, db.AuditActionAccountFreeze)
+}
+
+func (server *AdminServer) UnfreezeAccount(ctx context.Context, req *pb.UpdateAccountStatusRequest) (*pb.UpdateAccountStatusResponse, error) {
+	return server.updateAccountStatus(ctx, req, db.AccountStatusActive, db.AuditActionAccountUnfreeze)
+}
+
+func (server *AdminServer) updateAccountStatus(ctx context.Context, req *pb.UpdateAccountStatusRequest, accountStatus string, action string) (*pb.UpdateAccountStatusResponse, error) {
+	actor, err := server.authorizeBanker(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	violations := validateUpdateAccountStatusRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	result, err := server.store.UpdateAccountStatusTx(db.WithAuditActor(ctx, actor), db.UpdateAccountStatusTxParams{
+		AccountID: req.GetAccountId(),
+		Status:    accountStatus,
+		Action:    action,
+		Reason:    req.GetReason(),
+	})
+	if err != nil {
+		return nil, accountStatusError(err)
+	}
+
+	rsp := &pb.UpdateAccountStatusResponse{
+		Account: convertAccount(result.Account),
+	}
+	return rsp, nil
+}
+
+func validateUpdateAccountStatusRequest(req *pb.UpdateAccountStatusRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetAccountId() <= 0 {
+		violations = append(violations, fieldViolation("account_id", errors.New("must be a positive integer")))
+	}
+	if err := val.ValidateString(req.GetReason(), 1, 200); err != nil {
+		violations = append(violations, fieldViolation("reason", err))
+	}
+	return violations
+}
@@ -0,0 +1,187 @@
+package gapi
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestGetCustomerAccountAPI(t *testing.T) {
+	banker, _ := randomUser(t, util.BankerRole)
+	depositor, _ := randomUser(t, util.DepositorRole)
+	account := randomAccount(depositor.Username, util.USD)
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.GetCustomerAccountResponse, err error)
+	}{
+		{
+			name: "OK",
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.CreateAuditEventParams{
+					ActorUsername: pgtype.Text{String: banker.Username, Valid: true},
+					ActorRole:     util.BankerRole,
+					Action:        db.AuditActionAccountView,
+					TargetType:    db.AuditTargetAccount,
+					TargetID:      strconv.FormatInt(account.ID, 10),
+				}
+				store.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.AuditEvent{}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.GetCustomerAccountResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account.ID, res.GetAccount().GetId())
+				require.Equal(t, depositor.Username, res.GetAccount().GetOwner())
+			},
+		},
+		{
+			name: "NotFound",
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.GetCustomerAccountResponse, err error) {
+				requireStatusCode(t, err, codes.NotFound)
+			},
+		},
+		{
+			name: "AuditFailure",
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).Times(1).Return(db.AuditEvent{}, context.DeadlineExceeded)
+			},
+			checkResponse: func(t *testing.T, res *pb.GetCustomerAccountResponse, err error) {
+				requireStatusCode(t, err, codes.Internal)
+				require.Nil(t, res)
+			},
+		},
+		{
+			name: "DepositorCannotView",
+			user: depositor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.GetCustomerAccountResponse, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := NewAdminServer(newTestServer(t, store, nil))
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, tc.user.Username, tc.user.Role, time.Minute, token.TokenTypeAccessToken)
+			res, err := server.GetCustomerAccount(ctx, &pb.GetCustomerAccountRequest{AccountId: account.ID})
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+func TestFreezeAccountAPI(t *testing.T) {
+	banker, _ := randomUser(t, util.BankerRole)
+	depositor, _ := randomUser(t, util.DepositorRole)
+	account := randomAccount(depositor.Username, util.USD)
+
+	req := &pb.UpdateAccountStatusRequest{
+		AccountId: account.ID,
+		Reason:    "suspected fraud",
+	}
+
+	testCases := []struct {
+		name          string
+		req           *pb.UpdateAccountStatusRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.UpdateAccountStatusResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  req,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateAccountStatusTxParams{
+					AccountID: account.ID,
+					Status:    db.AccountStatusFrozen,
+					Action:    db.AuditActionAccountFreeze,
+					Reason:    req.GetReason(),
+				}
+				frozen := account
+				frozen.Status = db.AccountStatusFrozen
+				store.EXPECT().UpdateAccountStatusTx(eqAuditActor(banker.Username, util.BankerRole), gomock.Eq(arg)).Times(1).Return(db.UpdateAccountStatusTxResult{Account: frozen}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateAccountStatusResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, db.AccountStatusFrozen, res.GetAccount().GetStatus())
+			},
+		},
+		{
+			name: "AlreadyFrozen",
+			req:  req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Any()).Times(1).Return(db.UpdateAccountStatusTxResult{}, db.ErrAccountStatusUnchanged)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateAccountStatusResponse, err error) {
+				requireStatusCode(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "SystemAccount",
+			req:  req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Any()).Times(1).Return(db.UpdateAccountStatusTxResult{}, db.ErrSystemAccount)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateAccountStatusResponse, err error) {
+				requireStatusCode(t, err, codes.PermissionDenied)
+			},
+		},
+		{
+			name: "MissingReason",
+			req:  &pb.UpdateAccountStatusRequest{AccountId: account.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateAccountStatusResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := NewAdminServer(newTestServer(t, store, nil))
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, banker.Username, banker.Role, time.Minute, token.TokenTypeAccessToken)
+			res, err := server.FreezeAccount(ctx, tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
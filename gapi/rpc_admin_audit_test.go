@@ -0,0 +1,158 @@
+package gapi
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestListAuditEventsAPI(t *testing.T) {
+	banker, _ := randomUser(t, util.BankerRole)
+	depositor, _ := randomUser(t, util.DepositorRole)
+
+	event := db.AuditEvent{
+		ID:            util.RandomInt(1, 1000),
+		ActorUsername: pgtype.Text{String: depositor.Username, Valid: true},
+		ActorRole:     util.DepositorRole,
+		ClientIp:      "127.0.0.1",
+		Action:        db.AuditActionAccountClose,
+		TargetType:    db.AuditTargetAccount,
+		TargetID:      "1",
+		Before:        []byte(`{"status":"active"}`),
+		After:         []byte(`{"status":"closed"}`),
+		PrevHash:      []byte{0x01, 0x02},
+		Hash:          []byte{0x03, 0x04},
+		CreatedAt:     time.Now(),
+	}
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		req           *pb.ListAuditEventsRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.ListAuditEventsResponse, err error)
+	}{
+		{
+			name: "OK",
+			user: banker,
+			req: &pb.ListAuditEventsRequest{
+				ActorUsername: depositor.Username,
+				TargetType:    db.AuditTargetAccount,
+				PageId:        2,
+				PageSize:      5,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditEvents(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ListAuditEventsParams) ([]db.AuditEvent, error) {
+						require.Equal(t, pgtype.Text{String: depositor.Username, Valid: true}, arg.ActorUsername)
+						require.Equal(t, pgtype.Text{String: db.AuditTargetAccount, Valid: true}, arg.TargetType)
+						require.False(t, arg.Action.Valid)
+						require.False(t, arg.TargetID.Valid)
+						require.Equal(t, int32(5), arg.PageLimit)
+						require.Equal(t, int32(5), arg.PageOffset)
+						return []db.AuditEvent{event}, nil
+					})
+				store.EXPECT().
+					CreateAuditEvent(gomock.Any(), gomock.Eq(db.CreateAuditEventParams{
+						ActorUsername: pgtype.Text{String: banker.Username, Valid: true},
+						ActorRole:     util.BankerRole,
+						Action:        db.AuditActionAuditLogView,
+						TargetType:    db.AuditTargetAuditLog,
+					})).
+					Times(1).
+					Return(db.AuditEvent{}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAuditEventsResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetEvents(), 1)
+
+				got := res.GetEvents()[0]
+				require.Equal(t, event.ID, got.GetId())
+				require.Equal(t, depositor.Username, got.GetActorUsername())
+				require.JSONEq(t, `{"status":"closed"}`, got.GetAfter())
+				require.Equal(t, hex.EncodeToString(event.Hash), got.GetHash())
+			},
+		},
+		{
+			name: "InvalidPageSize",
+			user: banker,
+			req:  &pb.ListAuditEventsRequest{PageId: 1, PageSize: 100},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAuditEventsResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "NotBanker",
+			user: depositor,
+			req:  &pb.ListAuditEventsRequest{PageId: 1, PageSize: 5},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAuditEventsResponse, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := NewAdminServer(newTestServer(t, store, nil))
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, tc.user.Username, tc.user.Role, time.Minute, token.TokenTypeAccessToken)
+			res, err := server.ListAuditEvents(ctx, tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+func TestVerifyAuditLogAPI(t *testing.T) {
+	banker, _ := randomUser(t, util.BankerRole)
+
+	storeCtrl := gomock.NewController(t)
+	defer storeCtrl.Finish()
+	store := mockdb.NewMockStore(storeCtrl)
+
+	store.EXPECT().
+		ListBrokenAuditEvents(gomock.Any(), gomock.Eq(int32(maxBrokenAuditEvents))).
+		Times(1).
+		Return([]int64{7, 8}, nil)
+	store.EXPECT().
+		CreateAuditEvent(gomock.Any(), gomock.Eq(db.CreateAuditEventParams{
+			ActorUsername: pgtype.Text{String: banker.Username, Valid: true},
+			ActorRole:     util.BankerRole,
+			Action:        db.AuditActionAuditLogVerify,
+			TargetType:    db.AuditTargetAuditLog,
+			After:         []byte(`{"broken_events":2}`),
+		})).
+		Times(1).
+		Return(db.AuditEvent{}, nil)
+
+	server := NewAdminServer(newTestServer(t, store, nil))
+
+	ctx := newContextWithBearerToken(t, server.tokenMaker, banker.Username, banker.Role, time.Minute, token.TokenTypeAccessToken)
+	res, err := server.VerifyAuditLog(ctx, &pb.VerifyAuditLogRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []int64{7, 8}, res.GetBrokenEventIds())
+}
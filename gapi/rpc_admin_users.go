@@ -0,0 +1,116 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// likePatternEscaper escapes the wildcards of a LIKE pattern, so they match literally
+var likePatternEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (server *AdminServer) SearchUsers(ctx context.Context, req *pb.SearchUsersRequest) (*pb.SearchUsersResponse, error) {
+	actor, err := server.authorizeBanker(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	violations := validateSearchUsersRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	users, err := server.store.SearchUsers(ctx, db.SearchUsersParams{
+		Pattern:    "%" + likePatternEscaper.Replace(req.GetQuery()) + "%",
+		PageLimit:  req.GetPageSize(),
+		PageOffset: (req.GetPageId() - 1) * req.GetPageSize(),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to search users: %s", err)
+	}
+
+	err = server.recordAuditEvent(ctx, db.AuditRecord{
+		Actor:      actor,
+		Action:     db.AuditActionUserSearch,
+		TargetType: db.AuditTargetUser,
+		TargetID:   req.GetQuery(),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to record audit event: %s", err)
+	}
+
+	rsp := &pb.SearchUsersResponse{}
+	for _, user := range users {
+		rsp.Users = append(rsp.Users, convertUser(user))
+	}
+	return rsp, nil
+}
+
+func validateSearchUsersRequest(req *pb.SearchUsersRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateString(req.GetQuery(), 1, 100); err != nil {
+		violations = append(violations, fieldViolation("query", err))
+	}
+	if req.GetPageId() <= 0 {
+		violations = append(violations, fieldViolation("page_id", errors.New("must be a positive integer")))
+	}
+	if req.GetPageSize() < 5 || req.GetPageSize() > 50 {
+		violations = append(violations, fieldViolation("page_size", errors.New("must be between 5 and 50")))
+	}
+	return violations
+}
+
+func (server *AdminServer) ChangeUserRole(ctx context.Context, req *pb.ChangeUserRoleRequest) (*pb.ChangeUserRoleResponse, error) {
+	actor, err := server.authorizeBanker(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	violations := validateChangeUserRoleRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	if req.GetUsername() == actor.Username {
+		return nil, status.Errorf(codes.FailedPrecondition, "cannot change your own role")
+	}
+
+	result, err := server.store.ChangeUserRoleTx(db.WithAuditActor(ctx, actor), db.ChangeUserRoleTxParams{
+		Username: req.GetUsername(),
+		Role:     req.GetRole(),
+		Reason:   req.GetReason(),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "user not found")
+		}
+		if errors.Is(err, db.ErrUserRoleUnchanged) {
+			return nil, status.Errorf(codes.FailedPrecondition, "%s", err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to change user role: %s", err)
+	}
+
+	rsp := &pb.ChangeUserRoleResponse{
+		User: convertUser(result.User),
+	}
+	return rsp, nil
+}
+
+func validateChangeUserRoleRequest(req *pb.ChangeUserRoleRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateUsername(req.GetUsername()); err != nil {
+		violations = append(violations, fieldViolation("username", err))
+	}
+	if err := val.ValidateRole(req.GetRole()); err != nil {
+		violations = append(violations, fieldViolation("role", err))
+	}
+	if err := val.ValidateString(req.GetReason(), 1, 200); err != nil {
+		violations = append(violations, fieldViolation("reason", err))
+	}
+	return violations
+}
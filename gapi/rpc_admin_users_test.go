@@ -0,0 +1,146 @@
+package gapi
+
+import (
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSearchUsersAPI(t *testing.T) {
+	banker, _ := randomUser(t, util.BankerRole)
+	depositor, _ := randomUser(t, util.DepositorRole)
+
+	storeCtrl := gomock.NewController(t)
+	defer storeCtrl.Finish()
+	store := mockdb.NewMockStore(storeCtrl)
+
+	arg := db.SearchUsersParams{
+		Pattern:    `%50\%\_off%`,
+		PageLimit:  5,
+		PageOffset: 5,
+	}
+	store.EXPECT().SearchUsers(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.User{depositor}, nil)
+	store.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Eq(db.CreateAuditEventParams{
+		ActorUsername: pgtype.Text{String: banker.Username, Valid: true},
+		ActorRole:     util.BankerRole,
+		Action:        db.AuditActionUserSearch,
+		TargetType:    db.AuditTargetUser,
+		TargetID:      "50%_off",
+	})).Times(1).Return(db.AuditEvent{}, nil)
+
+	server := NewAdminServer(newTestServer(t, store, nil))
+
+	ctx := newContextWithBearerToken(t, server.tokenMaker, banker.Username, banker.Role, time.Minute, token.TokenTypeAccessToken)
+	res, err := server.SearchUsers(ctx, &pb.SearchUsersRequest{Query: "50%_off", PageId: 2, PageSize: 5})
+	require.NoError(t, err)
+	require.Len(t, res.GetUsers(), 1)
+	require.Equal(t, depositor.Username, res.GetUsers()[0].GetUsername())
+	require.Equal(t, util.DepositorRole, res.GetUsers()[0].GetRole())
+
+	ctx = newContextWithBearerToken(t, server.tokenMaker, depositor.Username, depositor.Role, time.Minute, token.TokenTypeAccessToken)
+	_, err = server.SearchUsers(ctx, &pb.SearchUsersRequest{Query: "a", PageId: 1, PageSize: 5})
+	requireStatusCode(t, err, codes.Unauthenticated)
+}
+
+func TestChangeUserRoleAPI(t *testing.T) {
+	banker, _ := randomUser(t, util.BankerRole)
+	depositor, _ := randomUser(t, util.DepositorRole)
+
+	testCases := []struct {
+		name          string
+		req           *pb.ChangeUserRoleRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.ChangeUserRoleResponse, err error)
+	}{
+		{
+			name: "OK",
+			req: &pb.ChangeUserRoleRequest{
+				Username: depositor.Username,
+				Role:     util.BankerRole,
+				Reason:   "joined the branch",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ChangeUserRoleTxParams{
+					Username: depositor.Username,
+					Role:     util.BankerRole,
+					Reason:   "joined the branch",
+				}
+				promoted := depositor
+				promoted.Role = util.BankerRole
+				store.EXPECT().ChangeUserRoleTx(eqAuditActor(banker.Username, util.BankerRole), gomock.Eq(arg)).Times(1).Return(db.ChangeUserRoleTxResult{User: promoted}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.ChangeUserRoleResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, util.BankerRole, res.GetUser().GetRole())
+			},
+		},
+		{
+			name: "OwnRole",
+			req: &pb.ChangeUserRoleRequest{
+				Username: banker.Username,
+				Role:     util.DepositorRole,
+				Reason:   "stepping down",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ChangeUserRoleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.ChangeUserRoleResponse, err error) {
+				requireStatusCode(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "UnsupportedRole",
+			req: &pb.ChangeUserRoleRequest{
+				Username: depositor.Username,
+				Role:     "admin",
+				Reason:   "joined the branch",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ChangeUserRoleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.ChangeUserRoleResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "UserNotFound",
+			req: &pb.ChangeUserRoleRequest{
+				Username: util.RandomOwner(),
+				Role:     util.BankerRole,
+				Reason:   "joined the branch",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ChangeUserRoleTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ChangeUserRoleTxResult{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, res *pb.ChangeUserRoleResponse, err error) {
+				requireStatusCode(t, err, codes.NotFound)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := NewAdminServer(newTestServer(t, store, nil))
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, banker.Username, banker.Role, time.Minute, token.TokenTypeAccessToken)
+			res, err := server.ChangeUserRole(ctx, tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
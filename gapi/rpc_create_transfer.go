@@ -0,0 +1,141 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/transfer"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/Ian-Balijawa/simplebank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	idempotentReplayedHeader = "idempotent-replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+func (server *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	authPayload, err := server.authorizeUser(ctx, []string{util.BankerRole, util.DepositorRole})
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	ctx = server.withAuditActor(ctx, authPayload.Username, authPayload.Role)
+
+	violations := validateCreateTransferRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	err = server.requireVerifiedEmail(ctx, authPayload.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	idempotencyKey := server.extractMetadata(ctx).IdempotencyKey
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return nil, status.Errorf(codes.InvalidArgument, "%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength)
+	}
+
+	prepared, err := server.prepareTransfer(ctx, req, authPayload.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	var idempotency *db.IdempotencyParams
+	if idempotencyKey != "" {
+		idempotency = &db.IdempotencyParams{
+			Username: authPayload.Username,
+			Key:      idempotencyKey,
+		}
+	}
+
+	if server.config.RequiresStepUp(req.GetAmount()) {
+		return server.createPendingTransfer(ctx, req, authPayload.Username, idempotency)
+	}
+
+	arg := prepared.Arg
+	arg.Idempotency = idempotency
+
+	result, err := server.store.TransferTx(ctx, arg)
+	if err != nil {
+		return nil, transferError(err)
+	}
+
+	if result.Replayed {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(idempotentReplayedHeader, "true"))
+	} else {
+		transfer.SendBalanceAlerts(ctx, server.store, server.taskDistributor, prepared.FromAccount, result.FromAccount)
+		transfer.SendBalanceAlerts(ctx, server.store, server.taskDistributor, prepared.ToAccount, result.ToAccount)
+	}
+
+	rsp := &pb.CreateTransferResponse{
+		Transfer:    convertTransfer(result.Transfer),
+		FromAccount: convertAccount(result.FromAccount),
+		ToAccount:   convertAccount(result.ToAccount),
+		FromEntry:   convertEntry(result.FromEntry),
+		ToEntry:     convertEntry(result.ToEntry),
+	}
+	return rsp, nil
+}
+
+// prepareTransfer checks the accounts of a transfer request made by username,
+// and returns the parameters of the transfer transaction
+func (server *Server) prepareTransfer(ctx context.Context, req *pb.CreateTransferRequest, username string) (transfer.Prepared, error) {
+	prepared, err := transfer.Prepare(ctx, server.store, server.fxRateProvider, transfer.Request{
+		FromAccountID:   req.GetFromAccountId(),
+		ToAccountID:     req.GetToAccountId(),
+		Amount:          req.GetAmount(),
+		Currency:        req.GetCurrency(),
+		ConvertCurrency: req.GetConvertCurrency(),
+	}, username)
+	if err != nil {
+		return prepared, transferError(err)
+	}
+	return prepared, nil
+}
+
+// transferError converts an error of transfer.Prepare or of a transfer transaction to a gRPC status error
+func transferError(err error) error {
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		return status.Errorf(codes.NotFound, "%s", err)
+	case errors.Is(err, transfer.ErrNotAccountOwner):
+		return status.Errorf(codes.PermissionDenied, "%s", err)
+	case errors.Is(err, transfer.ErrSameAccount),
+		errors.Is(err, transfer.ErrCurrencyMismatch),
+		errors.Is(err, transfer.ErrAmountTooSmall):
+		return status.Errorf(codes.InvalidArgument, "%s", err)
+	case errors.Is(err, db.ErrIdempotencyKeyConflict):
+		return status.Errorf(codes.AlreadyExists, "%s", err)
+	case errors.Is(err, util.ErrFXRateNotFound),
+		errors.Is(err, db.ErrPendingTransferNotPending),
+		errors.Is(err, db.ErrPendingTransferExpired),
+		db.IsTransferRejected(err):
+		return status.Errorf(codes.FailedPrecondition, "%s", err)
+	}
+	return status.Errorf(codes.Internal, "failed to transfer money: %s", err)
+}
+
+func validateCreateTransferRequest(req *pb.CreateTransferRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetFromAccountId() <= 0 {
+		violations = append(violations, fieldViolation("from_account_id", errors.New("must be a positive integer")))
+	}
+	if req.GetToAccountId() <= 0 {
+		violations = append(violations, fieldViolation("to_account_id", errors.New("must be a positive integer")))
+	}
+	if req.GetAmount() <= 0 {
+		violations = append(violations, fieldViolation("amount", errors.New("must be greater than 0")))
+	}
+	if err := val.ValidateCurrency(req.GetCurrency()); err != nil {
+		violations = append(violations, fieldViolation("currency", err))
+	}
+	return violations
+}
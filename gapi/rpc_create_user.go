@@ -47,7 +47,7 @@ func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 		},
 	}
 
-	txResult, err := server.store.CreateUserTx(ctx, arg)
+	txResult, err := server.store.CreateUserTx(server.withAuditActor(ctx, "", ""), arg)
 	if err != nil {
 		if db.ErrorCode(err) == db.UniqueViolation {
 			return nil, status.Error(codes.AlreadyExists, err.Error())
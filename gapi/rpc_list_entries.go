@@ -34,6 +34,11 @@ func (server *Server) ListEntries(ctx context.Context, req *pb.ListEntriesReques
 		return nil, status.Errorf(codes.Internal, "failed to get account: %s", err)
 	}
 
+	return server.listEntries(ctx, req)
+}
+
+// listEntries lists the entries of the account in the request, which the caller has been allowed to access
+func (server *Server) listEntries(ctx context.Context, req *pb.ListEntriesRequest) (*pb.ListEntriesResponse, error) {
 	minAmount := optionalInt64(req.MinAmount, minAmountDefault)
 	maxAmount := optionalInt64(req.MaxAmount, maxAmountDefault)
 
@@ -49,6 +54,10 @@ func (server *Server) ListEntries(ctx context.Context, req *pb.ListEntriesReques
 		return nil, status.Errorf(codes.InvalidArgument, "from_time must be before or equal to to_time")
 	}
 
+	if req.GetPageId() == 0 {
+		return server.listEntriesPage(ctx, req, minAmount, maxAmount, fromTime, toTime)
+	}
+
 	arg := db.ListEntriesFilteredDescParams{
 		AccountID:   req.GetAccountId(),
 		Amount:      minAmount,
@@ -77,16 +86,108 @@ func (server *Server) ListEntries(ctx context.Context, req *pb.ListEntriesReques
 		rsp.Entries = append(rsp.Entries, convertEntry(entry))
 	}
 
+	if req.GetIncludeJournals() {
+		rsp.Journals, err = server.listEntryJournals(ctx, req.GetAccountId(), entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return rsp, nil
 }
 
+// listEntriesPage lists entries after the position in the page token, ordered by (created_at, id),
+// so rows added while paging never shift the following pages
+func (server *Server) listEntriesPage(
+	ctx context.Context,
+	req *pb.ListEntriesRequest,
+	minAmount int64,
+	maxAmount int64,
+	fromTime time.Time,
+	toTime time.Time,
+) (*pb.ListEntriesResponse, error) {
+	ascending := req.SortOrder == pb.SortOrder_SORT_ORDER_ASC
+
+	cursor, err := util.PageCursor(req.GetPageToken(), ascending, fromTime, toTime)
+	if err != nil {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{
+			fieldViolation("page_token", err),
+		})
+	}
+
+	// one more row than the page size tells whether there is a next page
+	arg := db.ListEntriesKeysetDescParams{
+		AccountID:       req.GetAccountId(),
+		MinAmount:       minAmount,
+		MaxAmount:       maxAmount,
+		FromTime:        fromTime,
+		ToTime:          toTime,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		Limit:           req.GetPageSize() + 1,
+	}
+
+	var entries []db.Entry
+	if ascending {
+		entries, err = server.store.ListEntriesKeysetAsc(ctx, db.ListEntriesKeysetAscParams(arg))
+	} else {
+		entries, err = server.store.ListEntriesKeysetDesc(ctx, arg)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list entries: %s", err)
+	}
+
+	rsp := &pb.ListEntriesResponse{}
+	if len(entries) > int(req.GetPageSize()) {
+		entries = entries[:req.GetPageSize()]
+		last := entries[len(entries)-1]
+		rsp.NextPageToken = util.EncodePageToken(util.PageToken{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+			Ascending: ascending,
+		})
+	}
+
+	rsp.Entries = make([]*pb.Entry, 0, len(entries))
+	for _, entry := range entries {
+		rsp.Entries = append(rsp.Entries, convertEntry(entry))
+	}
+
+	if req.GetIncludeJournals() {
+		rsp.Journals, err = server.listEntryJournals(ctx, req.GetAccountId(), entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rsp, nil
+}
+
+// listEntryJournals returns the journal transactions that the entries of the account belong to,
+// with only the entries of the account in them
+func (server *Server) listEntryJournals(ctx context.Context, accountID int64, entries []db.Entry) ([]*pb.JournalTransaction, error) {
+	journals, err := db.ListAccountJournals(ctx, server.store, accountID, db.EntryJournalIDs(entries))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list journal transactions: %s", err)
+	}
+
+	pbJournals := make([]*pb.JournalTransaction, 0, len(journals))
+	for _, journal := range journals {
+		pbJournals = append(pbJournals, convertJournal(journal))
+	}
+	return pbJournals, nil
+}
+
 func validateListEntriesRequest(req *pb.ListEntriesRequest) (violations []*errdetails.BadRequest_FieldViolation) {
 	if req.GetAccountId() <= 0 {
 		violations = append(violations, fieldViolation("account_id", errors.New("must be a positive integer")))
 	}
-	if req.GetPageId() <= 0 {
+	if req.GetPageId() < 0 {
 		violations = append(violations, fieldViolation("page_id", errors.New("must be a positive integer")))
 	}
+	if req.GetPageId() > 0 && req.GetPageToken() != "" {
+		violations = append(violations, fieldViolation("page_token", errors.New("cannot be used together with page_id")))
+	}
 	if req.GetPageSize() < 5 || req.GetPageSize() > 50 {
 		violations = append(violations, fieldViolation("page_size", errors.New("must be between 5 and 50")))
 	}
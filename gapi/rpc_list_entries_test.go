@@ -0,0 +1,131 @@
+package gapi
+
+import (
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListEntriesAPI(t *testing.T) {
+	user, _ := randomUser(t, util.DepositorRole)
+	account := db.Account{ID: util.RandomInt(1, 1000), Owner: user.Username, Currency: util.USD}
+
+	pageSize := int32(5)
+	journal := db.JournalTransaction{
+		ID:         util.RandomInt(1, 1000),
+		Kind:       db.JournalKindTransfer,
+		TransferID: pgtype.Int8{Int64: util.RandomInt(1, 1000), Valid: true},
+		CreatedAt:  time.Now().UTC().Truncate(time.Microsecond),
+	}
+	journalID := pgtype.Int8{Int64: journal.ID, Valid: true}
+	entry := db.Entry{
+		ID:                   1,
+		AccountID:            account.ID,
+		Amount:               10,
+		CreatedAt:            journal.CreatedAt,
+		JournalTransactionID: journalID,
+	}
+	otherSide := db.Entry{
+		ID:                   2,
+		AccountID:            account.ID + 1,
+		Amount:               -10,
+		CreatedAt:            journal.CreatedAt,
+		JournalTransactionID: journalID,
+	}
+	// an entry made before journal transactions
+	orphan := db.Entry{ID: 3, AccountID: account.ID, Amount: 5, CreatedAt: journal.CreatedAt}
+
+	testCases := []struct {
+		name          string
+		req           *pb.ListEntriesRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.ListEntriesResponse, err error)
+	}{
+		{
+			name: "WithoutJournals",
+			req: &pb.ListEntriesRequest{
+				AccountId: account.ID,
+				PageSize:  pageSize,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListEntriesKeysetDesc(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Entry{entry, orphan}, nil)
+				store.EXPECT().ListJournalTransactionsByIDs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListEntriesResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetEntries(), 2)
+				require.Equal(t, journal.ID, res.GetEntries()[0].GetJournalTransactionId())
+				require.Nil(t, res.GetEntries()[1].JournalTransactionId)
+				require.Empty(t, res.GetJournals())
+			},
+		},
+		{
+			name: "IncludeJournals",
+			req: &pb.ListEntriesRequest{
+				AccountId:       account.ID,
+				PageId:          1,
+				PageSize:        pageSize,
+				IncludeJournals: true,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListEntriesFilteredDesc(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Entry{entry, orphan}, nil)
+				store.EXPECT().
+					ListJournalTransactionsByIDs(gomock.Any(), gomock.Eq([]int64{journal.ID})).
+					Times(1).
+					Return([]db.JournalTransaction{journal}, nil)
+				store.EXPECT().
+					ListEntriesByJournalTransactions(gomock.Any(), gomock.Eq([]int64{journal.ID})).
+					Times(1).
+					Return([]db.Entry{entry, otherSide}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListEntriesResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetEntries(), 2)
+				require.Len(t, res.GetJournals(), 1)
+
+				got := res.GetJournals()[0]
+				require.Equal(t, journal.ID, got.GetId())
+				require.Equal(t, db.JournalKindTransfer, got.GetKind())
+				require.Equal(t, journal.TransferID.Int64, got.GetTransferId())
+
+				// the other side of the transaction belongs to another account, which is not disclosed
+				require.Len(t, got.GetEntries(), 1)
+				require.Equal(t, entry.ID, got.GetEntries()[0].GetId())
+				require.Equal(t, account.ID, got.GetEntries()[0].GetAccountId())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := newTestServer(t, store, nil)
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, user.Role, time.Minute, token.TokenTypeAccessToken)
+			res, err := server.ListEntries(ctx, tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
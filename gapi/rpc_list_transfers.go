@@ -34,6 +34,11 @@ func (server *Server) ListTransfers(ctx context.Context, req *pb.ListTransfersRe
 		return nil, status.Errorf(codes.Internal, "failed to get account: %s", err)
 	}
 
+	return server.listTransfers(ctx, req)
+}
+
+// listTransfers lists the transfers of the account in the request, which the caller has been allowed to access
+func (server *Server) listTransfers(ctx context.Context, req *pb.ListTransfersRequest) (*pb.ListTransfersResponse, error) {
 	minAmount := optionalInt64(req.MinAmount, 0)
 	maxAmount := optionalInt64(req.MaxAmount, maxAmountDefault)
 
@@ -57,15 +62,19 @@ func (server *Server) ListTransfers(ctx context.Context, req *pb.ListTransfersRe
 		direction = "out"
 	}
 
+	if req.GetPageId() == 0 {
+		return server.listTransfersPage(ctx, req, direction, minAmount, maxAmount, fromTime, toTime)
+	}
+
 	arg := db.ListTransfersFilteredDescParams{
-		AccountID:   req.GetAccountId(),
-		Direction:   direction,
-		Amount:      minAmount,
-		Amount_2:    maxAmount,
-		CreatedAt:   fromTime,
-		CreatedAt_2: toTime,
-		Limit:       req.GetPageSize(),
-		Offset:      (req.GetPageId() - 1) * req.GetPageSize(),
+		AccountID: req.GetAccountId(),
+		Direction: direction,
+		MinAmount: minAmount,
+		MaxAmount: maxAmount,
+		FromTime:  fromTime,
+		ToTime:    toTime,
+		Limit:     req.GetPageSize(),
+		Offset:    (req.GetPageId() - 1) * req.GetPageSize(),
 	}
 
 	var transfers []db.Transfer
@@ -89,13 +98,78 @@ func (server *Server) ListTransfers(ctx context.Context, req *pb.ListTransfersRe
 	return rsp, nil
 }
 
+// listTransfersPage lists transfers after the position in the page token, ordered by (created_at, id),
+// so rows added while paging never shift the following pages
+func (server *Server) listTransfersPage(
+	ctx context.Context,
+	req *pb.ListTransfersRequest,
+	direction string,
+	minAmount int64,
+	maxAmount int64,
+	fromTime time.Time,
+	toTime time.Time,
+) (*pb.ListTransfersResponse, error) {
+	ascending := req.SortOrder == pb.SortOrder_SORT_ORDER_ASC
+
+	cursor, err := util.PageCursor(req.GetPageToken(), ascending, fromTime, toTime)
+	if err != nil {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{
+			fieldViolation("page_token", err),
+		})
+	}
+
+	// one more row than the page size tells whether there is a next page
+	arg := db.ListTransfersKeysetDescParams{
+		AccountID:       req.GetAccountId(),
+		Direction:       direction,
+		MinAmount:       minAmount,
+		MaxAmount:       maxAmount,
+		FromTime:        fromTime,
+		ToTime:          toTime,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		Limit:           req.GetPageSize() + 1,
+	}
+
+	var transfers []db.Transfer
+	if ascending {
+		transfers, err = server.store.ListTransfersKeysetAsc(ctx, db.ListTransfersKeysetAscParams(arg))
+	} else {
+		transfers, err = server.store.ListTransfersKeysetDesc(ctx, arg)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list transfers: %s", err)
+	}
+
+	rsp := &pb.ListTransfersResponse{}
+	if len(transfers) > int(req.GetPageSize()) {
+		transfers = transfers[:req.GetPageSize()]
+		last := transfers[len(transfers)-1]
+		rsp.NextPageToken = util.EncodePageToken(util.PageToken{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+			Ascending: ascending,
+		})
+	}
+
+	rsp.Transfers = make([]*pb.Transfer, 0, len(transfers))
+	for _, transfer := range transfers {
+		rsp.Transfers = append(rsp.Transfers, convertTransfer(transfer))
+	}
+
+	return rsp, nil
+}
+
 func validateListTransfersRequest(req *pb.ListTransfersRequest) (violations []*errdetails.BadRequest_FieldViolation) {
 	if req.GetAccountId() <= 0 {
 		violations = append(violations, fieldViolation("account_id", errors.New("must be a positive integer")))
 	}
-	if req.GetPageId() <= 0 {
+	if req.GetPageId() < 0 {
 		violations = append(violations, fieldViolation("page_id", errors.New("must be a positive integer")))
 	}
+	if req.GetPageId() > 0 && req.GetPageToken() != "" {
+		violations = append(violations, fieldViolation("page_token", errors.New("cannot be used together with page_id")))
+	}
 	if req.GetPageSize() < 5 || req.GetPageSize() > 50 {
 		violations = append(violations, fieldViolation("page_size", errors.New("must be between 5 and 50")))
 	}
@@ -0,0 +1,148 @@
+package gapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestListTransfersAPI(t *testing.T) {
+	user, _ := randomUser(t, util.DepositorRole)
+	account := db.Account{ID: util.RandomInt(1, 1000), Owner: user.Username, Currency: util.USD}
+
+	pageSize := int32(5)
+	transfers := make([]db.Transfer, pageSize+1)
+	for i := range transfers {
+		transfers[i] = db.Transfer{
+			ID:            int64(i + 1),
+			FromAccountID: account.ID,
+			ToAccountID:   account.ID + 1,
+			Amount:        util.RandomMoney(),
+			CreatedAt:     time.Now().Add(time.Duration(i) * time.Minute).UTC().Truncate(time.Microsecond),
+		}
+	}
+	last := transfers[pageSize-1]
+	nextPageToken := util.EncodePageToken(util.PageToken{CreatedAt: last.CreatedAt, ID: last.ID, Ascending: true})
+
+	testCases := []struct {
+		name          string
+		req           *pb.ListTransfersRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.ListTransfersResponse, err error)
+	}{
+		{
+			name: "FirstPage",
+			req: &pb.ListTransfersRequest{
+				AccountId: account.ID,
+				PageSize:  pageSize,
+				Direction: pb.TransferDirection_TRANSFER_DIRECTION_OUT,
+				SortOrder: pb.SortOrder_SORT_ORDER_ASC,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListTransfersKeysetAsc(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ListTransfersKeysetAscParams) ([]db.Transfer, error) {
+						require.Equal(t, "out", arg.Direction)
+						require.Equal(t, pageSize+1, arg.Limit)
+						require.Equal(t, arg.FromTime, arg.CursorCreatedAt)
+						require.Zero(t, arg.CursorID)
+						return transfers, nil
+					})
+			},
+			checkResponse: func(t *testing.T, res *pb.ListTransfersResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetTransfers(), int(pageSize))
+				require.Equal(t, nextPageToken, res.GetNextPageToken())
+			},
+		},
+		{
+			name: "LastPage",
+			req: &pb.ListTransfersRequest{
+				AccountId: account.ID,
+				PageSize:  pageSize,
+				PageToken: nextPageToken,
+				SortOrder: pb.SortOrder_SORT_ORDER_ASC,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListTransfersKeysetAsc(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ListTransfersKeysetAscParams) ([]db.Transfer, error) {
+						require.Equal(t, last.ID, arg.CursorID)
+						return transfers[pageSize:], nil
+					})
+			},
+			checkResponse: func(t *testing.T, res *pb.ListTransfersResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetTransfers(), 1)
+				require.Empty(t, res.GetNextPageToken())
+			},
+		},
+		{
+			name: "InvalidPageToken",
+			req: &pb.ListTransfersRequest{
+				AccountId: account.ID,
+				PageSize:  pageSize,
+				PageToken: "invalid",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListTransfersKeysetDesc(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListTransfersResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "PageIDWithPageToken",
+			req: &pb.ListTransfersRequest{
+				AccountId: account.ID,
+				PageId:    1,
+				PageSize:  pageSize,
+				PageToken: nextPageToken,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListTransfersResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := newTestServer(t, store, nil)
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, user.Role, time.Minute, token.TokenTypeAccessToken)
+			res, err := server.ListTransfers(ctx, tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
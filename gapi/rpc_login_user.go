@@ -9,6 +9,7 @@ import (
 	"github.com/Ian-Balijawa/simplebank/token"
 	"github.com/Ian-Balijawa/simplebank/util"
 	"github.com/Ian-Balijawa/simplebank/val"
+	"github.com/google/uuid"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -21,22 +22,75 @@ func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (
 		return nil, invalidArgumentError(violations)
 	}
 
+	clientIP := server.extractMetadata(ctx).ClientIP
+	reservation, err := server.reserveLoginAttempt(ctx, req.GetUsername(), clientIP)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := server.store.GetUser(ctx, req.GetUsername())
 	if err != nil {
 		if errors.Is(err, db.ErrRecordNotFound) {
-			return nil, status.Errorf(codes.NotFound, "user not found")
+			util.FakeCheckPassword(req.GetPassword())
+			return nil, server.rejectLogin(ctx, req.GetUsername(), clientIP, reservation)
 		}
 		return nil, status.Errorf(codes.Internal, "failed to find user")
 	}
 
 	err = util.CheckPassword(req.Password, user.HashedPassword)
 	if err != nil {
-		return nil, status.Errorf(codes.NotFound, "incorrect password")
+		return nil, server.rejectLogin(ctx, user.Username, clientIP, reservation)
+	}
+
+	// a correct password is not held against the client IP, the users behind one address would lock each other out
+	err = server.loginGuard.Release(ctx, reservation)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to release login attempt: %s", err)
 	}
 
+	otpRequired, err := server.isTOTPEnabled(ctx, user.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get two-factor authentication: %s", err)
+	}
+
+	// with two-factor authentication, the attempt against the username stays counted until the one-time password is sent
+	if otpRequired {
+		challengeToken, challengePayload, err := server.tokenMaker.CreateToken(
+			user.Username,
+			user.Role,
+			uuid.Nil,
+			util.TOTPLoginChallengeDuration,
+			token.TokenTypeLoginChallenge,
+		)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to create challenge token")
+		}
+
+		rsp := &pb.LoginUserResponse{
+			User:                    convertUser(user),
+			OtpRequired:             true,
+			ChallengeToken:          challengeToken,
+			ChallengeTokenExpiresAt: timestamppb.New(challengePayload.ExpiredAt),
+		}
+		return rsp, nil
+	}
+
+	err = server.loginGuard.RecordSuccess(ctx, user.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to reset failed logins: %s", err)
+	}
+
+	return server.createLoginSession(ctx, user)
+}
+
+// createLoginSession creates the session of a user who has proven who they are,
+// and the access and refresh tokens of the session
+func (server *Server) createLoginSession(ctx context.Context, user db.User) (*pb.LoginUserResponse, error) {
+	sessionID := uuid.New()
 	accessToken, accessPayload, err := server.tokenMaker.CreateToken(
 		user.Username,
 		user.Role,
+		sessionID,
 		server.config.AccessTokenDuration,
 		token.TokenTypeAccessToken,
 	)
@@ -47,6 +101,7 @@ func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (
 	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(
 		user.Username,
 		user.Role,
+		sessionID,
 		server.config.RefreshTokenDuration,
 		token.TokenTypeRefreshToken,
 	)
@@ -55,14 +110,15 @@ func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (
 	}
 
 	mtdt := server.extractMetadata(ctx)
-	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
-		ID:           refreshPayload.ID,
+	session, err := server.store.CreateSessionTx(server.withAuditActor(ctx, user.Username, user.Role), db.CreateSessionParams{
+		ID:           sessionID,
 		Username:     user.Username,
 		RefreshToken: refreshToken,
 		UserAgent:    mtdt.UserAgent,
 		ClientIp:     mtdt.ClientIP,
 		IsBlocked:    false,
 		ExpiresAt:    refreshPayload.ExpiredAt,
+		FamilyID:     sessionID,
 	})
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create session")
@@ -0,0 +1,231 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/lockout"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/Ian-Balijawa/simplebank/worker"
+	mockwk "github.com/Ian-Balijawa/simplebank/worker/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoginUserAPI(t *testing.T) {
+	user, password := randomUser(t, util.DepositorRole)
+	clientIP := "10.0.0.1"
+
+	testCases := []struct {
+		name          string
+		req           *pb.LoginUserRequest
+		buildStubs    func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor)
+		checkResponse func(t *testing.T, res *pb.LoginUserResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					ReserveLoginAttemptTx(gomock.Any(), eqLoginAttempt(lockout.KindUsername, user.Username)).
+					Times(1).
+					Return(db.ReserveLoginAttemptTxResult{Failure: db.LoginFailure{FailedCount: 1}}, nil)
+				reservedAt := time.Now()
+				previousFailedAt := reservedAt.Add(-time.Minute)
+				store.EXPECT().
+					ReserveLoginAttemptTx(gomock.Any(), eqLoginAttempt(lockout.KindClientIP, clientIP)).
+					Times(1).
+					Return(db.ReserveLoginAttemptTxResult{
+						Failure:          db.LoginFailure{FailedCount: 3, LastFailedAt: reservedAt},
+						PreviousFailedAt: previousFailedAt,
+					}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				refund := db.RefundLoginFailureParams{
+					Kind:             lockout.KindClientIP,
+					Subject:          clientIP,
+					ReservedAt:       reservedAt,
+					PreviousFailedAt: previousFailedAt,
+				}
+				store.EXPECT().RefundLoginFailure(gomock.Any(), gomock.Eq(refund)).Times(1).Return(nil)
+				arg := db.DeleteLoginFailureParams{Kind: lockout.KindUsername, Subject: user.Username}
+				store.EXPECT().DeleteLoginFailure(gomock.Any(), gomock.Eq(arg)).Times(1).Return(nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Any()).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.NotEmpty(t, res.GetAccessToken())
+			},
+		},
+		{
+			name: "UserNotFound",
+			req:  &pb.LoginUserRequest{Username: util.RandomOwner(), Password: password},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(db.ReserveLoginAttemptTxResult{
+						Failure:          db.LoginFailure{FailedCount: lockout.UsernameLockoutThreshold},
+						PreviousFailedAt: time.Now().Add(-time.Hour),
+					}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(2).Return(db.User{}, db.ErrRecordNotFound)
+				taskDistributor.EXPECT().DistributeTaskSendLoginLockout(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				requireIncorrectCredentials(t, err)
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: "incorrect"},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(db.ReserveLoginAttemptTxResult{Failure: db.LoginFailure{FailedCount: 1}}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().DeleteLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+				taskDistributor.EXPECT().DistributeTaskSendLoginLockout(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				requireIncorrectCredentials(t, err)
+			},
+		},
+		{
+			name: "LockedOut",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: "incorrect"},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(db.ReserveLoginAttemptTxResult{
+						Failure:          db.LoginFailure{FailedCount: lockout.UsernameLockoutThreshold},
+						PreviousFailedAt: time.Now().Add(-time.Hour),
+					}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(2).Return(user, nil)
+				taskDistributor.EXPECT().
+					DistributeTaskSendLoginLockout(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, payload *worker.PayloadSendLoginLockout, _ ...asynq.Option) error {
+						require.Equal(t, user.Username, payload.Username)
+						require.Contains(t, payload.ClientIP, clientIP)
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				requireIncorrectCredentials(t, err)
+			},
+		},
+		{
+			name: "TooManyAttempts",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().
+					ReserveLoginAttemptTx(gomock.Any(), eqLoginAttempt(lockout.KindUsername, user.Username)).
+					Times(1).
+					Return(db.ReserveLoginAttemptTxResult{Failure: db.LoginFailure{FailedCount: 1}}, nil)
+				store.EXPECT().
+					ReserveLoginAttemptTx(gomock.Any(), eqLoginAttempt(lockout.KindClientIP, clientIP)).
+					Times(1).
+					Return(db.ReserveLoginAttemptTxResult{
+						Failure:          db.LoginFailure{FailedCount: lockout.ClientIPLockoutThreshold + 1},
+						PreviousFailedAt: time.Now(),
+					}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				requireStatusCode(t, err, codes.ResourceExhausted)
+
+				st, _ := status.FromError(err)
+				require.Len(t, st.Details(), 1)
+				retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+				require.True(t, ok)
+				require.InDelta(t, lockout.LockoutDuration, retryInfo.GetRetryDelay().AsDuration(), float64(time.Second))
+			},
+		},
+		{
+			name: "InternalError",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ReserveLoginAttemptTxResult{}, sql.ErrConnDone)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				requireStatusCode(t, err, codes.Internal)
+			},
+		},
+		{
+			name: "InvalidUsername",
+			req:  &pb.LoginUserRequest{Username: "invalid-user#1", Password: password},
+			buildStubs: func(store *mockdb.MockStore, taskDistributor *mockwk.MockTaskDistributor) {
+				store.EXPECT().ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			taskCtrl := gomock.NewController(t)
+			defer taskCtrl.Finish()
+			taskDistributor := mockwk.NewMockTaskDistributor(taskCtrl)
+
+			tc.buildStubs(store, taskDistributor)
+			server := newTestServer(t, store, taskDistributor)
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{
+				Addr: &net.TCPAddr{IP: net.ParseIP(clientIP), Port: 54321},
+			})
+			res, err := server.LoginUser(ctx, tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+// requireIncorrectCredentials checks a failed login does not tell whether the username exists
+func requireIncorrectCredentials(t *testing.T, err error) {
+	requireStatusCode(t, err, codes.Unauthenticated)
+
+	st, _ := status.FromError(err)
+	require.Equal(t, "incorrect username or password", st.Message())
+}
+
+type eqLoginAttemptMatcher struct {
+	kind    string
+	subject string
+}
+
+func (expected eqLoginAttemptMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.RecordLoginFailureParams)
+	return ok && arg.Kind == expected.kind && arg.Subject == expected.subject
+}
+
+func (expected eqLoginAttemptMatcher) String() string {
+	return fmt.Sprintf("login attempt of %s %s", expected.kind, expected.subject)
+}
+
+// eqLoginAttempt matches the reservation of a login attempt of the subject, whatever its reset time
+func eqLoginAttempt(kind string, subject string) gomock.Matcher {
+	return eqLoginAttemptMatcher{kind, subject}
+}
@@ -0,0 +1,113 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/Ian-Balijawa/simplebank/val"
+	"github.com/Ian-Balijawa/simplebank/worker"
+	"github.com/hibiken/asynq"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errInvalidResetCode = errors.New("is invalid, used or expired")
+
+func (server *Server) RequestPasswordReset(ctx context.Context, req *pb.RequestPasswordResetRequest) (*pb.RequestPasswordResetResponse, error) {
+	violations := validateRequestPasswordResetRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	user, err := server.store.GetUserByEmail(ctx, req.GetEmail())
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			// the response doesn't tell whether the email address is registered
+			return &pb.RequestPasswordResetResponse{}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "failed to find user: %s", err)
+	}
+
+	// a request during the cooldown is answered like the others, or it would tell the address is registered
+	cooldown := server.config.PasswordResetCooldown()
+	lastPasswordReset, err := server.store.GetLastPasswordReset(ctx, user.Username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return nil, status.Errorf(codes.Internal, "failed to get last password reset: %s", err)
+	}
+	if err == nil && time.Since(lastPasswordReset.CreatedAt) < cooldown {
+		return &pb.RequestPasswordResetResponse{}, nil
+	}
+
+	taskPayload := &worker.PayloadSendPasswordReset{
+		Username: user.Username,
+	}
+	// the task is unique during the cooldown, so requests cannot pile up before its reset is created
+	opts := []asynq.Option{
+		asynq.MaxRetry(10),
+		asynq.Queue(worker.QueueCritical),
+		asynq.Unique(cooldown),
+	}
+
+	err = server.taskDistributor.DistributeTaskSendPasswordReset(ctx, taskPayload, opts...)
+	if err != nil && !errors.Is(err, asynq.ErrDuplicateTask) {
+		return nil, status.Errorf(codes.Internal, "failed to send password reset email: %s", err)
+	}
+
+	return &pb.RequestPasswordResetResponse{}, nil
+}
+
+func (server *Server) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.ResetPasswordResponse, error) {
+	violations := validateResetPasswordRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	hashedPassword, err := util.HashPassword(req.GetNewPassword())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to hash password: %s", err)
+	}
+
+	_, err = server.store.ResetPasswordTx(server.withAuditActor(ctx, "", ""), db.ResetPasswordTxParams{
+		ResetID:        req.GetResetId(),
+		CodeHash:       util.HashOTPCode(req.GetSecretCode()),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{
+				fieldViolation("secret_code", errInvalidResetCode),
+			})
+		}
+		return nil, status.Errorf(codes.Internal, "failed to reset password: %s", err)
+	}
+
+	return &pb.ResetPasswordResponse{}, nil
+}
+
+func validateRequestPasswordResetRequest(req *pb.RequestPasswordResetRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateEmail(req.GetEmail()); err != nil {
+		violations = append(violations, fieldViolation("email", err))
+	}
+	return violations
+}
+
+func validateResetPasswordRequest(req *pb.ResetPasswordRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetResetId() <= 0 {
+		violations = append(violations, fieldViolation("reset_id", errors.New("must be a positive integer")))
+	}
+
+	if err := val.ValidateSecretCode(req.GetSecretCode()); err != nil {
+		violations = append(violations, fieldViolation("secret_code", err))
+	}
+
+	if err := val.ValidatePassword(req.GetNewPassword()); err != nil {
+		violations = append(violations, fieldViolation("new_password", err))
+	}
+
+	return violations
+}
@@ -0,0 +1,207 @@
+package gapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/transfer"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/Ian-Balijawa/simplebank/worker"
+	"github.com/hibiken/asynq"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// createPendingTransfer holds a transfer over the step-up threshold until it is confirmed
+// with a TOTP code, or with a code sent by email to users without TOTP.
+// A retry with the same idempotency key returns the pending transfer of the first request, and sends no other code.
+func (server *Server) createPendingTransfer(
+	ctx context.Context,
+	req *pb.CreateTransferRequest,
+	username string,
+	idempotency *db.IdempotencyParams,
+) (*pb.CreateTransferResponse, error) {
+	totpEnabled, err := server.isTOTPEnabled(ctx, username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get two-factor authentication: %s", err)
+	}
+
+	method := db.VerificationMethodTOTP
+	if !totpEnabled {
+		if server.taskDistributor == nil {
+			return nil, status.Errorf(codes.Internal, "transfer confirmation codes cannot be sent")
+		}
+		method = db.VerificationMethodEmail
+	}
+
+	result, err := server.store.CreatePendingTransferTx(ctx, db.CreatePendingTransferTxParams{
+		CreatePendingTransferParams: db.CreatePendingTransferParams{
+			Owner:              username,
+			FromAccountID:      req.GetFromAccountId(),
+			ToAccountID:        req.GetToAccountId(),
+			Amount:             req.GetAmount(),
+			Currency:           req.GetCurrency(),
+			ConvertCurrency:    req.GetConvertCurrency(),
+			VerificationMethod: method,
+			ExpiresAt:          time.Now().Add(server.config.PendingTransferTTL()),
+		},
+		Idempotency: idempotency,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) {
+			return nil, transferError(err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create pending transfer: %s", err)
+	}
+
+	pendingTransfer := result.PendingTransfer
+	if result.Replayed {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(idempotentReplayedHeader, "true"))
+	} else if pendingTransfer.VerificationMethod == db.VerificationMethodEmail {
+		err = server.taskDistributor.DistributeTaskSendTransferCode(
+			ctx,
+			&worker.PayloadSendTransferCode{PendingTransferID: pendingTransfer.ID},
+			asynq.Queue(worker.QueueCritical),
+		)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to send transfer code: %s", err)
+		}
+	}
+
+	rsp := &pb.CreateTransferResponse{
+		PendingTransfer: convertPendingTransfer(pendingTransfer),
+	}
+	return rsp, nil
+}
+
+func (server *Server) ConfirmPendingTransfer(ctx context.Context, req *pb.ConfirmPendingTransferRequest) (*pb.CreateTransferResponse, error) {
+	authPayload, err := server.authorizeUser(ctx, []string{util.BankerRole, util.DepositorRole})
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	ctx = server.withAuditActor(ctx, authPayload.Username, authPayload.Role)
+
+	violations := validateConfirmPendingTransferRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	pendingTransfer, err := server.store.GetPendingTransfer(ctx, req.GetId())
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "pending transfer not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get pending transfer: %s", err)
+	}
+
+	if pendingTransfer.Owner != authPayload.Username {
+		return nil, status.Errorf(codes.PermissionDenied, "pending transfer doesn't belong to the authenticated user")
+	}
+
+	if pendingTransfer.Status != db.PendingTransferStatusPending {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s", db.ErrPendingTransferNotPending)
+	}
+	if !pendingTransfer.ExpiresAt.After(time.Now()) {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s", db.ErrPendingTransferExpired)
+	}
+
+	// the attempt is counted before the code is checked, so concurrent guesses are limited too
+	pendingTransfer, err = server.store.AddPendingTransferAttemptTx(ctx, pendingTransfer.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.FailedPrecondition, "%s", db.ErrPendingTransferNotPending)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to count attempt: %s", err)
+	}
+	if pendingTransfer.Attempts > util.MaxStepUpAttempts {
+		return nil, status.Errorf(codes.ResourceExhausted, "too many incorrect codes, please make the transfer again")
+	}
+
+	err = server.verifyPendingTransferCode(ctx, pendingTransfer, req.GetCode())
+	if err != nil {
+		return nil, err
+	}
+
+	transferReq := &pb.CreateTransferRequest{
+		FromAccountId:   pendingTransfer.FromAccountID,
+		ToAccountId:     pendingTransfer.ToAccountID,
+		Amount:          pendingTransfer.Amount,
+		Currency:        pendingTransfer.Currency,
+		ConvertCurrency: pendingTransfer.ConvertCurrency,
+	}
+	prepared, err := server.prepareTransfer(ctx, transferReq, authPayload.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := server.store.ConfirmPendingTransferTx(ctx, db.ConfirmPendingTransferTxParams{
+		PendingTransferID: pendingTransfer.ID,
+		Transfer:          prepared.Arg,
+	})
+	if err != nil {
+		return nil, transferError(err)
+	}
+
+	transfer.SendBalanceAlerts(ctx, server.store, server.taskDistributor, prepared.FromAccount, result.Transfer.FromAccount)
+	transfer.SendBalanceAlerts(ctx, server.store, server.taskDistributor, prepared.ToAccount, result.Transfer.ToAccount)
+
+	rsp := &pb.CreateTransferResponse{
+		Transfer:        convertTransfer(result.Transfer.Transfer),
+		FromAccount:     convertAccount(result.Transfer.FromAccount),
+		ToAccount:       convertAccount(result.Transfer.ToAccount),
+		FromEntry:       convertEntry(result.Transfer.FromEntry),
+		ToEntry:         convertEntry(result.Transfer.ToEntry),
+		PendingTransfer: convertPendingTransfer(result.PendingTransfer),
+	}
+	return rsp, nil
+}
+
+// verifyPendingTransferCode checks the code sent for a pending transfer
+func (server *Server) verifyPendingTransferCode(ctx context.Context, pendingTransfer db.PendingTransfer, code string) error {
+	incorrectCode := invalidArgumentError([]*errdetails.BadRequest_FieldViolation{
+		fieldViolation("code", errIncorrectCode),
+	})
+
+	if pendingTransfer.VerificationMethod == db.VerificationMethodTOTP {
+		userTOTP, err := server.store.GetUserTOTP(ctx, pendingTransfer.Owner)
+		if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+			return status.Errorf(codes.Internal, "failed to get two-factor authentication: %s", err)
+		}
+		if err != nil || !userTOTP.ConfirmedAt.Valid {
+			return status.Errorf(codes.FailedPrecondition, "two-factor authentication is not enabled")
+		}
+
+		ok, err := server.verifySecondFactor(ctx, userTOTP, code)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to verify code: %s", err)
+		}
+		if !ok {
+			return incorrectCode
+		}
+		return nil
+	}
+
+	codeHash := util.HashOTPCode(code)
+	if !pendingTransfer.CodeHash.Valid ||
+		subtle.ConstantTimeCompare([]byte(codeHash), []byte(pendingTransfer.CodeHash.String)) != 1 {
+		return incorrectCode
+	}
+	return nil
+}
+
+func validateConfirmPendingTransferRequest(req *pb.ConfirmPendingTransferRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetId() <= 0 {
+		violations = append(violations, fieldViolation("id", errors.New("must be a positive integer")))
+	}
+
+	violations = append(violations, validateOTPCode(req.GetCode())...)
+	return violations
+}
@@ -0,0 +1,251 @@
+package gapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/Ian-Balijawa/simplebank/worker"
+	mockwk "github.com/Ian-Balijawa/simplebank/worker/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+const testStepUpThreshold = 1000
+
+func TestCreateTransferStepUp(t *testing.T) {
+	user, _ := randomUser(t, util.DepositorRole)
+	account1 := randomAccount(user.Username, util.USD)
+	account2 := randomAccount(util.RandomOwner(), util.USD)
+	account2.ID = account1.ID + 1
+
+	storeCtrl := gomock.NewController(t)
+	defer storeCtrl.Finish()
+	store := mockdb.NewMockStore(storeCtrl)
+
+	distributorCtrl := gomock.NewController(t)
+	defer distributorCtrl.Finish()
+	distributor := mockwk.NewMockTaskDistributor(distributorCtrl)
+
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+	store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+	store.EXPECT().
+		CreatePendingTransferTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreatePendingTransferTxParams) (db.CreatePendingTransferTxResult, error) {
+			require.Equal(t, user.Username, arg.Owner)
+			require.Equal(t, db.VerificationMethodEmail, arg.VerificationMethod)
+			require.Nil(t, arg.Idempotency)
+			pendingTransfer := db.PendingTransfer{ID: 1, Owner: arg.Owner, Amount: arg.Amount, VerificationMethod: arg.VerificationMethod}
+			return db.CreatePendingTransferTxResult{PendingTransfer: pendingTransfer}, nil
+		})
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+	distributor.EXPECT().
+		DistributeTaskSendTransferCode(gomock.Any(), gomock.Eq(&worker.PayloadSendTransferCode{PendingTransferID: 1}), gomock.Any()).
+		Times(1).
+		Return(nil)
+
+	server := newTestServer(t, store, distributor)
+	server.config.StepUpTransferThreshold = testStepUpThreshold
+
+	ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, user.Role, time.Minute, token.TokenTypeAccessToken)
+	res, err := server.CreateTransfer(ctx, &pb.CreateTransferRequest{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        testStepUpThreshold + 1,
+		Currency:      util.USD,
+	})
+	require.NoError(t, err)
+	require.Nil(t, res.GetTransfer())
+	require.Equal(t, int64(1), res.GetPendingTransfer().GetId())
+	require.Equal(t, db.VerificationMethodEmail, res.GetPendingTransfer().GetVerificationMethod())
+}
+
+func TestCreateTransferStepUpIdempotentReplay(t *testing.T) {
+	user, _ := randomUser(t, util.DepositorRole)
+	account1 := randomAccount(user.Username, util.USD)
+	account2 := randomAccount(util.RandomOwner(), util.USD)
+	account2.ID = account1.ID + 1
+	idempotencyKey := util.RandomString(16)
+
+	storeCtrl := gomock.NewController(t)
+	defer storeCtrl.Finish()
+	store := mockdb.NewMockStore(storeCtrl)
+
+	distributorCtrl := gomock.NewController(t)
+	defer distributorCtrl.Finish()
+	distributor := mockwk.NewMockTaskDistributor(distributorCtrl)
+
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+	store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+	store.EXPECT().
+		CreatePendingTransferTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreatePendingTransferTxParams) (db.CreatePendingTransferTxResult, error) {
+			require.Equal(t, &db.IdempotencyParams{Username: user.Username, Key: idempotencyKey}, arg.Idempotency)
+			return db.CreatePendingTransferTxResult{
+				PendingTransfer: db.PendingTransfer{ID: 1, Owner: arg.Owner, VerificationMethod: db.VerificationMethodEmail},
+				Replayed:        true,
+			}, nil
+		})
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+	// the code of the first request is still the one to enter
+	distributor.EXPECT().DistributeTaskSendTransferCode(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store, distributor)
+	server.config.StepUpTransferThreshold = testStepUpThreshold
+
+	ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, user.Role, time.Minute, token.TokenTypeAccessToken)
+	md, _ := metadata.FromIncomingContext(ctx)
+	md.Set(idempotencyKeyHeader, idempotencyKey)
+	ctx = metadata.NewIncomingContext(ctx, md)
+
+	res, err := server.CreateTransfer(ctx, &pb.CreateTransferRequest{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        testStepUpThreshold + 1,
+		Currency:      util.USD,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), res.GetPendingTransfer().GetId())
+}
+
+func TestConfirmPendingTransferAPI(t *testing.T) {
+	user, _ := randomUser(t, util.DepositorRole)
+	userTOTP := randomUserTOTP(t, user.Username, true)
+	account1 := randomAccount(user.Username, util.USD)
+	account2 := randomAccount(util.RandomOwner(), util.USD)
+	account2.ID = account1.ID + 1
+
+	code, err := util.TOTPCode(userTOTP.Secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+
+	pendingTransfer := db.PendingTransfer{
+		ID:                 util.RandomInt(1, 1000),
+		Owner:              user.Username,
+		FromAccountID:      account1.ID,
+		ToAccountID:        account2.ID,
+		Amount:             testStepUpThreshold + 1,
+		Currency:           util.USD,
+		VerificationMethod: db.VerificationMethodTOTP,
+		Status:             db.PendingTransferStatusPending,
+		ExpiresAt:          time.Now().Add(time.Minute),
+	}
+
+	attempted := pendingTransfer
+	attempted.Attempts = 1
+
+	exhausted := pendingTransfer
+	exhausted.Attempts = util.MaxStepUpAttempts + 1
+
+	testCases := []struct {
+		name          string
+		code          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.CreateTransferResponse, err error)
+	}{
+		{
+			name: "OK",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(pendingTransfer, nil)
+				store.EXPECT().AddPendingTransferAttemptTx(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(attempted, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(userTOTP, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				arg := db.ConfirmPendingTransferTxParams{
+					PendingTransferID: pendingTransfer.ID,
+					Transfer: db.TransferTxParams{
+						FromAccountID:     account1.ID,
+						ToAccountID:       account2.ID,
+						Amount:            pendingTransfer.Amount,
+						EnforceDailyLimit: true,
+					},
+				}
+				store.EXPECT().ConfirmPendingTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, res.GetTransfer())
+			},
+		},
+		{
+			name: "ReplayedCode",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(pendingTransfer, nil)
+				store.EXPECT().AddPendingTransferAttemptTx(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(attempted, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().ConfirmPendingTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "TooManyAttempts",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(pendingTransfer, nil)
+				store.EXPECT().AddPendingTransferAttemptTx(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(exhausted, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ConfirmPendingTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				requireStatusCode(t, err, codes.ResourceExhausted)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(pendingTransfer, nil)
+				store.EXPECT().AddPendingTransferAttemptTx(gomock.Any(), gomock.Eq(pendingTransfer.ID)).Times(1).Return(attempted, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(userTOTP, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					ConfirmPendingTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ConfirmPendingTransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateTransferResponse, err error) {
+				requireStatusCode(t, err, codes.FailedPrecondition)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := newTestServer(t, store, nil)
+			server.config.StepUpTransferThreshold = testStepUpThreshold
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, user.Role, time.Minute, token.TokenTypeAccessToken)
+			res, err := server.ConfirmPendingTransfer(ctx, &pb.ConfirmPendingTransferRequest{
+				Id:   pendingTransfer.ID,
+				Code: tc.code,
+			})
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/transfer"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/Ian-Balijawa/simplebank/val"
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) CreateScheduledTransfer(ctx context.Context, req *pb.CreateScheduledTransferRequest) (*pb.CreateScheduledTransferResponse, error) {
+	authPayload, err := server.authorizeUser(ctx, []string{util.BankerRole, util.DepositorRole})
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	ctx = server.withAuditActor(ctx, authPayload.Username, authPayload.Role)
+
+	violations := validateCreateScheduledTransferRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	err = server.requireVerifiedEmail(ctx, authPayload.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	startAt := time.Now()
+	if req.StartAt != nil {
+		startAt = req.GetStartAt().AsTime()
+	}
+
+	nextRunAt, err := util.FirstRunTime(req.GetRecurrence(), req.GetCronExpression(), startAt)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+
+	if req.EndAt != nil && req.GetEndAt().AsTime().Before(nextRunAt) {
+		return nil, status.Errorf(codes.InvalidArgument, "end_at must not be before the first run at %s", nextRunAt.Format(time.RFC3339))
+	}
+
+	fromAccount, err := transfer.ValidAccount(ctx, server.store, req.GetFromAccountId(), req.GetCurrency())
+	if err != nil {
+		return nil, transferError(err)
+	}
+
+	if fromAccount.Owner != authPayload.Username {
+		return nil, transferError(transfer.ErrNotAccountOwner)
+	}
+
+	if _, err := transfer.ValidAccount(ctx, server.store, req.GetToAccountId(), req.GetCurrency()); err != nil {
+		return nil, transferError(err)
+	}
+
+	if server.config.RequiresStepUp(req.GetAmount()) {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s", util.ErrStepUpNotScheduled)
+	}
+
+	arg := db.CreateScheduledTransferParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.GetFromAccountId(),
+		ToAccountID:   req.GetToAccountId(),
+		Amount:        req.GetAmount(),
+		Recurrence:    req.GetRecurrence(),
+		NextRunAt:     nextRunAt,
+	}
+	switch req.GetRecurrence() {
+	case util.RecurrenceCron:
+		arg.CronExpression = pgtype.Text{String: req.GetCronExpression(), Valid: true}
+	case util.RecurrenceInterval:
+		arg.IntervalSeconds = pgtype.Int8{Int64: req.GetIntervalSeconds(), Valid: true}
+	}
+	if req.EndAt != nil {
+		arg.EndAt = pgtype.Timestamptz{Time: req.GetEndAt().AsTime(), Valid: true}
+	}
+	if req.MaxOccurrences != nil {
+		arg.MaxOccurrences = pgtype.Int4{Int32: req.GetMaxOccurrences(), Valid: true}
+	}
+
+	scheduledTransfer, err := server.store.CreateScheduledTransferTx(ctx, arg)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create scheduled transfer: %s", err)
+	}
+
+	rsp := &pb.CreateScheduledTransferResponse{
+		ScheduledTransfer: convertScheduledTransfer(scheduledTransfer),
+	}
+	return rsp, nil
+}
+
+func (server *Server) GetScheduledTransfer(ctx context.Context, req *pb.GetScheduledTransferRequest) (*pb.GetScheduledTransferResponse, error) {
+	authPayload, err := server.authorizeUser(ctx, []string{util.BankerRole, util.DepositorRole})
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if req.GetId() <= 0 {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{
+			fieldViolation("id", errors.New("must be a positive integer")),
+		})
+	}
+
+	scheduledTransfer, err := server.getOwnedScheduledTransfer(ctx, req.GetId(), authPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := &pb.GetScheduledTransferResponse{
+		ScheduledTransfer: convertScheduledTransfer(scheduledTransfer),
+	}
+	return rsp, nil
+}
+
+func (server *Server) ListScheduledTransfers(ctx context.Context, req *pb.ListScheduledTransfersRequest) (*pb.ListScheduledTransfersResponse, error) {
+	authPayload, err := server.authorizeUser(ctx, []string{util.BankerRole, util.DepositorRole})
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	violations := validatePage("account_id", req.GetAccountId(), req.GetPageId(), req.GetPageSize())
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	if _, err := server.getOwnedAccount(ctx, req.GetAccountId(), authPayload); err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "account not found")
+		}
+		if errors.Is(err, errPermissionDenied) {
+			return nil, status.Errorf(codes.PermissionDenied, "cannot access this account")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get account: %s", err)
+	}
+
+	scheduledTransfers, err := server.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		FromAccountID: req.GetAccountId(),
+		Limit:         req.GetPageSize(),
+		Offset:        (req.GetPageId() - 1) * req.GetPageSize(),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list scheduled transfers: %s", err)
+	}
+
+	rsp := &pb.ListScheduledTransfersResponse{
+		ScheduledTransfers: make([]*pb.ScheduledTransfer, 0, len(scheduledTransfers)),
+	}
+	for _, scheduledTransfer := range scheduledTransfers {
+		rsp.ScheduledTransfers = append(rsp.ScheduledTransfers, convertScheduledTransfer(scheduledTransfer))
+	}
+
+	return rsp, nil
+}
+
+func (server *Server) UpdateScheduledTransfer(ctx context.Context, req *pb.UpdateScheduledTransferRequest) (*pb.UpdateScheduledTransferResponse, error) {
+	authPayload, err := server.authorizeUser(ctx, []string{util.BankerRole, util.DepositorRole})
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	ctx = server.withAuditActor(ctx, authPayload.Username, authPayload.Role)
+
+	violations := validateUpdateScheduledTransferRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	scheduledTransfer, err := server.getOwnedScheduledTransfer(ctx, req.GetId(), authPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	if scheduledTransfer.Status != db.ScheduledTransferStatusActive {
+		return nil, status.Errorf(codes.FailedPrecondition, "scheduled transfer is %s", scheduledTransfer.Status)
+	}
+
+	if req.Amount != nil && server.config.RequiresStepUp(req.GetAmount()) {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s", util.ErrStepUpNotScheduled)
+	}
+
+	arg := db.UpdateScheduledTransferParams{
+		ID: req.GetId(),
+	}
+	if req.Amount != nil {
+		arg.Amount = pgtype.Int8{Int64: req.GetAmount(), Valid: true}
+	}
+	if req.EndAt != nil {
+		arg.EndAt = pgtype.Timestamptz{Time: req.GetEndAt().AsTime(), Valid: true}
+	}
+	if req.MaxOccurrences != nil {
+		arg.MaxOccurrences = pgtype.Int4{Int32: req.GetMaxOccurrences(), Valid: true}
+	}
+
+	scheduledTransfer, err = server.store.UpdateScheduledTransferTx(ctx, arg)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update scheduled transfer: %s", err)
+	}
+
+	rsp := &pb.UpdateScheduledTransferResponse{
+		ScheduledTransfer: convertScheduledTransfer(scheduledTransfer),
+	}
+	return rsp, nil
+}
+
+func (server *Server) CancelScheduledTransfer(ctx context.Context, req *pb.CancelScheduledTransferRequest) (*pb.CancelScheduledTransferResponse, error) {
+	authPayload, err := server.authorizeUser(ctx, []string{util.BankerRole, util.DepositorRole})
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	ctx = server.withAuditActor(ctx, authPayload.Username, authPayload.Role)
+
+	if req.GetId() <= 0 {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{
+			fieldViolation("id", errors.New("must be a positive integer")),
+		})
+	}
+
+	scheduledTransfer, err := server.getOwnedScheduledTransfer(ctx, req.GetId(), authPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	if scheduledTransfer.Status != db.ScheduledTransferStatusActive {
+		return nil, status.Errorf(codes.FailedPrecondition, "scheduled transfer is %s", scheduledTransfer.Status)
+	}
+
+	scheduledTransfer, err = server.store.UpdateScheduledTransferStatusTx(ctx, db.UpdateScheduledTransferStatusParams{
+		ID:     req.GetId(),
+		Status: db.ScheduledTransferStatusCancelled,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to cancel scheduled transfer: %s", err)
+	}
+
+	rsp := &pb.CancelScheduledTransferResponse{
+		ScheduledTransfer: convertScheduledTransfer(scheduledTransfer),
+	}
+	return rsp, nil
+}
+
+func (server *Server) ListScheduledTransferRuns(ctx context.Context, req *pb.ListScheduledTransferRunsRequest) (*pb.ListScheduledTransferRunsResponse, error) {
+	authPayload, err := server.authorizeUser(ctx, []string{util.BankerRole, util.DepositorRole})
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	violations := validatePage("id", req.GetId(), req.GetPageId(), req.GetPageSize())
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	if _, err := server.getOwnedScheduledTransfer(ctx, req.GetId(), authPayload); err != nil {
+		return nil, err
+	}
+
+	runs, err := server.store.ListScheduledTransferRuns(ctx, db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: req.GetId(),
+		Limit:               req.GetPageSize(),
+		Offset:              (req.GetPageId() - 1) * req.GetPageSize(),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list scheduled transfer runs: %s", err)
+	}
+
+	rsp := &pb.ListScheduledTransferRunsResponse{
+		Runs: make([]*pb.ScheduledTransferRun, 0, len(runs)),
+	}
+	for _, run := range runs {
+		rsp.Runs = append(rsp.Runs, convertScheduledTransferRun(run))
+	}
+
+	return rsp, nil
+}
+
+func (server *Server) getOwnedScheduledTransfer(ctx context.Context, id int64, payload *token.Payload) (db.ScheduledTransfer, error) {
+	scheduledTransfer, err := server.store.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return scheduledTransfer, status.Errorf(codes.NotFound, "scheduled transfer not found")
+		}
+		return scheduledTransfer, status.Errorf(codes.Internal, "failed to get scheduled transfer: %s", err)
+	}
+
+	if scheduledTransfer.Owner != payload.Username {
+		return scheduledTransfer, status.Errorf(codes.PermissionDenied, "cannot access this scheduled transfer")
+	}
+
+	return scheduledTransfer, nil
+}
+
+func validateCreateScheduledTransferRequest(req *pb.CreateScheduledTransferRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetFromAccountId() <= 0 {
+		violations = append(violations, fieldViolation("from_account_id", errors.New("must be a positive integer")))
+	}
+	if req.GetToAccountId() <= 0 {
+		violations = append(violations, fieldViolation("to_account_id", errors.New("must be a positive integer")))
+	}
+	if req.GetFromAccountId() == req.GetToAccountId() {
+		violations = append(violations, fieldViolation("to_account_id", errors.New("must be different from from_account_id")))
+	}
+	if req.GetAmount() <= 0 {
+		violations = append(violations, fieldViolation("amount", errors.New("must be greater than 0")))
+	}
+	if err := val.ValidateCurrency(req.GetCurrency()); err != nil {
+		violations = append(violations, fieldViolation("currency", err))
+	}
+
+	interval := time.Duration(req.GetIntervalSeconds()) * time.Second
+	if err := util.ValidateRecurrence(req.GetRecurrence(), req.GetCronExpression(), interval); err != nil {
+		violations = append(violations, fieldViolation("recurrence", err))
+	}
+
+	if req.StartAt != nil && req.StartAt.CheckValid() != nil {
+		violations = append(violations, fieldViolation("start_at", errors.New("must be a valid timestamp")))
+	}
+	if req.EndAt != nil && req.EndAt.CheckValid() != nil {
+		violations = append(violations, fieldViolation("end_at", errors.New("must be a valid timestamp")))
+	}
+	if req.MaxOccurrences != nil && req.GetMaxOccurrences() <= 0 {
+		violations = append(violations, fieldViolation("max_occurrences", errors.New("must be a positive integer")))
+	}
+
+	return violations
+}
+
+func validateUpdateScheduledTransferRequest(req *pb.UpdateScheduledTransferRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetId() <= 0 {
+		violations = append(violations, fieldViolation("id", errors.New("must be a positive integer")))
+	}
+	if req.Amount != nil && req.GetAmount() <= 0 {
+		violations = append(violations, fieldViolation("amount", errors.New("must be greater than 0")))
+	}
+	if req.EndAt != nil && req.EndAt.CheckValid() != nil {
+		violations = append(violations, fieldViolation("end_at", errors.New("must be a valid timestamp")))
+	}
+	if req.MaxOccurrences != nil && req.GetMaxOccurrences() <= 0 {
+		violations = append(violations, fieldViolation("max_occurrences", errors.New("must be a positive integer")))
+	}
+
+	return violations
+}
+
+func validatePage(idField string, id int64, pageID int32, pageSize int32) (violations []*errdetails.BadRequest_FieldViolation) {
+	if id <= 0 {
+		violations = append(violations, fieldViolation(idField, errors.New("must be a positive integer")))
+	}
+	if pageID <= 0 {
+		violations = append(violations, fieldViolation("page_id", errors.New("must be a positive integer")))
+	}
+	if pageSize < 5 || pageSize > 50 {
+		violations = append(violations, fieldViolation("page_size", errors.New("must be between 5 and 50")))
+	}
+
+	return violations
+}
@@ -0,0 +1,259 @@
+package gapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCreateScheduledTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t, util.DepositorRole)
+	user2, _ := randomUser(t, util.DepositorRole)
+
+	account1 := db.Account{ID: util.RandomInt(1, 1000), Owner: user1.Username, Currency: util.USD}
+	account2 := db.Account{ID: account1.ID + 1, Owner: user2.Username, Currency: util.USD}
+	startAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	cronExpression := "0 9 1 * *"
+
+	req := &pb.CreateScheduledTransferRequest{
+		FromAccountId:  account1.ID,
+		ToAccountId:    account2.ID,
+		Amount:         10,
+		Currency:       util.USD,
+		Recurrence:     util.RecurrenceCron,
+		CronExpression: cronExpression,
+		StartAt:        timestamppb.New(startAt),
+	}
+	nextRunAt, err := util.FirstRunTime(util.RecurrenceCron, cronExpression, startAt)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		req           *pb.CreateScheduledTransferRequest
+		buildStubs    func(store *mockdb.MockStore)
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checkResponse func(t *testing.T, res *pb.CreateScheduledTransferResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				arg := db.CreateScheduledTransferParams{
+					Owner:          user1.Username,
+					FromAccountID:  account1.ID,
+					ToAccountID:    account2.ID,
+					Amount:         req.GetAmount(),
+					Recurrence:     util.RecurrenceCron,
+					CronExpression: pgtype.Text{String: cronExpression, Valid: true},
+					NextRunAt:      nextRunAt,
+				}
+				store.EXPECT().CreateScheduledTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.ScheduledTransfer{
+					ID:             1,
+					Owner:          user1.Username,
+					Recurrence:     util.RecurrenceCron,
+					CronExpression: arg.CronExpression,
+					NextRunAt:      nextRunAt,
+					Status:         db.ScheduledTransferStatusActive,
+				}, nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user1.Username, user1.Role, time.Minute, token.TokenTypeAccessToken)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateScheduledTransferResponse, err error) {
+				require.NoError(t, err)
+				scheduledTransfer := res.GetScheduledTransfer()
+				require.Equal(t, cronExpression, scheduledTransfer.GetCronExpression())
+				require.Equal(t, db.ScheduledTransferStatusActive, scheduledTransfer.GetStatus())
+				require.True(t, nextRunAt.Equal(scheduledTransfer.GetNextRunAt().AsTime()))
+				require.Nil(t, scheduledTransfer.IntervalSeconds)
+			},
+		},
+		{
+			name: "PermissionDenied",
+			req:  req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().CreateScheduledTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user2.Username, user2.Role, time.Minute, token.TokenTypeAccessToken)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateScheduledTransferResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, st.Code())
+			},
+		},
+		{
+			name: "StepUpAmount",
+			req: &pb.CreateScheduledTransferRequest{
+				FromAccountId:  account1.ID,
+				ToAccountId:    account2.ID,
+				Amount:         testStepUpThreshold + 1,
+				Currency:       util.USD,
+				Recurrence:     util.RecurrenceCron,
+				CronExpression: cronExpression,
+				StartAt:        timestamppb.New(startAt),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().CreateScheduledTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user1.Username, user1.Role, time.Minute, token.TokenTypeAccessToken)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateScheduledTransferResponse, err error) {
+				requireStatusCode(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "IntervalTooShort",
+			req: &pb.CreateScheduledTransferRequest{
+				FromAccountId:   account1.ID,
+				ToAccountId:     account2.ID,
+				Amount:          10,
+				Currency:        util.USD,
+				Recurrence:      util.RecurrenceInterval,
+				IntervalSeconds: 1,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user1.Username, user1.Role, time.Minute, token.TokenTypeAccessToken)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateScheduledTransferResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			req:  req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateScheduledTransferResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := newTestServer(t, store, nil)
+			server.config.StepUpTransferThreshold = testStepUpThreshold
+
+			ctx := tc.buildContext(t, server.tokenMaker)
+			res, err := server.CreateScheduledTransfer(ctx, tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+func TestUpdateScheduledTransferAPI(t *testing.T) {
+	user, _ := randomUser(t, util.DepositorRole)
+
+	scheduledTransfer := db.ScheduledTransfer{
+		ID:            util.RandomInt(1, 1000),
+		Owner:         user.Username,
+		FromAccountID: util.RandomInt(1, 1000),
+		ToAccountID:   util.RandomInt(1001, 2000),
+		Amount:        util.RandomInt(1, testStepUpThreshold),
+		Recurrence:    util.RecurrenceOnce,
+		NextRunAt:     time.Now().Add(time.Hour),
+		Status:        db.ScheduledTransferStatusActive,
+	}
+
+	testCases := []struct {
+		name          string
+		amount        int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.UpdateScheduledTransferResponse, err error)
+	}{
+		{
+			name:   "OK",
+			amount: testStepUpThreshold,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+
+				arg := db.UpdateScheduledTransferParams{
+					ID:     scheduledTransfer.ID,
+					Amount: pgtype.Int8{Int64: testStepUpThreshold, Valid: true},
+				}
+				updated := scheduledTransfer
+				updated.Amount = testStepUpThreshold
+				store.EXPECT().UpdateScheduledTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(updated, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateScheduledTransferResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, int64(testStepUpThreshold), res.GetScheduledTransfer().GetAmount())
+			},
+		},
+		{
+			name:   "StepUpAmount",
+			amount: testStepUpThreshold + 1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().UpdateScheduledTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.UpdateScheduledTransferResponse, err error) {
+				requireStatusCode(t, err, codes.FailedPrecondition)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := newTestServer(t, store, nil)
+			server.config.StepUpTransferThreshold = testStepUpThreshold
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, user.Role, time.Minute, token.TokenTypeAccessToken)
+			res, err := server.UpdateScheduledTransfer(ctx, &pb.UpdateScheduledTransferRequest{
+				Id:     scheduledTransfer.ID,
+				Amount: &tc.amount,
+			})
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package gapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLogoutUserAPI(t *testing.T) {
+	user, _ := randomUser(t, util.DepositorRole)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore, refreshToken string, payload *token.Payload)
+		checkResponse func(t *testing.T, res *pb.LogoutUserResponse, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				session := db.Session{ID: payload.SessionID, Username: user.Username, RefreshToken: refreshToken}
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.SessionID)).Times(1).Return(session, nil)
+
+				arg := db.BlockSessionParams{ID: payload.SessionID, Username: user.Username}
+				session.IsBlocked = true
+				store.EXPECT().BlockSessionTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.LogoutUserResponse, err error) {
+				require.NoError(t, err)
+				require.True(t, res.GetSession().GetIsBlocked())
+			},
+		},
+		{
+			name: "MismatchedToken",
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				session := db.Session{ID: payload.SessionID, Username: user.Username, RefreshToken: "other"}
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.SessionID)).Times(1).Return(session, nil)
+				store.EXPECT().BlockSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LogoutUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, st.Code())
+			},
+		},
+		{
+			name: "SessionNotFound",
+			buildStubs: func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, db.ErrRecordNotFound)
+				store.EXPECT().BlockSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LogoutUserResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.NotFound, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			server := newTestServer(t, store, nil)
+			refreshToken, payload, err := server.tokenMaker.CreateToken(user.Username, user.Role, uuid.New(), time.Minute, token.TokenTypeRefreshToken)
+			require.NoError(t, err)
+
+			tc.buildStubs(store, refreshToken, payload)
+			res, err := server.LogoutUser(context.Background(), &pb.LogoutUserRequest{RefreshToken: refreshToken})
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+func TestRevokeSessionAPI(t *testing.T) {
+	user, _ := randomUser(t, util.DepositorRole)
+	sessionID := uuid.New()
+
+	testCases := []struct {
+		name          string
+		req           *pb.RevokeSessionRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.RevokeSessionResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.RevokeSessionRequest{SessionId: sessionID.String()},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.BlockSessionParams{ID: sessionID, Username: user.Username}
+				store.EXPECT().BlockSessionTx(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.Session{ID: sessionID, Username: user.Username, IsBlocked: true}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.RevokeSessionResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, sessionID.String(), res.GetSession().GetId())
+				require.True(t, res.GetSession().GetIsBlocked())
+			},
+		},
+		{
+			name: "OtherUsersSession",
+			req:  &pb.RevokeSessionRequest{SessionId: sessionID.String()},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockSessionTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, res *pb.RevokeSessionResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.NotFound, st.Code())
+			},
+		},
+		{
+			name: "InvalidSessionID",
+			req:  &pb.RevokeSessionRequest{SessionId: "invalid"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.RevokeSessionResponse, err error) {
+				require.Error(t, err)
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := newTestServer(t, store, nil)
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, user.Role, time.Minute, token.TokenTypeAccessToken)
+			res, err := server.RevokeSession(ctx, tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/Ian-Balijawa/simplebank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errIncorrectCode = errors.New("incorrect code")
+
+func (server *Server) VerifyLoginOTP(ctx context.Context, req *pb.VerifyLoginOTPRequest) (*pb.LoginUserResponse, error) {
+	violations := validateVerifyLoginOTPRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	challengePayload, err := server.tokenMaker.VerifyToken(req.GetChallengeToken(), token.TokenTypeLoginChallenge)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	clientIP := server.extractMetadata(ctx).ClientIP
+	reservation, err := server.reserveOTPAttempt(ctx, challengePayload, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := server.store.GetUser(ctx, challengePayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "user not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to find user")
+	}
+
+	userTOTP, err := server.store.GetUserTOTP(ctx, user.Username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return nil, status.Errorf(codes.Internal, "failed to get two-factor authentication: %s", err)
+	}
+	if err != nil || !userTOTP.ConfirmedAt.Valid {
+		return nil, status.Errorf(codes.FailedPrecondition, "two-factor authentication is not enabled")
+	}
+
+	ok, err := server.verifySecondFactor(ctx, userTOTP, req.GetCode())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to verify code: %s", err)
+	}
+	if !ok {
+		server.notifyLoginLockout(ctx, user.Username, clientIP, reservation)
+		return nil, unauthenticatedError(errIncorrectCode)
+	}
+
+	err = server.loginGuard.Release(ctx, reservation)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to release login attempt: %s", err)
+	}
+
+	// the failed logins are only forgotten once both factors are proven
+	err = server.loginGuard.RecordSuccess(ctx, user.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to reset failed logins: %s", err)
+	}
+
+	return server.createLoginSession(ctx, user)
+}
+
+func (server *Server) EnrollTOTP(ctx context.Context, req *pb.EnrollTOTPRequest) (*pb.EnrollTOTPResponse, error) {
+	authPayload, err := server.authorizeUser(ctx, []string{util.BankerRole, util.DepositorRole})
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	ctx = server.withAuditActor(ctx, authPayload.Username, authPayload.Role)
+
+	secret, err := util.RandomTOTPSecret()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%s", err)
+	}
+
+	userTOTP, err := server.store.UpsertUserTOTPTx(ctx, db.UpsertUserTOTPParams{
+		Username: authPayload.Username,
+		Secret:   secret,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.FailedPrecondition, "two-factor authentication is already enabled")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to enroll TOTP: %s", err)
+	}
+
+	rsp := &pb.EnrollTOTPResponse{
+		Secret:     userTOTP.Secret,
+		OtpauthUri: util.TOTPURI(util.TOTPIssuer, userTOTP.Username, userTOTP.Secret),
+	}
+	return rsp, nil
+}
+
+func (server *Server) ConfirmTOTP(ctx context.Context, req *pb.ConfirmTOTPRequest) (*pb.ConfirmTOTPResponse, error) {
+	authPayload, err := server.authorizeUser(ctx, []string{util.BankerRole, util.DepositorRole})
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	ctx = server.withAuditActor(ctx, authPayload.Username, authPayload.Role)
+
+	violations := validateOTPCode(req.GetCode())
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	userTOTP, err := server.store.GetUserTOTP(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.FailedPrecondition, "TOTP is not enrolled")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get two-factor authentication: %s", err)
+	}
+	if userTOTP.ConfirmedAt.Valid {
+		return nil, status.Errorf(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	}
+
+	err = server.reserveCodeAttempt(ctx, authPayload.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := util.ValidateTOTP(userTOTP.Secret, req.GetCode(), time.Now())
+	if !ok {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{
+			fieldViolation("code", errIncorrectCode),
+		})
+	}
+
+	err = server.loginGuard.RecordCodeSuccess(ctx, authPayload.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to reset incorrect codes: %s", err)
+	}
+
+	recoveryCodes, err := util.RandomRecoveryCodes(util.RecoveryCodeCount)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%s", err)
+	}
+
+	arg := db.ConfirmTOTPTxParams{
+		Username:           authPayload.Username,
+		Step:               step,
+		RecoveryCodeHashes: make([]string, 0, len(recoveryCodes)),
+	}
+	for _, code := range recoveryCodes {
+		arg.RecoveryCodeHashes = append(arg.RecoveryCodeHashes, util.HashRecoveryCode(code))
+	}
+
+	_, err = server.store.ConfirmTOTPTx(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.FailedPrecondition, "two-factor authentication is already enabled")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to confirm TOTP: %s", err)
+	}
+
+	rsp := &pb.ConfirmTOTPResponse{
+		RecoveryCodes: recoveryCodes,
+	}
+	return rsp, nil
+}
+
+func (server *Server) DisableTOTP(ctx context.Context, req *pb.DisableTOTPRequest) (*pb.DisableTOTPResponse, error) {
+	authPayload, err := server.authorizeUser(ctx, []string{util.BankerRole, util.DepositorRole})
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	ctx = server.withAuditActor(ctx, authPayload.Username, authPayload.Role)
+
+	violations := validateOTPCode(req.GetCode())
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	userTOTP, err := server.store.GetUserTOTP(ctx, authPayload.Username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return nil, status.Errorf(codes.Internal, "failed to get two-factor authentication: %s", err)
+	}
+	if err != nil || !userTOTP.ConfirmedAt.Valid {
+		return nil, status.Errorf(codes.FailedPrecondition, "two-factor authentication is not enabled")
+	}
+
+	err = server.reserveCodeAttempt(ctx, authPayload.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := server.verifySecondFactor(ctx, userTOTP, req.GetCode())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to verify code: %s", err)
+	}
+	if !ok {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{
+			fieldViolation("code", errIncorrectCode),
+		})
+	}
+
+	err = server.loginGuard.RecordCodeSuccess(ctx, authPayload.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to reset incorrect codes: %s", err)
+	}
+
+	err = server.store.DisableTOTPTx(ctx, authPayload.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to disable TOTP: %s", err)
+	}
+
+	return &pb.DisableTOTPResponse{}, nil
+}
+
+// isTOTPEnabled tells whether the user must send a one-time password to log in
+func (server *Server) isTOTPEnabled(ctx context.Context, username string) (bool, error) {
+	userTOTP, err := server.store.GetUserTOTP(ctx, username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return userTOTP.ConfirmedAt.Valid, nil
+}
+
+// verifySecondFactor checks a TOTP code or a recovery code of the user,
+// and marks it as used so it cannot be sent again
+func (server *Server) verifySecondFactor(ctx context.Context, userTOTP db.UserTotp, code string) (bool, error) {
+	var err error
+	if util.IsTOTPCode(code) {
+		step, ok := util.ValidateTOTP(userTOTP.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+
+		_, err = server.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+			Username: userTOTP.Username,
+			Step:     step,
+		})
+	} else {
+		_, err = server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			Username: userTOTP.Username,
+			CodeHash: util.HashRecoveryCode(code),
+		})
+	}
+
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func validateVerifyLoginOTPRequest(req *pb.VerifyLoginOTPRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetChallengeToken() == "" {
+		violations = append(violations, fieldViolation("challenge_token", errors.New("must not be empty")))
+	}
+
+	violations = append(violations, validateOTPCode(req.GetCode())...)
+	return violations
+}
+
+func validateOTPCode(code string) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateOTPCode(code); err != nil {
+		violations = append(violations, fieldViolation("code", err))
+	}
+	return violations
+}
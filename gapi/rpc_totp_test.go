@@ -0,0 +1,514 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/lockout"
+	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func randomUserTOTP(t *testing.T, username string, confirmed bool) db.UserTotp {
+	secret, err := util.RandomTOTPSecret()
+	require.NoError(t, err)
+
+	return db.UserTotp{
+		Username:    username,
+		Secret:      secret,
+		ConfirmedAt: pgtype.Timestamptz{Time: time.Now(), Valid: confirmed},
+	}
+}
+
+// incorrectTOTPCode returns a code the secret does not give at the moment
+func incorrectTOTPCode(secret string) string {
+	for i := 0; ; i++ {
+		code := fmt.Sprintf("%06d", i)
+		if _, ok := util.ValidateTOTP(secret, code, time.Now()); !ok {
+			return code
+		}
+	}
+}
+
+func requireStatusCode(t *testing.T, err error, code codes.Code) {
+	require.Error(t, err)
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, code, st.Code())
+}
+
+func TestLoginUserWithTOTP(t *testing.T) {
+	user, password := randomUser(t, util.DepositorRole)
+	userTOTP := randomUserTOTP(t, user.Username, true)
+
+	storeCtrl := gomock.NewController(t)
+	defer storeCtrl.Finish()
+	store := mockdb.NewMockStore(storeCtrl)
+
+	store.EXPECT().
+		ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.ReserveLoginAttemptTxResult{Failure: db.LoginFailure{FailedCount: 1}}, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().DeleteLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+	store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store, nil)
+	res, err := server.LoginUser(context.Background(), &pb.LoginUserRequest{
+		Username: user.Username,
+		Password: password,
+	})
+	require.NoError(t, err)
+	require.True(t, res.GetOtpRequired())
+	require.Empty(t, res.GetAccessToken())
+	require.Empty(t, res.GetRefreshToken())
+
+	// the challenge token cannot be used as an access token
+	payload, err := server.tokenMaker.VerifyToken(res.GetChallengeToken(), token.TokenTypeLoginChallenge)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, payload.Username)
+	_, err = server.tokenMaker.VerifyToken(res.GetChallengeToken(), token.TokenTypeAccessToken)
+	require.Error(t, err)
+}
+
+func TestVerifyLoginOTPAPI(t *testing.T) {
+	user, _ := randomUser(t, util.DepositorRole)
+	userTOTP := randomUserTOTP(t, user.Username, true)
+
+	code, err := util.TOTPCode(userTOTP.Secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	recoveryCode := "abcde-fghij"
+
+	testCases := []struct {
+		name          string
+		code          string
+		tokenType     token.TokenType
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.LoginUserResponse, err error)
+	}{
+		{
+			name:      "OK",
+			code:      code,
+			tokenType: token.TokenTypeLoginChallenge,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOTPAttempt(store, 1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				arg := db.UseTOTPStepParams{Username: user.Username, Step: util.TOTPStep(time.Now())}
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Eq(arg)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().
+					DeleteLoginFailure(gomock.Any(), gomock.Eq(db.DeleteLoginFailureParams{
+						Kind:    lockout.KindUsername,
+						Subject: user.Username,
+					})).
+					Times(1).
+					Return(nil)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.False(t, res.GetOtpRequired())
+				require.NotEmpty(t, res.GetAccessToken())
+				require.NotEmpty(t, res.GetRefreshToken())
+			},
+		},
+		{
+			name:      "RecoveryCode",
+			code:      recoveryCode,
+			tokenType: token.TokenTypeLoginChallenge,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOTPAttempt(store, 1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				arg := db.UseRecoveryCodeParams{Username: user.Username, CodeHash: util.HashRecoveryCode(recoveryCode)}
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TotpRecoveryCode{}, nil)
+				store.EXPECT().DeleteLoginFailure(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.NotEmpty(t, res.GetAccessToken())
+			},
+		},
+		{
+			name:      "ReplayedCode",
+			code:      code,
+			tokenType: token.TokenTypeLoginChallenge,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOTPAttempt(store, 1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().DeleteLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+			},
+		},
+		{
+			name:      "IncorrectCode",
+			code:      incorrectTOTPCode(userTOTP.Secret),
+			tokenType: token.TokenTypeLoginChallenge,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOTPAttempt(store, 1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+			},
+		},
+		{
+			name:      "AccessTokenAsChallenge",
+			code:      code,
+			tokenType: token.TokenTypeAccessToken,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+			},
+		},
+		{
+			name:      "ChallengeSpent",
+			code:      code,
+			tokenType: token.TokenTypeLoginChallenge,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOTPAttempt(store, lockout.ChallengeAttempts+1)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				requireStatusCode(t, err, codes.Unauthenticated)
+				require.Contains(t, err.Error(), lockout.ErrChallengeSpent.Error())
+			},
+		},
+		{
+			name:      "TooManyAttempts",
+			code:      code,
+			tokenType: token.TokenTypeLoginChallenge,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(db.ReserveLoginAttemptTxResult{
+						Failure:          db.LoginFailure{FailedCount: lockout.UsernameLockoutThreshold + 1},
+						PreviousFailedAt: time.Now(),
+					}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.LoginUserResponse, err error) {
+				requireStatusCode(t, err, codes.ResourceExhausted)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := newTestServer(t, store, nil)
+
+			challengeToken, _, err := server.tokenMaker.CreateToken(user.Username, user.Role, uuid.Nil, time.Minute, tc.tokenType)
+			require.NoError(t, err)
+
+			res, err := server.VerifyLoginOTP(context.Background(), &pb.VerifyLoginOTPRequest{
+				ChallengeToken: challengeToken,
+				Code:           tc.code,
+			})
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+// expectOTPAttempt expects a one-time password to be counted against the username
+// and the login challenge, which then has challengeFailedCount failures
+func expectOTPAttempt(store *mockdb.MockStore, challengeFailedCount int32) {
+	store.EXPECT().
+		ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureParams) (db.ReserveLoginAttemptTxResult, error) {
+			if arg.Kind == lockout.KindLoginChallenge {
+				return db.ReserveLoginAttemptTxResult{Failure: db.LoginFailure{FailedCount: challengeFailedCount}}, nil
+			}
+			return db.ReserveLoginAttemptTxResult{Failure: db.LoginFailure{FailedCount: 2}}, nil
+		})
+}
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t, util.DepositorRole)
+	pending := randomUserTOTP(t, user.Username, false)
+
+	code, err := util.TOTPCode(pending.Secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		code          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.ConfirmTOTPResponse, err error)
+	}{
+		{
+			name: "OK",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(pending, nil)
+				expectCodeAttempt(store, user.Username, 1)
+				expectCodeSuccess(store, user.Username)
+				store.EXPECT().
+					ConfirmTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ConfirmTOTPTxParams) (db.ConfirmTOTPTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, util.TOTPStep(time.Now()), arg.Step)
+						require.Len(t, arg.RecoveryCodeHashes, util.RecoveryCodeCount)
+						return db.ConfirmTOTPTxResult{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTOTPResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetRecoveryCodes(), util.RecoveryCodeCount)
+			},
+		},
+		{
+			name: "IncorrectCode",
+			code: incorrectTOTPCode(pending.Secret),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(pending, nil)
+				expectCodeAttempt(store, user.Username, 1)
+				store.EXPECT().DeleteLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ConfirmTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTOTPResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "TooManyAttempts",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(pending, nil)
+				expectCodeAttempt(store, user.Username, lockout.UsernameLockoutThreshold+1)
+				store.EXPECT().ConfirmTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTOTPResponse, err error) {
+				requireStatusCode(t, err, codes.ResourceExhausted)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(randomUserTOTP(t, user.Username, true), nil)
+				store.EXPECT().ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ConfirmTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTOTPResponse, err error) {
+				requireStatusCode(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().ConfirmTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTOTPResponse, err error) {
+				requireStatusCode(t, err, codes.FailedPrecondition)
+			},
+		},
+		{
+			name: "InvalidCode",
+			code: "12",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.ConfirmTOTPResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := newTestServer(t, store, nil)
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, user.Role, time.Minute, token.TokenTypeAccessToken)
+			res, err := server.ConfirmTOTP(ctx, &pb.ConfirmTOTPRequest{Code: tc.code})
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+func TestDisableTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t, util.DepositorRole)
+	userTOTP := randomUserTOTP(t, user.Username, true)
+
+	code, err := util.TOTPCode(userTOTP.Secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		code          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.DisableTOTPResponse, err error)
+	}{
+		{
+			name: "OK",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				expectCodeAttempt(store, user.Username, 1)
+				arg := db.UseTOTPStepParams{Username: user.Username, Step: util.TOTPStep(time.Now())}
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Eq(arg)).Times(1).Return(userTOTP, nil)
+				expectCodeSuccess(store, user.Username)
+				store.EXPECT().DisableTOTPTx(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, res *pb.DisableTOTPResponse, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "IncorrectCode",
+			code: incorrectTOTPCode(userTOTP.Secret),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				expectCodeAttempt(store, user.Username, 1)
+				store.EXPECT().DeleteLoginFailure(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DisableTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.DisableTOTPResponse, err error) {
+				requireStatusCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "TooManyAttempts",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+				expectCodeAttempt(store, user.Username, lockout.UsernameLockoutThreshold+1)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DisableTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.DisableTOTPResponse, err error) {
+				requireStatusCode(t, err, codes.ResourceExhausted)
+			},
+		},
+		{
+			name: "NotEnabled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DisableTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.DisableTOTPResponse, err error) {
+				requireStatusCode(t, err, codes.FailedPrecondition)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			storeCtrl := gomock.NewController(t)
+			defer storeCtrl.Finish()
+			store := mockdb.NewMockStore(storeCtrl)
+
+			tc.buildStubs(store)
+			server := newTestServer(t, store, nil)
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, user.Role, time.Minute, token.TokenTypeAccessToken)
+			res, err := server.DisableTOTP(ctx, &pb.DisableTOTPRequest{Code: tc.code})
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+// expectCodeAttempt expects a code sent to change the two-factor authentication of the username to be counted,
+// which then has failedCount incorrect codes
+func expectCodeAttempt(store *mockdb.MockStore, username string, failedCount int32) {
+	store.EXPECT().
+		ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureParams) (db.ReserveLoginAttemptTxResult, error) {
+			if arg.Kind != lockout.KindTOTPCode || arg.Subject != username {
+				return db.ReserveLoginAttemptTxResult{}, fmt.Errorf("unexpected login failure %s %s", arg.Kind, arg.Subject)
+			}
+			return db.ReserveLoginAttemptTxResult{
+				Failure: db.LoginFailure{FailedCount: failedCount},
+				// the previous code was sent a moment ago, so only a locked out user has to wait
+				PreviousFailedAt: time.Now().Add(-time.Second),
+			}, nil
+		})
+}
+
+// expectCodeSuccess expects the incorrect codes of the username to be forgotten
+func expectCodeSuccess(store *mockdb.MockStore, username string) {
+	store.EXPECT().
+		DeleteLoginFailure(gomock.Any(), gomock.Eq(db.DeleteLoginFailureParams{
+			Kind:    lockout.KindTOTPCode,
+			Subject: username,
+		})).
+		Times(1).
+		Return(nil)
+}
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t, util.DepositorRole)
+
+	storeCtrl := gomock.NewController(t)
+	defer storeCtrl.Finish()
+	store := mockdb.NewMockStore(storeCtrl)
+
+	store.EXPECT().
+		UpsertUserTOTPTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.UpsertUserTOTPParams) (db.UserTotp, error) {
+			return db.UserTotp{Username: arg.Username, Secret: arg.Secret}, nil
+		})
+
+	server := newTestServer(t, store, nil)
+	ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, user.Role, time.Minute, token.TokenTypeAccessToken)
+
+	res, err := server.EnrollTOTP(ctx, &pb.EnrollTOTPRequest{})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.GetSecret())
+	require.Contains(t, res.GetOtpauthUri(), "secret="+res.GetSecret())
+
+	store.EXPECT().UpsertUserTOTPTx(gomock.Any(), gomock.Any()).Times(1).Return(db.UserTotp{}, db.ErrRecordNotFound)
+	_, err = server.EnrollTOTP(ctx, &pb.EnrollTOTPRequest{})
+	requireStatusCode(t, err, codes.FailedPrecondition)
+}
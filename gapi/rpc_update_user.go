@@ -21,6 +21,8 @@ func (server *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 		return nil, unauthenticatedError(err)
 	}
 
+	ctx = server.withAuditActor(ctx, authPayload.Username, authPayload.Role)
+
 	violations := validateUpdateUserRequest(req)
 	if violations != nil {
 		return nil, invalidArgumentError(violations)
@@ -59,7 +61,15 @@ func (server *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 		}
 	}
 
-	user, err := server.store.UpdateUser(ctx, arg)
+	var user db.User
+	if req.Password != nil {
+		// a new password logs the user out everywhere
+		var result db.ChangePasswordTxResult
+		result, err = server.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{UpdateUserParams: arg})
+		user = result.User
+	} else {
+		user, err = server.store.UpdateUserTx(ctx, arg)
+	}
 	if err != nil {
 		if errors.Is(err, db.ErrRecordNotFound) {
 			return nil, status.Errorf(codes.NotFound, "user not found")
@@ -2,10 +2,15 @@ package gapi
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
 	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/util"
 	"github.com/Ian-Balijawa/simplebank/val"
+	"github.com/Ian-Balijawa/simplebank/worker"
+	"github.com/hibiken/asynq"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -17,11 +22,15 @@ func (server *Server) VerifyEmail(ctx context.Context, req *pb.VerifyEmailReques
 		return nil, invalidArgumentError(violations)
 	}
 
-	txResult, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+	txResult, err := server.store.VerifyEmailTx(server.withAuditActor(ctx, "", ""), db.VerifyEmailTxParams{
 		EmailId:    req.GetEmailId(),
 		SecretCode: req.GetSecretCode(),
 	})
 	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			err = errors.New("code is incorrect, used or expired")
+			return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation("secret_code", err)})
+		}
 		return nil, status.Errorf(codes.Internal, "failed to verify email")
 	}
 
@@ -42,3 +51,81 @@ func validateVerifyEmailRequest(req *pb.VerifyEmailRequest) (violations []*errde
 
 	return violations
 }
+
+var (
+	errEmailAlreadyVerified = errors.New("email is already verified")
+	errEmailNotVerified     = errors.New("email address is not verified")
+	errVerifyEmailCooldown  = errors.New("a verification email was sent recently")
+)
+
+func (server *Server) ResendVerifyEmail(ctx context.Context, req *pb.ResendVerifyEmailRequest) (*pb.ResendVerifyEmailResponse, error) {
+	authPayload, err := server.authorizeUser(ctx, []string{util.BankerRole, util.DepositorRole})
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "user not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get user: %s", err)
+	}
+
+	if user.IsEmailVerified {
+		return nil, status.Error(codes.FailedPrecondition, errEmailAlreadyVerified.Error())
+	}
+
+	cooldown := server.config.VerifyEmailCooldown()
+	lastVerifyEmail, err := server.store.GetLastVerifyEmail(ctx, user.Username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return nil, status.Errorf(codes.Internal, "failed to get last verify email: %s", err)
+	}
+	if err == nil {
+		if wait := time.Until(lastVerifyEmail.CreatedAt.Add(cooldown)); wait > 0 {
+			return nil, resourceExhaustedStatus(errVerifyEmailCooldown, wait).Err()
+		}
+	}
+
+	if server.taskDistributor == nil {
+		return nil, status.Errorf(codes.Internal, "verification emails cannot be sent")
+	}
+
+	// the task is unique during the cooldown, so resends cannot pile up before its email is created
+	err = server.taskDistributor.DistributeTaskSendVerifyEmail(
+		ctx,
+		&worker.PayloadSendVerifyEmail{Username: user.Username},
+		asynq.MaxRetry(10),
+		asynq.Queue(worker.QueueCritical),
+		asynq.Unique(cooldown),
+	)
+	if err != nil {
+		if errors.Is(err, asynq.ErrDuplicateTask) {
+			return nil, resourceExhaustedStatus(errVerifyEmailCooldown, cooldown).Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to distribute task to send verify email: %s", err)
+	}
+
+	return &pb.ResendVerifyEmailResponse{}, nil
+}
+
+// requireVerifiedEmail returns a FailedPrecondition error if the policy requires a verified email
+// and the email address of the user is not verified yet
+func (server *Server) requireVerifiedEmail(ctx context.Context, username string) error {
+	if !server.config.RequireVerifiedEmail {
+		return nil
+	}
+
+	user, err := server.store.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return status.Errorf(codes.NotFound, "user not found")
+		}
+		return status.Errorf(codes.Internal, "failed to get user: %s", err)
+	}
+
+	if !user.IsEmailVerified {
+		return status.Error(codes.FailedPrecondition, errEmailNotVerified.Error())
+	}
+	return nil
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 
 	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/lockout"
 	"github.com/Ian-Balijawa/simplebank/pb"
+	"github.com/Ian-Balijawa/simplebank/ratelimit"
+	"github.com/Ian-Balijawa/simplebank/revocation"
 	"github.com/Ian-Balijawa/simplebank/token"
 	"github.com/Ian-Balijawa/simplebank/util"
 	"github.com/Ian-Balijawa/simplebank/worker"
@@ -16,21 +19,53 @@ type Server struct {
 	config          util.Config
 	store           db.Store
 	tokenMaker      token.Maker
+	tokenChecker    revocation.Checker
+	loginGuard      lockout.Guard
+	rateLimiter     ratelimit.Limiter
+	rateLimitPolicy ratelimit.Policy
 	taskDistributor worker.TaskDistributor
+	fxRateProvider  util.FXRateProvider
+	trustedProxies  util.TrustedProxies
 }
 
 // NewServer creates a new gRPC server.
 func NewServer(config util.Config, store db.Store, taskDistributor worker.TaskDistributor) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	tokenMaker, err := token.NewMakerFromConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	fxRateProvider, err := util.LoadStaticFXRateProvider(config.FXRatesFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create exchange rate provider: %w", err)
+	}
+
+	rateLimiter, err := ratelimit.NewLimiterFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limiter: %w", err)
+	}
+
+	rateLimitPolicy, err := ratelimit.NewPolicyFromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limit policy: %w", err)
+	}
+
+	trustedProxies, err := util.ParseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse trusted proxies: %w", err)
+	}
+
 	server := &Server{
 		config:          config,
 		store:           store,
 		tokenMaker:      tokenMaker,
+		tokenChecker:    revocation.NewCache(store, config.TokenRevocationCacheTTL),
+		loginGuard:      lockout.NewTracker(store),
+		rateLimiter:     rateLimiter,
+		rateLimitPolicy: rateLimitPolicy,
 		taskDistributor: taskDistributor,
+		fxRateProvider:  fxRateProvider,
+		trustedProxies:  trustedProxies,
 	}
 
 	return server, nil
@@ -0,0 +1,165 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+)
+
+// DefaultBatchSize is how many accounts are checked per batch, unless told otherwise
+const DefaultBatchSize = 500
+
+// Report lists every inconsistency found in the ledger
+type Report struct {
+	CheckedAccounts     int                  `json:"checked_accounts"`
+	BalanceDrifts       []BalanceDrift       `json:"balance_drifts"`
+	OrphanedEntries     []db.Entry           `json:"orphaned_entries"`
+	UnbalancedTransfers []UnbalancedTransfer `json:"unbalanced_transfers"`
+	UnbalancedJournals  []UnbalancedJournal  `json:"unbalanced_journals"`
+}
+
+// BalanceDrift is an account whose balance differs from the sum of its entries
+type BalanceDrift struct {
+	AccountID    int64  `json:"account_id"`
+	Currency     string `json:"currency"`
+	Balance      int64  `json:"balance"`
+	EntriesTotal int64  `json:"entries_total"`
+	// Drift is how much the balance exceeds the sum of the entries
+	Drift int64 `json:"drift"`
+}
+
+// UnbalancedTransfer is a transfer whose journal transaction doesn't debit its amount from the from account
+// and credit its to amount to the to account
+type UnbalancedTransfer struct {
+	TransferID       int64 `json:"transfer_id"`
+	FromAccountID    int64 `json:"from_account_id"`
+	ToAccountID      int64 `json:"to_account_id"`
+	Amount           int64 `json:"amount"`
+	ToAmount         int64 `json:"to_amount"`
+	EntryCount       int32 `json:"entry_count"`
+	FromEntriesTotal int64 `json:"from_entries_total"`
+	ToEntriesTotal   int64 `json:"to_entries_total"`
+}
+
+// UnbalancedJournal is a journal transaction whose entries don't sum to zero in a currency
+type UnbalancedJournal struct {
+	JournalTransactionID int64  `json:"journal_transaction_id"`
+	Currency             string `json:"currency"`
+	Total                int64  `json:"total"`
+}
+
+// Consistent reports whether no inconsistency was found
+func (report *Report) Consistent() bool {
+	return len(report.BalanceDrifts) == 0 &&
+		len(report.OrphanedEntries) == 0 &&
+		len(report.UnbalancedTransfers) == 0 &&
+		len(report.UnbalancedJournals) == 0
+}
+
+// Verify scans the accounts in batches of batchSize and reports the balances that drifted from their entries,
+// the entries of no journal transaction and the transfers whose entries don't match them.
+// It then scans the journal transactions in batches of batchSize and reports those that don't sum to zero.
+// Each check reads a single snapshot, so transfers made during the scan don't show up as inconsistencies.
+func Verify(ctx context.Context, store db.Querier, batchSize int32) (*Report, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive")
+	}
+
+	report := &Report{
+		BalanceDrifts:       []BalanceDrift{},
+		OrphanedEntries:     []db.Entry{},
+		UnbalancedTransfers: []UnbalancedTransfer{},
+		UnbalancedJournals:  []UnbalancedJournal{},
+	}
+
+	afterID := int64(0)
+	for {
+		accounts, err := store.ListAccountLedgerTotals(ctx, db.ListAccountLedgerTotalsParams{
+			AfterID:   afterID,
+			BatchSize: batchSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list account totals: %w", err)
+		}
+		if len(accounts) == 0 {
+			break
+		}
+
+		for _, account := range accounts {
+			if account.Balance != account.EntriesTotal {
+				report.BalanceDrifts = append(report.BalanceDrifts, BalanceDrift{
+					AccountID:    account.ID,
+					Currency:     account.Currency,
+					Balance:      account.Balance,
+					EntriesTotal: account.EntriesTotal,
+					Drift:        account.Balance - account.EntriesTotal,
+				})
+			}
+		}
+		report.CheckedAccounts += len(accounts)
+
+		// the accounts of a batch are the only ones within its ID range
+		fromAccountID := accounts[0].ID
+		toAccountID := accounts[len(accounts)-1].ID
+
+		entries, err := store.ListOrphanedEntries(ctx, db.ListOrphanedEntriesParams{
+			FromAccountID: fromAccountID,
+			ToAccountID:   toAccountID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list orphaned entries: %w", err)
+		}
+		report.OrphanedEntries = append(report.OrphanedEntries, entries...)
+
+		transfers, err := store.ListUnbalancedTransfers(ctx, db.ListUnbalancedTransfersParams{
+			FromAccountID: fromAccountID,
+			ToAccountID:   toAccountID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list unbalanced transfers: %w", err)
+		}
+		for _, transfer := range transfers {
+			report.UnbalancedTransfers = append(report.UnbalancedTransfers, UnbalancedTransfer{
+				TransferID:       transfer.ID,
+				FromAccountID:    transfer.FromAccountID,
+				ToAccountID:      transfer.ToAccountID,
+				Amount:           transfer.Amount,
+				ToAmount:         transfer.ToAmount,
+				EntryCount:       transfer.EntryCount,
+				FromEntriesTotal: transfer.FromEntriesTotal,
+				ToEntriesTotal:   transfer.ToEntriesTotal,
+			})
+		}
+
+		if len(accounts) < int(batchSize) {
+			break
+		}
+		afterID = toAccountID
+	}
+
+	// journal transactions are never deleted, so their IDs are scanned in fixed ranges
+	lastJournalID, err := store.GetLastJournalTransactionID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last journal transaction: %w", err)
+	}
+
+	for fromID := int64(1); fromID <= lastJournalID; fromID += int64(batchSize) {
+		journals, err := store.ListUnbalancedJournalTransactions(ctx, db.ListUnbalancedJournalTransactionsParams{
+			FromID: fromID,
+			ToID:   fromID + int64(batchSize) - 1,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list unbalanced journal transactions: %w", err)
+		}
+		for _, journal := range journals {
+			report.UnbalancedJournals = append(report.UnbalancedJournals, UnbalancedJournal{
+				JournalTransactionID: journal.JournalTransactionID,
+				Currency:             journal.Currency,
+				Total:                journal.Total,
+			})
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,129 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	firstBatch := []db.ListAccountLedgerTotalsRow{
+		{ID: 1, Currency: util.USD, Balance: 100, EntriesTotal: 100},
+		{ID: 3, Currency: util.EUR, Balance: 80, EntriesTotal: 50},
+	}
+	secondBatch := []db.ListAccountLedgerTotalsRow{
+		{ID: 4, Currency: util.USD, Balance: 0, EntriesTotal: 0},
+	}
+	orphan := db.Entry{ID: 7, AccountID: 4, Amount: 10}
+	unbalanced := db.ListUnbalancedTransfersRow{
+		ID:               9,
+		FromAccountID:    1,
+		ToAccountID:      3,
+		Amount:           20,
+		ToAmount:         20,
+		EntryCount:       1,
+		FromEntriesTotal: -20,
+	}
+
+	gomock.InOrder(
+		store.EXPECT().
+			ListAccountLedgerTotals(gomock.Any(), gomock.Eq(db.ListAccountLedgerTotalsParams{AfterID: 0, BatchSize: 2})).
+			Times(1).
+			Return(firstBatch, nil),
+		store.EXPECT().
+			ListAccountLedgerTotals(gomock.Any(), gomock.Eq(db.ListAccountLedgerTotalsParams{AfterID: 3, BatchSize: 2})).
+			Times(1).
+			Return(secondBatch, nil),
+	)
+	store.EXPECT().
+		ListOrphanedEntries(gomock.Any(), gomock.Eq(db.ListOrphanedEntriesParams{FromAccountID: 1, ToAccountID: 3})).
+		Times(1).
+		Return(nil, nil)
+	store.EXPECT().
+		ListOrphanedEntries(gomock.Any(), gomock.Eq(db.ListOrphanedEntriesParams{FromAccountID: 4, ToAccountID: 4})).
+		Times(1).
+		Return([]db.Entry{orphan}, nil)
+	store.EXPECT().
+		ListUnbalancedTransfers(gomock.Any(), gomock.Eq(db.ListUnbalancedTransfersParams{FromAccountID: 1, ToAccountID: 3})).
+		Times(1).
+		Return([]db.ListUnbalancedTransfersRow{unbalanced}, nil)
+	store.EXPECT().
+		ListUnbalancedTransfers(gomock.Any(), gomock.Eq(db.ListUnbalancedTransfersParams{FromAccountID: 4, ToAccountID: 4})).
+		Times(1).
+		Return(nil, nil)
+
+	store.EXPECT().
+		GetLastJournalTransactionID(gomock.Any()).
+		Times(1).
+		Return(int64(3), nil)
+	store.EXPECT().
+		ListUnbalancedJournalTransactions(gomock.Any(), gomock.Eq(db.ListUnbalancedJournalTransactionsParams{FromID: 1, ToID: 2})).
+		Times(1).
+		Return(nil, nil)
+	store.EXPECT().
+		ListUnbalancedJournalTransactions(gomock.Any(), gomock.Eq(db.ListUnbalancedJournalTransactionsParams{FromID: 3, ToID: 4})).
+		Times(1).
+		Return([]db.ListUnbalancedJournalTransactionsRow{{JournalTransactionID: 3, Currency: util.EUR, Total: 5}}, nil)
+
+	report, err := Verify(context.Background(), store, 2)
+	require.NoError(t, err)
+	require.False(t, report.Consistent())
+	require.Equal(t, 3, report.CheckedAccounts)
+
+	require.Equal(t, []BalanceDrift{
+		{AccountID: 3, Currency: util.EUR, Balance: 80, EntriesTotal: 50, Drift: 30},
+	}, report.BalanceDrifts)
+	require.Equal(t, []db.Entry{orphan}, report.OrphanedEntries)
+	require.Equal(t, []UnbalancedTransfer{
+		{
+			TransferID:       9,
+			FromAccountID:    1,
+			ToAccountID:      3,
+			Amount:           20,
+			ToAmount:         20,
+			EntryCount:       1,
+			FromEntriesTotal: -20,
+		},
+	}, report.UnbalancedTransfers)
+}
+
+func TestVerifyConsistent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	// a full batch is followed by an empty one
+	gomock.InOrder(
+		store.EXPECT().
+			ListAccountLedgerTotals(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return([]db.ListAccountLedgerTotalsRow{{ID: 5, Balance: 10, EntriesTotal: 10}}, nil),
+		store.EXPECT().
+			ListAccountLedgerTotals(gomock.Any(), gomock.Eq(db.ListAccountLedgerTotalsParams{AfterID: 5, BatchSize: 1})).
+			Times(1).
+			Return(nil, nil),
+	)
+	store.EXPECT().ListOrphanedEntries(gomock.Any(), gomock.Any()).Times(1).Return(nil, nil)
+	store.EXPECT().ListUnbalancedTransfers(gomock.Any(), gomock.Any()).Times(1).Return(nil, nil)
+	store.EXPECT().GetLastJournalTransactionID(gomock.Any()).Times(1).Return(int64(1), nil)
+	store.EXPECT().
+		ListUnbalancedJournalTransactions(gomock.Any(), gomock.Eq(db.ListUnbalancedJournalTransactionsParams{FromID: 1, ToID: 1})).
+		Times(1).
+		Return(nil, nil)
+
+	report, err := Verify(context.Background(), store, 1)
+	require.NoError(t, err)
+	require.True(t, report.Consistent())
+	require.Equal(t, 1, report.CheckedAccounts)
+	require.NotNil(t, report.OrphanedEntries)
+	require.NotNil(t, report.UnbalancedJournals)
+}
@@ -0,0 +1,247 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+)
+
+// Kinds of subjects whose failed logins are counted
+const (
+	KindUsername = "username"
+	KindClientIP = "client_ip"
+	// KindLoginChallenge counts the one-time passwords sent with a login challenge token, by its ID
+	KindLoginChallenge = "login_challenge"
+	// KindTOTPCode counts the incorrect codes a signed-in user sends to confirm or disable two-factor authentication
+	KindTOTPCode = "totp_code"
+)
+
+const (
+	// freeAttempts is the number of failed logins allowed before the delays start
+	freeAttempts = 3
+	// baseDelay is the wait after the first failure over freeAttempts, it doubles with each further failure
+	baseDelay = time.Second
+	// maxDelay bounds the wait before the lockout threshold is reached
+	maxDelay = time.Minute
+
+	// UsernameLockoutThreshold is the number of failed logins that locks out a username
+	UsernameLockoutThreshold = 10
+	// ClientIPLockoutThreshold is the number of failed logins that locks out a client IP,
+	// it is higher since many users may share an address
+	ClientIPLockoutThreshold = 50
+	// LockoutDuration is how long a locked out username or client IP cannot log in.
+	// Failures older than that are forgotten.
+	LockoutDuration = 15 * time.Minute
+
+	// ChallengeAttempts is the number of one-time passwords that may be sent with a login challenge,
+	// after which the user has to log in with their password again
+	ChallengeAttempts = 5
+)
+
+// ErrTooManyAttempts is returned when a login is attempted before the delay of the previous failures is over
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+// ErrChallengeSpent is returned when a login challenge has no attempts left
+var ErrChallengeSpent = errors.New("too many incorrect codes, log in again")
+
+// Guard limits how fast passwords can be guessed
+type Guard interface {
+	// Reserve counts a login attempt as failed before the password is checked,
+	// so that concurrent guesses cannot all get in before the first of them is counted
+	Reserve(ctx context.Context, username string, clientIP string) (Reservation, error)
+	// ReserveChallenge counts a one-time password sent with a login challenge as failed before it is checked.
+	// It is counted against the username and client IP like a password, and against the challenge.
+	ReserveChallenge(ctx context.Context, username string, challengeID string, clientIP string) (Reservation, error)
+	// Release takes back the attempt the reservation counted against the client IP, once its password or code was correct,
+	// so that the users sharing an address are not locked out by logging in
+	Release(ctx context.Context, reservation Reservation) error
+	// RecordSuccess forgets the failed logins of the username
+	RecordSuccess(ctx context.Context, username string) error
+	// ReserveCode counts a code sent by a signed-in user to change their two-factor authentication as incorrect
+	// before it is checked, so that a stolen session cannot guess codes any faster than a password
+	ReserveCode(ctx context.Context, username string) (Reservation, error)
+	// RecordCodeSuccess forgets the incorrect codes of the username
+	RecordCodeSuccess(ctx context.Context, username string) error
+}
+
+// Reservation is a login attempt counted as failed until it succeeds
+type Reservation struct {
+	// Wait is how long the username or client IP must wait before trying to log in again,
+	// or zero if the attempt may go ahead
+	Wait time.Duration
+	// LockedOut is true when the attempt, unless it succeeds, is the failure that locks out the username
+	LockedOut bool
+	// ChallengeSpent is true when the login challenge of the attempt has no attempts left
+	ChallengeSpent bool
+
+	// clientIP is counted against unless it is empty, ipCount is the attempt counted against it
+	clientIP string
+	ipCount  counted
+}
+
+// counted is an attempt counted against a subject
+type counted struct {
+	failedAt         time.Time
+	previousFailedAt time.Time
+}
+
+// Store is the part of the database failed logins are counted in
+type Store interface {
+	ReserveLoginAttemptTx(ctx context.Context, arg db.RecordLoginFailureParams) (db.ReserveLoginAttemptTxResult, error)
+	RefundLoginFailure(ctx context.Context, arg db.RefundLoginFailureParams) error
+	DeleteLoginFailure(ctx context.Context, arg db.DeleteLoginFailureParams) error
+}
+
+// Tracker is a Guard that counts failed logins in the database,
+// so the limits hold across all server instances
+type Tracker struct {
+	store Store
+}
+
+// NewTracker creates a new Tracker counting failed logins in the store
+func NewTracker(store Store) *Tracker {
+	return &Tracker{
+		store: store,
+	}
+}
+
+func (tracker *Tracker) Reserve(ctx context.Context, username string, clientIP string) (Reservation, error) {
+	now := time.Now()
+
+	failedCount, wait, _, err := tracker.reserve(ctx, KindUsername, username, UsernameLockoutThreshold, now)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	reservation := Reservation{
+		Wait:      wait,
+		LockedOut: failedCount == UsernameLockoutThreshold,
+	}
+
+	clientIP = normalizeClientIP(clientIP)
+	if clientIP == "" {
+		return reservation, nil
+	}
+
+	_, ipWait, ipCount, err := tracker.reserve(ctx, KindClientIP, clientIP, ClientIPLockoutThreshold, now)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	reservation.Wait = max(reservation.Wait, ipWait)
+	reservation.clientIP = clientIP
+	reservation.ipCount = ipCount
+	return reservation, nil
+}
+
+func (tracker *Tracker) ReserveChallenge(ctx context.Context, username string, challengeID string, clientIP string) (Reservation, error) {
+	reservation, err := tracker.Reserve(ctx, username, clientIP)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	// the codes of a challenge are limited by their number, not delayed
+	failedCount, _, _, err := tracker.reserve(ctx, KindLoginChallenge, challengeID, ChallengeAttempts, time.Now())
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	reservation.ChallengeSpent = failedCount > ChallengeAttempts
+	return reservation, nil
+}
+
+func (tracker *Tracker) Release(ctx context.Context, reservation Reservation) error {
+	if reservation.clientIP == "" {
+		return nil
+	}
+
+	// only the attempt is taken back, the other failures of the client IP are kept,
+	// or a single valid account would reset them
+	return tracker.store.RefundLoginFailure(ctx, db.RefundLoginFailureParams{
+		Kind:             KindClientIP,
+		Subject:          reservation.clientIP,
+		ReservedAt:       reservation.ipCount.failedAt,
+		PreviousFailedAt: reservation.ipCount.previousFailedAt,
+	})
+}
+
+func (tracker *Tracker) RecordSuccess(ctx context.Context, username string) error {
+	return tracker.store.DeleteLoginFailure(ctx, db.DeleteLoginFailureParams{
+		Kind:    KindUsername,
+		Subject: username,
+	})
+}
+
+func (tracker *Tracker) ReserveCode(ctx context.Context, username string) (Reservation, error) {
+	// the codes are counted apart from the logins, so guessing them doesn't lock the user out of logging in
+	failedCount, wait, _, err := tracker.reserve(ctx, KindTOTPCode, username, UsernameLockoutThreshold, time.Now())
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	reservation := Reservation{
+		Wait:      wait,
+		LockedOut: failedCount == UsernameLockoutThreshold,
+	}
+	return reservation, nil
+}
+
+func (tracker *Tracker) RecordCodeSuccess(ctx context.Context, username string) error {
+	return tracker.store.DeleteLoginFailure(ctx, db.DeleteLoginFailureParams{
+		Kind:    KindTOTPCode,
+		Subject: username,
+	})
+}
+
+// reserve counts the attempt of the subject and returns its failed count with the attempt,
+// how long the failures before the attempt make it wait, and what it takes to refund the attempt
+func (tracker *Tracker) reserve(ctx context.Context, kind string, subject string, threshold int32, now time.Time) (int32, time.Duration, counted, error) {
+	result, err := tracker.store.ReserveLoginAttemptTx(ctx, db.RecordLoginFailureParams{
+		Kind:        kind,
+		Subject:     subject,
+		ResetBefore: now.Add(-LockoutDuration),
+	})
+	if err != nil {
+		return 0, 0, counted{}, err
+	}
+
+	attempt := counted{
+		failedAt:         result.Failure.LastFailedAt,
+		previousFailedAt: result.PreviousFailedAt,
+	}
+
+	failedCount := result.Failure.FailedCount
+	wait := BlockedUntil(failedCount-1, result.PreviousFailedAt, threshold).Sub(now)
+	if wait < 0 {
+		return failedCount, 0, attempt, nil
+	}
+	return failedCount, wait, attempt, nil
+}
+
+// BlockedUntil returns when a subject with failedCount failures, the last one at lastFailedAt,
+// can try to log in again
+func BlockedUntil(failedCount int32, lastFailedAt time.Time, threshold int32) time.Time {
+	if failedCount >= threshold {
+		return lastFailedAt.Add(LockoutDuration)
+	}
+	if failedCount <= freeAttempts {
+		return lastFailedAt
+	}
+
+	delay := maxDelay
+	if shift := failedCount - freeAttempts - 1; shift < 32 {
+		delay = min(baseDelay<<shift, maxDelay)
+	}
+	return lastFailedAt.Add(delay)
+}
+
+// normalizeClientIP drops the port of a peer address, so all connections of a client are counted together
+func normalizeClientIP(clientIP string) string {
+	if host, _, err := net.SplitHostPort(clientIP); err == nil {
+		return host
+	}
+	return clientIP
+}
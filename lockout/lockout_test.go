@@ -0,0 +1,285 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockedUntil(t *testing.T) {
+	lastFailedAt := time.Now()
+
+	testCases := []struct {
+		failedCount int32
+		wait        time.Duration
+	}{
+		{failedCount: 1, wait: 0},
+		{failedCount: freeAttempts, wait: 0},
+		{failedCount: freeAttempts + 1, wait: baseDelay},
+		{failedCount: freeAttempts + 2, wait: 2 * baseDelay},
+		{failedCount: UsernameLockoutThreshold - 1, wait: 32 * baseDelay},
+		{failedCount: UsernameLockoutThreshold, wait: LockoutDuration},
+		{failedCount: UsernameLockoutThreshold + 5, wait: LockoutDuration},
+	}
+
+	for _, tc := range testCases {
+		blockedUntil := BlockedUntil(tc.failedCount, lastFailedAt, UsernameLockoutThreshold)
+		require.Equal(t, tc.wait, blockedUntil.Sub(lastFailedAt), "failed count %d", tc.failedCount)
+	}
+
+	// the delays of a client IP are capped until its own threshold
+	blockedUntil := BlockedUntil(ClientIPLockoutThreshold-1, lastFailedAt, ClientIPLockoutThreshold)
+	require.Equal(t, maxDelay, blockedUntil.Sub(lastFailedAt))
+}
+
+func TestTrackerReserve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	username := util.RandomOwner()
+	clientIP := "10.0.0.1"
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureParams) (db.ReserveLoginAttemptTxResult, error) {
+			require.Equal(t, KindUsername, arg.Kind)
+			require.Equal(t, username, arg.Subject)
+			require.WithinDuration(t, time.Now().Add(-LockoutDuration), arg.ResetBefore, time.Second)
+			return db.ReserveLoginAttemptTxResult{Failure: db.LoginFailure{FailedCount: 1}}, nil
+		})
+	store.EXPECT().
+		ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureParams) (db.ReserveLoginAttemptTxResult, error) {
+			require.Equal(t, KindClientIP, arg.Kind)
+			require.Equal(t, clientIP, arg.Subject)
+			return db.ReserveLoginAttemptTxResult{
+				Failure:          db.LoginFailure{FailedCount: ClientIPLockoutThreshold + 1},
+				PreviousFailedAt: time.Now(),
+			}, nil
+		})
+
+	tracker := NewTracker(store)
+	reservation, err := tracker.Reserve(context.Background(), username, clientIP+":54321")
+	require.NoError(t, err)
+	require.InDelta(t, LockoutDuration, reservation.Wait, float64(time.Second))
+	require.False(t, reservation.LockedOut)
+}
+
+func TestTrackerReserveLockedOut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	username := util.RandomOwner()
+
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().
+			ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.ReserveLoginAttemptTxResult{
+				Failure:          db.LoginFailure{FailedCount: UsernameLockoutThreshold},
+				PreviousFailedAt: time.Now().Add(-maxDelay),
+			}, nil),
+		store.EXPECT().
+			ReserveLoginAttemptTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.ReserveLoginAttemptTxResult{
+				Failure:          db.LoginFailure{FailedCount: UsernameLockoutThreshold + 1},
+				PreviousFailedAt: time.Now(),
+			}, nil),
+	)
+
+	tracker := NewTracker(store)
+
+	// the attempt reaching the threshold may still go ahead, and locks out the username if it fails
+	reservation, err := tracker.Reserve(context.Background(), username, "")
+	require.NoError(t, err)
+	require.Zero(t, reservation.Wait)
+	require.True(t, reservation.LockedOut)
+
+	// the username is locked out by the attempt reaching the threshold only, so it is notified once
+	reservation, err = tracker.Reserve(context.Background(), username, "")
+	require.NoError(t, err)
+	require.InDelta(t, LockoutDuration, reservation.Wait, float64(time.Second))
+	require.False(t, reservation.LockedOut)
+}
+
+// memoryStore counts failed logins in memory, one reservation at a time like the database does
+type memoryStore struct {
+	mu       sync.Mutex
+	failures map[string]db.LoginFailure
+}
+
+func (store *memoryStore) ReserveLoginAttemptTx(_ context.Context, arg db.RecordLoginFailureParams) (db.ReserveLoginAttemptTxResult, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key := arg.Kind + ":" + arg.Subject
+	previous := store.failures[key]
+
+	failure := db.LoginFailure{Kind: arg.Kind, Subject: arg.Subject, FailedCount: previous.FailedCount + 1, LastFailedAt: time.Now()}
+	if previous.LastFailedAt.Before(arg.ResetBefore) {
+		failure.FailedCount = 1
+	}
+	store.failures[key] = failure
+
+	return db.ReserveLoginAttemptTxResult{Failure: failure, PreviousFailedAt: previous.LastFailedAt}, nil
+}
+
+func (store *memoryStore) RefundLoginFailure(_ context.Context, arg db.RefundLoginFailureParams) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key := arg.Kind + ":" + arg.Subject
+	failure, ok := store.failures[key]
+	if !ok {
+		return nil
+	}
+
+	failure.FailedCount = max(failure.FailedCount-1, 0)
+	if failure.LastFailedAt.Equal(arg.ReservedAt) {
+		failure.LastFailedAt = arg.PreviousFailedAt
+	}
+	store.failures[key] = failure
+	return nil
+}
+
+func (store *memoryStore) DeleteLoginFailure(_ context.Context, arg db.DeleteLoginFailureParams) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.failures, arg.Kind+":"+arg.Subject)
+	return nil
+}
+
+func TestTrackerReserveConcurrent(t *testing.T) {
+	tracker := NewTracker(&memoryStore{failures: make(map[string]db.LoginFailure)})
+	username := util.RandomOwner()
+
+	n := UsernameLockoutThreshold
+	errs := make(chan error)
+	reservations := make(chan Reservation)
+
+	// run n concurrent guesses of the same username
+	for i := 0; i < n; i++ {
+		go func() {
+			reservation, err := tracker.Reserve(context.Background(), username, "10.0.0.1")
+
+			errs <- err
+			reservations <- reservation
+		}()
+	}
+
+	allowed := 0
+	lockedOut := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+
+		reservation := <-reservations
+		if reservation.Wait == 0 {
+			allowed++
+		}
+		if reservation.LockedOut {
+			lockedOut++
+		}
+	}
+
+	// only the attempts before the delays start may go ahead at once
+	require.Equal(t, freeAttempts+1, allowed)
+	require.Equal(t, 1, lockedOut)
+
+	// a success resets the username
+	require.NoError(t, tracker.RecordSuccess(context.Background(), username))
+	reservation, err := tracker.Reserve(context.Background(), username, "")
+	require.NoError(t, err)
+	require.Zero(t, reservation.Wait)
+}
+
+func TestTrackerReserveChallenge(t *testing.T) {
+	tracker := NewTracker(&memoryStore{failures: make(map[string]db.LoginFailure)})
+	username := util.RandomOwner()
+	challengeID := util.RandomString(16)
+
+	// the codes of a challenge are counted without a delay until it is spent
+	for i := 0; i < ChallengeAttempts; i++ {
+		reservation, err := tracker.ReserveChallenge(context.Background(), username, challengeID, "")
+		require.NoError(t, err)
+		require.False(t, reservation.ChallengeSpent)
+	}
+
+	reservation, err := tracker.ReserveChallenge(context.Background(), username, challengeID, "")
+	require.NoError(t, err)
+	require.True(t, reservation.ChallengeSpent)
+
+	// a new challenge does not reset the failures of the username
+	reservation, err = tracker.ReserveChallenge(context.Background(), username, util.RandomString(16), "")
+	require.NoError(t, err)
+	require.False(t, reservation.ChallengeSpent)
+	require.NotZero(t, reservation.Wait)
+}
+
+func TestTrackerReserveCode(t *testing.T) {
+	tracker := NewTracker(&memoryStore{failures: make(map[string]db.LoginFailure)})
+	username := util.RandomOwner()
+
+	for i := 0; i < freeAttempts+1; i++ {
+		reservation, err := tracker.ReserveCode(context.Background(), username)
+		require.NoError(t, err)
+		require.Zero(t, reservation.Wait)
+	}
+
+	reservation, err := tracker.ReserveCode(context.Background(), username)
+	require.NoError(t, err)
+	require.NotZero(t, reservation.Wait)
+
+	// the incorrect codes don't delay the logins of the user
+	reservation, err = tracker.Reserve(context.Background(), username, "")
+	require.NoError(t, err)
+	require.Zero(t, reservation.Wait)
+
+	require.NoError(t, tracker.RecordCodeSuccess(context.Background(), username))
+
+	reservation, err = tracker.ReserveCode(context.Background(), username)
+	require.NoError(t, err)
+	require.Zero(t, reservation.Wait)
+}
+
+func TestTrackerReleaseClientIP(t *testing.T) {
+	tracker := NewTracker(&memoryStore{failures: make(map[string]db.LoginFailure)})
+	clientIP := "10.0.0.1"
+
+	// the users behind one address log in successfully many more times than the address may fail
+	for i := int32(0); i < ClientIPLockoutThreshold+5; i++ {
+		username := util.RandomOwner()
+
+		reservation, err := tracker.Reserve(context.Background(), username, clientIP)
+		require.NoError(t, err)
+		require.Zero(t, reservation.Wait)
+		require.False(t, reservation.LockedOut)
+
+		require.NoError(t, tracker.Release(context.Background(), reservation))
+		require.NoError(t, tracker.RecordSuccess(context.Background(), username))
+	}
+
+	// the failures of the address are still counted
+	for i := 0; i < freeAttempts+1; i++ {
+		reservation, err := tracker.Reserve(context.Background(), util.RandomOwner(), clientIP)
+		require.NoError(t, err)
+		require.Zero(t, reservation.Wait)
+	}
+
+	reservation, err := tracker.Reserve(context.Background(), util.RandomOwner(), clientIP)
+	require.NoError(t, err)
+	require.NotZero(t, reservation.Wait)
+}
@@ -57,6 +57,11 @@ func main() {
 		log.Fatal().Err(err).Msg("cannot connect to db")
 	}
 
+	// commands only read the database, migrating it is left to the servers
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(ctx, db.NewStore(connPool), os.Args[1:], os.Stdout))
+	}
+
 	runDBMigration(config.MigrationURL, config.DBSource)
 
 	store := db.NewStore(connPool)
@@ -70,6 +75,7 @@ func main() {
 	waitGroup, ctx := errgroup.WithContext(ctx)
 
 	runTaskProcessor(ctx, waitGroup, config, redisOpt, store)
+	runTaskScheduler(ctx, waitGroup, redisOpt)
 	runGatewayServer(ctx, waitGroup, config, store, taskDistributor)
 	runGrpcServer(ctx, waitGroup, config, store, taskDistributor)
 
@@ -100,7 +106,7 @@ func runTaskProcessor(
 	store db.Store,
 ) {
 	mailer := mail.NewGmailSender(config.EmailSenderName, config.EmailSenderAddress, config.EmailSenderPassword)
-	taskProcessor := worker.NewRedisTaskProcessor(redisOpt, store, mailer)
+	taskProcessor := worker.NewRedisTaskProcessor(redisOpt, store, mailer, config)
 
 	log.Info().Msg("start task processor")
 	err := taskProcessor.Start()
@@ -119,6 +125,30 @@ func runTaskProcessor(
 	})
 }
 
+func runTaskScheduler(
+	ctx context.Context,
+	waitGroup *errgroup.Group,
+	redisOpt asynq.RedisClientOpt,
+) {
+	taskScheduler := worker.NewRedisTaskScheduler(redisOpt)
+
+	log.Info().Msg("start task scheduler")
+	err := taskScheduler.Start()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to start task scheduler")
+	}
+
+	waitGroup.Go(func() error {
+		<-ctx.Done()
+		log.Info().Msg("graceful shutdown task scheduler")
+
+		taskScheduler.Shutdown()
+		log.Info().Msg("task scheduler is stopped")
+
+		return nil
+	})
+}
+
 func runGrpcServer(
 	ctx context.Context,
 	waitGroup *errgroup.Group,
@@ -131,9 +161,10 @@ func runGrpcServer(
 		log.Fatal().Err(err).Msg("cannot create server")
 	}
 
-	gprcLogger := grpc.UnaryInterceptor(gapi.GrpcLogger)
-	grpcServer := grpc.NewServer(gprcLogger)
+	interceptors := grpc.ChainUnaryInterceptor(gapi.GrpcLogger, server.RateLimiter)
+	grpcServer := grpc.NewServer(interceptors)
 	pb.RegisterSimpleBankServer(grpcServer, server)
+	pb.RegisterSimpleBankAdminServer(grpcServer, gapi.NewAdminServer(server))
 	reflection.Register(grpcServer)
 
 	listener, err := net.Listen("tcp", config.GRPCServerAddress)
@@ -188,15 +219,23 @@ func runGatewayServer(
 		},
 	})
 
-	grpcMux := runtime.NewServeMux(jsonOption)
+	headerMatcher := runtime.WithIncomingHeaderMatcher(gapi.IncomingHeaderMatcher)
+
+	grpcMux := runtime.NewServeMux(jsonOption, headerMatcher)
 
 	err = pb.RegisterSimpleBankHandlerServer(ctx, grpcMux, server)
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot register handler server")
 	}
 
+	err = pb.RegisterSimpleBankAdminHandlerServer(ctx, grpcMux, gapi.NewAdminServer(server))
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot register admin handler server")
+	}
+
 	mux := http.NewServeMux()
 	mux.Handle("/", grpcMux)
+	mux.HandleFunc(gapi.JWKSPath, server.JWKSHandler)
 
 	statikFS, err := fs.New()
 	if err != nil {
@@ -220,10 +259,11 @@ func runGatewayServer(
 		AllowedHeaders: []string{
 			"Content-Type",
 			"Authorization",
+			"Idempotency-Key",
 		},
 		AllowCredentials: true,
 	})
-	handler := c.Handler(gapi.HttpLogger(mux))
+	handler := c.Handler(gapi.HttpLogger(server.HttpRateLimiter(mux)))
 
 	httpServer := &http.Server{
 		Handler: handler,
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the buckets that are full again are dropped
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	// fullAt is when the bucket is full again, so it no longer needs to be kept
+	fullAt time.Time
+}
+
+// MemoryLimiter is a Limiter keeping the buckets in memory,
+// so every server instance limits the requests it serves on its own
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryLimiter creates a new MemoryLimiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (limiter *MemoryLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := limiter.now()
+	limiter.sweep(now)
+
+	interval := rule.interval()
+	capacity := float64(rule.Limit)
+
+	b, ok := limiter.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updatedAt: now}
+		limiter.buckets[key] = b
+	}
+
+	b.tokens = min(capacity, b.tokens+float64(now.Sub(b.updatedAt))/float64(interval))
+	b.updatedAt = now
+
+	var result Result
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+
+	b.fullAt = now.Add(time.Duration((capacity - b.tokens) * float64(interval)))
+	return result, nil
+}
+
+func (limiter *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(limiter.lastSweep) < sweepInterval {
+		return
+	}
+
+	for key, b := range limiter.buckets {
+		if !now.Before(b.fullAt) {
+			delete(limiter.buckets, key)
+		}
+	}
+	limiter.lastSweep = now
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+
+	rule := Rule{Limit: 3, Period: 3 * time.Second}
+	ctx := context.Background()
+
+	// the whole limit can be used in a burst
+	for i := 0; i < rule.Limit; i++ {
+		result, err := limiter.Allow(ctx, "alice", rule)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	result, err := limiter.Allow(ctx, "alice", rule)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, time.Second, result.RetryAfter)
+
+	// the buckets of other keys are not affected
+	result, err = limiter.Allow(ctx, "bob", rule)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// one request is earned back each Period/Limit
+	now = now.Add(500 * time.Millisecond)
+	result, err = limiter.Allow(ctx, "alice", rule)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	result, err = limiter.Allow(ctx, "alice", rule)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	result, err = limiter.Allow(ctx, "alice", rule)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	_, err := limiter.Allow(ctx, "alice", Rule{Limit: 10, Period: time.Second})
+	require.NoError(t, err)
+	_, err = limiter.Allow(ctx, "bob", Rule{Limit: 1, Period: time.Hour})
+	require.NoError(t, err)
+	require.Len(t, limiter.buckets, 2)
+
+	// the bucket of alice is full again and dropped, bob still has to wait
+	now = now.Add(sweepInterval)
+	_, err = limiter.Allow(ctx, "carol", DefaultRule)
+	require.NoError(t, err)
+	require.Len(t, limiter.buckets, 2)
+	require.NotContains(t, limiter.buckets, "alice")
+	require.Contains(t, limiter.buckets, "bob")
+}
@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/go-redis/redis/v8"
+)
+
+// Backends the buckets can be kept in
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// Classes of routes sharing a limit
+const (
+	ClassDefault  = "default"
+	ClassLogin    = "login"
+	ClassTransfer = "transfer"
+)
+
+// Default rules of the classes when none is configured
+var (
+	DefaultRule         = Rule{Limit: 300, Period: time.Minute}
+	DefaultLoginRule    = Rule{Limit: 10, Period: time.Minute}
+	DefaultTransferRule = Rule{Limit: 30, Period: time.Minute}
+)
+
+// ErrRateLimited is returned to a client that made more requests than its rule allows
+var ErrRateLimited = errors.New("too many requests")
+
+// Rule allows Limit requests per Period. The requests can come in a burst,
+// after which one more is allowed each Period/Limit.
+type Rule struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParseRule parses a rule written as "<limit>/<period>", like "10/1m"
+func ParseRule(s string) (Rule, error) {
+	limit, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("rate limit rule %q is not <limit>/<period>", s)
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return Rule{}, fmt.Errorf("invalid limit of rate limit rule %q", s)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Rule{}, fmt.Errorf("invalid period of rate limit rule %q", s)
+	}
+
+	return Rule{Limit: n, Period: d}, nil
+}
+
+// interval is the time it takes to earn back one request
+func (rule Rule) interval() time.Duration {
+	return rule.Period / time.Duration(rule.Limit)
+}
+
+// Result is the decision of a Limiter on a request
+type Result struct {
+	Allowed bool
+	// RetryAfter is how long to wait before a request would be allowed, when it is not
+	RetryAfter time.Duration
+}
+
+// Limiter keeps a token bucket per key
+type Limiter interface {
+	// Allow takes a token from the bucket of the key, refilled according to the rule
+	Allow(ctx context.Context, key string, rule Rule) (Result, error)
+}
+
+// Policy holds the rule of each class of routes
+type Policy map[string]Rule
+
+// Rule returns the rule of the class, or the default rule if the class has none
+func (policy Policy) Rule(class string) Rule {
+	if rule, ok := policy[class]; ok {
+		return rule
+	}
+	return policy[ClassDefault]
+}
+
+// NewPolicyFromConfig creates the policy from the rules in config,
+// keeping the default rule of any class that is not configured
+func NewPolicyFromConfig(config util.Config) (Policy, error) {
+	policy := Policy{
+		ClassDefault:  DefaultRule,
+		ClassLogin:    DefaultLoginRule,
+		ClassTransfer: DefaultTransferRule,
+	}
+
+	configured := map[string]string{
+		ClassDefault:  config.RateLimitDefault,
+		ClassLogin:    config.RateLimitLogin,
+		ClassTransfer: config.RateLimitTransfer,
+	}
+	for class, s := range configured {
+		if s == "" {
+			continue
+		}
+
+		rule, err := ParseRule(s)
+		if err != nil {
+			return nil, err
+		}
+		policy[class] = rule
+	}
+
+	return policy, nil
+}
+
+// NewLimiterFromConfig creates the Limiter of the backend selected in config.
+// The redis backend shares the buckets across all server instances.
+func NewLimiterFromConfig(config util.Config) (Limiter, error) {
+	switch config.RateLimitBackend {
+	case "", BackendMemory:
+		return NewMemoryLimiter(), nil
+	case BackendRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr: config.RedisAddress,
+		})
+		return NewRedisLimiter(client), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", config.RateLimitBackend)
+	}
+}
+
+// Key returns the bucket key of a request of the class,
+// by username when it is authenticated or by client IP otherwise.
+// The port of a peer address is dropped, so all connections of a client share the bucket.
+func Key(class string, username string, clientIP string) string {
+	if username != "" {
+		return class + ":user:" + username
+	}
+
+	if host, _, err := net.SplitHostPort(clientIP); err == nil {
+		clientIP = host
+	}
+	return class + ":ip:" + clientIP
+}
+
+// RetryAfterSeconds rounds up a wait to whole seconds, as sent in a Retry-After header
+func RetryAfterSeconds(wait time.Duration) int {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	return max(seconds, 1)
+}
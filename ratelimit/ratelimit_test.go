@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("10/1m")
+	require.NoError(t, err)
+	require.Equal(t, Rule{Limit: 10, Period: time.Minute}, rule)
+	require.Equal(t, 6*time.Second, rule.interval())
+
+	for _, s := range []string{"", "10", "ten/1m", "0/1m", "10/minute", "10/-1s"} {
+		_, err := ParseRule(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestNewPolicyFromConfig(t *testing.T) {
+	policy, err := NewPolicyFromConfig(util.Config{RateLimitLogin: "5/30s"})
+	require.NoError(t, err)
+	require.Equal(t, Rule{Limit: 5, Period: 30 * time.Second}, policy.Rule(ClassLogin))
+	require.Equal(t, DefaultTransferRule, policy.Rule(ClassTransfer))
+	require.Equal(t, DefaultRule, policy.Rule("unknown"))
+
+	_, err = NewPolicyFromConfig(util.Config{RateLimitTransfer: "invalid"})
+	require.Error(t, err)
+}
+
+func TestNewLimiterFromConfig(t *testing.T) {
+	limiter, err := NewLimiterFromConfig(util.Config{})
+	require.NoError(t, err)
+	require.IsType(t, &MemoryLimiter{}, limiter)
+
+	limiter, err = NewLimiterFromConfig(util.Config{RateLimitBackend: BackendRedis, RedisAddress: "localhost:6379"})
+	require.NoError(t, err)
+	require.IsType(t, &RedisLimiter{}, limiter)
+
+	_, err = NewLimiterFromConfig(util.Config{RateLimitBackend: "unknown"})
+	require.Error(t, err)
+}
+
+func TestKey(t *testing.T) {
+	require.Equal(t, "login:user:alice", Key(ClassLogin, "alice", "10.0.0.1"))
+	require.Equal(t, "login:ip:10.0.0.1", Key(ClassLogin, "", "10.0.0.1"))
+	require.Equal(t, "login:ip:10.0.0.1", Key(ClassLogin, "", "10.0.0.1:54321"))
+	require.Equal(t, "login:ip:::1", Key(ClassLogin, "", "[::1]:54321"))
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	require.Equal(t, 1, RetryAfterSeconds(0))
+	require.Equal(t, 1, RetryAfterSeconds(200*time.Millisecond))
+	require.Equal(t, 6, RetryAfterSeconds(5*time.Second+time.Millisecond))
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// keyPrefix keeps the buckets apart from the other data in redis
+const keyPrefix = "ratelimit:"
+
+// tokenBucketScript takes a token from the bucket at KEYS[1] holding up to ARGV[1] tokens,
+// refilled with one token every ARGV[2] microseconds.
+// The clock of redis is used, so the server instances agree on the time.
+// It returns whether the request is allowed and, if not, the microseconds to wait.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated_at')
+local tokens = tonumber(bucket[1])
+local updated_at = tonumber(bucket[2])
+if tokens == nil or updated_at == nil then
+  tokens = capacity
+  updated_at = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - updated_at) / interval)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) * interval)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated_at', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) * interval / 1000) + 1000)
+
+return {allowed, wait}
+`)
+
+// RedisLimiter is a Limiter keeping the buckets in redis,
+// so the limits hold across all server instances
+type RedisLimiter struct {
+	client redis.Scripter
+}
+
+// NewRedisLimiter creates a new RedisLimiter keeping the buckets with the client
+func NewRedisLimiter(client redis.Scripter) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+	}
+}
+
+func (limiter *RedisLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	interval := rule.interval().Microseconds()
+
+	values, err := tokenBucketScript.Run(ctx, limiter.client, []string{keyPrefix + key}, rule.Limit, max(interval, 1)).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take token from bucket: %w", err)
+	}
+	if len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected reply of token bucket script: %v", values)
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		RetryAfter: time.Duration(values[1]) * time.Microsecond,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisLimiter(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	config, err := util.LoadConfig("..")
+	require.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: config.RedisAddress})
+	defer client.Close()
+	limiter := NewRedisLimiter(client)
+
+	ctx := context.Background()
+	key := Key(ClassLogin, util.RandomOwner(), "")
+	rule := Rule{Limit: 3, Period: time.Minute}
+
+	for i := 0; i < rule.Limit; i++ {
+		result, err := limiter.Allow(ctx, key, rule)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	result, err := limiter.Allow(ctx, key, rule)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.InDelta(t, rule.interval(), result.RetryAfter, float64(time.Second))
+
+	ttl, err := client.PTTL(ctx, keyPrefix+key).Result()
+	require.NoError(t, err)
+	require.Greater(t, ttl, time.Duration(0))
+}
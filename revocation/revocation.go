@@ -0,0 +1,124 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/google/uuid"
+)
+
+// DefaultTTL is how long a session status is cached when no TTL is configured
+const DefaultTTL = 5 * time.Second
+
+// maxEntries bounds the number of sessions kept in the cache
+const maxEntries = 10000
+
+// ErrTokenRevoked is returned for a token whose session was blocked,
+// has expired or no longer matches the role of its user
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// Checker checks whether a verified access token is still allowed
+type Checker interface {
+	Check(ctx context.Context, payload *token.Payload) error
+}
+
+// Store is the part of the database the cache reads sessions from
+type Store interface {
+	GetSessionStatus(ctx context.Context, id uuid.UUID) (db.GetSessionStatusRow, error)
+}
+
+type entry struct {
+	status    db.GetSessionStatusRow
+	found     bool
+	fetchedAt time.Time
+}
+
+// Cache is a Checker that keeps the status of recently seen sessions in memory,
+// so blocking a session or changing a role takes effect within its TTL
+type Cache struct {
+	store   Store
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[uuid.UUID]entry
+}
+
+// NewCache creates a new Cache reading sessions from the store
+func NewCache(store Store, ttl time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	return &Cache{
+		store:   store,
+		ttl:     ttl,
+		entries: make(map[uuid.UUID]entry),
+	}
+}
+
+// Check returns ErrTokenRevoked if the session of the token can no longer be used
+func (cache *Cache) Check(ctx context.Context, payload *token.Payload) error {
+	e, err := cache.get(ctx, payload.SessionID)
+	if err != nil {
+		return err
+	}
+
+	if !e.found ||
+		e.status.IsBlocked ||
+		time.Now().After(e.status.ExpiresAt) ||
+		e.status.Username != payload.Username ||
+		e.status.Role != payload.Role {
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+func (cache *Cache) get(ctx context.Context, sessionID uuid.UUID) (entry, error) {
+	now := time.Now()
+
+	cache.mu.Lock()
+	e, ok := cache.entries[sessionID]
+	cache.mu.Unlock()
+	if ok && now.Sub(e.fetchedAt) < cache.ttl {
+		return e, nil
+	}
+
+	// the lock is not held while querying, concurrent misses may read the same session twice
+	status, err := cache.store.GetSessionStatus(ctx, sessionID)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return entry{}, err
+	}
+
+	e = entry{
+		status:    status,
+		found:     err == nil,
+		fetchedAt: now,
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if len(cache.entries) >= maxEntries {
+		cache.evict(now)
+	}
+	cache.entries[sessionID] = e
+
+	return e, nil
+}
+
+// evict removes the stale entries, or every entry if none of them is stale
+func (cache *Cache) evict(now time.Time) {
+	for id, e := range cache.entries {
+		if now.Sub(e.fetchedAt) >= cache.ttl {
+			delete(cache.entries, id)
+		}
+	}
+
+	if len(cache.entries) >= maxEntries {
+		cache.entries = make(map[uuid.UUID]entry)
+	}
+}
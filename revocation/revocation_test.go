@@ -0,0 +1,64 @@
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/token"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheExpiresStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	payload, err := token.NewPayload(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute, token.TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	status := db.GetSessionStatusRow{
+		ID:        payload.SessionID,
+		Username:  payload.Username,
+		Role:      payload.Role,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	blocked := status
+	blocked.IsBlocked = true
+
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().GetSessionStatus(gomock.Any(), gomock.Eq(payload.SessionID)).Times(1).Return(status, nil),
+		store.EXPECT().GetSessionStatus(gomock.Any(), gomock.Eq(payload.SessionID)).Times(1).Return(blocked, nil),
+	)
+
+	ttl := 50 * time.Millisecond
+	cache := NewCache(store, ttl)
+
+	require.NoError(t, cache.Check(context.Background(), payload))
+	// the session is blocked, but the cached status is still used
+	require.NoError(t, cache.Check(context.Background(), payload))
+
+	time.Sleep(ttl)
+	require.ErrorIs(t, cache.Check(context.Background(), payload), ErrTokenRevoked)
+}
+
+func TestCacheEvictsStaleEntries(t *testing.T) {
+	cache := NewCache(nil, time.Minute)
+	now := time.Now()
+
+	for i := 0; i < maxEntries; i++ {
+		fetchedAt := now
+		if i%2 == 0 {
+			fetchedAt = now.Add(-time.Hour)
+		}
+		cache.entries[uuid.New()] = entry{fetchedAt: fetchedAt}
+	}
+
+	cache.evict(now)
+	require.Len(t, cache.entries, maxEntries/2)
+}
@@ -0,0 +1,43 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// WriteCSV writes the statement as CSV, with the opening and closing balances
+// as the first and last rows around the entries
+func (statement *Statement) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	records := [][]string{
+		{"date", "description", "entry_id", "amount", "balance", "currency"},
+		statement.balanceRecord(statement.From, "opening balance", statement.OpeningBalance),
+	}
+	for _, line := range statement.Lines {
+		records = append(records, []string{
+			line.CreatedAt.UTC().Format(time.RFC3339),
+			"entry",
+			strconv.FormatInt(line.EntryID, 10),
+			strconv.FormatInt(line.Amount, 10),
+			strconv.FormatInt(line.Balance, 10),
+			statement.Account.Currency,
+		})
+	}
+	records = append(records, statement.balanceRecord(statement.To, "closing balance", statement.ClosingBalance))
+
+	return writer.WriteAll(records)
+}
+
+func (statement *Statement) balanceRecord(at time.Time, description string, balance int64) []string {
+	return []string{
+		at.UTC().Format(time.RFC3339),
+		description,
+		"",
+		"",
+		strconv.FormatInt(balance, 10),
+		statement.Account.Currency,
+	}
+}
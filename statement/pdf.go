@@ -0,0 +1,66 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	pdfFont       = "Helvetica"
+	pdfLineHeight = 7
+)
+
+var pdfColumnWidths = []float64{60, 40, 45, 45}
+
+// WritePDF writes the statement as a single table PDF document
+func (statement *Statement) WritePDF(w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(fmt.Sprintf("Simple Bank statement for account %d", statement.Account.ID), true)
+	pdf.AddPage()
+
+	pdf.SetFont(pdfFont, "B", 16)
+	pdf.Cell(0, 10, "Simple Bank account statement")
+	pdf.Ln(12)
+
+	pdf.SetFont(pdfFont, "", 10)
+	for _, line := range []string{
+		fmt.Sprintf("Account: %d", statement.Account.ID),
+		fmt.Sprintf("Owner: %s", statement.Account.Owner),
+		fmt.Sprintf("Currency: %s", statement.Account.Currency),
+		fmt.Sprintf("Period: %s - %s", statement.From.UTC().Format(time.RFC3339), statement.To.UTC().Format(time.RFC3339)),
+		fmt.Sprintf("Opening balance: %d", statement.OpeningBalance),
+		fmt.Sprintf("Closing balance: %d", statement.ClosingBalance),
+	} {
+		pdf.Cell(0, 6, line)
+		pdf.Ln(6)
+	}
+	pdf.Ln(4)
+
+	pdf.SetFont(pdfFont, "B", 10)
+	writePDFRow(pdf, "Date", "Entry", "Amount", "Balance")
+
+	pdf.SetFont(pdfFont, "", 10)
+	writePDFRow(pdf, statement.From.UTC().Format(time.RFC3339), "opening", "", strconv.FormatInt(statement.OpeningBalance, 10))
+	for _, line := range statement.Lines {
+		writePDFRow(pdf,
+			line.CreatedAt.UTC().Format(time.RFC3339),
+			strconv.FormatInt(line.EntryID, 10),
+			strconv.FormatInt(line.Amount, 10),
+			strconv.FormatInt(line.Balance, 10),
+		)
+	}
+	writePDFRow(pdf, statement.To.UTC().Format(time.RFC3339), "closing", "", strconv.FormatInt(statement.ClosingBalance, 10))
+
+	return pdf.Output(w)
+}
+
+func writePDFRow(pdf *fpdf.Fpdf, date string, entry string, amount string, balance string) {
+	pdf.CellFormat(pdfColumnWidths[0], pdfLineHeight, date, "1", 0, "L", false, 0, "")
+	pdf.CellFormat(pdfColumnWidths[1], pdfLineHeight, entry, "1", 0, "L", false, 0, "")
+	pdf.CellFormat(pdfColumnWidths[2], pdfLineHeight, amount, "1", 0, "R", false, 0, "")
+	pdf.CellFormat(pdfColumnWidths[3], pdfLineHeight, balance, "1", 1, "R", false, 0, "")
+}
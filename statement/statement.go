@@ -0,0 +1,158 @@
+package statement
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+)
+
+// Supported statement formats
+const (
+	FormatCSV = "csv"
+	FormatPDF = "pdf"
+)
+
+const pageSize = 500
+
+// Statement lists every entry of an account over a period,
+// with the balance before, after and in between each of them
+type Statement struct {
+	Account        db.Account
+	From           time.Time
+	To             time.Time
+	OpeningBalance int64
+	ClosingBalance int64
+	Lines          []Line
+}
+
+// Line is a single entry of a statement
+type Line struct {
+	EntryID   int64
+	CreatedAt time.Time
+	Amount    int64
+	// Balance is the running balance of the account after the entry
+	Balance int64
+}
+
+// Generate builds the statement of the account for the period [from, to).
+// The opening balance is derived from the current balance and every entry made since from,
+// so the account and its entries are read within a single read-only transaction.
+func Generate(ctx context.Context, store db.Store, account db.Account, from time.Time, to time.Time) (*Statement, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("statement period must end after it starts")
+	}
+
+	var statement *Statement
+	err := store.ReadTx(ctx, func(q db.Querier) error {
+		var err error
+		statement, err = generate(ctx, q, account.ID, from, to)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statement, nil
+}
+
+func generate(ctx context.Context, q db.Querier, accountID int64, from time.Time, to time.Time) (*Statement, error) {
+	account, err := q.GetAccount(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	totalSince, err := q.GetEntriesTotalSince(ctx, db.GetEntriesTotalSinceParams{
+		AccountID: account.ID,
+		CreatedAt: from,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entries total: %w", err)
+	}
+
+	statement := &Statement{
+		Account:        account,
+		From:           from,
+		To:             to,
+		OpeningBalance: account.Balance - totalSince,
+	}
+
+	// entry IDs are positive, so the first page starts right at from
+	cursorCreatedAt, cursorID := from, int64(0)
+	balance := statement.OpeningBalance
+	for {
+		entries, err := q.ListEntriesKeysetAsc(ctx, db.ListEntriesKeysetAscParams{
+			AccountID: account.ID,
+			MinAmount: math.MinInt64,
+			MaxAmount: math.MaxInt64,
+			FromTime:  from,
+			// entries are stored with microsecond precision and the query bounds are inclusive
+			ToTime:          to.Add(-time.Microsecond),
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           pageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list entries: %w", err)
+		}
+
+		for _, entry := range entries {
+			balance += entry.Amount
+			statement.Lines = append(statement.Lines, Line{
+				EntryID:   entry.ID,
+				CreatedAt: entry.CreatedAt,
+				Amount:    entry.Amount,
+				Balance:   balance,
+			})
+		}
+
+		if len(entries) < pageSize {
+			break
+		}
+		last := entries[len(entries)-1]
+		cursorCreatedAt, cursorID = last.CreatedAt, last.ID
+	}
+
+	statement.ClosingBalance = balance
+	return statement, nil
+}
+
+// IsSupportedFormat returns true if the statement can be rendered in the format
+func IsSupportedFormat(format string) bool {
+	switch format {
+	case FormatCSV, FormatPDF:
+		return true
+	}
+	return false
+}
+
+// ContentType returns the MIME type of a statement rendered in the format
+func ContentType(format string) string {
+	if format == FormatPDF {
+		return "application/pdf"
+	}
+	return "text/csv"
+}
+
+// FileName returns the name of the file the statement is saved or attached as
+func (statement *Statement) FileName(format string) string {
+	return fmt.Sprintf("statement-%d-%s-%s.%s",
+		statement.Account.ID,
+		statement.From.Format("20060102"),
+		statement.To.Format("20060102"),
+		format,
+	)
+}
+
+// Render writes the statement to w in the format
+func (statement *Statement) Render(w io.Writer, format string) error {
+	switch format {
+	case FormatCSV:
+		return statement.WriteCSV(w)
+	case FormatPDF:
+		return statement.WritePDF(w)
+	}
+	return fmt.Errorf("unsupported statement format: %s", format)
+}
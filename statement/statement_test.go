@@ -0,0 +1,147 @@
+package statement
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	account := db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    util.RandomOwner(),
+		Balance:  500,
+		Currency: util.USD,
+	}
+	from := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	entries := []db.Entry{
+		{ID: 1, AccountID: account.ID, Amount: 100, CreatedAt: from.Add(time.Hour)},
+		{ID: 2, AccountID: account.ID, Amount: -30, CreatedAt: from.Add(2 * time.Hour)},
+	}
+
+	// 50 has been added to the account since the period ended
+	expectReadTx(store)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+	store.EXPECT().
+		GetEntriesTotalSince(gomock.Any(), gomock.Eq(db.GetEntriesTotalSinceParams{AccountID: account.ID, CreatedAt: from})).
+		Times(1).
+		Return(int64(120), nil)
+	store.EXPECT().
+		ListEntriesKeysetAsc(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ListEntriesKeysetAscParams) ([]db.Entry, error) {
+			require.Equal(t, account.ID, arg.AccountID)
+			require.Equal(t, from, arg.FromTime)
+			require.True(t, arg.ToTime.Before(to))
+			require.Equal(t, from, arg.CursorCreatedAt)
+			require.Zero(t, arg.CursorID)
+			return entries, nil
+		})
+
+	statement, err := Generate(context.Background(), store, account, from, to)
+	require.NoError(t, err)
+	require.Equal(t, int64(380), statement.OpeningBalance)
+	require.Equal(t, int64(450), statement.ClosingBalance)
+	require.Len(t, statement.Lines, 2)
+	require.Equal(t, int64(480), statement.Lines[0].Balance)
+	require.Equal(t, int64(450), statement.Lines[1].Balance)
+
+	var buf bytes.Buffer
+	require.NoError(t, statement.Render(&buf, FormatCSV))
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	require.Equal(t, "380", records[1][4])
+	require.Equal(t, "450", records[4][4])
+
+	buf.Reset()
+	require.NoError(t, statement.Render(&buf, FormatPDF))
+	require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+
+	require.Error(t, statement.Render(&buf, "xlsx"))
+}
+
+func TestGenerateInvalidPeriod(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	now := time.Now()
+	_, err := Generate(context.Background(), store, db.Account{}, now, now)
+	require.Error(t, err)
+}
+
+func TestGeneratePages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	account := db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    util.RandomOwner(),
+		Balance:  pageSize + 1,
+		Currency: util.USD,
+	}
+	from := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	// every entry has the same time, so only the ID tells the pages apart
+	createdAt := from.Add(time.Hour)
+	entries := make([]db.Entry, pageSize+1)
+	for i := range entries {
+		entries[i] = db.Entry{ID: int64(i + 1), AccountID: account.ID, Amount: 1, CreatedAt: createdAt}
+	}
+
+	expectReadTx(store)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	store.EXPECT().GetEntriesTotalSince(gomock.Any(), gomock.Any()).Times(1).Return(int64(pageSize+1), nil)
+	gomock.InOrder(
+		store.EXPECT().
+			ListEntriesKeysetAsc(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, arg db.ListEntriesKeysetAscParams) ([]db.Entry, error) {
+				require.Equal(t, from, arg.CursorCreatedAt)
+				require.Zero(t, arg.CursorID)
+				return entries[:pageSize], nil
+			}),
+		store.EXPECT().
+			ListEntriesKeysetAsc(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, arg db.ListEntriesKeysetAscParams) ([]db.Entry, error) {
+				require.Equal(t, createdAt, arg.CursorCreatedAt)
+				require.Equal(t, int64(pageSize), arg.CursorID)
+				return entries[pageSize:], nil
+			}),
+	)
+
+	statement, err := Generate(context.Background(), store, account, from, to)
+	require.NoError(t, err)
+	require.Zero(t, statement.OpeningBalance)
+	require.Equal(t, int64(pageSize+1), statement.ClosingBalance)
+	require.Len(t, statement.Lines, pageSize+1)
+}
+
+// expectReadTx runs the function of the read transaction against the mock store itself
+func expectReadTx(store *mockdb.MockStore) {
+	store.EXPECT().
+		ReadTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, fn func(db.Querier) error) error {
+			return fn(store)
+		})
+}
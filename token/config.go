@@ -0,0 +1,64 @@
+package token
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+)
+
+// Kinds of token maker that can be chosen with TOKEN_MAKER
+const (
+	MakerPaseto       = "paseto"
+	MakerPasetoPublic = "paseto_public"
+	MakerJWT          = "jwt"
+	MakerJWTPublic    = "jwt_public"
+)
+
+// NewMakerFromConfig creates the token maker chosen in the config,
+// a symmetric PASETO maker when none is chosen
+func NewMakerFromConfig(config util.Config) (Maker, error) {
+	switch config.TokenMaker {
+	case "", MakerPaseto:
+		return NewPasetoMaker(config.TokenSymmetricKey)
+	case MakerPasetoPublic:
+		keyring := NewKeyring()
+		if config.TokenSigningKey != "" {
+			err := keyring.SetSigningKey(config.TokenSigningKeyID, config.TokenSigningKey)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err := keyring.AddVerificationKeys(config.TokenVerificationKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewPasetoPublicMaker(keyring)
+	case MakerJWT:
+		return NewJWTMaker(config.TokenSymmetricKey)
+	case MakerJWTPublic:
+		keySet := NewJWTKeySet()
+		if config.TokenSigningKeyFile != "" {
+			privateKeyPEM, err := os.ReadFile(config.TokenSigningKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read signing key: %w", err)
+			}
+
+			err = keySet.SetSigningKey(config.TokenSigningKeyID, privateKeyPEM)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err := keySet.AddVerificationKeyFiles(config.TokenVerificationKeyFiles)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewAsymmetricJWTMaker(keySet)
+	default:
+		return nil, fmt.Errorf("unsupported token maker: %s", config.TokenMaker)
+	}
+}
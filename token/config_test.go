@@ -0,0 +1,77 @@
+package token
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMakerFromConfig(t *testing.T) {
+	maker, err := NewMakerFromConfig(util.Config{TokenSymmetricKey: util.RandomString(32)})
+	require.NoError(t, err)
+	require.IsType(t, &PasetoMaker{}, maker)
+
+	privateKey, publicKey := randomSigningKey(t)
+	signer, err := NewMakerFromConfig(util.Config{
+		TokenMaker:        MakerPasetoPublic,
+		TokenSigningKeyID: "key-1",
+		TokenSigningKey:   privateKey,
+	})
+	require.NoError(t, err)
+
+	verifier, err := NewMakerFromConfig(util.Config{
+		TokenMaker:            MakerPasetoPublic,
+		TokenVerificationKeys: []string{"key-1:" + publicKey},
+	})
+	require.NoError(t, err)
+
+	token, _, err := signer.CreateToken(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	_, err = verifier.VerifyToken(token, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	_, err = NewMakerFromConfig(util.Config{TokenMaker: MakerPasetoPublic})
+	require.Error(t, err)
+
+	_, err = NewMakerFromConfig(util.Config{TokenMaker: "unknown"})
+	require.Error(t, err)
+}
+
+func TestNewJWTMakerFromConfig(t *testing.T) {
+	privateKey, publicKey := randomRSAKeyPair(t)
+	dir := t.TempDir()
+
+	privateKeyFile := filepath.Join(dir, "private.pem")
+	require.NoError(t, os.WriteFile(privateKeyFile, privateKey, 0o600))
+	publicKeyFile := filepath.Join(dir, "public.pem")
+	require.NoError(t, os.WriteFile(publicKeyFile, publicKey, 0o600))
+
+	signer, err := NewMakerFromConfig(util.Config{
+		TokenMaker:          MakerJWTPublic,
+		TokenSigningKeyID:   "key-1",
+		TokenSigningKeyFile: privateKeyFile,
+	})
+	require.NoError(t, err)
+
+	verifier, err := NewMakerFromConfig(util.Config{
+		TokenMaker:                MakerJWTPublic,
+		TokenVerificationKeyFiles: []string{"key-1:" + publicKeyFile},
+	})
+	require.NoError(t, err)
+
+	token, _, err := signer.CreateToken(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	_, err = verifier.VerifyToken(token, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	maker, err := NewMakerFromConfig(util.Config{TokenMaker: MakerJWT, TokenSymmetricKey: util.RandomString(32)})
+	require.NoError(t, err)
+	require.Empty(t, maker.(KeySetPublisher).JWKS().Keys)
+}
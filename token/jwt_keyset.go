@@ -0,0 +1,191 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSAKeyBits = 2048
+
+// JWTKeySet holds the RSA and ECDSA keys of an asymmetric JWTMaker: an optional signing key
+// and every public key that tokens may still be verified with during a rotation
+type JWTKeySet struct {
+	signingKeyID     string
+	signingKey       crypto.Signer
+	signingMethod    jwt.SigningMethod
+	verificationKeys map[string]crypto.PublicKey
+}
+
+// JSONWebKey is a public key in the JSON Web Key format of RFC 7517
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet is the document published at /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// NewJWTKeySet creates an empty key set
+func NewJWTKeySet() *JWTKeySet {
+	return &JWTKeySet{
+		verificationKeys: make(map[string]crypto.PublicKey),
+	}
+}
+
+// SetSigningKey sets the PEM encoded RSA or ECDSA P-256 private key new tokens are signed with,
+// with RS256 or ES256 respectively. Its public key is also added to the verification keys.
+func (keySet *JWTKeySet) SetSigningKey(keyID string, privateKeyPEM []byte) error {
+	if keyID == "" {
+		return fmt.Errorf("key id must not be empty")
+	}
+
+	var signingKey crypto.Signer
+	if key, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeyPEM); err == nil {
+		signingKey = key
+	} else if key, err := jwt.ParseECPrivateKeyFromPEM(privateKeyPEM); err == nil {
+		signingKey = key
+	} else {
+		return fmt.Errorf("invalid signing key: must be a PEM encoded RSA or ECDSA private key")
+	}
+
+	method, err := signingMethodFor(signingKey.Public())
+	if err != nil {
+		return err
+	}
+
+	keySet.signingKeyID = keyID
+	keySet.signingKey = signingKey
+	keySet.signingMethod = method
+	keySet.verificationKeys[keyID] = signingKey.Public()
+	return nil
+}
+
+// AddVerificationKey adds a PEM encoded RSA or ECDSA P-256 public key tokens may be verified with
+func (keySet *JWTKeySet) AddVerificationKey(keyID string, publicKeyPEM []byte) error {
+	if keyID == "" {
+		return fmt.Errorf("key id must not be empty")
+	}
+
+	var publicKey crypto.PublicKey
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(publicKeyPEM); err == nil {
+		publicKey = key
+	} else if key, err := jwt.ParseECPublicKeyFromPEM(publicKeyPEM); err == nil {
+		publicKey = key
+	} else {
+		return fmt.Errorf("invalid verification key %s: must be a PEM encoded RSA or ECDSA public key", keyID)
+	}
+
+	if _, err := signingMethodFor(publicKey); err != nil {
+		return fmt.Errorf("invalid verification key %s: %w", keyID, err)
+	}
+
+	keySet.verificationKeys[keyID] = publicKey
+	return nil
+}
+
+// AddVerificationKeyFiles adds public keys given as "<key id>:<path to PEM file>"
+func (keySet *JWTKeySet) AddVerificationKeyFiles(keys []string) error {
+	for _, key := range keys {
+		keyID, path, ok := strings.Cut(key, ":")
+		if !ok {
+			return fmt.Errorf("invalid verification key %q: must be <key id>:<file>", key)
+		}
+
+		publicKeyPEM, err := os.ReadFile(strings.TrimSpace(path))
+		if err != nil {
+			return fmt.Errorf("cannot read verification key %s: %w", keyID, err)
+		}
+
+		err = keySet.AddVerificationKey(strings.TrimSpace(keyID), publicKeyPEM)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JWKS returns the verification keys as a JSON Web Key Set, sorted by key id
+func (keySet *JWTKeySet) JWKS() JSONWebKeySet {
+	jwks := JSONWebKeySet{
+		Keys: make([]JSONWebKey, 0, len(keySet.verificationKeys)),
+	}
+
+	for keyID, publicKey := range keySet.verificationKeys {
+		jwk := JSONWebKey{
+			Kid: keyID,
+			Use: "sig",
+		}
+
+		switch key := publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.Alg = jwt.SigningMethodRS256.Alg()
+			jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (key.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Alg = jwt.SigningMethodES256.Alg()
+			jwk.Crv = key.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size)))
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+	return jwks
+}
+
+// verificationKey returns the key of a key id with the only signing method it may be used with
+func (keySet *JWTKeySet) verificationKey(keyID string) (crypto.PublicKey, jwt.SigningMethod, error) {
+	publicKey, ok := keySet.verificationKeys[keyID]
+	if !ok {
+		return nil, nil, ErrUnknownKeyID
+	}
+
+	method, err := signingMethodFor(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return publicKey, method, nil
+}
+
+func signingMethodFor(publicKey crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ECDSA key must use the P-256 curve")
+		}
+		return jwt.SigningMethodES256, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
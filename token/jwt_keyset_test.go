@@ -0,0 +1,163 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeKeyPair(t *testing.T, privateKey crypto.Signer) ([]byte, []byte) {
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	publicDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+}
+
+func randomRSAKeyPair(t *testing.T) ([]byte, []byte) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+	require.NoError(t, err)
+	return encodeKeyPair(t, privateKey)
+}
+
+func randomECKeyPair(t *testing.T) ([]byte, []byte) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return encodeKeyPair(t, privateKey)
+}
+
+func newAsymmetricJWTMaker(t *testing.T, keyID string, privateKeyPEM []byte) Maker {
+	keySet := NewJWTKeySet()
+	require.NoError(t, keySet.SetSigningKey(keyID, privateKeyPEM))
+
+	maker, err := NewAsymmetricJWTMaker(keySet)
+	require.NoError(t, err)
+	return maker
+}
+
+func TestAsymmetricJWTMaker(t *testing.T) {
+	rsaPrivateKey, _ := randomRSAKeyPair(t)
+	ecPrivateKey, _ := randomECKeyPair(t)
+
+	for alg, privateKeyPEM := range map[string][]byte{"RS256": rsaPrivateKey, "ES256": ecPrivateKey} {
+		t.Run(alg, func(t *testing.T) {
+			maker := newAsymmetricJWTMaker(t, "key-1", privateKeyPEM)
+
+			username := util.RandomOwner()
+			sessionID := uuid.New()
+
+			token, _, err := maker.CreateToken(username, util.DepositorRole, sessionID, time.Minute, TokenTypeAccessToken)
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Payload{})
+			require.NoError(t, err)
+			require.Equal(t, alg, parsed.Method.Alg())
+			require.Equal(t, "key-1", parsed.Header["kid"])
+
+			payload, err := maker.VerifyToken(token, TokenTypeAccessToken)
+			require.NoError(t, err)
+			require.Equal(t, username, payload.Username)
+			require.Equal(t, sessionID, payload.SessionID)
+		})
+	}
+}
+
+func TestAsymmetricJWTKeyRotation(t *testing.T) {
+	oldPrivateKey, oldPublicKey := randomECKeyPair(t)
+	oldMaker := newAsymmetricJWTMaker(t, "key-1", oldPrivateKey)
+
+	oldToken, _, err := oldMaker.CreateToken(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	newPrivateKey, _ := randomRSAKeyPair(t)
+	keySet := NewJWTKeySet()
+	require.NoError(t, keySet.SetSigningKey("key-2", newPrivateKey))
+
+	path := filepath.Join(t.TempDir(), "key-1.pem")
+	require.NoError(t, os.WriteFile(path, oldPublicKey, 0o600))
+	require.NoError(t, keySet.AddVerificationKeyFiles([]string{"key-1:" + path}))
+
+	maker, err := NewAsymmetricJWTMaker(keySet)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(oldToken, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	jwks := maker.(KeySetPublisher).JWKS()
+	require.Len(t, jwks.Keys, 2)
+	require.Equal(t, "key-1", jwks.Keys[0].Kid)
+	require.Equal(t, "EC", jwks.Keys[0].Kty)
+	require.Equal(t, "P-256", jwks.Keys[0].Crv)
+	require.Equal(t, "ES256", jwks.Keys[0].Alg)
+	require.Len(t, jwks.Keys[0].X, 43)
+	require.Equal(t, "key-2", jwks.Keys[1].Kid)
+	require.Equal(t, "RSA", jwks.Keys[1].Kty)
+	require.Equal(t, "RS256", jwks.Keys[1].Alg)
+	require.Equal(t, "AQAB", jwks.Keys[1].E)
+}
+
+func TestAsymmetricJWTInvalidTokens(t *testing.T) {
+	privateKey, publicKey := randomRSAKeyPair(t)
+	maker := newAsymmetricJWTMaker(t, "key-1", privateKey)
+
+	payload, err := NewPayload(util.RandomOwner(), util.BankerRole, uuid.New(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// the public key used as an HMAC secret must not be accepted
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	hmacToken.Header["kid"] = "key-1"
+	confused, err := hmacToken.SignedString(publicKey)
+	require.NoError(t, err)
+
+	otherPrivateKey, _ := randomRSAKeyPair(t)
+	other := newAsymmetricJWTMaker(t, "key-1", otherPrivateKey)
+	forged, _, err := other.CreateToken(util.RandomOwner(), util.BankerRole, uuid.New(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	unknown := newAsymmetricJWTMaker(t, "key-9", privateKey)
+	unknownKeyID, _, err := unknown.CreateToken(util.RandomOwner(), util.BankerRole, uuid.New(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	for _, token := range []string{confused, forged, unknownKeyID} {
+		payload, err := maker.VerifyToken(token, TokenTypeAccessToken)
+		require.EqualError(t, err, ErrInvalidToken.Error())
+		require.Nil(t, payload)
+	}
+}
+
+func TestInvalidJWTKeySetKeys(t *testing.T) {
+	keySet := NewJWTKeySet()
+
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	smallPrivateKey, smallPublicKey := encodeKeyPair(t, smallKey)
+
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	p384PrivateKey, _ := encodeKeyPair(t, p384Key)
+
+	require.Error(t, keySet.SetSigningKey("key-1", []byte("not a key")))
+	require.Error(t, keySet.SetSigningKey("key-1", smallPrivateKey))
+	require.Error(t, keySet.SetSigningKey("key-1", p384PrivateKey))
+	require.Error(t, keySet.AddVerificationKey("key-1", smallPublicKey))
+	require.Error(t, keySet.AddVerificationKeyFiles([]string{"no-separator"}))
+
+	_, err = NewAsymmetricJWTMaker(keySet)
+	require.Error(t, err)
+}
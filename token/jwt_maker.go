@@ -6,43 +6,80 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 const minSecretKeySize = 32
 
-// JWTMaker is a JSON Web Token maker
+// JWTMaker is a JSON Web Token maker, tokens are signed with HS256 and a shared secret,
+// or with RS256 or ES256 and the key set of an asymmetric maker
 type JWTMaker struct {
 	secretKey string
+	keySet    *JWTKeySet
 }
 
-// NewJWTMaker creates a new JWTMaker
+// NewJWTMaker creates a new JWTMaker signing tokens with HS256
 func NewJWTMaker(secretKey string) (Maker, error) {
 	if len(secretKey) < minSecretKeySize {
 		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
 	}
-	return &JWTMaker{secretKey}, nil
+	return &JWTMaker{secretKey: secretKey}, nil
 }
 
-// CreateToken creates a new token for a specific username and duration
-func (maker *JWTMaker) CreateToken(username string, role string, duration time.Duration, tokenType TokenType) (string, *Payload, error) {
-	payload, err := NewPayload(username, role, duration, tokenType)
+// NewAsymmetricJWTMaker creates a new JWTMaker signing tokens with the key set's signing key,
+// and verifying them with the key named by their kid header
+func NewAsymmetricJWTMaker(keySet *JWTKeySet) (Maker, error) {
+	if len(keySet.verificationKeys) == 0 {
+		return nil, fmt.Errorf("key set must hold at least one key")
+	}
+	return &JWTMaker{keySet: keySet}, nil
+}
+
+// CreateToken creates a new token for a specific username, session and duration
+func (maker *JWTMaker) CreateToken(username string, role string, sessionID uuid.UUID, duration time.Duration, tokenType TokenType) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, sessionID, duration, tokenType)
 	if err != nil {
 		return "", payload, err
 	}
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	token, err := jwtToken.SignedString([]byte(maker.secretKey))
+	if maker.keySet == nil {
+		jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+		token, err := jwtToken.SignedString([]byte(maker.secretKey))
+		return token, payload, err
+	}
+
+	if maker.keySet.signingKey == nil {
+		return "", nil, ErrNoSigningKey
+	}
+
+	jwtToken := jwt.NewWithClaims(maker.keySet.signingMethod, payload)
+	jwtToken.Header["kid"] = maker.keySet.signingKeyID
+	token, err := jwtToken.SignedString(maker.keySet.signingKey)
 	return token, payload, err
 }
 
 // VerifyToken checks if the token is valid or not
 func (maker *JWTMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		_, ok := token.Method.(*jwt.SigningMethodHMAC)
+		if maker.keySet == nil {
+			_, ok := token.Method.(*jwt.SigningMethodHMAC)
+			if !ok {
+				return nil, ErrInvalidToken
+			}
+			return []byte(maker.secretKey), nil
+		}
+
+		keyID, ok := token.Header["kid"].(string)
 		if !ok {
 			return nil, ErrInvalidToken
 		}
-		return []byte(maker.secretKey), nil
+
+		// the key decides the algorithm, so a token cannot pick a weaker one
+		publicKey, method, err := maker.keySet.verificationKey(keyID)
+		if err != nil || token.Method.Alg() != method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return publicKey, nil
 	}
 
 	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
@@ -65,3 +102,12 @@ func (maker *JWTMaker) VerifyToken(token string, tokenType TokenType) (*Payload,
 
 	return payload, nil
 }
+
+// JWKS returns the public keys tokens are verified with,
+// which is empty for a maker using a shared secret
+func (maker *JWTMaker) JWKS() JSONWebKeySet {
+	if maker.keySet == nil {
+		return JSONWebKeySet{Keys: []JSONWebKey{}}
+	}
+	return maker.keySet.JWKS()
+}
@@ -0,0 +1,92 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownKeyID is returned when a token is signed with a key the keyring does not hold
+var ErrUnknownKeyID = errors.New("unknown key id")
+
+// Keyring holds the Ed25519 keys of a PasetoPublicMaker: an optional signing key
+// and every public key that tokens may still be verified with during a rotation
+type Keyring struct {
+	signingKeyID     string
+	signingKey       ed25519.PrivateKey
+	verificationKeys map[string]ed25519.PublicKey
+}
+
+// NewKeyring creates an empty keyring
+func NewKeyring() *Keyring {
+	return &Keyring{
+		verificationKeys: make(map[string]ed25519.PublicKey),
+	}
+}
+
+// SetSigningKey sets the key new tokens are signed with, from its hex encoded 32 bytes seed
+// or 64 bytes private key. Its public key is also added to the verification keys.
+func (keyring *Keyring) SetSigningKey(keyID string, privateKeyHex string) error {
+	if keyID == "" {
+		return fmt.Errorf("key id must not be empty")
+	}
+
+	key, err := hex.DecodeString(privateKeyHex)
+	if err != nil {
+		return fmt.Errorf("invalid signing key: %w", err)
+	}
+
+	var privateKey ed25519.PrivateKey
+	switch len(key) {
+	case ed25519.SeedSize:
+		privateKey = ed25519.NewKeyFromSeed(key)
+	case ed25519.PrivateKeySize:
+		privateKey = ed25519.PrivateKey(key)
+		if !ed25519.NewKeyFromSeed(privateKey.Seed()).Equal(privateKey) {
+			return fmt.Errorf("invalid signing key: public part does not match the seed")
+		}
+	default:
+		return fmt.Errorf("invalid signing key size: must be %d or %d bytes", ed25519.SeedSize, ed25519.PrivateKeySize)
+	}
+
+	keyring.signingKeyID = keyID
+	keyring.signingKey = privateKey
+	keyring.verificationKeys[keyID] = privateKey.Public().(ed25519.PublicKey)
+	return nil
+}
+
+// AddVerificationKey adds a hex encoded public key tokens may be verified with
+func (keyring *Keyring) AddVerificationKey(keyID string, publicKeyHex string) error {
+	if keyID == "" {
+		return fmt.Errorf("key id must not be empty")
+	}
+
+	key, err := hex.DecodeString(publicKeyHex)
+	if err != nil {
+		return fmt.Errorf("invalid verification key %s: %w", keyID, err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid verification key %s size: must be %d bytes", keyID, ed25519.PublicKeySize)
+	}
+
+	keyring.verificationKeys[keyID] = ed25519.PublicKey(key)
+	return nil
+}
+
+// AddVerificationKeys adds public keys given as "<key id>:<hex encoded public key>"
+func (keyring *Keyring) AddVerificationKeys(keys []string) error {
+	for _, key := range keys {
+		keyID, publicKeyHex, ok := strings.Cut(key, ":")
+		if !ok {
+			return fmt.Errorf("invalid verification key %q: must be <key id>:<public key>", key)
+		}
+
+		err := keyring.AddVerificationKey(strings.TrimSpace(keyID), strings.TrimSpace(publicKeyHex))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
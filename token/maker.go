@@ -2,13 +2,21 @@ package token
 
 import (
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Maker is an interface for managing tokens
 type Maker interface {
-	// CreateToken creates a new token for a specific username and duration
-	CreateToken(username string, role string, duration time.Duration, tokenType TokenType) (string, *Payload, error)
+	// CreateToken creates a new token for a specific username, session and duration
+	CreateToken(username string, role string, sessionID uuid.UUID, duration time.Duration, tokenType TokenType) (string, *Payload, error)
 
 	// VerifyToken checks if the token is valid or not
 	VerifyToken(token string, tokenType TokenType) (*Payload, error)
 }
+
+// KeySetPublisher is implemented by the makers whose verification keys may be published,
+// so other services can verify tokens on their own
+type KeySetPublisher interface {
+	JWKS() JSONWebKeySet
+}
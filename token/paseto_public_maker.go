@@ -0,0 +1,126 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/google/uuid"
+)
+
+// ErrNoSigningKey is returned when creating a token with a keyring that can only verify them
+var ErrNoSigningKey = errors.New("no signing key")
+
+// pasetoFooter is the unencrypted but signed footer of a token,
+// it tells which key of the keyring verifies the token
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// PasetoPublicMaker is a PASETO v4.public token maker,
+// tokens are signed with an Ed25519 private key and verified with its public key
+type PasetoPublicMaker struct {
+	signingKeyID     string
+	signingKey       *paseto.V4AsymmetricSecretKey
+	verificationKeys map[string]paseto.V4AsymmetricPublicKey
+	parser           paseto.Parser
+}
+
+// NewPasetoPublicMaker creates a new PasetoPublicMaker
+func NewPasetoPublicMaker(keyring *Keyring) (Maker, error) {
+	if len(keyring.verificationKeys) == 0 {
+		return nil, fmt.Errorf("keyring must hold at least one key")
+	}
+
+	maker := &PasetoPublicMaker{
+		signingKeyID:     keyring.signingKeyID,
+		verificationKeys: make(map[string]paseto.V4AsymmetricPublicKey, len(keyring.verificationKeys)),
+		// the expiration is checked by Payload.Valid, which tells an expired token from an invalid one
+		parser: paseto.NewParserWithoutExpiryCheck(),
+	}
+
+	if keyring.signingKey != nil {
+		signingKey, err := paseto.NewV4AsymmetricSecretKeyFromEd25519(keyring.signingKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signing key: %w", err)
+		}
+		maker.signingKey = &signingKey
+	}
+
+	for keyID, key := range keyring.verificationKeys {
+		verificationKey, err := paseto.NewV4AsymmetricPublicKeyFromEd25519(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid verification key %s: %w", keyID, err)
+		}
+		maker.verificationKeys[keyID] = verificationKey
+	}
+
+	return maker, nil
+}
+
+// CreateToken creates a new token for a specific username, session and duration
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, sessionID uuid.UUID, duration time.Duration, tokenType TokenType) (string, *Payload, error) {
+	if maker.signingKey == nil {
+		return "", nil, ErrNoSigningKey
+	}
+
+	payload, err := NewPayload(username, role, sessionID, duration, tokenType)
+	if err != nil {
+		return "", payload, err
+	}
+
+	claims, err := json.Marshal(payload)
+	if err != nil {
+		return "", payload, err
+	}
+
+	footer, err := json.Marshal(pasetoFooter{KeyID: maker.signingKeyID})
+	if err != nil {
+		return "", payload, err
+	}
+
+	token, err := paseto.NewTokenFromClaimsJSON(claims, footer)
+	if err != nil {
+		return "", payload, err
+	}
+
+	return token.V4Sign(*maker.signingKey, nil), payload, nil
+}
+
+// VerifyToken checks if the token is valid or not
+func (maker *PasetoPublicMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
+	// the footer is read before it is verified only to pick the key
+	footer, err := maker.parser.UnsafeParseFooter(paseto.V4Public, token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var f pasetoFooter
+	if err := json.Unmarshal(footer, &f); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, ok := maker.verificationKeys[f.KeyID]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	parsed, err := maker.parser.ParseV4Public(key, token, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	if err := json.Unmarshal(parsed.ClaimsJSON(), payload); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Valid(tokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,150 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const pasetoV4PublicHeader = "v4.public."
+
+func randomSigningKey(t *testing.T) (string, string) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return hex.EncodeToString(privateKey.Seed()), hex.EncodeToString(publicKey)
+}
+
+func newPasetoPublicMaker(t *testing.T, keyID string, privateKeyHex string) Maker {
+	keyring := NewKeyring()
+	require.NoError(t, keyring.SetSigningKey(keyID, privateKeyHex))
+
+	maker, err := NewPasetoPublicMaker(keyring)
+	require.NoError(t, err)
+	return maker
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	privateKey, _ := randomSigningKey(t)
+	maker := newPasetoPublicMaker(t, "key-1", privateKey)
+
+	username := util.RandomOwner()
+	role := util.DepositorRole
+	sessionID := uuid.New()
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, sessionID, duration, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token, pasetoV4PublicHeader))
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.Equal(t, sessionID, payload.SessionID)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	privateKey, _ := randomSigningKey(t)
+	maker := newPasetoPublicMaker(t, "key-1", privateKey)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, uuid.New(), -time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token, TokenTypeAccessToken)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestTamperedPasetoPublicToken(t *testing.T) {
+	privateKey, _ := randomSigningKey(t)
+	maker := newPasetoPublicMaker(t, "key-1", privateKey)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// a token signed with another key under the same key id
+	otherPrivateKey, _ := randomSigningKey(t)
+	other := newPasetoPublicMaker(t, "key-1", otherPrivateKey)
+	forged, _, err := other.CreateToken(util.RandomOwner(), util.BankerRole, uuid.New(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	body, _, _ := strings.Cut(strings.TrimPrefix(forged, pasetoV4PublicHeader), ".")
+	_, footer, _ := strings.Cut(strings.TrimPrefix(token, pasetoV4PublicHeader), ".")
+
+	for _, tampered := range []string{
+		forged,
+		pasetoV4PublicHeader + body + "." + footer,
+		strings.TrimSuffix(token, "."+footer),
+		"v2.public." + strings.TrimPrefix(token, pasetoV4PublicHeader),
+	} {
+		payload, err := maker.VerifyToken(tampered, TokenTypeAccessToken)
+		require.EqualError(t, err, ErrInvalidToken.Error())
+		require.Nil(t, payload)
+	}
+}
+
+func TestPasetoPublicKeyRotation(t *testing.T) {
+	oldPrivateKey, oldPublicKey := randomSigningKey(t)
+	oldMaker := newPasetoPublicMaker(t, "key-1", oldPrivateKey)
+
+	oldToken, _, err := oldMaker.CreateToken(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// the new signing key is in use, the old one is still accepted for verification
+	newPrivateKey, newPublicKey := randomSigningKey(t)
+	keyring := NewKeyring()
+	require.NoError(t, keyring.SetSigningKey("key-2", newPrivateKey))
+	require.NoError(t, keyring.AddVerificationKeys([]string{"key-1:" + oldPublicKey}))
+	maker, err := NewPasetoPublicMaker(keyring)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(oldToken, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	newToken, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// a service that only verifies tokens holds the public keys and cannot create tokens
+	verifierKeyring := NewKeyring()
+	require.NoError(t, verifierKeyring.AddVerificationKeys([]string{"key-2:" + newPublicKey}))
+	verifier, err := NewPasetoPublicMaker(verifierKeyring)
+	require.NoError(t, err)
+
+	_, err = verifier.VerifyToken(newToken, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	_, err = verifier.VerifyToken(oldToken, TokenTypeAccessToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	_, _, err = verifier.CreateToken(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute, TokenTypeAccessToken)
+	require.ErrorIs(t, err, ErrNoSigningKey)
+}
+
+func TestInvalidKeyringKeys(t *testing.T) {
+	keyring := NewKeyring()
+
+	require.Error(t, keyring.SetSigningKey("", hex.EncodeToString(make([]byte, ed25519.SeedSize))))
+	require.Error(t, keyring.SetSigningKey("key-1", "not hex"))
+	require.Error(t, keyring.SetSigningKey("key-1", hex.EncodeToString(make([]byte, 16))))
+	require.Error(t, keyring.AddVerificationKeys([]string{"no-separator"}))
+	require.Error(t, keyring.AddVerificationKeys([]string{"key-1:" + hex.EncodeToString(make([]byte, 16))}))
+
+	_, err := NewPasetoPublicMaker(keyring)
+	require.Error(t, err)
+}
@@ -19,6 +19,8 @@ type TokenType byte
 const (
 	TokenTypeAccessToken  = 1
 	TokenTypeRefreshToken = 2
+	// TokenTypeLoginChallenge proves the password of a user who still has to send a one-time password
+	TokenTypeLoginChallenge = 3
 )
 
 // Payload contains the payload data of the token
@@ -27,12 +29,13 @@ type Payload struct {
 	Type      TokenType `json:"token_type"`
 	Username  string    `json:"username"`
 	Role      string    `json:"role"`
+	SessionID uuid.UUID `json:"session_id"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
 
-// NewPayload creates a new token payload with a specific username and duration
-func NewPayload(username string, role string, duration time.Duration, tokenType TokenType) (*Payload, error) {
+// NewPayload creates a new token payload with a specific username, session and duration
+func NewPayload(username string, role string, sessionID uuid.UUID, duration time.Duration, tokenType TokenType) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -43,6 +46,7 @@ func NewPayload(username string, role string, duration time.Duration, tokenType
 		Type:      tokenType,
 		Username:  username,
 		Role:      role,
+		SessionID: sessionID,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
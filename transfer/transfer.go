@@ -0,0 +1,171 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/Ian-Balijawa/simplebank/worker"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrNotAccountOwner  = errors.New("from account doesn't belong to the authenticated user")
+	ErrAmountTooSmall   = errors.New("amount is too small to convert")
+	ErrSameAccount      = errors.New("cannot transfer to the same account")
+)
+
+// Request is a transfer asked for by a user, over either API
+type Request struct {
+	FromAccountID   int64
+	ToAccountID     int64
+	Amount          int64
+	Currency        string
+	ConvertCurrency bool
+}
+
+// Prepared is a checked transfer request, with the accounts as they were before the transfer
+type Prepared struct {
+	Arg         db.TransferTxParams
+	FromAccount db.Account
+	ToAccount   db.Account
+}
+
+// Prepare checks the accounts of a transfer request made by username,
+// converts the amount when the currencies differ, and returns the parameters of the transfer transaction.
+// The errors are ErrSameAccount, db.ErrRecordNotFound, ErrCurrencyMismatch, ErrNotAccountOwner,
+// util.ErrFXRateNotFound and ErrAmountTooSmall, wrapped, or an internal error.
+func Prepare(ctx context.Context, store db.Querier, fxRateProvider util.FXRateProvider, req Request, username string) (Prepared, error) {
+	var prepared Prepared
+
+	if req.FromAccountID == req.ToAccountID {
+		return prepared, fmt.Errorf("account [%d]: %w", req.FromAccountID, ErrSameAccount)
+	}
+
+	fromAccount, err := ValidAccount(ctx, store, req.FromAccountID, req.Currency)
+	if err != nil {
+		return prepared, err
+	}
+
+	if fromAccount.Owner != username {
+		return prepared, ErrNotAccountOwner
+	}
+
+	var toAccount db.Account
+	if req.ConvertCurrency {
+		toAccount, err = FindAccount(ctx, store, req.ToAccountID)
+	} else {
+		toAccount, err = ValidAccount(ctx, store, req.ToAccountID, req.Currency)
+	}
+	if err != nil {
+		return prepared, err
+	}
+
+	prepared = Prepared{
+		Arg: db.TransferTxParams{
+			FromAccountID:     req.FromAccountID,
+			ToAccountID:       req.ToAccountID,
+			Amount:            req.Amount,
+			EnforceDailyLimit: true,
+		},
+		FromAccount: fromAccount,
+		ToAccount:   toAccount,
+	}
+	if toAccount.Currency != fromAccount.Currency {
+		prepared.Arg.Conversion, err = convertCurrency(ctx, fxRateProvider, fromAccount.Currency, toAccount.Currency, req.Amount)
+		if err != nil {
+			return prepared, err
+		}
+	}
+
+	return prepared, nil
+}
+
+// FindAccount returns the account, or db.ErrRecordNotFound wrapped with its ID
+func FindAccount(ctx context.Context, store db.Querier, accountID int64) (db.Account, error) {
+	account, err := store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return account, fmt.Errorf("account [%d] not found: %w", accountID, err)
+		}
+		return account, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	return account, nil
+}
+
+// ValidAccount returns the account if it is in the currency, or ErrCurrencyMismatch
+func ValidAccount(ctx context.Context, store db.Querier, accountID int64, currency string) (db.Account, error) {
+	account, err := FindAccount(ctx, store, accountID)
+	if err != nil {
+		return account, err
+	}
+
+	if account.Currency != currency {
+		return account, fmt.Errorf("account [%d] %w: %s vs %s", account.ID, ErrCurrencyMismatch, account.Currency, currency)
+	}
+
+	return account, nil
+}
+
+func convertCurrency(ctx context.Context, fxRateProvider util.FXRateProvider, from string, to string, amount int64) (*db.CurrencyConversion, error) {
+	rate, err := fxRateProvider.GetRate(ctx, from, to)
+	if err != nil {
+		if errors.Is(err, util.ErrFXRateNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+
+	toAmount, exchangeRate := util.ConvertAmount(amount, rate)
+	if toAmount <= 0 {
+		return nil, fmt.Errorf("%w from %s to %s", ErrAmountTooSmall, from, to)
+	}
+
+	return &db.CurrencyConversion{
+		ToAmount:     toAmount,
+		ExchangeRate: exchangeRate,
+	}, nil
+}
+
+// SendBalanceAlerts queues an alert when a transfer moved the balance of the account
+// across one of the thresholds set by its owner.
+// Alerts are best effort, so failures are ignored.
+func SendBalanceAlerts(ctx context.Context, store db.Querier, taskDistributor worker.TaskDistributor, before db.Account, after db.Account) {
+	if taskDistributor == nil {
+		return
+	}
+
+	alert, err := store.GetAccountAlert(ctx, after.ID)
+	if err != nil {
+		return
+	}
+
+	if alert.LowBalanceThreshold > 0 &&
+		before.Balance > alert.LowBalanceThreshold &&
+		after.Balance <= alert.LowBalanceThreshold {
+		_ = taskDistributor.DistributeTaskSendAccountAlert(ctx, &worker.PayloadAccountAlert{
+			Username:  after.Owner,
+			AccountID: after.ID,
+			Balance:   after.Balance,
+			Threshold: alert.LowBalanceThreshold,
+			Direction: worker.AlertDirectionLow,
+			Currency:  after.Currency,
+		})
+	}
+
+	if alert.HighBalanceThreshold > 0 &&
+		before.Balance < alert.HighBalanceThreshold &&
+		after.Balance >= alert.HighBalanceThreshold {
+		_ = taskDistributor.DistributeTaskSendAccountAlert(ctx, &worker.PayloadAccountAlert{
+			Username:  after.Owner,
+			AccountID: after.ID,
+			Balance:   after.Balance,
+			Threshold: alert.HighBalanceThreshold,
+			Direction: worker.AlertDirectionHigh,
+			Currency:  after.Currency,
+		})
+	}
+}
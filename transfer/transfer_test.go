@@ -0,0 +1,153 @@
+package transfer
+
+import (
+	"context"
+	"testing"
+
+	mockdb "github.com/Ian-Balijawa/simplebank/db/mock"
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/Ian-Balijawa/simplebank/worker"
+	mockwk "github.com/Ian-Balijawa/simplebank/worker/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepare(t *testing.T) {
+	owner := util.RandomOwner()
+	usdAccount := db.Account{ID: 1, Owner: owner, Currency: util.USD}
+	otherUSDAccount := db.Account{ID: 2, Owner: util.RandomOwner(), Currency: util.USD}
+	eurAccount := db.Account{ID: 3, Owner: util.RandomOwner(), Currency: util.EUR}
+	gbpAccount := db.Account{ID: 5, Owner: util.RandomOwner(), Currency: util.GBP}
+
+	fxRateProvider, err := util.NewStaticFXRateProvider(map[string]string{"USD/EUR": "0.8", "USD/GBP": "0.1"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		req           Request
+		username      string
+		checkResponse func(t *testing.T, prepared Prepared, err error)
+	}{
+		{
+			name:     "OK",
+			req:      Request{FromAccountID: 1, ToAccountID: 2, Amount: 10, Currency: util.USD},
+			username: owner,
+			checkResponse: func(t *testing.T, prepared Prepared, err error) {
+				require.NoError(t, err)
+				require.Equal(t, db.TransferTxParams{
+					FromAccountID:     1,
+					ToAccountID:       2,
+					Amount:            10,
+					EnforceDailyLimit: true,
+				}, prepared.Arg)
+				require.Equal(t, usdAccount, prepared.FromAccount)
+				require.Equal(t, otherUSDAccount, prepared.ToAccount)
+			},
+		},
+		{
+			name:     "Conversion",
+			req:      Request{FromAccountID: 1, ToAccountID: 3, Amount: 10, Currency: util.USD, ConvertCurrency: true},
+			username: owner,
+			checkResponse: func(t *testing.T, prepared Prepared, err error) {
+				require.NoError(t, err)
+				require.Equal(t, &db.CurrencyConversion{ToAmount: 8, ExchangeRate: "0.80000000"}, prepared.Arg.Conversion)
+			},
+		},
+		{
+			name:     "AmountTooSmall",
+			req:      Request{FromAccountID: 1, ToAccountID: 5, Amount: 1, Currency: util.USD, ConvertCurrency: true},
+			username: owner,
+			checkResponse: func(t *testing.T, prepared Prepared, err error) {
+				require.ErrorIs(t, err, ErrAmountTooSmall)
+			},
+		},
+		{
+			name:     "CurrencyMismatch",
+			req:      Request{FromAccountID: 1, ToAccountID: 3, Amount: 10, Currency: util.USD},
+			username: owner,
+			checkResponse: func(t *testing.T, prepared Prepared, err error) {
+				require.ErrorIs(t, err, ErrCurrencyMismatch)
+			},
+		},
+		{
+			name:     "NotAccountOwner",
+			req:      Request{FromAccountID: 1, ToAccountID: 2, Amount: 10, Currency: util.USD},
+			username: otherUSDAccount.Owner,
+			checkResponse: func(t *testing.T, prepared Prepared, err error) {
+				require.ErrorIs(t, err, ErrNotAccountOwner)
+			},
+		},
+		{
+			name:     "SameAccount",
+			req:      Request{FromAccountID: 1, ToAccountID: 1, Amount: 10, Currency: util.USD},
+			username: owner,
+			checkResponse: func(t *testing.T, prepared Prepared, err error) {
+				require.ErrorIs(t, err, ErrSameAccount)
+			},
+		},
+		{
+			name:     "AccountNotFound",
+			req:      Request{FromAccountID: 1, ToAccountID: 4, Amount: 10, Currency: util.USD},
+			username: owner,
+			checkResponse: func(t *testing.T, prepared Prepared, err error) {
+				require.ErrorIs(t, err, db.ErrRecordNotFound)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				DoAndReturn(func(_ context.Context, id int64) (db.Account, error) {
+					for _, account := range []db.Account{usdAccount, otherUSDAccount, eurAccount, gbpAccount} {
+						if account.ID == id {
+							return account, nil
+						}
+					}
+					return db.Account{}, db.ErrRecordNotFound
+				})
+
+			prepared, err := Prepare(context.Background(), store, fxRateProvider, tc.req, tc.username)
+			tc.checkResponse(t, prepared, err)
+		})
+	}
+}
+
+func TestSendBalanceAlerts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	distributor := mockwk.NewMockTaskDistributor(ctrl)
+
+	before := db.Account{ID: 1, Owner: util.RandomOwner(), Balance: 150, Currency: util.USD}
+	after := before
+	after.Balance = 90
+
+	store.EXPECT().
+		GetAccountAlert(gomock.Any(), gomock.Eq(before.ID)).
+		Times(1).
+		Return(db.AccountAlert{AccountID: before.ID, LowBalanceThreshold: 100, HighBalanceThreshold: 1000}, nil)
+	distributor.EXPECT().
+		DistributeTaskSendAccountAlert(gomock.Any(), gomock.Eq(&worker.PayloadAccountAlert{
+			Username:  after.Owner,
+			AccountID: after.ID,
+			Balance:   90,
+			Threshold: 100,
+			Direction: worker.AlertDirectionLow,
+			Currency:  util.USD,
+		})).
+		Times(1).
+		Return(nil)
+
+	SendBalanceAlerts(context.Background(), store, distributor, before, after)
+}
@@ -0,0 +1,91 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "::1"})
+	require.NoError(t, err)
+	require.Len(t, proxies, 3)
+
+	_, err = ParseTrustedProxies([]string{"proxy.local"})
+	require.Error(t, err)
+
+	_, err = ParseTrustedProxies([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		proxies      TrustedProxies
+		remoteAddr   string
+		forwardedFor string
+		clientIP     string
+	}{
+		{
+			name:         "NoTrustedProxies",
+			remoteAddr:   "203.0.113.7:54321",
+			forwardedFor: "198.51.100.1",
+			clientIP:     "203.0.113.7",
+		},
+		{
+			name:         "UntrustedRemote",
+			proxies:      proxies,
+			remoteAddr:   "203.0.113.7:54321",
+			forwardedFor: "198.51.100.1",
+			clientIP:     "203.0.113.7",
+		},
+		{
+			name:         "TrustedRemote",
+			proxies:      proxies,
+			remoteAddr:   "10.0.0.2:54321",
+			forwardedFor: "198.51.100.1",
+			clientIP:     "198.51.100.1",
+		},
+		{
+			name:         "SpoofedBeforeTrustedProxies",
+			proxies:      proxies,
+			remoteAddr:   "10.0.0.2:54321",
+			forwardedFor: "192.0.2.66, 198.51.100.1, 10.0.0.3",
+			clientIP:     "198.51.100.1",
+		},
+		{
+			name:         "TrustedProxyIP",
+			proxies:      proxies,
+			remoteAddr:   "192.168.1.1:54321",
+			forwardedFor: "198.51.100.1",
+			clientIP:     "198.51.100.1",
+		},
+		{
+			name:         "InvalidHop",
+			proxies:      proxies,
+			remoteAddr:   "10.0.0.2:54321",
+			forwardedFor: "198.51.100.1, unknown",
+			clientIP:     "10.0.0.2",
+		},
+		{
+			name:       "NoHeader",
+			proxies:    proxies,
+			remoteAddr: "10.0.0.2:54321",
+			clientIP:   "10.0.0.2",
+		},
+		{
+			name:       "RemoteWithoutPort",
+			remoteAddr: "203.0.113.7",
+			clientIP:   "203.0.113.7",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.clientIP, tc.proxies.ClientIP(tc.remoteAddr, tc.forwardedFor))
+		})
+	}
+}
@@ -9,19 +9,37 @@ import (
 // Config stores all configuration of the application.
 // The values are read by viper from a config file or environment variable.
 type Config struct {
-	Environment          string        `mapstructure:"ENVIRONMENT"`
-	AllowedOrigins       []string      `mapstructure:"ALLOWED_ORIGINS"`
-	DBSource             string        `mapstructure:"DB_SOURCE"`
-	MigrationURL         string        `mapstructure:"MIGRATION_URL"`
-	RedisAddress         string        `mapstructure:"REDIS_ADDRESS"`
-	HTTPServerAddress    string        `mapstructure:"HTTP_SERVER_ADDRESS"`
-	GRPCServerAddress    string        `mapstructure:"GRPC_SERVER_ADDRESS"`
-	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
-	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
-	EmailSenderName      string        `mapstructure:"EMAIL_SENDER_NAME"`
-	EmailSenderAddress   string        `mapstructure:"EMAIL_SENDER_ADDRESS"`
-	EmailSenderPassword  string        `mapstructure:"EMAIL_SENDER_PASSWORD"`
+	Environment                  string        `mapstructure:"ENVIRONMENT"`
+	AllowedOrigins               []string      `mapstructure:"ALLOWED_ORIGINS"`
+	TrustedProxies               []string      `mapstructure:"TRUSTED_PROXIES"`
+	DBSource                     string        `mapstructure:"DB_SOURCE"`
+	MigrationURL                 string        `mapstructure:"MIGRATION_URL"`
+	RedisAddress                 string        `mapstructure:"REDIS_ADDRESS"`
+	HTTPServerAddress            string        `mapstructure:"HTTP_SERVER_ADDRESS"`
+	GRPCServerAddress            string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	TokenMaker                   string        `mapstructure:"TOKEN_MAKER"`
+	TokenSymmetricKey            string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenSigningKeyID            string        `mapstructure:"TOKEN_SIGNING_KEY_ID"`
+	TokenSigningKey              string        `mapstructure:"TOKEN_SIGNING_KEY"`
+	TokenVerificationKeys        []string      `mapstructure:"TOKEN_VERIFICATION_KEYS"`
+	TokenSigningKeyFile          string        `mapstructure:"TOKEN_SIGNING_KEY_FILE"`
+	TokenVerificationKeyFiles    []string      `mapstructure:"TOKEN_VERIFICATION_KEY_FILES"`
+	AccessTokenDuration          time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration         time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	TokenRevocationCacheTTL      time.Duration `mapstructure:"TOKEN_REVOCATION_CACHE_TTL"`
+	EmailSenderName              string        `mapstructure:"EMAIL_SENDER_NAME"`
+	EmailSenderAddress           string        `mapstructure:"EMAIL_SENDER_ADDRESS"`
+	EmailSenderPassword          string        `mapstructure:"EMAIL_SENDER_PASSWORD"`
+	FXRatesFile                  string        `mapstructure:"FX_RATES_FILE"`
+	StepUpTransferThreshold      int64         `mapstructure:"STEP_UP_TRANSFER_THRESHOLD"`
+	PendingTransferDuration      time.Duration `mapstructure:"PENDING_TRANSFER_DURATION"`
+	RequireVerifiedEmail         bool          `mapstructure:"REQUIRE_VERIFIED_EMAIL"`
+	VerifyEmailResendCooldown    time.Duration `mapstructure:"VERIFY_EMAIL_RESEND_COOLDOWN"`
+	PasswordResetRequestCooldown time.Duration `mapstructure:"PASSWORD_RESET_REQUEST_COOLDOWN"`
+	RateLimitBackend             string        `mapstructure:"RATE_LIMIT_BACKEND"`
+	RateLimitDefault             string        `mapstructure:"RATE_LIMIT_DEFAULT"`
+	RateLimitLogin               string        `mapstructure:"RATE_LIMIT_LOGIN"`
+	RateLimitTransfer            string        `mapstructure:"RATE_LIMIT_TRANSFER"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
@@ -0,0 +1,112 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// FXRatePrecision is the number of decimal places an exchange rate is rounded to
+// before it is applied, matching the precision of transfers.exchange_rate.
+const FXRatePrecision = 8
+
+var ErrFXRateNotFound = errors.New("exchange rate not found")
+
+// FXRateProvider returns the exchange rate between two currencies,
+// as the amount of the to currency that one unit of the from currency buys.
+type FXRateProvider interface {
+	GetRate(ctx context.Context, from string, to string) (*big.Rat, error)
+}
+
+// StaticFXRateProvider serves exchange rates from a fixed in-memory table.
+type StaticFXRateProvider struct {
+	rates map[string]*big.Rat
+}
+
+// NewStaticFXRateProvider creates a provider from rates keyed by currency pair, such as "USD/EUR".
+// Rates are decimal strings. The inverse of each pair is derived when it isn't listed.
+func NewStaticFXRateProvider(rates map[string]string) (*StaticFXRateProvider, error) {
+	provider := &StaticFXRateProvider{
+		rates: make(map[string]*big.Rat, len(rates)),
+	}
+
+	for pair, value := range rates {
+		from, to, ok := strings.Cut(pair, "/")
+		if !ok || !IsSupportedCurrency(from) || !IsSupportedCurrency(to) {
+			return nil, fmt.Errorf("invalid currency pair %q", pair)
+		}
+
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid exchange rate %q for %s", value, pair)
+		}
+
+		provider.rates[pair] = rate
+	}
+
+	return provider, nil
+}
+
+// LoadStaticFXRateProvider creates a provider from a JSON file of rates keyed by currency pair.
+// An empty path gives a provider without any rates.
+func LoadStaticFXRateProvider(path string) (*StaticFXRateProvider, error) {
+	rates := map[string]string{}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read exchange rates: %w", err)
+		}
+
+		err = json.Unmarshal(data, &rates)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse exchange rates: %w", err)
+		}
+	}
+
+	return NewStaticFXRateProvider(rates)
+}
+
+// GetRate returns the exchange rate from one currency to another
+func (provider *StaticFXRateProvider) GetRate(ctx context.Context, from string, to string) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+
+	if rate, ok := provider.rates[from+"/"+to]; ok {
+		return new(big.Rat).Set(rate), nil
+	}
+
+	if rate, ok := provider.rates[to+"/"+from]; ok {
+		return new(big.Rat).Inv(rate), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s/%s", ErrFXRateNotFound, from, to)
+}
+
+// ConvertAmount converts an amount in minor units with the given exchange rate.
+// The rate is rounded to FXRatePrecision decimal places first and returned as a decimal string,
+// so that the applied rate can be recorded next to the converted amount.
+// The converted amount is rounded half away from zero.
+func ConvertAmount(amount int64, rate *big.Rat) (converted int64, appliedRate string) {
+	appliedRate = rate.FloatString(FXRatePrecision)
+	rounded, _ := new(big.Rat).SetString(appliedRate)
+
+	value := new(big.Rat).Mul(big.NewRat(amount, 1), rounded)
+
+	// round half away from zero: (2 * num + den) / (2 * den), with the sign applied after
+	num := new(big.Int).Abs(value.Num())
+	den := value.Denom()
+	num.Mul(num, big.NewInt(2)).Add(num, den)
+	num.Quo(num, new(big.Int).Mul(den, big.NewInt(2)))
+
+	converted = num.Int64()
+	if value.Sign() < 0 {
+		converted = -converted
+	}
+	return
+}
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticFXRateProvider(t *testing.T) {
+	provider, err := NewStaticFXRateProvider(map[string]string{
+		"USD/EUR": "0.8",
+	})
+	require.NoError(t, err)
+
+	rate, err := provider.GetRate(context.Background(), USD, EUR)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(4, 5), rate)
+
+	rate, err = provider.GetRate(context.Background(), EUR, USD)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(5, 4), rate)
+
+	rate, err = provider.GetRate(context.Background(), CAD, CAD)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(1, 1), rate)
+
+	_, err = provider.GetRate(context.Background(), USD, CAD)
+	require.ErrorIs(t, err, ErrFXRateNotFound)
+}
+
+func TestNewStaticFXRateProviderInvalid(t *testing.T) {
+	_, err := NewStaticFXRateProvider(map[string]string{"USD-EUR": "0.8"})
+	require.Error(t, err)
+
+	_, err = NewStaticFXRateProvider(map[string]string{"USD/XYZ": "0.8"})
+	require.Error(t, err)
+
+	_, err = NewStaticFXRateProvider(map[string]string{"USD/EUR": "-1"})
+	require.Error(t, err)
+}
+
+func TestLoadStaticFXRateProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	err := os.WriteFile(path, []byte(`{"USD/GBP": "0.75"}`), 0600)
+	require.NoError(t, err)
+
+	provider, err := LoadStaticFXRateProvider(path)
+	require.NoError(t, err)
+
+	rate, err := provider.GetRate(context.Background(), USD, GBP)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(3, 4), rate)
+
+	provider, err = LoadStaticFXRateProvider("")
+	require.NoError(t, err)
+
+	_, err = provider.GetRate(context.Background(), USD, GBP)
+	require.ErrorIs(t, err, ErrFXRateNotFound)
+}
+
+func TestConvertAmount(t *testing.T) {
+	converted, appliedRate := ConvertAmount(1000, big.NewRat(4, 5))
+	require.Equal(t, int64(800), converted)
+	require.Equal(t, "0.80000000", appliedRate)
+
+	// the rate is rounded before it is applied
+	converted, appliedRate = ConvertAmount(300000000, big.NewRat(1, 3))
+	require.Equal(t, int64(99999999), converted)
+	require.Equal(t, "0.33333333", appliedRate)
+
+	// the amount is rounded half away from zero
+	converted, _ = ConvertAmount(5, big.NewRat(1, 2))
+	require.Equal(t, int64(3), converted)
+
+	converted, _ = ConvertAmount(-5, big.NewRat(1, 2))
+	require.Equal(t, int64(-3), converted)
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrInvalidPageToken is returned when a page token cannot be decoded
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// PageToken is the position of the last row of a page in a list ordered by (created_at, id).
+// Clients receive it as an opaque string and send it back to get the next page.
+type PageToken struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        int64     `json:"id"`
+	// Ascending is the sort order of the list the token was issued for
+	Ascending bool `json:"ascending,omitempty"`
+}
+
+// EncodePageToken returns the opaque string form of the token
+func EncodePageToken(token PageToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodePageToken parses a token returned by EncodePageToken.
+// It fails with ErrInvalidPageToken if the token is malformed or was issued for the other sort order.
+func DecodePageToken(value string, ascending bool) (PageToken, error) {
+	var token PageToken
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return token, ErrInvalidPageToken
+	}
+
+	if err := json.Unmarshal(data, &token); err != nil || token.ID <= 0 || token.Ascending != ascending {
+		return token, ErrInvalidPageToken
+	}
+
+	return token, nil
+}
+
+// PageCursor returns the position a page of a list ordered by (created_at, id) starts after.
+// Without a page token, that is just outside the [from, to] time range in the direction of the sort order.
+func PageCursor(pageToken string, ascending bool, from time.Time, to time.Time) (PageToken, error) {
+	if pageToken != "" {
+		return DecodePageToken(pageToken, ascending)
+	}
+
+	if ascending {
+		return PageToken{CreatedAt: from, ID: 0, Ascending: true}, nil
+	}
+	return PageToken{CreatedAt: to, ID: math.MaxInt64}, nil
+}
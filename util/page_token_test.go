@@ -0,0 +1,54 @@
+package util
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageToken(t *testing.T) {
+	token := PageToken{
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		ID:        RandomInt(1, 1000),
+		Ascending: true,
+	}
+
+	value := EncodePageToken(token)
+	require.NotEmpty(t, value)
+
+	decoded, err := DecodePageToken(value, true)
+	require.NoError(t, err)
+	require.True(t, token.CreatedAt.Equal(decoded.CreatedAt))
+	require.Equal(t, token.ID, decoded.ID)
+
+	_, err = DecodePageToken(value, false)
+	require.ErrorIs(t, err, ErrInvalidPageToken)
+
+	_, err = DecodePageToken("not a token", true)
+	require.ErrorIs(t, err, ErrInvalidPageToken)
+
+	_, err = DecodePageToken(EncodePageToken(PageToken{}), false)
+	require.ErrorIs(t, err, ErrInvalidPageToken)
+}
+
+func TestPageCursor(t *testing.T) {
+	from := time.Now().Add(-time.Hour).UTC()
+	to := time.Now().UTC()
+
+	cursor, err := PageCursor("", true, from, to)
+	require.NoError(t, err)
+	require.Equal(t, from, cursor.CreatedAt)
+	require.Zero(t, cursor.ID)
+
+	cursor, err = PageCursor("", false, from, to)
+	require.NoError(t, err)
+	require.Equal(t, to, cursor.CreatedAt)
+	require.Equal(t, int64(math.MaxInt64), cursor.ID)
+
+	token := PageToken{CreatedAt: from.Add(time.Minute), ID: 42}
+	cursor, err = PageCursor(EncodePageToken(token), false, from, to)
+	require.NoError(t, err)
+	require.Equal(t, token.ID, cursor.ID)
+}
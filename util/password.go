@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"sync"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -19,3 +20,17 @@ func HashPassword(password string) (string, error) {
 func CheckPassword(password string, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+var (
+	fakeHashOnce sync.Once
+	fakeHash     []byte
+)
+
+// FakeCheckPassword spends as long as CheckPassword does against a real hash,
+// so a login for an unknown username cannot be told apart by its timing
+func FakeCheckPassword(password string) {
+	fakeHashOnce.Do(func() {
+		fakeHash, _ = bcrypt.GenerateFromPassword([]byte(RandomString(16)), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(fakeHash, []byte(password))
+}
@@ -0,0 +1,15 @@
+package util
+
+import "time"
+
+// DefaultPasswordResetRequestCooldown is how long a user waits between two password reset emails
+// when no cooldown is configured
+const DefaultPasswordResetRequestCooldown = time.Minute
+
+// PasswordResetCooldown returns how long a user waits between two password reset emails
+func (config Config) PasswordResetCooldown() time.Duration {
+	if config.PasswordResetRequestCooldown <= 0 {
+		return DefaultPasswordResetRequestCooldown
+	}
+	return config.PasswordResetRequestCooldown
+}
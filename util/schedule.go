@@ -0,0 +1,93 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Constants for all supported recurrences of a scheduled transfer
+const (
+	RecurrenceOnce     = "once"
+	RecurrenceInterval = "interval"
+	RecurrenceCron     = "cron"
+)
+
+// MinScheduleInterval is the shortest interval between two runs of a recurring schedule.
+// Due schedules are picked up once a minute, so shorter intervals could not be honored.
+const MinScheduleInterval = time.Minute
+
+// IsSupportedRecurrence returns true if the recurrence is supported
+func IsSupportedRecurrence(recurrence string) bool {
+	switch recurrence {
+	case RecurrenceOnce, RecurrenceInterval, RecurrenceCron:
+		return true
+	}
+	return false
+}
+
+// ValidateRecurrence checks that the settings a recurrence relies on are present and valid
+func ValidateRecurrence(recurrence string, cronExpression string, interval time.Duration) error {
+	switch recurrence {
+	case RecurrenceOnce:
+		return nil
+	case RecurrenceInterval:
+		if interval < MinScheduleInterval {
+			return fmt.Errorf("interval must be at least %s", MinScheduleInterval)
+		}
+		return nil
+	case RecurrenceCron:
+		if _, err := cron.ParseStandard(cronExpression); err != nil {
+			return fmt.Errorf("invalid cron expression: %w", err)
+		}
+		return nil
+	}
+	return errors.New("unsupported recurrence")
+}
+
+// FirstRunTime returns when a schedule starting at the given time runs for the first time.
+// Cron schedules first run at the earliest matching time at or after the start.
+func FirstRunTime(recurrence string, cronExpression string, start time.Time) (time.Time, error) {
+	if recurrence != RecurrenceCron {
+		return start, nil
+	}
+
+	schedule, err := cron.ParseStandard(cronExpression)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cron expression: %w", err)
+	}
+	return schedule.Next(start.Add(-time.Second)), nil
+}
+
+// NextRunTime returns the first run of a recurring schedule after now, following its previous run.
+// Runs missed while the schedule was not processed are skipped rather than caught up.
+// It returns false if the schedule doesn't recur.
+func NextRunTime(recurrence string, cronExpression string, interval time.Duration, previous time.Time, now time.Time) (time.Time, bool) {
+	switch recurrence {
+	case RecurrenceInterval:
+		if interval <= 0 {
+			return time.Time{}, false
+		}
+
+		next := previous.Add(interval)
+		if !next.After(now) {
+			missed := now.Sub(previous) / interval
+			next = previous.Add((missed + 1) * interval)
+		}
+		return next, true
+	case RecurrenceCron:
+		schedule, err := cron.ParseStandard(cronExpression)
+		if err != nil {
+			return time.Time{}, false
+		}
+
+		after := previous
+		if now.After(after) {
+			after = now
+		}
+		return schedule.Next(after), true
+	}
+	return time.Time{}, false
+}
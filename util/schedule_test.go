@@ -0,0 +1,55 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRecurrence(t *testing.T) {
+	require.NoError(t, ValidateRecurrence(RecurrenceOnce, "", 0))
+	require.NoError(t, ValidateRecurrence(RecurrenceInterval, "", time.Hour))
+	require.NoError(t, ValidateRecurrence(RecurrenceCron, "0 9 1 * *", 0))
+
+	require.Error(t, ValidateRecurrence(RecurrenceInterval, "", time.Second))
+	require.Error(t, ValidateRecurrence(RecurrenceCron, "every day", 0))
+	require.Error(t, ValidateRecurrence("weekly", "", 0))
+}
+
+func TestFirstRunTime(t *testing.T) {
+	start := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	first, err := FirstRunTime(RecurrenceInterval, "", start)
+	require.NoError(t, err)
+	require.Equal(t, start, first)
+
+	first, err = FirstRunTime(RecurrenceCron, "0 9 1 * *", start)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC), first)
+
+	// a start that matches the cron expression is the first run
+	first, err = FirstRunTime(RecurrenceCron, "0 12 * * *", start)
+	require.NoError(t, err)
+	require.Equal(t, start, first)
+}
+
+func TestNextRunTime(t *testing.T) {
+	previous := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	_, ok := NextRunTime(RecurrenceOnce, "", 0, previous, previous)
+	require.False(t, ok)
+
+	next, ok := NextRunTime(RecurrenceInterval, "", time.Hour, previous, previous.Add(time.Minute))
+	require.True(t, ok)
+	require.Equal(t, previous.Add(time.Hour), next)
+
+	// missed runs are skipped
+	next, ok = NextRunTime(RecurrenceInterval, "", time.Hour, previous, previous.Add(150*time.Minute))
+	require.True(t, ok)
+	require.Equal(t, previous.Add(3*time.Hour), next)
+
+	next, ok = NextRunTime(RecurrenceCron, "0 9 1 * *", 0, previous, previous.Add(time.Minute))
+	require.True(t, ok)
+	require.Equal(t, time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC), next)
+}
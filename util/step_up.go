@@ -0,0 +1,69 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// DefaultPendingTransferDuration is how long a transfer waits for its second factor
+// when no duration is configured
+const DefaultPendingTransferDuration = 10 * time.Minute
+
+// MaxStepUpAttempts is the number of codes that can be tried for a pending transfer
+const MaxStepUpAttempts = 5
+
+// ErrStepUpNotScheduled is returned when a scheduled transfer would move an amount needing a fresh second factor,
+// which nobody is there to give when it runs
+var ErrStepUpNotScheduled = errors.New("amount requires a second factor and cannot be scheduled")
+
+// RequiresStepUp tells whether a transfer of amount needs a fresh second factor.
+// Step-up authentication is disabled when no threshold is configured.
+//
+// The amount is compared as it is, in the currency of the from account: the threshold is
+// the same number for every currency, so it is worth more in a stronger one.
+func (config Config) RequiresStepUp(amount int64) bool {
+	return config.StepUpTransferThreshold > 0 && amount > config.StepUpTransferThreshold
+}
+
+// PendingTransferTTL returns how long a transfer waits for its second factor
+func (config Config) PendingTransferTTL() time.Duration {
+	if config.PendingTransferDuration <= 0 {
+		return DefaultPendingTransferDuration
+	}
+	return config.PendingTransferDuration
+}
+
+// RandomOTPCode generates a numeric one-time code to be sent by email
+func RandomOTPCode() (string, error) {
+	max := big.NewInt(1_000_000)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, n.Int64()), nil
+}
+
+// RandomSecretCode generates a secret code of n lowercase letters to be sent by email
+func RandomSecretCode(n int) (string, error) {
+	max := big.NewInt(int64(len(alphabet)))
+	code := make([]byte, n)
+	for i := range code {
+		k, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate code: %w", err)
+		}
+		code[i] = alphabet[k.Int64()]
+	}
+	return string(code), nil
+}
+
+// HashOTPCode returns the hash an emailed code is stored as
+func HashOTPCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiresStepUp(t *testing.T) {
+	config := Config{}
+	require.False(t, config.RequiresStepUp(1_000_000))
+
+	config.StepUpTransferThreshold = 1000
+	require.False(t, config.RequiresStepUp(1000))
+	require.True(t, config.RequiresStepUp(1001))
+}
+
+func TestPendingTransferTTL(t *testing.T) {
+	config := Config{}
+	require.Equal(t, DefaultPendingTransferDuration, config.PendingTransferTTL())
+
+	config.PendingTransferDuration = time.Minute
+	require.Equal(t, time.Minute, config.PendingTransferTTL())
+}
+
+func TestRandomOTPCode(t *testing.T) {
+	code, err := RandomOTPCode()
+	require.NoError(t, err)
+	require.True(t, IsTOTPCode(code))
+	require.Len(t, HashOTPCode(code), 64)
+}
+
+func TestRandomSecretCode(t *testing.T) {
+	code, err := RandomSecretCode(32)
+	require.NoError(t, err)
+	require.Len(t, code, 32)
+	require.Empty(t, strings.Trim(code, alphabet))
+
+	other, err := RandomSecretCode(32)
+	require.NoError(t, err)
+	require.NotEqual(t, code, other)
+}
@@ -0,0 +1,15 @@
+package util
+
+import "time"
+
+// DefaultVerifyEmailResendCooldown is how long a user waits between two verification emails
+// when no cooldown is configured
+const DefaultVerifyEmailResendCooldown = time.Minute
+
+// VerifyEmailCooldown returns how long a user waits between two verification emails
+func (config Config) VerifyEmailCooldown() time.Duration {
+	if config.VerifyEmailResendCooldown <= 0 {
+		return DefaultVerifyEmailResendCooldown
+	}
+	return config.VerifyEmailResendCooldown
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/mail"
 	"regexp"
+
+	"github.com/Ian-Balijawa/simplebank/util"
 )
 
 var (
@@ -43,6 +45,10 @@ func ValidatePassword(value string) error {
 	return ValidateString(value, 6, 100)
 }
 
+func ValidateOTPCode(value string) error {
+	return ValidateString(value, util.TOTPDigits, 20)
+}
+
 func ValidateEmail(value string) error {
 	if err := ValidateString(value, 3, 200); err != nil {
 		return err
@@ -63,3 +69,17 @@ func ValidateEmailId(value int64) error {
 func ValidateSecretCode(value string) error {
 	return ValidateString(value, 32, 128)
 }
+
+func ValidateCurrency(value string) error {
+	if !util.IsSupportedCurrency(value) {
+		return fmt.Errorf("is not a supported currency")
+	}
+	return nil
+}
+
+func ValidateRole(value string) error {
+	if !util.IsSupportedRole(value) {
+		return fmt.Errorf("is not a supported role")
+	}
+	return nil
+}
@@ -17,6 +17,26 @@ type TaskDistributor interface {
 		payload *PayloadAccountAlert,
 		opts ...asynq.Option,
 	) error
+	DistributeTaskSendStatement(
+		ctx context.Context,
+		payload *PayloadSendStatement,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskSendPasswordReset(
+		ctx context.Context,
+		payload *PayloadSendPasswordReset,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskSendTransferCode(
+		ctx context.Context,
+		payload *PayloadSendTransferCode,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskSendLoginLockout(
+		ctx context.Context,
+		payload *PayloadSendLoginLockout,
+		opts ...asynq.Option,
+	) error
 }
 
 type RedisTaskDistributor struct {
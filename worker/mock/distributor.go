@@ -6,11 +6,10 @@ package mockwk
 
 import (
 	context "context"
-	reflect "reflect"
-
 	worker "github.com/Ian-Balijawa/simplebank/worker"
 	gomock "github.com/golang/mock/gomock"
 	asynq "github.com/hibiken/asynq"
+	reflect "reflect"
 )
 
 // MockTaskDistributor is a mock of TaskDistributor interface
@@ -36,40 +35,116 @@ func (m *MockTaskDistributor) EXPECT() *MockTaskDistributorMockRecorder {
 	return m.recorder
 }
 
-// DistributeTaskSendVerifyEmail mocks base method
-func (m *MockTaskDistributor) DistributeTaskSendVerifyEmail(arg0 context.Context, arg1 *worker.PayloadSendVerifyEmail, arg2 ...asynq.Option) error {
+// DistributeTaskSendAccountAlert mocks base method
+func (m *MockTaskDistributor) DistributeTaskSendAccountAlert(arg0 context.Context, arg1 *worker.PayloadAccountAlert, arg2 ...asynq.Option) error {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{arg0, arg1}
 	for _, a := range arg2 {
 		varargs = append(varargs, a)
 	}
-	ret := m.ctrl.Call(m, "DistributeTaskSendVerifyEmail", varargs...)
+	ret := m.ctrl.Call(m, "DistributeTaskSendAccountAlert", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DistributeTaskSendVerifyEmail indicates an expected call of DistributeTaskSendVerifyEmail
-func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendVerifyEmail(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+// DistributeTaskSendAccountAlert indicates an expected call of DistributeTaskSendAccountAlert
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendAccountAlert(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	varargs := append([]interface{}{arg0, arg1}, arg2...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendVerifyEmail", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendVerifyEmail), varargs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendAccountAlert", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendAccountAlert), varargs...)
 }
 
-// DistributeTaskSendAccountAlert mocks base method
-func (m *MockTaskDistributor) DistributeTaskSendAccountAlert(arg0 context.Context, arg1 *worker.PayloadAccountAlert, arg2 ...asynq.Option) error {
+// DistributeTaskSendLoginLockout mocks base method
+func (m *MockTaskDistributor) DistributeTaskSendLoginLockout(arg0 context.Context, arg1 *worker.PayloadSendLoginLockout, arg2 ...asynq.Option) error {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{arg0, arg1}
 	for _, a := range arg2 {
 		varargs = append(varargs, a)
 	}
-	ret := m.ctrl.Call(m, "DistributeTaskSendAccountAlert", varargs...)
+	ret := m.ctrl.Call(m, "DistributeTaskSendLoginLockout", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DistributeTaskSendAccountAlert indicates an expected call of DistributeTaskSendAccountAlert
-func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendAccountAlert(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+// DistributeTaskSendLoginLockout indicates an expected call of DistributeTaskSendLoginLockout
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendLoginLockout(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	varargs := append([]interface{}{arg0, arg1}, arg2...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendAccountAlert", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendAccountAlert), varargs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendLoginLockout", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendLoginLockout), varargs...)
+}
+
+// DistributeTaskSendPasswordReset mocks base method
+func (m *MockTaskDistributor) DistributeTaskSendPasswordReset(arg0 context.Context, arg1 *worker.PayloadSendPasswordReset, arg2 ...asynq.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskSendPasswordReset", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskSendPasswordReset indicates an expected call of DistributeTaskSendPasswordReset
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendPasswordReset(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendPasswordReset", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendPasswordReset), varargs...)
+}
+
+// DistributeTaskSendStatement mocks base method
+func (m *MockTaskDistributor) DistributeTaskSendStatement(arg0 context.Context, arg1 *worker.PayloadSendStatement, arg2 ...asynq.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskSendStatement", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskSendStatement indicates an expected call of DistributeTaskSendStatement
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendStatement(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendStatement", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendStatement), varargs...)
+}
+
+// DistributeTaskSendTransferCode mocks base method
+func (m *MockTaskDistributor) DistributeTaskSendTransferCode(arg0 context.Context, arg1 *worker.PayloadSendTransferCode, arg2 ...asynq.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskSendTransferCode", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskSendTransferCode indicates an expected call of DistributeTaskSendTransferCode
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendTransferCode(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendTransferCode", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendTransferCode), varargs...)
+}
+
+// DistributeTaskSendVerifyEmail mocks base method
+func (m *MockTaskDistributor) DistributeTaskSendVerifyEmail(arg0 context.Context, arg1 *worker.PayloadSendVerifyEmail, arg2 ...asynq.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskSendVerifyEmail", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskSendVerifyEmail indicates an expected call of DistributeTaskSendVerifyEmail
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendVerifyEmail(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendVerifyEmail", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendVerifyEmail), varargs...)
 }
@@ -5,6 +5,7 @@ import (
 
 	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
 	"github.com/Ian-Balijawa/simplebank/mail"
+	"github.com/Ian-Balijawa/simplebank/util"
 	"github.com/go-redis/redis/v8"
 	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog/log"
@@ -20,15 +21,25 @@ type TaskProcessor interface {
 	Shutdown()
 	ProcessTaskSendVerifyEmail(ctx context.Context, task *asynq.Task) error
 	ProcessTaskSendAccountAlert(ctx context.Context, task *asynq.Task) error
+	ProcessTaskExecuteScheduledTransfers(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendStatement(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendMonthlyStatements(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendPasswordReset(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendTransferCode(ctx context.Context, task *asynq.Task) error
+	ProcessTaskExpirePendingTransfers(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendLoginLockout(ctx context.Context, task *asynq.Task) error
+	ProcessTaskVerifyLedger(ctx context.Context, task *asynq.Task) error
 }
 
 type RedisTaskProcessor struct {
-	server *asynq.Server
-	store  db.Store
-	mailer mail.EmailSender
+	server      *asynq.Server
+	store       db.Store
+	mailer      mail.EmailSender
+	distributor TaskDistributor
+	config      util.Config
 }
 
-func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt, store db.Store, mailer mail.EmailSender) TaskProcessor {
+func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt, store db.Store, mailer mail.EmailSender, config util.Config) TaskProcessor {
 	logger := NewLogger()
 	redis.SetLogger(logger)
 
@@ -48,9 +59,11 @@ func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt, store db.Store, mailer
 	)
 
 	return &RedisTaskProcessor{
-		server: server,
-		store:  store,
-		mailer: mailer,
+		server:      server,
+		store:       store,
+		mailer:      mailer,
+		distributor: NewRedisTaskDistributor(redisOpt),
+		config:      config,
 	}
 }
 
@@ -59,6 +72,14 @@ func (processor *RedisTaskProcessor) Start() error {
 
 	mux.HandleFunc(TaskSendVerifyEmail, processor.ProcessTaskSendVerifyEmail)
 	mux.HandleFunc(TaskSendAccountAlert, processor.ProcessTaskSendAccountAlert)
+	mux.HandleFunc(TaskExecuteScheduledTransfers, processor.ProcessTaskExecuteScheduledTransfers)
+	mux.HandleFunc(TaskSendStatement, processor.ProcessTaskSendStatement)
+	mux.HandleFunc(TaskSendMonthlyStatements, processor.ProcessTaskSendMonthlyStatements)
+	mux.HandleFunc(TaskSendPasswordReset, processor.ProcessTaskSendPasswordReset)
+	mux.HandleFunc(TaskSendTransferCode, processor.ProcessTaskSendTransferCode)
+	mux.HandleFunc(TaskExpirePendingTransfers, processor.ProcessTaskExpirePendingTransfers)
+	mux.HandleFunc(TaskSendLoginLockout, processor.ProcessTaskSendLoginLockout)
+	mux.HandleFunc(TaskVerifyLedger, processor.ProcessTaskVerifyLedger)
 
 	return processor.server.Start(mux)
 }
@@ -0,0 +1,87 @@
+package worker
+
+import (
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// TaskScheduler enqueues periodic tasks
+type TaskScheduler interface {
+	Start() error
+	Shutdown()
+}
+
+type RedisTaskScheduler struct {
+	scheduler *asynq.Scheduler
+}
+
+func NewRedisTaskScheduler(redisOpt asynq.RedisClientOpt) TaskScheduler {
+	scheduler := asynq.NewScheduler(redisOpt, &asynq.SchedulerOpts{
+		Logger: NewLogger(),
+	})
+
+	return &RedisTaskScheduler{
+		scheduler: scheduler,
+	}
+}
+
+func (scheduler *RedisTaskScheduler) Start() error {
+	// the task is unique for a minute so overlapping runs never process the same schedules twice,
+	// and it isn't retried because the next run picks up whatever is still due
+	task := asynq.NewTask(TaskExecuteScheduledTransfers, nil)
+	_, err := scheduler.scheduler.Register(
+		ScheduledTransfersInterval,
+		task,
+		asynq.Queue(QueueCritical),
+		asynq.Unique(time.Minute),
+		asynq.MaxRetry(0),
+	)
+	if err != nil {
+		return err
+	}
+
+	// the statement emails are enqueued with their own task IDs, so a retry does not send them twice
+	task = asynq.NewTask(TaskSendMonthlyStatements, nil)
+	_, err = scheduler.scheduler.Register(
+		MonthlyStatementsSchedule,
+		task,
+		asynq.Queue(QueueDefault),
+		asynq.Unique(time.Hour),
+	)
+	if err != nil {
+		return err
+	}
+
+	// expiring is idempotent, so a missed run is simply caught up by the next one
+	task = asynq.NewTask(TaskExpirePendingTransfers, nil)
+	_, err = scheduler.scheduler.Register(
+		PendingTransfersExpiryInterval,
+		task,
+		asynq.Queue(QueueDefault),
+		asynq.Unique(time.Minute),
+		asynq.MaxRetry(0),
+	)
+	if err != nil {
+		return err
+	}
+
+	// the check only reads the ledger, so a failed run is left to the next night
+	task = asynq.NewTask(TaskVerifyLedger, nil)
+	_, err = scheduler.scheduler.Register(
+		LedgerVerificationSchedule,
+		task,
+		asynq.Queue(QueueDefault),
+		asynq.Unique(time.Hour),
+		asynq.MaxRetry(0),
+	)
+	if err != nil {
+		return err
+	}
+
+	return scheduler.scheduler.Start()
+}
+
+func (scheduler *RedisTaskScheduler) Shutdown() {
+	scheduler.scheduler.Shutdown()
+}
@@ -0,0 +1,117 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TaskExecuteScheduledTransfers = "task:execute_scheduled_transfers"
+
+	// ScheduledTransfersInterval is how often due scheduled transfers are picked up
+	ScheduledTransfersInterval = "@every 1m"
+
+	scheduledTransfersBatchSize = 100
+)
+
+func (processor *RedisTaskProcessor) ProcessTaskExecuteScheduledTransfers(ctx context.Context, task *asynq.Task) error {
+	now := time.Now()
+
+	schedules, err := processor.store.ListDueScheduledTransfers(ctx, db.ListDueScheduledTransfersParams{
+		NextRunAt: now,
+		Limit:     scheduledTransfersBatchSize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list due scheduled transfers: %w", err)
+	}
+
+	for _, schedule := range schedules {
+		// a schedule that fails unexpectedly stays due and is retried on the next run of the task
+		err := processor.executeScheduledTransfer(ctx, schedule, now)
+		if err != nil {
+			log.Error().Err(err).Int64("scheduled_transfer_id", schedule.ID).Msg("failed to execute scheduled transfer")
+		}
+	}
+
+	log.Info().Str("type", task.Type()).Int("count", len(schedules)).Msg("processed task")
+	return nil
+}
+
+func (processor *RedisTaskProcessor) executeScheduledTransfer(ctx context.Context, schedule db.ScheduledTransfer, now time.Time) error {
+	arg := db.RecordScheduledTransferRunTxParams{
+		ScheduledTransfer: schedule,
+	}
+
+	// a schedule made before the step-up threshold was lowered fails its runs instead of skipping the second factor
+	if processor.config.RequiresStepUp(schedule.Amount) {
+		arg.Error = util.ErrStepUpNotScheduled.Error()
+	} else {
+		// the idempotency key makes a retried run replay the transfer instead of moving money twice
+		result, err := processor.store.TransferTx(ctx, db.TransferTxParams{
+			FromAccountID:     schedule.FromAccountID,
+			ToAccountID:       schedule.ToAccountID,
+			Amount:            schedule.Amount,
+			EnforceDailyLimit: true,
+			Idempotency: &db.IdempotencyParams{
+				Username: schedule.Owner,
+				Key:      fmt.Sprintf("scheduled-transfer:%d:%d", schedule.ID, schedule.NextRunAt.Unix()),
+			},
+		})
+		if err != nil {
+			if !isScheduledTransferFailure(err) {
+				return fmt.Errorf("failed to transfer money: %w", err)
+			}
+			arg.Error = err.Error()
+		} else {
+			arg.TransferID = result.Transfer.ID
+		}
+	}
+
+	arg.NextRunAt, arg.Finished = nextScheduledRun(schedule, now)
+
+	_, err := processor.store.RecordScheduledTransferRunTx(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			// the run was already recorded, or the schedule was cancelled meanwhile
+			return nil
+		}
+		return fmt.Errorf("failed to record scheduled transfer run: %w", err)
+	}
+
+	return nil
+}
+
+// isScheduledTransferFailure reports whether a transfer error is a failure of the run itself,
+// which is recorded, rather than a temporary problem worth retrying
+func isScheduledTransferFailure(err error) bool {
+	return db.IsTransferRejected(err) ||
+		errors.Is(err, db.ErrIdempotencyKeyConflict) ||
+		db.ErrorCode(err) == db.ForeignKeyViolation
+}
+
+// nextScheduledRun returns when the schedule runs after its current run,
+// or true if the current run is its last one
+func nextScheduledRun(schedule db.ScheduledTransfer, now time.Time) (time.Time, bool) {
+	if schedule.MaxOccurrences.Valid && schedule.Occurrences+1 >= schedule.MaxOccurrences.Int32 {
+		return time.Time{}, true
+	}
+
+	interval := time.Duration(schedule.IntervalSeconds.Int64) * time.Second
+	next, ok := util.NextRunTime(schedule.Recurrence, schedule.CronExpression.String, interval, schedule.NextRunAt, now)
+	if !ok {
+		return time.Time{}, true
+	}
+
+	if schedule.EndAt.Valid && next.After(schedule.EndAt.Time) {
+		return time.Time{}, true
+	}
+
+	return next, false
+}
@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TaskExpirePendingTransfers = "task:expire_pending_transfers"
+
+	// PendingTransfersExpiryInterval is how often unconfirmed pending transfers are expired
+	PendingTransfersExpiryInterval = "@every 1m"
+)
+
+func (processor *RedisTaskProcessor) ProcessTaskExpirePendingTransfers(ctx context.Context, task *asynq.Task) error {
+	expired, err := processor.store.ExpirePendingTransfers(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to expire pending transfers: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Int64("count", expired).Msg("processed task")
+	return nil
+}
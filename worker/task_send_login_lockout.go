@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskSendLoginLockout = "task:send_login_lockout"
+
+type PayloadSendLoginLockout struct {
+	Username    string    `json:"username"`
+	ClientIP    string    `json:"client_ip"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+func (distributor *RedisTaskDistributor) DistributeTaskSendLoginLockout(
+	ctx context.Context,
+	payload *PayloadSendLoginLockout,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	task := asynq.NewTask(TaskSendLoginLockout, jsonPayload, opts...)
+	info, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("queue", info.Queue).Int("max_retry", info.MaxRetry).Msg("enqueued task")
+	return nil
+}
+
+func (processor *RedisTaskProcessor) ProcessTaskSendLoginLockout(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendLoginLockout
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	user, err := processor.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	subject := "Your Simple Bank account is temporarily locked"
+	content := fmt.Sprintf(`Hello %s,<br/>
+	There were too many failed attempts to log in to your account, the last one from %s.<br/>
+	Logging in is blocked until %s.<br/>
+	If it was not you, we recommend you change your password once the lock is over.<br/>
+	`, user.FullName, payload.ClientIP, payload.LockedUntil.UTC().Format("2006-01-02 15:04 MST"))
+	to := []string{user.Email}
+
+	err = processor.mailer.SendEmail(subject, content, to, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send login lockout email: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("email", user.Email).Msg("processed task")
+	return nil
+}
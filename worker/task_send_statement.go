@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/statement"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TaskSendStatement         = "task:send_statement"
+	TaskSendMonthlyStatements = "task:send_monthly_statements"
+
+	// MonthlyStatementsSchedule sends the statements of the previous month at the start of each month
+	MonthlyStatementsSchedule = "0 2 1 * *"
+
+	monthlyStatementsBatchSize = 100
+)
+
+type PayloadSendStatement struct {
+	AccountID int64     `json:"account_id"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Format    string    `json:"format"`
+}
+
+func (distributor *RedisTaskDistributor) DistributeTaskSendStatement(
+	ctx context.Context,
+	payload *PayloadSendStatement,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	task := asynq.NewTask(TaskSendStatement, jsonPayload, opts...)
+	info, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("queue", info.Queue).Int("max_retry", info.MaxRetry).Msg("enqueued task")
+	return nil
+}
+
+func (processor *RedisTaskProcessor) ProcessTaskSendStatement(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendStatement
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	if !statement.IsSupportedFormat(payload.Format) {
+		return fmt.Errorf("unsupported statement format %s: %w", payload.Format, asynq.SkipRetry)
+	}
+
+	account, err := processor.store.GetAccount(ctx, payload.AccountID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return fmt.Errorf("account doesn't exist: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get account: %w", err)
+	}
+
+	user, err := processor.store.GetUser(ctx, account.Owner)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	accountStatement, err := statement.Generate(ctx, processor.store, account, payload.From, payload.To)
+	if err != nil {
+		return fmt.Errorf("failed to generate statement: %w", err)
+	}
+
+	// the statement is written under its own name because that is the name the attachment gets
+	dir, err := os.MkdirTemp("", "simplebank-statement-")
+	if err != nil {
+		return fmt.Errorf("failed to create statement directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, accountStatement.FileName(payload.Format))
+	if err := writeStatementFile(path, accountStatement, payload.Format); err != nil {
+		return err
+	}
+
+	subject := "Your Simple Bank account statement"
+	content := fmt.Sprintf(`Hello %s,<br/>
+Please find attached the statement of your account %d<br/>
+from %s to %s.<br/>
+`, user.FullName, account.ID, payload.From.UTC().Format(time.DateOnly), payload.To.UTC().Format(time.DateOnly))
+	to := []string{user.Email}
+
+	err = processor.mailer.SendEmail(subject, content, to, nil, nil, []string{path})
+	if err != nil {
+		return fmt.Errorf("failed to send statement email: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("email", user.Email).Msg("processed task")
+	return nil
+}
+
+func writeStatementFile(path string, accountStatement *statement.Statement, format string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create statement file: %w", err)
+	}
+	defer file.Close()
+
+	if err := accountStatement.Render(file, format); err != nil {
+		return fmt.Errorf("failed to render statement: %w", err)
+	}
+
+	return file.Close()
+}
+
+// ProcessTaskSendMonthlyStatements enqueues a statement email of the previous calendar month for every account
+func (processor *RedisTaskProcessor) ProcessTaskSendMonthlyStatements(ctx context.Context, task *asynq.Task) error {
+	from, to := previousMonth(time.Now())
+
+	count := 0
+	afterID := int64(0)
+	for {
+		accounts, err := processor.store.ListAllAccounts(ctx, db.ListAllAccountsParams{
+			ID:    afterID,
+			Limit: monthlyStatementsBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list accounts: %w", err)
+		}
+
+		for _, account := range accounts {
+			// the task ID makes a retry of this task skip statements that were already enqueued
+			err := processor.distributor.DistributeTaskSendStatement(ctx, &PayloadSendStatement{
+				AccountID: account.ID,
+				From:      from,
+				To:        to,
+				Format:    statement.FormatPDF,
+			}, asynq.TaskID(fmt.Sprintf("statement:%d:%s", account.ID, from.Format("2006-01"))))
+			if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+				return fmt.Errorf("failed to distribute statement task: %w", err)
+			}
+			count++
+		}
+
+		if len(accounts) < monthlyStatementsBatchSize {
+			break
+		}
+		afterID = accounts[len(accounts)-1].ID
+	}
+
+	log.Info().Str("type", task.Type()).Int("count", count).Msg("processed task")
+	return nil
+}
+
+// previousMonth returns the start and end of the calendar month before now, in UTC
+func previousMonth(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return to.AddDate(0, -1, 0), to
+}
@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	db "github.com/Ian-Balijawa/simplebank/db/sqlc"
+	"github.com/Ian-Balijawa/simplebank/util"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+const TaskSendTransferCode = "task:send_transfer_code"
+
+type PayloadSendTransferCode struct {
+	PendingTransferID int64 `json:"pending_transfer_id"`
+}
+
+func (distributor *RedisTaskDistributor) DistributeTaskSendTransferCode(
+	ctx context.Context,
+	payload *PayloadSendTransferCode,
+	opts ...asynq.Option,
+) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	task := asynq.NewTask(TaskSendTransferCode, jsonPayload, opts...)
+	info, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("queue", info.Queue).Int("max_retry", info.MaxRetry).Msg("enqueued task")
+	return nil
+}
+
+// ProcessTaskSendTransferCode emails the code that confirms a pending transfer.
+// The code is generated here so it never leaves the worker in plain text except in the email.
+func (processor *RedisTaskProcessor) ProcessTaskSendTransferCode(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendTransferCode
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	code, err := util.RandomOTPCode()
+	if err != nil {
+		return err
+	}
+
+	pendingTransfer, err := processor.store.SetPendingTransferCode(ctx, db.SetPendingTransferCodeParams{
+		ID:       payload.PendingTransferID,
+		CodeHash: pgtype.Text{String: util.HashOTPCode(code), Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			// the transfer was confirmed or expired before the code was sent
+			return fmt.Errorf("pending transfer is no longer pending: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to set pending transfer code: %w", err)
+	}
+
+	user, err := processor.store.GetUser(ctx, pendingTransfer.Owner)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	subject := "Simple Bank transfer confirmation code"
+	content := fmt.Sprintf(`Hello %s,<br/>
+Your code to confirm the transfer of %d %s from account %d to account %d is <b>%s</b>.<br/>
+It expires at %s. If you did not make this transfer, please change your password.<br/>
+`, user.FullName, pendingTransfer.Amount, pendingTransfer.Currency, pendingTransfer.FromAccountID,
+		pendingTransfer.ToAccountID, code, pendingTransfer.ExpiresAt.UTC().Format("2006-01-02 15:04 MST"))
+	to := []string{user.Email}
+
+	err = processor.mailer.SendEmail(subject, content, to, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to send transfer code: %w", err)
+	}
+
+	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+		Str("email", user.Email).Msg("processed task")
+	return nil
+}
@@ -49,6 +49,18 @@ func (processor *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Cont
 		return fmt.Errorf("failed to get user: %w", err)
 	}
 
+	if user.IsEmailVerified {
+		log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+			Msg("email is already verified")
+		return nil
+	}
+
+	// only the code of the last email can be used
+	err = processor.store.InvalidateVerifyEmails(ctx, user.Username)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate previous verify emails: %w", err)
+	}
+
 	verifyEmail, err := processor.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
 		Username:   user.Username,
 		Email:      user.Email,
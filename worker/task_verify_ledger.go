@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Ian-Balijawa/simplebank/ledger"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TaskVerifyLedger = "task:verify_ledger"
+
+	// LedgerVerificationSchedule checks the ledger every night, when few transfers are made
+	LedgerVerificationSchedule = "0 3 * * *"
+)
+
+func (processor *RedisTaskProcessor) ProcessTaskVerifyLedger(ctx context.Context, task *asynq.Task) error {
+	report, err := ledger.Verify(ctx, processor.store, ledger.DefaultBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to verify ledger: %w", err)
+	}
+
+	// an inconsistent ledger needs a human, running the task again would find the same
+	if !report.Consistent() {
+		log.Error().Str("type", task.Type()).Interface("report", report).Msg("ledger is inconsistent")
+		return nil
+	}
+
+	log.Info().Str("type", task.Type()).Int("accounts", report.CheckedAccounts).Msg("processed task")
+	return nil
+}